@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS borrow_records;
+DROP TABLE IF EXISTS authors;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS libraries;
+DROP TABLE IF EXISTS lecturers;
+DROP TABLE IF EXISTS students;
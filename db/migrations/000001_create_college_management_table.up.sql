@@ -1,5 +1,3 @@
-USE college_management_system;
-
 CREATE TABLE IF NOT EXISTS students(
     id INT AUTO_INCREMENT PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS enrollments;
+DROP TABLE IF EXISTS sections;
+DROP TABLE IF EXISTS course_prerequisites;
+DROP TABLE IF EXISTS courses;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS courses (
+    course_id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    title VARCHAR(100) NOT NULL,
+    credits INT NOT NULL,
+    dept VARCHAR(50) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS course_prerequisites (
+    course_id INT NOT NULL,
+    prerequisite_id INT NOT NULL,
+    PRIMARY KEY (course_id, prerequisite_id),
+    FOREIGN KEY (course_id) REFERENCES courses(course_id) ON DELETE CASCADE,
+    FOREIGN KEY (prerequisite_id) REFERENCES courses(course_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sections (
+    section_id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id INT NOT NULL,
+    term VARCHAR(20) NOT NULL,
+    capacity INT NOT NULL,
+    FOREIGN KEY (course_id) REFERENCES courses(course_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    enrollment_id INT AUTO_INCREMENT PRIMARY KEY,
+    section_id INT NOT NULL,
+    student_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    waitlist_position INT,
+    enrolled_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE KEY uq_enrollment (section_id, student_id),
+    INDEX idx_enrollment_status (section_id, status, waitlist_position),
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS section_instructors (
+    section_id INT NOT NULL,
+    lecturer_id CHAR(24) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    assigned_at DATETIME NOT NULL,
+    PRIMARY KEY (section_id, lecturer_id),
+    INDEX idx_instructor_lecturer (lecturer_id),
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS section_meetings;
+DROP TABLE IF EXISTS rooms;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS rooms (
+    room_id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    building VARCHAR(100) NOT NULL,
+    capacity INT NOT NULL,
+    features VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS section_meetings (
+    meeting_id INT AUTO_INCREMENT PRIMARY KEY,
+    section_id INT NOT NULL,
+    room_id INT NOT NULL,
+    day_of_week TINYINT NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    INDEX idx_meeting_room (room_id, day_of_week),
+    INDEX idx_meeting_day (day_of_week, start_time),
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE,
+    FOREIGN KEY (room_id) REFERENCES rooms(room_id)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS timetable_candidate_entries;
+DROP TABLE IF EXISTS timetable_candidates;
+DROP TABLE IF EXISTS timetable_jobs;
+DROP TABLE IF EXISTS lecturer_preferences;
+
+ALTER TABLE sections
+    DROP COLUMN meeting_minutes,
+    DROP COLUMN weekly_meetings;
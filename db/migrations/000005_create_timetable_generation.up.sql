@@ -0,0 +1,44 @@
+ALTER TABLE sections
+    ADD COLUMN weekly_meetings INT NOT NULL DEFAULT 2,
+    ADD COLUMN meeting_minutes INT NOT NULL DEFAULT 60;
+
+CREATE TABLE IF NOT EXISTS lecturer_preferences (
+    preference_id INT AUTO_INCREMENT PRIMARY KEY,
+    lecturer_id CHAR(24) NOT NULL,
+    day_of_week TINYINT NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    INDEX idx_preference_lecturer (lecturer_id)
+);
+
+CREATE TABLE IF NOT EXISTS timetable_jobs (
+    job_id INT AUTO_INCREMENT PRIMARY KEY,
+    term VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    progress INT NOT NULL DEFAULT 0,
+    message VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    finished_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS timetable_candidates (
+    candidate_id INT AUTO_INCREMENT PRIMARY KEY,
+    job_id INT NOT NULL,
+    penalty DOUBLE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (job_id) REFERENCES timetable_jobs(job_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS timetable_candidate_entries (
+    candidate_id INT NOT NULL,
+    section_id INT NOT NULL,
+    room_id INT NOT NULL,
+    day_of_week TINYINT NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    INDEX idx_candidate_entry (candidate_id),
+    FOREIGN KEY (candidate_id) REFERENCES timetable_candidates(candidate_id) ON DELETE CASCADE,
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE,
+    FOREIGN KEY (room_id) REFERENCES rooms(room_id)
+);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS term_locks;
+DROP TABLE IF EXISTS final_grades;
+DROP TABLE IF EXISTS assessment_scores;
+DROP TABLE IF EXISTS assessments;
+
+ALTER TABLE sections DROP FOREIGN KEY fk_sections_grade_scale,
+    DROP COLUMN grade_scale_id;
+
+DROP TABLE IF EXISTS grade_scale_bands;
+DROP TABLE IF EXISTS grade_scales;
+DROP TABLE IF EXISTS user_roles;
@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS user_roles (
+    email VARCHAR(100) NOT NULL,
+    role VARCHAR(30) NOT NULL,
+    PRIMARY KEY (email, role)
+);
+
+CREATE TABLE IF NOT EXISTS grade_scales (
+    scale_id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS grade_scale_bands (
+    scale_id INT NOT NULL,
+    letter VARCHAR(5) NOT NULL,
+    min_percent DECIMAL(5,2) NOT NULL,
+    grade_points DECIMAL(3,2) NOT NULL,
+    PRIMARY KEY (scale_id, letter),
+    FOREIGN KEY (scale_id) REFERENCES grade_scales(scale_id) ON DELETE CASCADE
+);
+
+ALTER TABLE sections ADD COLUMN grade_scale_id INT NULL,
+    ADD CONSTRAINT fk_sections_grade_scale FOREIGN KEY (grade_scale_id) REFERENCES grade_scales(scale_id);
+
+CREATE TABLE IF NOT EXISTS assessments (
+    assessment_id INT AUTO_INCREMENT PRIMARY KEY,
+    section_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    weight DECIMAL(5,2) NOT NULL,
+    max_score DECIMAL(6,2) NOT NULL,
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS assessment_scores (
+    assessment_id INT NOT NULL,
+    student_id INT NOT NULL,
+    score DECIMAL(6,2) NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (assessment_id, student_id),
+    FOREIGN KEY (assessment_id) REFERENCES assessments(assessment_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS final_grades (
+    section_id INT NOT NULL,
+    student_id INT NOT NULL,
+    percent DECIMAL(5,2) NOT NULL,
+    letter VARCHAR(5) NOT NULL,
+    grade_points DECIMAL(3,2) NOT NULL,
+    credits INT NOT NULL,
+    finalized_at DATETIME NOT NULL,
+    PRIMARY KEY (section_id, student_id),
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS term_locks (
+    term VARCHAR(20) PRIMARY KEY,
+    finalized_at DATETIME NOT NULL,
+    finalized_by VARCHAR(100) NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS transcripts (
+    verification_code CHAR(16) PRIMARY KEY,
+    student_id INT NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    signature CHAR(64) NOT NULL,
+    issued_at DATETIME NOT NULL,
+    issued_by VARCHAR(100) NOT NULL,
+    INDEX idx_transcript_student (student_id),
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS attendance_corrections;
+DROP TABLE IF EXISTS attendance;
+DROP TABLE IF EXISTS class_sessions;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS class_sessions (
+    session_id INT AUTO_INCREMENT PRIMARY KEY,
+    section_id INT NOT NULL,
+    meeting_id INT,
+    room_id INT NOT NULL,
+    session_date DATE NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    UNIQUE KEY uq_class_session (section_id, session_date, start_time),
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE,
+    FOREIGN KEY (meeting_id) REFERENCES section_meetings(meeting_id) ON DELETE SET NULL,
+    FOREIGN KEY (room_id) REFERENCES rooms(room_id)
+);
+
+CREATE TABLE IF NOT EXISTS attendance (
+    session_id INT NOT NULL,
+    student_id INT NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    marked_by VARCHAR(100) NOT NULL,
+    marked_at DATETIME NOT NULL,
+    PRIMARY KEY (session_id, student_id),
+    FOREIGN KEY (session_id) REFERENCES class_sessions(session_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS attendance_corrections (
+    correction_id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    student_id INT NOT NULL,
+    old_status VARCHAR(10) NOT NULL,
+    new_status VARCHAR(10) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    corrected_by VARCHAR(100) NOT NULL,
+    corrected_at DATETIME NOT NULL,
+    INDEX idx_correction_session (session_id, student_id),
+    FOREIGN KEY (session_id) REFERENCES class_sessions(session_id) ON DELETE CASCADE
+);
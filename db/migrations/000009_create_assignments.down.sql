@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS submission_grades;
+DROP TABLE IF EXISTS submissions;
+DROP TABLE IF EXISTS assignments;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS assignments (
+    assignment_id INT AUTO_INCREMENT PRIMARY KEY,
+    section_id INT NOT NULL,
+    title VARCHAR(150) NOT NULL,
+    description TEXT,
+    due_at DATETIME NOT NULL,
+    max_score DECIMAL(6,2) NOT NULL,
+    late_penalty_per_day DECIMAL(5,2) NOT NULL DEFAULT 0,
+    max_late_days INT NOT NULL DEFAULT 0,
+    created_by VARCHAR(100) NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS submissions (
+    submission_id INT AUTO_INCREMENT PRIMARY KEY,
+    assignment_id INT NOT NULL,
+    student_id INT NOT NULL,
+    version INT NOT NULL,
+    blob_key VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    submitted_at DATETIME NOT NULL,
+    late_days INT NOT NULL DEFAULT 0,
+    UNIQUE KEY uq_submission_version (assignment_id, student_id, version),
+    FOREIGN KEY (assignment_id) REFERENCES assignments(assignment_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS submission_grades (
+    submission_id INT PRIMARY KEY,
+    raw_score DECIMAL(6,2) NOT NULL,
+    penalty_percent DECIMAL(5,2) NOT NULL,
+    final_score DECIMAL(6,2) NOT NULL,
+    feedback TEXT,
+    graded_by VARCHAR(100) NOT NULL,
+    graded_at DATETIME NOT NULL,
+    FOREIGN KEY (submission_id) REFERENCES submissions(submission_id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS exam_invigilators;
+DROP TABLE IF EXISTS exam_seats;
+DROP TABLE IF EXISTS exams;
+DROP TABLE IF EXISTS exam_slots;
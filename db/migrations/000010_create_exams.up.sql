@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS exam_slots (
+    slot_id INT AUTO_INCREMENT PRIMARY KEY,
+    term VARCHAR(20) NOT NULL,
+    exam_date DATE NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    UNIQUE KEY uq_exam_slot (term, exam_date, start_time)
+);
+
+CREATE TABLE IF NOT EXISTS exams (
+    exam_id INT AUTO_INCREMENT PRIMARY KEY,
+    section_id INT NOT NULL UNIQUE,
+    slot_id INT NOT NULL,
+    scheduled_by VARCHAR(100) NOT NULL,
+    scheduled_at DATETIME NOT NULL,
+    FOREIGN KEY (section_id) REFERENCES sections(section_id) ON DELETE CASCADE,
+    FOREIGN KEY (slot_id) REFERENCES exam_slots(slot_id)
+);
+
+CREATE TABLE IF NOT EXISTS exam_seats (
+    slot_id INT NOT NULL,
+    room_id INT NOT NULL,
+    seat_row INT NOT NULL,
+    seat_col INT NOT NULL,
+    exam_id INT NOT NULL,
+    student_id INT NOT NULL,
+    PRIMARY KEY (slot_id, room_id, seat_row, seat_col),
+    UNIQUE KEY uq_exam_seat_student (exam_id, student_id),
+    FOREIGN KEY (slot_id) REFERENCES exam_slots(slot_id) ON DELETE CASCADE,
+    FOREIGN KEY (room_id) REFERENCES rooms(room_id),
+    FOREIGN KEY (exam_id) REFERENCES exams(exam_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS exam_invigilators (
+    slot_id INT NOT NULL,
+    room_id INT NOT NULL,
+    lecturer_id CHAR(24) NOT NULL,
+    PRIMARY KEY (slot_id, room_id, lecturer_id),
+    UNIQUE KEY uq_invigilator_slot (slot_id, lecturer_id),
+    FOREIGN KEY (slot_id) REFERENCES exam_slots(slot_id) ON DELETE CASCADE,
+    FOREIGN KEY (room_id) REFERENCES rooms(room_id)
+);
@@ -0,0 +1,83 @@
+CREATE TABLE IF NOT EXISTS fee_plans (
+    plan_id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    dept VARCHAR(50) NOT NULL,
+    program VARCHAR(50) NOT NULL DEFAULT '',
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_fee_plan_scope (dept, program)
+);
+
+CREATE TABLE IF NOT EXISTS fee_plan_items (
+    item_id INT AUTO_INCREMENT PRIMARY KEY,
+    plan_id INT NOT NULL,
+    description VARCHAR(150) NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    per_credit BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (plan_id) REFERENCES fee_plans(plan_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    invoice_id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    term VARCHAR(20) NOT NULL,
+    plan_id INT,
+    currency CHAR(3) NOT NULL,
+    total_cents BIGINT NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    issued_at DATETIME NOT NULL,
+    due_at DATETIME NOT NULL,
+    UNIQUE KEY uq_invoice_student_term (student_id, term),
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE,
+    FOREIGN KEY (plan_id) REFERENCES fee_plans(plan_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    line_id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    description VARCHAR(150) NOT NULL,
+    quantity INT NOT NULL,
+    unit_cents BIGINT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    student_id INT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    refunded_cents BIGINT NOT NULL DEFAULT 0,
+    gateway VARCHAR(30) NOT NULL,
+    gateway_ref VARCHAR(100),
+    status VARCHAR(10) NOT NULL,
+    failure_reason VARCHAR(255),
+    created_by VARCHAR(100) NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id),
+    FOREIGN KEY (student_id) REFERENCES students(id)
+);
+
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+    txn_id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    invoice_id INT,
+    kind VARCHAR(10) NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    memo VARCHAR(255) NOT NULL,
+    created_by VARCHAR(100) NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_ledger_student (student_id),
+    FOREIGN KEY (student_id) REFERENCES students(id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id)
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    entry_id INT AUTO_INCREMENT PRIMARY KEY,
+    txn_id INT NOT NULL,
+    account VARCHAR(30) NOT NULL,
+    debit_cents BIGINT NOT NULL DEFAULT 0,
+    credit_cents BIGINT NOT NULL DEFAULT 0,
+    INDEX idx_ledger_account (account),
+    FOREIGN KEY (txn_id) REFERENCES ledger_transactions(txn_id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS scholarship_awards;
+DROP TABLE IF EXISTS scholarship_applications;
+DROP TABLE IF EXISTS scholarships;
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS scholarships (
+    scholarship_id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    kind VARCHAR(10) NOT NULL,
+    amount_cents BIGINT NOT NULL DEFAULT 0,
+    percent DECIMAL(5,2) NOT NULL DEFAULT 0,
+    min_gpa DECIMAL(3,2),
+    dept VARCHAR(50),
+    max_income_band INT,
+    renewable BOOLEAN NOT NULL DEFAULT FALSE,
+    renewal_min_gpa DECIMAL(3,2),
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scholarship_applications (
+    application_id INT AUTO_INCREMENT PRIMARY KEY,
+    scholarship_id INT NOT NULL,
+    student_id INT NOT NULL,
+    term VARCHAR(20) NOT NULL,
+    income_band INT,
+    statement TEXT,
+    status VARCHAR(10) NOT NULL,
+    decision_note VARCHAR(255),
+    decided_by VARCHAR(100),
+    decided_at DATETIME,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_scholarship_student (scholarship_id, student_id),
+    FOREIGN KEY (scholarship_id) REFERENCES scholarships(scholarship_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scholarship_awards (
+    award_id INT AUTO_INCREMENT PRIMARY KEY,
+    application_id INT NOT NULL,
+    term VARCHAR(20) NOT NULL,
+    status VARCHAR(12) NOT NULL,
+    applied_cents BIGINT NOT NULL DEFAULT 0,
+    credit_cents BIGINT NOT NULL DEFAULT 0,
+    invoice_id INT,
+    note VARCHAR(255),
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_award_term (application_id, term),
+    FOREIGN KEY (application_id) REFERENCES scholarship_applications(application_id) ON DELETE CASCADE,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id)
+);
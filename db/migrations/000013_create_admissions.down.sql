@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS offer_letters;
+DROP TABLE IF EXISTS application_reviews;
+DROP TABLE IF EXISTS application_stage_history;
+DROP TABLE IF EXISTS application_documents;
+DROP TABLE IF EXISTS admission_applications;
+DROP TABLE IF EXISTS applicants;
@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS applicants (
+    applicant_id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    age INT NOT NULL,
+    email VARCHAR(100) NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS admission_applications (
+    application_id INT AUTO_INCREMENT PRIMARY KEY,
+    applicant_id INT NOT NULL,
+    term VARCHAR(20) NOT NULL,
+    dept VARCHAR(50) NOT NULL,
+    program VARCHAR(50) NOT NULL DEFAULT '',
+    form TEXT,
+    stage VARCHAR(15) NOT NULL,
+    student_id INT UNIQUE,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE KEY uq_application_term (applicant_id, term, dept, program),
+    INDEX idx_application_stage (stage),
+    FOREIGN KEY (applicant_id) REFERENCES applicants(applicant_id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS application_documents (
+    application_id INT NOT NULL,
+    doc_type VARCHAR(30) NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    blob_key VARCHAR(255),
+    filename VARCHAR(255),
+    note VARCHAR(255),
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (application_id, doc_type),
+    FOREIGN KEY (application_id) REFERENCES admission_applications(application_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS application_stage_history (
+    history_id INT AUTO_INCREMENT PRIMARY KEY,
+    application_id INT NOT NULL,
+    from_stage VARCHAR(15),
+    to_stage VARCHAR(15) NOT NULL,
+    note VARCHAR(255),
+    changed_by VARCHAR(100) NOT NULL,
+    changed_at DATETIME NOT NULL,
+    FOREIGN KEY (application_id) REFERENCES admission_applications(application_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS application_reviews (
+    review_id INT AUTO_INCREMENT PRIMARY KEY,
+    application_id INT NOT NULL,
+    reviewer VARCHAR(100) NOT NULL,
+    score INT NOT NULL,
+    comments TEXT,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_review_reviewer (application_id, reviewer),
+    FOREIGN KEY (application_id) REFERENCES admission_applications(application_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS offer_letters (
+    application_id INT PRIMARY KEY,
+    reference VARCHAR(20) NOT NULL UNIQUE,
+    conditions TEXT,
+    issued_by VARCHAR(100) NOT NULL,
+    issued_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME,
+    FOREIGN KEY (application_id) REFERENCES admission_applications(application_id) ON DELETE CASCADE
+);
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS leave_types (
+    leave_type VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    annual_days INT NOT NULL,
+    paid BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+INSERT IGNORE INTO leave_types (leave_type, name, annual_days, paid) VALUES
+    ('casual', 'Casual leave', 12, TRUE),
+    ('sick', 'Sick leave', 10, TRUE),
+    ('earned', 'Earned leave', 15, TRUE),
+    ('unpaid', 'Unpaid leave', 30, FALSE);
+
+CREATE TABLE IF NOT EXISTS leave_balances (
+    lecturer_id CHAR(24) NOT NULL,
+    leave_type VARCHAR(20) NOT NULL,
+    year INT NOT NULL,
+    entitled_days INT NOT NULL,
+    PRIMARY KEY (lecturer_id, leave_type, year),
+    FOREIGN KEY (leave_type) REFERENCES leave_types(leave_type) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS leave_requests (
+    leave_id INT AUTO_INCREMENT PRIMARY KEY,
+    lecturer_id CHAR(24) NOT NULL,
+    leave_type VARCHAR(20) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    days INT NOT NULL,
+    reason VARCHAR(255),
+    status VARCHAR(10) NOT NULL,
+    requested_by VARCHAR(100) NOT NULL,
+    decided_by VARCHAR(100),
+    decided_at DATETIME,
+    decision_note VARCHAR(255),
+    created_at DATETIME NOT NULL,
+    INDEX idx_leave_lecturer (lecturer_id, start_date),
+    INDEX idx_leave_status (status),
+    FOREIGN KEY (leave_type) REFERENCES leave_types(leave_type)
+);
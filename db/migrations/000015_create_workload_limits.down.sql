@@ -0,0 +1 @@
+DROP TABLE IF EXISTS workload_limits;
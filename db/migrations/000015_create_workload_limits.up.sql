@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS workload_limits (
+    designation VARCHAR(50) PRIMARY KEY,
+    min_credit_hours INT NOT NULL DEFAULT 0,
+    max_credit_hours INT NOT NULL,
+    max_contact_hours DECIMAL(5,2) NOT NULL,
+    max_sections INT NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS payslip_lines_immutable_delete;
+DROP TRIGGER IF EXISTS payslip_lines_immutable_update;
+DROP TRIGGER IF EXISTS payslips_immutable_delete;
+DROP TRIGGER IF EXISTS payslips_immutable_update;
+DROP TABLE IF EXISTS payslip_lines;
+DROP TABLE IF EXISTS payslips;
+DROP TABLE IF EXISTS payroll_runs;
+DROP TABLE IF EXISTS pay_components;
+DROP TABLE IF EXISTS pay_grades;
@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS pay_grades (
+    designation VARCHAR(50) PRIMARY KEY,
+    grade VARCHAR(20) NOT NULL,
+    basic_cents BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pay_components (
+    component_id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    calc VARCHAR(10) NOT NULL,
+    amount_cents BIGINT NOT NULL DEFAULT 0,
+    percent DECIMAL(5,2) NOT NULL DEFAULT 0,
+    designation VARCHAR(50),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_component_name (name, designation)
+);
+
+CREATE TABLE IF NOT EXISTS payroll_runs (
+    run_id INT AUTO_INCREMENT PRIMARY KEY,
+    period CHAR(7) NOT NULL UNIQUE,
+    payslips INT NOT NULL,
+    skipped INT NOT NULL,
+    run_by VARCHAR(100) NOT NULL,
+    run_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS payslips (
+    payslip_id INT AUTO_INCREMENT PRIMARY KEY,
+    run_id INT NOT NULL,
+    lecturer_id CHAR(24) NOT NULL,
+    period CHAR(7) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    designation VARCHAR(50) NOT NULL,
+    grade VARCHAR(20) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    basic_cents BIGINT NOT NULL,
+    allowances_cents BIGINT NOT NULL,
+    unpaid_leave_days INT NOT NULL,
+    unpaid_leave_cents BIGINT NOT NULL,
+    gross_cents BIGINT NOT NULL,
+    deductions_cents BIGINT NOT NULL,
+    net_cents BIGINT NOT NULL,
+    reference VARCHAR(20) NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_payslip_lecturer (run_id, lecturer_id),
+    INDEX idx_payslip_lecturer (lecturer_id, period),
+    FOREIGN KEY (run_id) REFERENCES payroll_runs(run_id)
+);
+
+CREATE TABLE IF NOT EXISTS payslip_lines (
+    payslip_id INT NOT NULL,
+    line_no INT NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    PRIMARY KEY (payslip_id, line_no),
+    FOREIGN KEY (payslip_id) REFERENCES payslips(payslip_id)
+);
+
+CREATE TRIGGER payslips_immutable_update BEFORE UPDATE ON payslips FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payslips are immutable';
+CREATE TRIGGER payslips_immutable_delete BEFORE DELETE ON payslips FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payslips are immutable';
+CREATE TRIGGER payslip_lines_immutable_update BEFORE UPDATE ON payslip_lines FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payslips are immutable';
+CREATE TRIGGER payslip_lines_immutable_delete BEFORE DELETE ON payslip_lines FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payslips are immutable';
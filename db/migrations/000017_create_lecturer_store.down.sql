@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS lecturers;
+
+CREATE TABLE IF NOT EXISTS lecturers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    age INT NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    designation VARCHAR(100) NOT NULL
+);
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS lecturers;
+
+CREATE TABLE IF NOT EXISTS lecturers (
+    id CHAR(24) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    age INT NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    designation VARCHAR(100) NOT NULL,
+    department VARCHAR(100),
+    UNIQUE KEY uq_lecturer_email (email),
+    INDEX idx_lecturer_designation (designation, name),
+    INDEX idx_lecturer_department (department)
+);
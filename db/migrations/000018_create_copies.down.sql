@@ -0,0 +1,10 @@
+ALTER TABLE libraries
+    ADD COLUMN available_copies INT NOT NULL DEFAULT 0;
+
+ALTER TABLE borrow_records
+    DROP FOREIGN KEY fk_borrow_copy;
+ALTER TABLE borrow_records
+    DROP INDEX idx_borrow_copy,
+    DROP COLUMN copy_id;
+
+DROP TABLE IF EXISTS copies;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS copies (
+    copy_id INT AUTO_INCREMENT PRIMARY KEY,
+    barcode VARCHAR(64) NOT NULL,
+    book_id INT NOT NULL,
+    shelf_location VARCHAR(100) NOT NULL DEFAULT '',
+    copy_condition VARCHAR(10) NOT NULL DEFAULT 'good',
+    status VARCHAR(10) NOT NULL DEFAULT 'available',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE KEY uq_copy_barcode (barcode),
+    INDEX idx_copy_book_status (book_id, status),
+    FOREIGN KEY (book_id) REFERENCES books(book_id) ON DELETE CASCADE
+);
+
+-- every existing book gets one available copy, relabelled later by the librarians
+INSERT INTO copies (barcode , book_id , status , created_at , updated_at)
+SELECT CONCAT('LEGACY-' , book_id) , book_id , 'available' , UTC_TIMESTAMP() , UTC_TIMESTAMP() FROM books;
+
+-- and every open loan a copy on loan, so it can still be returned by barcode
+INSERT INTO copies (barcode , book_id , status , created_at , updated_at)
+SELECT CONCAT('LEGACY-' , book_id , '-L' , borrow_id) , book_id , 'on_loan' , UTC_TIMESTAMP() , UTC_TIMESTAMP()
+FROM borrow_records WHERE return_date IS NULL;
+
+ALTER TABLE borrow_records
+    ADD COLUMN copy_id INT NULL,
+    ADD INDEX idx_borrow_copy (copy_id, return_date),
+    ADD CONSTRAINT fk_borrow_copy FOREIGN KEY (copy_id) REFERENCES copies(copy_id) ON DELETE CASCADE;
+
+UPDATE borrow_records br
+JOIN copies c ON c.barcode = CONCAT('LEGACY-' , br.book_id , '-L' , br.borrow_id)
+SET br.copy_id = c.copy_id
+WHERE br.return_date IS NULL;
+
+ALTER TABLE libraries
+    DROP COLUMN available_copies;
@@ -0,0 +1,6 @@
+ALTER TABLE borrow_records
+    DROP INDEX idx_borrow_user_open,
+    DROP COLUMN renewals,
+    DROP COLUMN due_date;
+
+DROP TABLE IF EXISTS loan_policies;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS loan_policies (
+    usertype VARCHAR(20) PRIMARY KEY,
+    loan_days INT NOT NULL,
+    max_loans INT NOT NULL,
+    max_renewals INT NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+INSERT INTO loan_policies (usertype , loan_days , max_loans , max_renewals , updated_by , updated_at) VALUES
+    ('student' , 14 , 5 , 2 , 'system' , NOW()),
+    ('lecturer' , 30 , 15 , 3 , 'system' , NOW());
+
+ALTER TABLE borrow_records
+    ADD COLUMN due_date DATE NULL,
+    ADD COLUMN renewals INT NOT NULL DEFAULT 0,
+    ADD INDEX idx_borrow_user_open (user_id, usertype, return_date);
+
+-- loans still open start their loan period no earlier than today, so nothing is overdue for time before due dates existed
+UPDATE borrow_records br
+    LEFT JOIN loan_policies p ON p.usertype=br.usertype
+    SET br.due_date=DATE_ADD(
+        CASE WHEN br.return_date IS NULL THEN GREATEST(br.borrow_date, CURDATE()) ELSE br.borrow_date END,
+        INTERVAL COALESCE(p.loan_days, 14) DAY);
+
+ALTER TABLE borrow_records
+    MODIFY due_date DATE NOT NULL;
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS library_fine_ledger;
+
+ALTER TABLE borrow_records
+    DROP INDEX idx_borrow_due_open,
+    DROP COLUMN fine_cents;
+
+ALTER TABLE loan_policies
+    DROP COLUMN fine_block_cents,
+    DROP COLUMN max_fine_cents,
+    DROP COLUMN grace_days,
+    DROP COLUMN fine_per_day_cents;
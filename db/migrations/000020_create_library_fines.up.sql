@@ -0,0 +1,26 @@
+ALTER TABLE loan_policies
+    ADD COLUMN fine_per_day_cents BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN grace_days INT NOT NULL DEFAULT 0,
+    ADD COLUMN max_fine_cents BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN fine_block_cents BIGINT NOT NULL DEFAULT 0;
+
+UPDATE loan_policies SET fine_per_day_cents=25 , grace_days=2 , max_fine_cents=1000 , fine_block_cents=500 WHERE usertype='student';
+UPDATE loan_policies SET fine_per_day_cents=10 , grace_days=3 , max_fine_cents=500 , fine_block_cents=1000 WHERE usertype='lecturer';
+
+ALTER TABLE borrow_records
+    ADD COLUMN fine_cents BIGINT NOT NULL DEFAULT 0,
+    ADD INDEX idx_borrow_due_open (return_date, due_date);
+
+CREATE TABLE IF NOT EXISTS library_fine_ledger (
+    entry_id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    usertype VARCHAR(20) NOT NULL,
+    borrow_id INT NULL,
+    kind VARCHAR(10) NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    memo VARCHAR(255) NOT NULL,
+    created_by VARCHAR(100) NOT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_fine_ledger_user (user_id, usertype),
+    FOREIGN KEY (borrow_id) REFERENCES borrow_records(borrow_id) ON DELETE SET NULL
+);
@@ -0,0 +1,6 @@
+UPDATE copies SET status='available' WHERE status='on_hold';
+
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE loan_policies
+    DROP COLUMN hold_pickup_days;
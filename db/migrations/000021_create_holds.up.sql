@@ -0,0 +1,20 @@
+ALTER TABLE loan_policies
+    ADD COLUMN hold_pickup_days INT NOT NULL DEFAULT 3;
+
+CREATE TABLE IF NOT EXISTS holds (
+    hold_id INT AUTO_INCREMENT PRIMARY KEY,
+    book_id INT NOT NULL,
+    user_id INT NOT NULL,
+    usertype VARCHAR(20) NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    copy_id INT NULL,
+    created_at DATETIME NOT NULL,
+    ready_at DATETIME NULL,
+    pickup_by DATE NULL,
+    closed_at DATETIME NULL,
+    INDEX idx_hold_queue (book_id, status, hold_id),
+    INDEX idx_hold_user (user_id, usertype, status),
+    INDEX idx_hold_pickup (status, pickup_by),
+    FOREIGN KEY (book_id) REFERENCES books(book_id) ON DELETE CASCADE,
+    FOREIGN KEY (copy_id) REFERENCES copies(copy_id) ON DELETE SET NULL
+);
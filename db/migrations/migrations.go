@@ -0,0 +1,456 @@
+// Package migrations embeds the MySQL schema migrations and applies them.
+//
+// Files are named NNNNNN_description.up.sql and NNNNNN_description.down.sql.
+// Applied versions are recorded in schema_migrations with the checksum of their up file,
+// and every command holds a MySQL named lock so instances starting together never race.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockName is the MySQL named lock held while migrating
+const lockName = "college_management_schema_migrations"
+
+// lockTimeout is how long, in seconds, to wait for another instance to finish migrating
+const lockTimeout = 60
+
+// Migration is one version of the schema
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status is the state of one migration in the database
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	Dirty     bool       `json:"dirty"`
+	Modified  bool       `json:"modified"`
+	Unknown   bool       `json:"unknown"`
+	Appliedat *time.Time `json:"applied_at,omitempty"`
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+	Version   int
+	Name      string
+	Checksum  string
+	Dirty     bool
+	Appliedat time.Time
+}
+
+// DirtyError is returned when a migration stopped midway. Since MySQL DDL is not transactional
+// the schema has to be checked by hand before recording the right version with Force.
+type DirtyError struct {
+	Version int
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migration %d is dirty, repair the schema and run migrate force", e.Version)
+}
+
+// ChecksumError is returned when an applied migration file was edited afterwards
+type ChecksumError struct {
+	Version int
+	Name    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("migration %d_%s was changed after it was applied, add a new migration instead", e.Version, e.Name)
+}
+
+// errors returned by the runner
+var (
+	ErrLocked         = errors.New("another instance is migrating the database")
+	ErrNoDown         = errors.New("migration has no down file")
+	ErrUnknownVersion = errors.New("no migration with this version")
+)
+
+// Load reads the embedded migrations in version order
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+// load reads the migrations of a file system in version order
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, file := range names {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s must end in .up.sql or .down.sql", file)
+		}
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s must start with a version number", file)
+		}
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a migration into statements on semicolons outside quotes and comments,
+// since the driver runs one statement per Exec. Line comments are dropped, block comments are kept
+// so MySQL still sees /*! ... */ hints.
+func splitStatements(script string) []string {
+	var statements []string
+	var b strings.Builder
+	var quote rune
+	lineComment, blockComment := false, false
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case lineComment:
+			if c == '\n' {
+				lineComment = false
+				b.WriteRune(c)
+			}
+			continue
+		case blockComment:
+			b.WriteRune(c)
+			if c == '*' && i+1 < len(runes) && runes[i+1] == '/' {
+				i++
+				b.WriteRune(runes[i])
+				blockComment = false
+			}
+			continue
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			blockComment = true
+			i++
+			b.WriteString("/*")
+			continue
+		case quote != 0:
+			b.WriteRune(c)
+			if c == '\\' && i+1 < len(runes) {
+				i++
+				b.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			lineComment = true
+			continue
+		case c == '#':
+			lineComment = true
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			if s := strings.TrimSpace(b.String()); s != "" {
+				statements = append(statements, s)
+			}
+			b.Reset()
+			continue
+		}
+		b.WriteRune(c)
+	}
+	if s := strings.TrimSpace(b.String()); s != "" {
+		statements = append(statements, s)
+	}
+	return statements
+}
+
+// Runner applies the embedded migrations to a MySQL database
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+	// Logf reports each applied migration, it may be nil
+	Logf func(format string, args ...any)
+}
+
+// New loads the embedded migrations for a database
+func New(db *sql.DB) (*Runner, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
+
+// withLock runs fn on a single connection holding the migration lock
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(? , ?)", lockName, lockTimeout).Scan(&got); err != nil {
+		return err
+	}
+	if got.Int64 != 1 {
+		return ErrLocked
+	}
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lockName)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE,
+		applied_at DATETIME NOT NULL
+	)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// loadApplied reads schema_migrations keyed by version
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int]applied, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version , name , checksum , dirty , applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int]applied{}
+	for rows.Next() {
+		var a applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.Dirty, &a.Appliedat); err != nil {
+			return nil, err
+		}
+		done[a.Version] = a
+	}
+	return done, rows.Err()
+}
+
+// checkApplied refuses to continue from a dirty or edited schema
+func (r *Runner) checkApplied(done map[int]applied) error {
+	for _, a := range done {
+		if a.Dirty {
+			return &DirtyError{Version: a.Version}
+		}
+	}
+	for _, m := range r.migrations {
+		if a, ok := done[m.Version]; ok && a.Checksum != m.Checksum {
+			return &ChecksumError{Version: m.Version, Name: m.Name}
+		}
+	}
+	return nil
+}
+
+// exec runs every statement of a script
+func exec(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w\n%s", err, stmt)
+		}
+	}
+	return nil
+}
+
+// apply runs one migration up, the row stays dirty if it fails
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, m Migration) error {
+	_, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version , name , checksum , dirty , applied_at) VALUES (? , ? , ? , TRUE , ?)
+		ON DUPLICATE KEY UPDATE dirty=TRUE`, m.Version, m.Name, m.Checksum, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := exec(ctx, conn, m.Up); err != nil {
+		return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+	}
+	if _, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty=FALSE , applied_at=? WHERE version=?", time.Now(), m.Version); err != nil {
+		return err
+	}
+	r.logf("applied migration %d_%s", m.Version, m.Name)
+	return nil
+}
+
+// revert runs one migration down, the row stays dirty if it fails
+func (r *Runner) revert(ctx context.Context, conn *sql.Conn, m Migration) error {
+	if strings.TrimSpace(m.Down) == "" {
+		return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, ErrNoDown)
+	}
+	if _, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty=TRUE WHERE version=?", m.Version); err != nil {
+		return err
+	}
+	if err := exec(ctx, conn, m.Down); err != nil {
+		return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+	}
+	if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version=?", m.Version); err != nil {
+		return err
+	}
+	r.logf("reverted migration %d_%s", m.Version, m.Name)
+	return nil
+}
+
+// Up applies every pending migration
+func (r *Runner) Up(ctx context.Context) error {
+	return r.Goto(ctx, r.migrations[len(r.migrations)-1].Version)
+}
+
+// Down reverts the most recently applied migrations, steps at a time
+func (r *Runner) Down(ctx context.Context, steps int) error {
+	return r.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := r.checkApplied(done); err != nil {
+			return err
+		}
+		for i := len(r.migrations) - 1; i >= 0 && steps > 0; i-- {
+			if _, ok := done[r.migrations[i].Version]; !ok {
+				continue
+			}
+			if err := r.revert(ctx, conn, r.migrations[i]); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Goto applies or reverts migrations until exactly those up to version are applied, 0 reverts everything
+func (r *Runner) Goto(ctx context.Context, version int) error {
+	if version != 0 && r.find(version) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return r.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := r.checkApplied(done); err != nil {
+			return err
+		}
+		for i := len(r.migrations) - 1; i >= 0; i-- {
+			m := r.migrations[i]
+			if _, ok := done[m.Version]; ok && m.Version > version {
+				if err := r.revert(ctx, conn, m); err != nil {
+					return err
+				}
+			}
+		}
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; !ok && m.Version <= version {
+				if err := r.apply(ctx, conn, m); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Force records migrations up to version as applied and those above as not applied, without running any SQL.
+// It clears a dirty state after a manual repair, and baselines databases that were migrated by hand.
+func (r *Runner) Force(ctx context.Context, version int) error {
+	if version != 0 && r.find(version) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return r.withLock(ctx, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version>?", version); err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if m.Version > version {
+				break
+			}
+			_, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version , name , checksum , dirty , applied_at) VALUES (? , ? , ? , FALSE , ?)
+				ON DUPLICATE KEY UPDATE name=VALUES(name) , checksum=VALUES(checksum) , dirty=FALSE`, m.Version, m.Name, m.Checksum, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		r.logf("forced schema version %d", version)
+		return nil
+	})
+}
+
+// Status reports every embedded migration, and any applied one this binary does not know
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			s := Status{Version: m.Version, Name: m.Name}
+			if a, ok := done[m.Version]; ok {
+				at := a.Appliedat
+				s.Applied, s.Dirty, s.Modified, s.Appliedat = true, a.Dirty, a.Checksum != m.Checksum, &at
+				delete(done, m.Version)
+			}
+			statuses = append(statuses, s)
+		}
+		for _, a := range done {
+			at := a.Appliedat
+			statuses = append(statuses, Status{Version: a.Version, Name: a.Name, Applied: true, Dirty: a.Dirty, Unknown: true, Appliedat: &at})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// find returns the embedded migration with a version
+func (r *Runner) find(version int) *Migration {
+	for i := range r.migrations {
+		if r.migrations[i].Version == version {
+			return &r.migrations[i]
+		}
+	}
+	return nil
+}
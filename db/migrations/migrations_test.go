@@ -0,0 +1,107 @@
+package migrations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"two statements", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			[]string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{"trailing statement without semicolon", "DROP TABLE a;\nDROP TABLE b\n",
+			[]string{"DROP TABLE a", "DROP TABLE b"}},
+		{"empty statements", ";;\n  ;DROP TABLE a;;", []string{"DROP TABLE a"}},
+		{"semicolon in single quotes", "INSERT INTO t VALUES ('a;b');",
+			[]string{"INSERT INTO t VALUES ('a;b')"}},
+		{"semicolon in double quotes", `INSERT INTO t VALUES ("a;b");`,
+			[]string{`INSERT INTO t VALUES ("a;b")`}},
+		{"semicolon in backticks", "CREATE TABLE `a;b` (id INT);",
+			[]string{"CREATE TABLE `a;b` (id INT)"}},
+		{"escaped quote", `INSERT INTO t VALUES ('it\'s; fine');`,
+			[]string{`INSERT INTO t VALUES ('it\'s; fine')`}},
+		{"doubled quote", "INSERT INTO t VALUES ('it''s; fine');",
+			[]string{"INSERT INTO t VALUES ('it''s; fine')"}},
+		{"comment markers in a string", "INSERT INTO t VALUES ('-- #;');",
+			[]string{"INSERT INTO t VALUES ('-- #;')"}},
+		{"semicolon in dash comment", "-- drop a; then b\nDROP TABLE a;",
+			[]string{"DROP TABLE a"}},
+		{"semicolon in hash comment", "DROP TABLE a; # gone; really\nDROP TABLE b;",
+			[]string{"DROP TABLE a", "DROP TABLE b"}},
+		{"semicolon in block comment", "DROP TABLE a /* a; b */;",
+			[]string{"DROP TABLE a /* a; b */"}},
+		{"comment only", "-- nothing to do;\n# still nothing;\n", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := splitStatements(tc.script); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"up and down", map[string]string{
+			"000002_b.up.sql": "B", "000001_a.up.sql": "A", "000001_a.down.sql": "a",
+		}, ""},
+		{"mismatched up and down names", map[string]string{
+			"000001_create_a.up.sql": "A", "000001_create_b.down.sql": "a",
+		}, "different names"},
+		{"missing up file", map[string]string{
+			"000001_a.up.sql": "A", "000002_b.down.sql": "b",
+		}, "no up file"},
+		{"no direction", map[string]string{"000001_a.sql": "A"}, "must end in .up.sql or .down.sql"},
+		{"unknown direction", map[string]string{"000001_a.sideways.sql": "A"}, "must end in .up.sql or .down.sql"},
+		{"no version", map[string]string{"create_a.up.sql": "A"}, "must start with a version number"},
+		{"version zero", map[string]string{"000000_a.up.sql": "A"}, "must start with a version number"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, body := range tc.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(body)}
+			}
+			migrations, err := load(fsys)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Version != 2 {
+				t.Fatalf("got %+v, want versions 1 and 2 in order", migrations)
+			}
+			if m := migrations[0]; m.Name != "a" || m.Up != "A" || m.Down != "a" || len(m.Checksum) != 64 {
+				t.Fatalf("got %+v", m)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, m := range migrations {
+		if m.Version != k+1 {
+			t.Fatalf("migration %d_%s found where version %d was expected", m.Version, m.Name, k+1)
+		}
+		if len(splitStatements(m.Up)) == 0 {
+			t.Errorf("migration %d_%s has no statements", m.Version, m.Name)
+		}
+	}
+}
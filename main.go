@@ -1,8 +1,17 @@
 package main
 
-import "project/project"
+import (
+	"os"
+
+	"project/project"
+)
 
 func main() {
 
+	// migrate subcommand, see project.MigrateCommand
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(project.MigrateCommand(os.Args[2:]))
+	}
+
 	project.CollegeManagementSystem()
 }
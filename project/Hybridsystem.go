@@ -34,12 +34,50 @@ type RedisInstance struct {
 	Client *redis.Client
 }
 
+// Get reads a cached value. A nil instance is a cache that holds nothing, so handlers run without Redis in tests.
+func (r *RedisInstance) Get(ctx context.Context, key string) *redis.StringCmd {
+	if r == nil || r.Client == nil {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return r.Client.Get(ctx, key)
+}
+
+// Set caches a value, a nil instance drops it
+func (r *RedisInstance) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	if r == nil || r.Client == nil {
+		return redis.NewStatusResult("", nil)
+	}
+	return r.Client.Set(ctx, key, value, ttl)
+}
+
+// Del removes cached keys, a nil instance has none
+func (r *RedisInstance) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	if r == nil || r.Client == nil {
+		return redis.NewIntResult(0, nil)
+	}
+	return r.Client.Del(ctx, keys...)
+}
+
+// Incr bumps a cache version, a nil instance has nothing to invalidate
+func (r *RedisInstance) Incr(ctx context.Context, key string) *redis.IntCmd {
+	if r == nil || r.Client == nil {
+		return redis.NewIntResult(0, nil)
+	}
+	return r.Client.Incr(ctx, key)
+}
+
 // HybridHandler aggregates MySQL , MongoDB , Redis instances along with a shared context.
 type HybridHandler struct {
-	MySQL   *MySQLInstance
-	MongoDB *MongoDBInstance
-	Redis   *RedisInstance
-	Ctx     context.Context
+	MySQL     *MySQLInstance
+	MongoDB   *MongoDBInstance
+	Redis     *RedisInstance
+	Students  StudentRepository
+	Lecturers LecturerRepository
+	Libraries LibraryRepository
+	Blobs     BlobStore
+	Payments  PaymentGateway
+	Roles     RoleLookup
+	Ctx       context.Context
 }
 
 // connectMySQL initilizes a MySQL connection using DSN from environment variables.
@@ -71,6 +109,11 @@ func ConnectMongoDB() (*MongoDBInstance, error) {
 
 	// select database and connections
 	db := client.Database(os.Getenv("MONGO_DB"))
+
+	// ensure collections, validators and indexes
+	if err := MigrateMongoDB(ctx, db); err != nil {
+		return nil, err
+	}
 	return &MongoDBInstance{
 		Mongo:    client,
 		DB:       db,
@@ -111,6 +154,13 @@ func CollegeManagementSystem() {
 	}
 	SecretKey = []byte(secret)
 
+	// Ensures the transcript signing key is set
+	transcriptSecret := os.Getenv("TRANSCRIPT_SECRET")
+	if transcriptSecret == "" {
+		log.Fatal("TRANSCRIPT_SECRET is not set or empty")
+	}
+	TranscriptKey = []byte(transcriptSecret)
+
 	// Initilizes Redis
 	redisinstance, err := ConnectRedis()
 	if err != nil {
@@ -123,14 +173,54 @@ func CollegeManagementSystem() {
 		panic(err)
 	}
 
+	// Apply pending MySQL migrations unless MIGRATE_ON_START=false
+	if migrateOnStart() {
+		if err := MigrateMySQL(context.Background(), mysqlinstance); err != nil {
+			panic(err)
+		}
+	}
+
 	// Initilizes MongoDB
 	mongodbinstance, err := ConnectMongoDB()
 	if err != nil {
 		panic(err)
 	}
 
+	// Select the store behind each repository
+	students, err := NewStudentRepository(storeFor("STUDENT_STORE", StoreMySQL), mysqlinstance)
+	if err != nil {
+		panic(err)
+	}
+	lecturers, err := NewLecturerRepository(storeFor("LECTURER_STORE", StoreMongoDB), mysqlinstance, mongodbinstance)
+	if err != nil {
+		panic(err)
+	}
+	libraries, err := NewLibraryRepository(storeFor("LIBRARY_STORE", StoreMySQL), mysqlinstance)
+	if err != nil {
+		panic(err)
+	}
+
+	// Initilizes local file storage for uploads
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+	blobs, err := NewLocalBlobStore(uploadDir)
+	if err != nil {
+		panic(err)
+	}
+
+	// Select the payment gateway, card payments stay off until one is configured
+	payments, err := NewPaymentGateway(storeFor("PAYMENT_GATEWAY", ""))
+	if err != nil {
+		panic(err)
+	}
+
 	// Create handler with all DB instanmces
-	handler := &HybridHandler{Redis: redisinstance, MySQL: mysqlinstance, MongoDB: mongodbinstance, Ctx: context.Background()}
+	handler := &HybridHandler{Redis: redisinstance, MySQL: mysqlinstance, MongoDB: mongodbinstance, Students: students, Lecturers: lecturers, Libraries: libraries, Blobs: blobs, Payments: payments, Ctx: context.Background()}
+
+	// Expire unclaimed holds and charge overdue library fines nightly
+	go handler.RunLibraryJobs(handler.Ctx)
 
 	// Setup HTTP routers
 	r := mux.NewRouter()
@@ -156,11 +246,190 @@ func CollegeManagementSystem() {
 
 	// Library routes
 	r.HandleFunc("/libraries", handler.CreateLibraryHandler).Methods("POST")
-	r.HandleFunc("/libraries/{id}", handler.GetLibraryByIDHandler).Methods("GEt")
+	r.HandleFunc("/libraries", handler.GetLibrariesHandler).Methods("GET")
+	r.HandleFunc("/libraries/{id}", handler.GetLibraryByIDHandler).Methods("GET")
+	r.Handle("/libraries/{id}", JwtMiddleware(http.HandlerFunc(handler.UpdateLibraryHandler))).Methods("PUT")
+	r.Handle("/libraries/{id}", JwtMiddleware(http.HandlerFunc(handler.DeleteLibraryHandler))).Methods("DELETE")
+	r.HandleFunc("/libraries/{id}/books", handler.GetLibraryBooksHandler).Methods("GET")
+	r.Handle("/libraries/{id}/books", JwtMiddleware(http.HandlerFunc(handler.AddLibraryBookHandler))).Methods("POST")
+	r.Handle("/libraries/{id}/books/{bookId}", JwtMiddleware(http.HandlerFunc(handler.UpdateLibraryBookHandler))).Methods("PUT")
+	r.Handle("/libraries/{id}/books/{bookId}", JwtMiddleware(http.HandlerFunc(handler.DeleteLibraryBookHandler))).Methods("DELETE")
+	r.HandleFunc("/libraries/{id}/books/{bookId}/copies", handler.GetCopiesHandler).Methods("GET")
+	r.Handle("/libraries/{id}/books/{bookId}/copies", JwtMiddleware(http.HandlerFunc(handler.AddCopyHandler))).Methods("POST")
+	r.HandleFunc("/copies/{barcode}", handler.GetCopyHandler).Methods("GET")
+	r.Handle("/copies/{barcode}", JwtMiddleware(http.HandlerFunc(handler.UpdateCopyHandler))).Methods("PUT")
+
+	// Author routes
+	r.HandleFunc("/authors", handler.GetAuthorsHandler).Methods("GET")
+	r.Handle("/authors", JwtMiddleware(http.HandlerFunc(handler.CreateAuthorHandler))).Methods("POST")
+	r.HandleFunc("/authors/{id}", handler.GetAuthorHandler).Methods("GET")
+	r.Handle("/authors/{id}", JwtMiddleware(http.HandlerFunc(handler.UpdateAuthorHandler))).Methods("PUT")
+	r.Handle("/authors/{id}", JwtMiddleware(http.HandlerFunc(handler.DeleteAuthorHandler))).Methods("DELETE")
+
+	// Course and section routes
+	r.HandleFunc("/courses", handler.CreateCourseHandler).Methods("POST")
+	r.HandleFunc("/courses/{id}", handler.GetCourseByIDHandler).Methods("GET")
+	r.HandleFunc("/sections", handler.CreateSectionHandler).Methods("POST")
+	r.HandleFunc("/sections/{id}", handler.GetSectionByIDHandler).Methods("GET")
+
+	// Enrollment routes
+	r.HandleFunc("/sections/{id}/enrollments", handler.EnrollStudentHandler).Methods("POST")
+	r.HandleFunc("/sections/{id}/enrollments", handler.GetSectionEnrollmentsHandler).Methods("GET")
+	r.HandleFunc("/sections/{id}/enrollments/{studentId}", handler.DropEnrollmentHandler).Methods("DELETE")
+
+	// Section instructor routes
+	r.HandleFunc("/sections/{id}/instructors", handler.AssignInstructorHandler).Methods("POST")
+	r.HandleFunc("/sections/{id}/instructors", handler.GetSectionInstructorsHandler).Methods("GET")
+	r.HandleFunc("/sections/{id}/instructors/{lecturerId}", handler.RemoveInstructorHandler).Methods("DELETE")
+	r.HandleFunc("/lecturers/{id}/sections", handler.GetLecturerSectionsHandler).Methods("GET")
+
+	// Room and scheduling routes
+	r.HandleFunc("/rooms", handler.CreateRoomHandler).Methods("POST")
+	r.HandleFunc("/rooms", handler.GetRoomsHandler).Methods("GET")
+	r.HandleFunc("/sections/{id}/meetings", handler.CreateMeetingHandler).Methods("POST")
+	r.HandleFunc("/sections/{id}/meetings", handler.GetSectionMeetingsHandler).Methods("GET")
+	r.HandleFunc("/meetings/{id}", handler.DeleteMeetingHandler).Methods("DELETE")
+	r.HandleFunc("/students/{id}/timetable", handler.GetStudentTimetableHandler).Methods("GET")
+	r.HandleFunc("/lecturers/{id}/timetable", handler.GetLecturerTimetableHandler).Methods("GET")
+
+	// Timetable generation routes
+	r.HandleFunc("/timetables/jobs", handler.CreateTimetableJobHandler).Methods("POST")
+	r.HandleFunc("/timetables/jobs/{id}", handler.GetTimetableJobHandler).Methods("GET")
+	r.HandleFunc("/timetables/jobs/{id}/candidates", handler.GetTimetableCandidatesHandler).Methods("GET")
+	r.HandleFunc("/timetables/candidates/{id}", handler.GetTimetableCandidateHandler).Methods("GET")
+	r.HandleFunc("/timetables/candidates/{id}/publish", handler.PublishTimetableCandidateHandler).Methods("POST")
+	r.HandleFunc("/lecturers/{id}/preferences", handler.SetLecturerPreferencesHandler).Methods("PUT")
+
+	// Gradebook routes, protected by JWT so lecturers and registrars can be identified
+	r.Handle("/grade-scales", JwtMiddleware(http.HandlerFunc(handler.CreateGradeScaleHandler))).Methods("POST")
+	r.HandleFunc("/grade-scales", handler.GetGradeScalesHandler).Methods("GET")
+	r.Handle("/sections/{id}/grade-scale", JwtMiddleware(http.HandlerFunc(handler.SetSectionGradeScaleHandler))).Methods("PUT")
+	r.Handle("/sections/{id}/assessments", JwtMiddleware(http.HandlerFunc(handler.CreateAssessmentHandler))).Methods("POST")
+	r.HandleFunc("/sections/{id}/assessments", handler.GetSectionAssessmentsHandler).Methods("GET")
+	r.Handle("/assessments/{id}/scores", JwtMiddleware(http.HandlerFunc(handler.RecordScoresHandler))).Methods("PUT")
+	r.Handle("/sections/{id}/grades", JwtMiddleware(http.HandlerFunc(handler.GetSectionGradesHandler))).Methods("GET")
+	r.Handle("/terms/{term}/finalize", JwtMiddleware(http.HandlerFunc(handler.FinalizeTermHandler))).Methods("POST")
+	r.Handle("/students/{id}/gpa", JwtMiddleware(http.HandlerFunc(handler.GetStudentGPAHandler))).Methods("GET")
+
+	// Transcript routes
+	r.Handle("/students/{id}/transcript", JwtMiddleware(http.HandlerFunc(handler.GetStudentTranscriptHandler))).Methods("GET")
+	r.HandleFunc("/transcripts/verify/{code}", handler.VerifyTranscriptHandler).Methods("GET")
+
+	// Attendance routes
+	r.Handle("/sections/{id}/sessions", JwtMiddleware(http.HandlerFunc(handler.GenerateSessionsHandler))).Methods("POST")
+	r.Handle("/sections/{id}/sessions", JwtMiddleware(http.HandlerFunc(handler.GetSectionSessionsHandler))).Methods("GET")
+	r.Handle("/sessions/{id}/attendance", JwtMiddleware(http.HandlerFunc(handler.BulkAttendanceHandler))).Methods("POST")
+	r.Handle("/sessions/{id}/attendance/{studentId}", JwtMiddleware(http.HandlerFunc(handler.CorrectAttendanceHandler))).Methods("PUT")
+	r.Handle("/sections/{id}/attendance", JwtMiddleware(http.HandlerFunc(handler.GetSectionAttendanceHandler))).Methods("GET")
+	r.Handle("/attendance/alerts", JwtMiddleware(http.HandlerFunc(handler.GetAttendanceAlertsHandler))).Methods("GET")
+
+	// Assignment routes
+	r.Handle("/sections/{id}/assignments", JwtMiddleware(http.HandlerFunc(handler.CreateAssignmentHandler))).Methods("POST")
+	r.HandleFunc("/sections/{id}/assignments", handler.GetSectionAssignmentsHandler).Methods("GET")
+	r.HandleFunc("/assignments/{id}", handler.GetAssignmentByIDHandler).Methods("GET")
+	r.Handle("/assignments/{id}/submissions", JwtMiddleware(http.HandlerFunc(handler.SubmitAssignmentHandler))).Methods("POST")
+	r.Handle("/assignments/{id}/submissions", JwtMiddleware(http.HandlerFunc(handler.GetAssignmentSubmissionsHandler))).Methods("GET")
+	r.Handle("/submissions/{id}/file", JwtMiddleware(http.HandlerFunc(handler.DownloadSubmissionHandler))).Methods("GET")
+	r.Handle("/submissions/{id}/grade", JwtMiddleware(http.HandlerFunc(handler.GradeSubmissionHandler))).Methods("PUT")
+
+	// Exam routes
+	r.Handle("/exam-slots", JwtMiddleware(http.HandlerFunc(handler.CreateExamSlotHandler))).Methods("POST")
+	r.HandleFunc("/exam-slots", handler.GetExamSlotsHandler).Methods("GET")
+	r.Handle("/sections/{id}/exam", JwtMiddleware(http.HandlerFunc(handler.ScheduleExamHandler))).Methods("PUT")
+	r.HandleFunc("/exams/clashes", handler.GetExamClashesHandler).Methods("GET")
+	r.Handle("/exam-slots/{id}/seating", JwtMiddleware(http.HandlerFunc(handler.GenerateSeatingHandler))).Methods("POST")
+	r.HandleFunc("/exam-slots/{id}/seating", handler.GetSeatingHandler).Methods("GET")
+	r.Handle("/exam-slots/{id}/invigilators", JwtMiddleware(http.HandlerFunc(handler.AssignInvigilatorsHandler))).Methods("POST")
+	r.HandleFunc("/exam-slots/{id}/invigilators", handler.GetInvigilatorsHandler).Methods("GET")
+	r.Handle("/students/{id}/hall-ticket", JwtMiddleware(http.HandlerFunc(handler.GetHallTicketHandler))).Methods("GET")
+
+	// Fee, invoice and payment routes
+	r.Handle("/fee-plans", JwtMiddleware(http.HandlerFunc(handler.CreateFeePlanHandler))).Methods("POST")
+	r.HandleFunc("/fee-plans", handler.GetFeePlansHandler).Methods("GET")
+	r.Handle("/students/{id}/invoices", JwtMiddleware(http.HandlerFunc(handler.CreateInvoiceHandler))).Methods("POST")
+	r.Handle("/invoices/generate", JwtMiddleware(http.HandlerFunc(handler.GenerateTermInvoicesHandler))).Methods("POST")
+	r.Handle("/invoices/{id}", JwtMiddleware(http.HandlerFunc(handler.GetInvoiceHandler))).Methods("GET")
+	r.Handle("/invoices/{id}/waivers", JwtMiddleware(http.HandlerFunc(handler.WaiveInvoiceHandler))).Methods("POST")
+	if handler.Payments != nil {
+		r.Handle("/invoices/{id}/payments", JwtMiddleware(http.HandlerFunc(handler.PayInvoiceHandler))).Methods("POST")
+		r.Handle("/payments/{id}/refunds", JwtMiddleware(http.HandlerFunc(handler.RefundPaymentHandler))).Methods("POST")
+	} else {
+		log.Println("PAYMENT_GATEWAY is not set, card payments and refunds are disabled")
+	}
+	r.Handle("/students/{id}/account", JwtMiddleware(http.HandlerFunc(handler.GetStudentAccountHandler))).Methods("GET")
+
+	// Scholarship routes
+	r.Handle("/scholarships", JwtMiddleware(http.HandlerFunc(handler.CreateScholarshipHandler))).Methods("POST")
+	r.HandleFunc("/scholarships", handler.GetScholarshipsHandler).Methods("GET")
+	r.Handle("/scholarships/renewals", JwtMiddleware(http.HandlerFunc(handler.EvaluateRenewalsHandler))).Methods("POST")
+	r.Handle("/scholarships/{id}/applications", JwtMiddleware(http.HandlerFunc(handler.ApplyScholarshipHandler))).Methods("POST")
+	r.Handle("/scholarships/{id}/applications", JwtMiddleware(http.HandlerFunc(handler.GetScholarshipApplicationsHandler))).Methods("GET")
+	r.Handle("/scholarship-applications/{id}/decision", JwtMiddleware(http.HandlerFunc(handler.DecideScholarshipApplicationHandler))).Methods("POST")
+	r.Handle("/students/{id}/scholarships", JwtMiddleware(http.HandlerFunc(handler.GetStudentScholarshipsHandler))).Methods("GET")
+
+	// Admission routes
+	r.HandleFunc("/admissions/applications", handler.SubmitAdmissionHandler).Methods("POST")
+	r.Handle("/admissions/applications", JwtMiddleware(http.HandlerFunc(handler.GetAdmissionsHandler))).Methods("GET")
+	r.Handle("/admissions/applications/{id}", JwtMiddleware(http.HandlerFunc(handler.GetAdmissionHandler))).Methods("GET")
+	r.Handle("/admissions/applications/{id}/documents/{type}", JwtMiddleware(http.HandlerFunc(handler.UploadAdmissionDocumentHandler))).Methods("PUT")
+	r.Handle("/admissions/applications/{id}/documents/{type}/verify", JwtMiddleware(http.HandlerFunc(handler.VerifyAdmissionDocumentHandler))).Methods("POST")
+	r.Handle("/admissions/applications/{id}/reviews", JwtMiddleware(http.HandlerFunc(handler.ReviewAdmissionHandler))).Methods("POST")
+	r.Handle("/admissions/applications/{id}/stage", JwtMiddleware(http.HandlerFunc(handler.ChangeAdmissionStageHandler))).Methods("POST")
+	r.Handle("/admissions/applications/{id}/offer", JwtMiddleware(http.HandlerFunc(handler.MakeOfferHandler))).Methods("POST")
+	r.Handle("/admissions/applications/{id}/offer-letter", JwtMiddleware(http.HandlerFunc(handler.GetOfferLetterHandler))).Methods("GET")
+	r.Handle("/admissions/applications/{id}/accept", JwtMiddleware(http.HandlerFunc(handler.AcceptOfferHandler))).Methods("POST")
+	r.Handle("/admissions/applications/{id}/convert", JwtMiddleware(http.HandlerFunc(handler.ConvertAdmissionHandler))).Methods("POST")
+	r.Handle("/students/{id}/admission", JwtMiddleware(http.HandlerFunc(handler.GetStudentAdmissionHandler))).Methods("GET")
+
+	// Lecturer leave routes
+	r.HandleFunc("/leave-types", handler.GetLeaveTypesHandler).Methods("GET")
+	r.Handle("/leave-types", JwtMiddleware(http.HandlerFunc(handler.SaveLeaveTypeHandler))).Methods("POST")
+	r.Handle("/lecturers/{id}/leave-balances", JwtMiddleware(http.HandlerFunc(handler.GetLeaveBalancesHandler))).Methods("GET")
+	r.Handle("/lecturers/{id}/leave-balances", JwtMiddleware(http.HandlerFunc(handler.SetLeaveBalanceHandler))).Methods("PUT")
+	r.Handle("/lecturers/{id}/leaves", JwtMiddleware(http.HandlerFunc(handler.RequestLeaveHandler))).Methods("POST")
+	r.Handle("/lecturers/{id}/leaves", JwtMiddleware(http.HandlerFunc(handler.GetLecturerLeavesHandler))).Methods("GET")
+	r.Handle("/leaves", JwtMiddleware(http.HandlerFunc(handler.GetLeavesHandler))).Methods("GET")
+	r.Handle("/leaves/{id}", JwtMiddleware(http.HandlerFunc(handler.GetLeaveHandler))).Methods("GET")
+	r.Handle("/leaves/{id}/decision", JwtMiddleware(http.HandlerFunc(handler.DecideLeaveHandler))).Methods("POST")
+	r.Handle("/leaves/{id}/cancel", JwtMiddleware(http.HandlerFunc(handler.CancelLeaveHandler))).Methods("POST")
+
+	// Workload routes
+	r.HandleFunc("/workload-limits", handler.GetWorkloadLimitsHandler).Methods("GET")
+	r.Handle("/workload-limits/{designation}", JwtMiddleware(http.HandlerFunc(handler.SaveWorkloadLimitHandler))).Methods("PUT")
+	r.Handle("/reports/workload", JwtMiddleware(http.HandlerFunc(handler.GetWorkloadReportHandler))).Methods("GET")
+
+	// Payroll routes
+	r.Handle("/pay-grades", JwtMiddleware(http.HandlerFunc(handler.GetPayGradesHandler))).Methods("GET")
+	r.Handle("/pay-grades/{designation}", JwtMiddleware(http.HandlerFunc(handler.SavePayGradeHandler))).Methods("PUT")
+	r.Handle("/pay-components", JwtMiddleware(http.HandlerFunc(handler.GetPayComponentsHandler))).Methods("GET")
+	r.Handle("/pay-components", JwtMiddleware(http.HandlerFunc(handler.CreatePayComponentHandler))).Methods("POST")
+	r.Handle("/pay-components/{id}", JwtMiddleware(http.HandlerFunc(handler.RetirePayComponentHandler))).Methods("DELETE")
+	r.Handle("/payroll/runs", JwtMiddleware(http.HandlerFunc(handler.RunPayrollHandler))).Methods("POST")
+	r.Handle("/payroll/runs", JwtMiddleware(http.HandlerFunc(handler.GetPayrollRunsHandler))).Methods("GET")
+	r.Handle("/payroll/runs/{id}", JwtMiddleware(http.HandlerFunc(handler.GetPayrollRunHandler))).Methods("GET")
+	r.Handle("/lecturers/{id}/payslips", JwtMiddleware(http.HandlerFunc(handler.GetLecturerPayslipsHandler))).Methods("GET")
+	r.Handle("/payslips/{id}", JwtMiddleware(http.HandlerFunc(handler.GetPayslipHandler))).Methods("GET")
+	r.Handle("/payslips/{id}/download", JwtMiddleware(http.HandlerFunc(handler.DownloadPayslipHandler))).Methods("GET")
 
 	// Borrow_records routes
 	r.HandleFunc("/borrow", handler.Borrowbooks).Methods("POST")
 	r.HandleFunc("/return", handler.ReturnBooksHandler).Methods("POST")
+	r.HandleFunc("/loans/{id}/renew", handler.RenewLoanHandler).Methods("POST")
+
+	// Loan policy routes
+	r.HandleFunc("/loan-policies", handler.GetLoanPoliciesHandler).Methods("GET")
+	r.Handle("/loan-policies/{usertype}", JwtMiddleware(http.HandlerFunc(handler.SaveLoanPolicyHandler))).Methods("PUT")
+
+	// Hold routes
+	r.HandleFunc("/holds", handler.PlaceHoldHandler).Methods("POST")
+	r.HandleFunc("/holds/{id}", handler.GetHoldHandler).Methods("GET")
+	r.HandleFunc("/holds/{id}", handler.CancelHoldHandler).Methods("DELETE")
+
+	// Library fine routes
+	r.Handle("/fines/assess", JwtMiddleware(http.HandlerFunc(handler.AssessFinesHandler))).Methods("POST")
+	r.Handle("/fines/{usertype}/{userId}", JwtMiddleware(http.HandlerFunc(handler.GetFineAccountHandler))).Methods("GET")
+	r.Handle("/fines/{usertype}/{userId}/payments", JwtMiddleware(http.HandlerFunc(handler.PayFineHandler))).Methods("POST")
+	r.Handle("/fines/{usertype}/{userId}/waivers", JwtMiddleware(http.HandlerFunc(handler.WaiveFineHandler))).Methods("POST")
 
 	fmt.Println("Server running on port:8080")
 	http.ListenAndServe(":8080", r)
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// testLibrarian is the only user holding a role in handlers built by newMemoryHandler
+const testLibrarian = "librarian@gmail.com"
+
+// newMemoryHandler returns a handler over the memory repositories with no MySQL, MongoDB or Redis
+func newMemoryHandler() *HybridHandler {
+	return &HybridHandler{
+		Students:  NewMemoryStudentRepository(),
+		Lecturers: NewMemoryLecturerRepository(),
+		Libraries: NewMemoryLibraryRepository(),
+		Roles: func(email, role string) (bool, error) {
+			return email == testLibrarian && role == RoleLibrarian, nil
+		},
+		Ctx: context.Background(),
+	}
+}
+
+// testMySQL connects to the database TEST_MYSQL_DSN names and applies the migrations.
+// Tests that need MySQL are skipped when it is unset. The DSN needs parseTime=true.
+func testMySQL(t *testing.T) *MySQLInstance {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m := &MySQLInstance{db: db}
+	if err := MigrateMySQL(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// serve runs a handler on a request with the route variables and signed in user JwtMiddleware would set
+func serve(t *testing.T, h http.HandlerFunc, method, target string, vars map[string]string, user string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, target, &buf)
+	if vars != nil {
+		r = mux.SetURLVars(r, vars)
+	}
+	if user != "" {
+		r.Header.Set("X-User-Email", user)
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+// decode reads a JSON response into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+}
+
+// wantStatus fails the test unless the response has the status
+func wantStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+}
@@ -0,0 +1,998 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// admission stages, an application moves forward through them and may be rejected before acceptance
+const (
+	StageSubmitted   = "submitted"
+	StageUnderReview = "under_review"
+	StageInterview   = "interview"
+	StageOffered     = "offered"
+	StageAccepted    = "accepted"
+	StageRejected    = "rejected"
+	StageEnrolled    = "enrolled"
+)
+
+// stageTransitions lists the stages each stage may move to
+var stageTransitions = map[string][]string{
+	StageSubmitted:   {StageUnderReview, StageRejected},
+	StageUnderReview: {StageInterview, StageOffered, StageRejected},
+	StageInterview:   {StageOffered, StageRejected},
+	StageOffered:     {StageAccepted, StageRejected},
+	StageAccepted:    {StageEnrolled},
+}
+
+// document checklist statuses
+const (
+	DocumentRequired = "required"
+	DocumentReceived = "received"
+	DocumentVerified = "verified"
+	DocumentRejected = "rejected"
+)
+
+// RequiredDocuments is the checklist created for every application
+var RequiredDocuments = []string{"id_proof", "academic_records", "photo"}
+
+// Applicant is a prospective student, identified by email across applications
+type Applicant struct {
+	Applicantid int       `json:"applicant_id"`
+	Name        string    `json:"name"`
+	Age         int       `json:"age"`
+	Email       string    `json:"email"`
+	Createdat   time.Time `json:"created_at"`
+}
+
+// ApplicationDocument is one entry of an application's document checklist
+type ApplicationDocument struct {
+	Doctype   string    `json:"doc_type"`
+	Status    string    `json:"status"`
+	Filename  string    `json:"filename,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	Updatedat time.Time `json:"updated_at"`
+}
+
+// ApplicationReview is one reviewer's score out of 10
+type ApplicationReview struct {
+	Reviewer  string    `json:"reviewer"`
+	Score     int       `json:"score"`
+	Comments  string    `json:"comments"`
+	Createdat time.Time `json:"created_at"`
+}
+
+// StageChange is one step of an application's history
+type StageChange struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Note      string    `json:"note,omitempty"`
+	Changedby string    `json:"changed_by"`
+	Changedat time.Time `json:"changed_at"`
+}
+
+// OfferLetter is the admission offer made to an applicant
+type OfferLetter struct {
+	Reference  string     `json:"reference"`
+	Conditions string     `json:"conditions,omitempty"`
+	Issuedby   string     `json:"issued_by"`
+	Issuedat   time.Time  `json:"issued_at"`
+	Expiresat  time.Time  `json:"expires_at"`
+	Acceptedat *time.Time `json:"accepted_at,omitempty"`
+}
+
+// AdmissionApplication is an applicant's application to a department for a term
+type AdmissionApplication struct {
+	Applicationid int                   `json:"application_id"`
+	Applicant     Applicant             `json:"applicant"`
+	Term          string                `json:"term"`
+	Dept          string                `json:"dept"`
+	Program       string                `json:"program"`
+	Form          json.RawMessage       `json:"form,omitempty"`
+	Stage         string                `json:"stage"`
+	Studentid     *int                  `json:"student_id,omitempty"`
+	Createdat     time.Time             `json:"created_at"`
+	Updatedat     time.Time             `json:"updated_at"`
+	Documents     []ApplicationDocument `json:"documents,omitempty"`
+	Reviews       []ApplicationReview   `json:"reviews,omitempty"`
+	Averagescore  float64               `json:"average_score,omitempty"`
+	History       []StageChange         `json:"history,omitempty"`
+	Offer         *OfferLetter          `json:"offer,omitempty"`
+}
+
+// StageChangeError is returned for a move the pipeline does not allow
+type StageChangeError struct {
+	From, To string
+}
+
+func (e *StageChangeError) Error() string {
+	return fmt.Sprintf("cannot move application from %s to %s", e.From, e.To)
+}
+
+// errors returned by the admission helpers
+var (
+	ErrAdmissionNotFound     = errors.New("admission application not found")
+	ErrAdmissionExists       = errors.New("applicant already applied to this department and program for the term")
+	ErrUnknownDocument       = errors.New("document is not on the checklist")
+	ErrDocumentMissing       = errors.New("document has not been uploaded")
+	ErrDocumentsIncomplete   = errors.New("every checklist document must be verified first")
+	ErrNoReviews             = errors.New("application needs at least one review")
+	ErrReviewClosed          = errors.New("reviews are only taken while under review or at interview")
+	ErrOfferExpired          = errors.New("offer has expired")
+	ErrApplicationFinished   = errors.New("application is closed")
+	ErrOfferExpiryInPast     = errors.New("expires_at must be in the future")
+	ErrOfferMissing          = errors.New("no offer has been made")
+	ErrInvalidDocumentStatus = errors.New("status must be verified or rejected")
+	ErrInvalidStudentRecord  = errors.New("applicant details are not a valid student record")
+)
+
+// loadAdmission fetches an application with its applicant
+func loadAdmission(q dbtx, id int) (*AdmissionApplication, error) {
+	var app AdmissionApplication
+	var form sql.NullString
+	var studentID sql.NullInt64
+	err := q.QueryRow(`SELECT aa.application_id , aa.term , aa.dept , aa.program , aa.form , aa.stage , aa.student_id , aa.created_at , aa.updated_at ,
+		ap.applicant_id , ap.name , ap.age , ap.email , ap.created_at
+		FROM admission_applications aa JOIN applicants ap ON ap.applicant_id=aa.applicant_id
+		WHERE aa.application_id=?`, id).
+		Scan(&app.Applicationid, &app.Term, &app.Dept, &app.Program, &form, &app.Stage, &studentID, &app.Createdat, &app.Updatedat,
+			&app.Applicant.Applicantid, &app.Applicant.Name, &app.Applicant.Age, &app.Applicant.Email, &app.Applicant.Createdat)
+	if err == sql.ErrNoRows {
+		return nil, ErrAdmissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if form.Valid && form.String != "" {
+		app.Form = json.RawMessage(form.String)
+	}
+	if studentID.Valid {
+		id := int(studentID.Int64)
+		app.Studentid = &id
+	}
+	return &app, nil
+}
+
+// loadAdmissionDetails adds the checklist, reviews, history and offer to an application
+func loadAdmissionDetails(q dbtx, app *AdmissionApplication) error {
+	rows, err := q.Query("SELECT doc_type , status , filename , note , updated_at FROM application_documents WHERE application_id=? ORDER BY doc_type", app.Applicationid)
+	if err != nil {
+		return err
+	}
+	app.Documents = []ApplicationDocument{}
+	for rows.Next() {
+		var d ApplicationDocument
+		var filename, note sql.NullString
+		if err := rows.Scan(&d.Doctype, &d.Status, &filename, &note, &d.Updatedat); err != nil {
+			rows.Close()
+			return err
+		}
+		d.Filename, d.Note = filename.String, note.String
+		app.Documents = append(app.Documents, d)
+	}
+	rows.Close()
+
+	rows, err = q.Query("SELECT reviewer , score , comments , created_at FROM application_reviews WHERE application_id=? ORDER BY created_at", app.Applicationid)
+	if err != nil {
+		return err
+	}
+	app.Reviews = []ApplicationReview{}
+	total := 0
+	for rows.Next() {
+		var rv ApplicationReview
+		var comments sql.NullString
+		if err := rows.Scan(&rv.Reviewer, &rv.Score, &comments, &rv.Createdat); err != nil {
+			rows.Close()
+			return err
+		}
+		rv.Comments = comments.String
+		total += rv.Score
+		app.Reviews = append(app.Reviews, rv)
+	}
+	rows.Close()
+	if len(app.Reviews) > 0 {
+		app.Averagescore = round2(float64(total) / float64(len(app.Reviews)))
+	}
+
+	rows, err = q.Query("SELECT from_stage , to_stage , note , changed_by , changed_at FROM application_stage_history WHERE application_id=? ORDER BY history_id", app.Applicationid)
+	if err != nil {
+		return err
+	}
+	app.History = []StageChange{}
+	for rows.Next() {
+		var c StageChange
+		var from, note sql.NullString
+		if err := rows.Scan(&from, &c.To, &note, &c.Changedby, &c.Changedat); err != nil {
+			rows.Close()
+			return err
+		}
+		c.From, c.Note = from.String, note.String
+		app.History = append(app.History, c)
+	}
+	rows.Close()
+
+	offer, err := loadOffer(q, app.Applicationid)
+	if err != nil && err != ErrOfferMissing {
+		return err
+	}
+	app.Offer = offer
+	return nil
+}
+
+// loadOffer fetches the offer letter of an application
+func loadOffer(q dbtx, applicationID int) (*OfferLetter, error) {
+	var o OfferLetter
+	var conditions sql.NullString
+	var accepted sql.NullTime
+	err := q.QueryRow("SELECT reference , conditions , issued_by , issued_at , expires_at , accepted_at FROM offer_letters WHERE application_id=?", applicationID).
+		Scan(&o.Reference, &conditions, &o.Issuedby, &o.Issuedat, &o.Expiresat, &accepted)
+	if err == sql.ErrNoRows {
+		return nil, ErrOfferMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.Conditions = conditions.String
+	if accepted.Valid {
+		o.Acceptedat = &accepted.Time
+	}
+	return &o, nil
+}
+
+// changeStage moves an application to a new stage and records the step in its history
+func changeStage(q dbtx, app *AdmissionApplication, to, note, actor string) error {
+	allowed := false
+	for _, next := range stageTransitions[app.Stage] {
+		if next == to {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return &StageChangeError{From: app.Stage, To: to}
+	}
+	now := time.Now()
+	if _, err := q.Exec("UPDATE admission_applications SET stage=? , updated_at=? WHERE application_id=?", to, now, app.Applicationid); err != nil {
+		return err
+	}
+	if _, err := q.Exec("INSERT INTO application_stage_history (application_id , from_stage , to_stage , note , changed_by , changed_at) VALUES (? , ? , ? , ? , ? , ?)",
+		app.Applicationid, app.Stage, to, note, actor, now); err != nil {
+		return err
+	}
+	app.Stage, app.Updatedat = to, now
+	return nil
+}
+
+// lockAdmission loads an application inside a transaction with its row locked
+func lockAdmission(tx *sql.Tx, id int) (*AdmissionApplication, error) {
+	var locked int
+	err := tx.QueryRow("SELECT application_id FROM admission_applications WHERE application_id=? FOR UPDATE", id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return nil, ErrAdmissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return loadAdmission(tx, id)
+}
+
+// requireApplicantOrAdmissions writes a 403 and returns false unless the current user is the applicant or admissions staff
+func (a *HybridHandler) requireApplicantOrAdmissions(w http.ResponseWriter, r *http.Request, applicantEmail string) bool {
+	if email := currentUser(r); email != "" && strings.EqualFold(email, applicantEmail) {
+		return true
+	}
+	return a.requireRole(w, r, RoleAdmissions)
+}
+
+// writeAdmissionError maps admission errors to HTTP responses
+func writeAdmissionError(w http.ResponseWriter, err error) {
+	var stage *StageChangeError
+	switch {
+	case errors.Is(err, ErrAdmissionNotFound), errors.Is(err, ErrUnknownDocument), errors.Is(err, ErrOfferMissing), errors.Is(err, ErrBlobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAdmissionExists), errors.As(err, &stage):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrOfferExpiryInPast), errors.Is(err, ErrInvalidDocumentStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrDocumentMissing), errors.Is(err, ErrDocumentsIncomplete), errors.Is(err, ErrNoReviews), errors.Is(err, ErrReviewClosed),
+		errors.Is(err, ErrOfferExpired), errors.Is(err, ErrApplicationFinished), errors.Is(err, ErrInvalidStudentRecord):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SubmitAdmissionHandler files an application, applicant details must pass the same checks as a student record
+func (a *HybridHandler) SubmitAdmissionHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Decode incoming JSON request body
+	var req struct {
+		Applicant Applicant       `json:"applicant"`
+		Term      string          `json:"term"`
+		Dept      string          `json:"dept"`
+		Program   string          `json:"program"`
+		Form      json.RawMessage `json:"form"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// Validate applicant data as a future student
+	err := ValidateStudent(Student{Name: req.Applicant.Name, Age: req.Applicant.Age, Email: req.Applicant.Email, Dept: req.Dept})
+	if err == nil && strings.TrimSpace(req.Term) == "" {
+		err = fmt.Errorf("term cannot be empty")
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	var app *AdmissionApplication
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// reuse the applicant record of earlier applications
+		now := time.Now()
+		var applicantID int64
+		err = tx.QueryRow("SELECT applicant_id FROM applicants WHERE email=? FOR UPDATE", req.Applicant.Email).Scan(&applicantID)
+		if err == sql.ErrNoRows {
+			res, err := tx.Exec("INSERT INTO applicants (name , age , email , created_at) VALUES (? , ? , ? , ?)", req.Applicant.Name, req.Applicant.Age, req.Applicant.Email, now)
+			if err != nil {
+				return err
+			}
+			applicantID, _ = res.LastInsertId()
+		} else if err != nil {
+			return err
+		}
+
+		var form interface{}
+		if len(req.Form) > 0 {
+			form = string(req.Form)
+		}
+		res, err := tx.Exec("INSERT IGNORE INTO admission_applications (applicant_id , term , dept , program , form , stage , created_at , updated_at) VALUES (? , ? , ? , ? , ? , ? , ? , ?)",
+			applicantID, req.Term, req.Dept, req.Program, form, StageSubmitted, now, now)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrAdmissionExists
+		}
+		id, _ := res.LastInsertId()
+		for _, doc := range RequiredDocuments {
+			if _, err := tx.Exec("INSERT INTO application_documents (application_id , doc_type , status , updated_at) VALUES (? , ? , ? , ?)", id, doc, DocumentRequired, now); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("INSERT INTO application_stage_history (application_id , to_stage , changed_by , changed_at) VALUES (? , ? , ? , ?)", id, StageSubmitted, req.Applicant.Email, now); err != nil {
+			return err
+		}
+		if app, err = loadAdmission(tx, int(id)); err != nil {
+			return err
+		}
+		if err := loadAdmissionDetails(tx, app); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	go LogActivity("SUBMIT_ADMISSION", req.Applicant.Email)
+	go AuditLog("SUBMIT", "ADMISSION_APPLICATION", app.Applicationid, req.Applicant.Email)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(app)
+}
+
+// GetAdmissionsHandler lists applications (?stage=, ?term=, ?dept=), admissions staff only
+func (a *HybridHandler) GetAdmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleAdmissions) {
+		return
+	}
+
+	query := "SELECT application_id FROM admission_applications WHERE 1=1"
+	var args []interface{}
+	for _, filter := range []string{"stage", "term", "dept"} {
+		if v := r.URL.Query().Get(filter); v != "" {
+			query += " AND " + filter + "=?"
+			args = append(args, v)
+		}
+	}
+	rows, err := a.MySQL.db.Query(query+" ORDER BY created_at", args...)
+	if err != nil {
+		http.Error(w, "unable to fetch applications", http.StatusInternalServerError)
+		return
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	apps := []AdmissionApplication{}
+	for _, id := range ids {
+		app, err := loadAdmission(a.MySQL.db, id)
+		if err != nil {
+			writeAdmissionError(w, err)
+			return
+		}
+		apps = append(apps, *app)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apps)
+}
+
+// GetAdmissionHandler returns an application with its checklist, reviews, history and offer
+func (a *HybridHandler) GetAdmissionHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	app, err := loadAdmission(a.MySQL.db, id)
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+	if !a.requireApplicantOrAdmissions(w, r, app.Applicant.Email) {
+		return
+	}
+	if err := loadAdmissionDetails(a.MySQL.db, app); err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+// UploadAdmissionDocumentHandler stores a checklist document (multipart field file), replacing an earlier upload
+func (a *HybridHandler) UploadAdmissionDocumentHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	docType := vars["type"]
+
+	app, err := loadAdmission(a.MySQL.db, id)
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+	if !a.requireApplicantOrAdmissions(w, r, app.Applicant.Email) {
+		return
+	}
+	if app.Stage == StageRejected || app.Stage == StageEnrolled {
+		writeAdmissionError(w, ErrApplicationFinished)
+		return
+	}
+	var oldKey sql.NullString
+	err = a.MySQL.db.QueryRow("SELECT blob_key FROM application_documents WHERE application_id=? AND doc_type=?", id, docType).Scan(&oldKey)
+	if err == sql.ErrNoRows {
+		writeAdmissionError(w, ErrUnknownDocument)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Parse the multipart form within the upload limit
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes())
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required and must be within the upload limit", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	code, err := newVerificationCode()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filename := filepath.Base(header.Filename)
+	key := fmt.Sprintf("admissions/%d/%s-%s-%s", id, docType, strings.ToLower(code), filename)
+	ctx, cancel := context.WithTimeout(a.Ctx, 2*time.Minute)
+	defer cancel()
+	if _, err := a.Blobs.Put(ctx, key, file); err != nil {
+		http.Error(w, "failed to store file", http.StatusInternalServerError)
+		return
+	}
+	if _, err := a.MySQL.db.Exec("UPDATE application_documents SET status=? , blob_key=? , filename=? , note=NULL , updated_at=? WHERE application_id=? AND doc_type=?",
+		DocumentReceived, key, filename, time.Now(), id, docType); err != nil {
+		a.Blobs.Delete(context.Background(), key)
+		http.Error(w, "failed to record document", http.StatusInternalServerError)
+		return
+	}
+	if oldKey.Valid {
+		a.Blobs.Delete(context.Background(), oldKey.String)
+	}
+
+	go LogActivity("UPLOAD_ADMISSION_DOCUMENT", currentUser(r))
+	go AuditLog("UPLOAD", "ADMISSION_DOCUMENT", fmt.Sprintf("%d %s", id, docType), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ApplicationDocument{Doctype: docType, Status: DocumentReceived, Filename: filename, Updatedat: time.Now()})
+}
+
+// VerifyAdmissionDocumentHandler marks an uploaded document verified or rejected, admissions staff only
+func (a *HybridHandler) VerifyAdmissionDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleAdmissions) {
+		return
+	}
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	docType := vars["type"]
+
+	// Decode incoming JSON request body
+	var req struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Status != DocumentVerified && req.Status != DocumentRejected {
+		writeAdmissionError(w, ErrInvalidDocumentStatus)
+		return
+	}
+
+	var key sql.NullString
+	err = a.MySQL.db.QueryRow("SELECT blob_key FROM application_documents WHERE application_id=? AND doc_type=?", id, docType).Scan(&key)
+	if err == sql.ErrNoRows {
+		writeAdmissionError(w, ErrUnknownDocument)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !key.Valid {
+		writeAdmissionError(w, ErrDocumentMissing)
+		return
+	}
+	if _, err := a.MySQL.db.Exec("UPDATE application_documents SET status=? , note=? , updated_at=? WHERE application_id=? AND doc_type=?", req.Status, req.Note, time.Now(), id, docType); err != nil {
+		http.Error(w, "failed to update document", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("VERIFY_ADMISSION_DOCUMENT", currentUser(r))
+	go AuditLog(strings.ToUpper(req.Status), "ADMISSION_DOCUMENT", fmt.Sprintf("%d %s", id, docType), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"application_id": id, "doc_type": docType, "status": req.Status})
+}
+
+// ReviewAdmissionHandler records the current reviewer's score out of 10, a second review replaces the first
+func (a *HybridHandler) ReviewAdmissionHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleAdmissions) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var review ApplicationReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if review.Score < 1 || review.Score > 10 {
+		http.Error(w, "score must be between 1 and 10", http.StatusBadRequest)
+		return
+	}
+
+	app, err := loadAdmission(a.MySQL.db, id)
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+	if app.Stage != StageUnderReview && app.Stage != StageInterview {
+		writeAdmissionError(w, ErrReviewClosed)
+		return
+	}
+
+	review.Reviewer, review.Createdat = currentUser(r), time.Now()
+	_, err = a.MySQL.db.Exec(`INSERT INTO application_reviews (application_id , reviewer , score , comments , created_at) VALUES (? , ? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE score=VALUES(score) , comments=VALUES(comments) , created_at=VALUES(created_at)`,
+		id, review.Reviewer, review.Score, review.Comments, review.Createdat)
+	if err != nil {
+		http.Error(w, "failed to save review", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("REVIEW_ADMISSION", review.Reviewer)
+	go AuditLog("REVIEW", "ADMISSION_APPLICATION", fmt.Sprintf("%d score=%d", id, review.Score), review.Reviewer)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// ChangeAdmissionStageHandler moves an application to under_review, interview or rejected, admissions staff only.
+// Offers, acceptance and enrollment have their own endpoints.
+func (a *HybridHandler) ChangeAdmissionStageHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleAdmissions) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Stage string `json:"stage"`
+		Note  string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Stage != StageUnderReview && req.Stage != StageInterview && req.Stage != StageRejected {
+		http.Error(w, "stage must be under_review, interview or rejected", http.StatusBadRequest)
+		return
+	}
+
+	actor := currentUser(r)
+	var app *AdmissionApplication
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if app, err = lockAdmission(tx, id); err != nil {
+			return err
+		}
+		if err := changeStage(tx, app, req.Stage, req.Note, actor); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	go LogActivity("CHANGE_ADMISSION_STAGE", actor)
+	go AuditLog(strings.ToUpper(req.Stage), "ADMISSION_APPLICATION", id, actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+// MakeOfferHandler issues an offer letter once every document is verified and the application was reviewed
+func (a *HybridHandler) MakeOfferHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleAdmissions) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Expiresat  time.Time `json:"expires_at"`
+		Conditions string    `json:"conditions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if !req.Expiresat.After(time.Now()) {
+		writeAdmissionError(w, ErrOfferExpiryInPast)
+		return
+	}
+
+	actor := currentUser(r)
+	offer := &OfferLetter{Conditions: req.Conditions, Issuedby: actor, Issuedat: time.Now(), Expiresat: req.Expiresat}
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		app, err := lockAdmission(tx, id)
+		if err != nil {
+			return err
+		}
+		var pending, reviews int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM application_documents WHERE application_id=? AND status<>?", id, DocumentVerified).Scan(&pending); err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrDocumentsIncomplete
+		}
+		if err := tx.QueryRow("SELECT COUNT(*) FROM application_reviews WHERE application_id=?", id).Scan(&reviews); err != nil {
+			return err
+		}
+		if reviews == 0 {
+			return ErrNoReviews
+		}
+		if err := changeStage(tx, app, StageOffered, "offer issued", actor); err != nil {
+			return err
+		}
+		if offer.Reference, err = newVerificationCode(); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO offer_letters (application_id , reference , conditions , issued_by , issued_at , expires_at) VALUES (? , ? , ? , ? , ? , ?)",
+			id, offer.Reference, offer.Conditions, offer.Issuedby, offer.Issuedat, offer.Expiresat); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	go LogActivity("MAKE_OFFER", actor)
+	go AuditLog("OFFER", "ADMISSION_APPLICATION", fmt.Sprintf("%d ref=%s", id, offer.Reference), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(offer)
+}
+
+// GetOfferLetterHandler renders the offer letter as PDF
+func (a *HybridHandler) GetOfferLetterHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	app, err := loadAdmission(a.MySQL.db, id)
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+	if !a.requireApplicantOrAdmissions(w, r, app.Applicant.Email) {
+		return
+	}
+	offer, err := loadOffer(a.MySQL.db, id)
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	program := app.Dept
+	if app.Program != "" {
+		program += " - " + app.Program
+	}
+	lines := []string{
+		"OFFER OF ADMISSION",
+		"",
+		"Reference: " + offer.Reference,
+		"Date:      " + offer.Issuedat.Format("2006-01-02"),
+		"",
+		"Dear " + app.Applicant.Name + ",",
+		"",
+		"We are pleased to offer you admission to " + program,
+		"starting in term " + app.Term + ".",
+		"",
+	}
+	if offer.Conditions != "" {
+		lines = append(lines, "This offer is subject to the following conditions:", "  "+offer.Conditions, "")
+	}
+	lines = append(lines,
+		"Please accept this offer before "+offer.Expiresat.Format("2006-01-02 15:04 MST")+".",
+		"",
+		"Admissions Office",
+	)
+	pdf := renderPDF(lines, map[string]string{
+		"Title":   "Offer of Admission - " + app.Applicant.Name,
+		"Subject": "Reference " + offer.Reference,
+		"Creator": "College Management System",
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=offer-%s.pdf", offer.Reference))
+	w.Write(pdf)
+}
+
+// AcceptOfferHandler records the applicant's acceptance of an unexpired offer
+func (a *HybridHandler) AcceptOfferHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	app, err := loadAdmission(a.MySQL.db, id)
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+	if !a.requireApplicantOrAdmissions(w, r, app.Applicant.Email) {
+		return
+	}
+
+	actor := currentUser(r)
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if app, err = lockAdmission(tx, id); err != nil {
+			return err
+		}
+		offer, err := loadOffer(tx, id)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if now.After(offer.Expiresat) {
+			return ErrOfferExpired
+		}
+		if err := changeStage(tx, app, StageAccepted, "offer "+offer.Reference+" accepted", actor); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE offer_letters SET accepted_at=? WHERE application_id=?", now, id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	go LogActivity("ACCEPT_OFFER", actor)
+	go AuditLog("ACCEPT", "ADMISSION_APPLICATION", id, actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+// ConvertAdmissionHandler creates the Student record for an accepted application, admissions staff only.
+// The student goes through ValidateStudent and insertStudent like any other, and stays linked to the application.
+func (a *HybridHandler) ConvertAdmissionHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleAdmissions) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	actor := currentUser(r)
+	var student Student
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		app, err := lockAdmission(tx, id)
+		if err != nil {
+			return err
+		}
+		if app.Stage != StageAccepted {
+			return &StageChangeError{From: app.Stage, To: StageEnrolled}
+		}
+
+		student = Student{Name: app.Applicant.Name, Age: app.Applicant.Age, Email: app.Applicant.Email, Dept: app.Dept}
+		if err := ValidateStudent(student); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidStudentRecord, err)
+		}
+		if err := insertStudent(tx, &student); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE admission_applications SET student_id=? WHERE application_id=?", student.Id, id); err != nil {
+			return err
+		}
+		if err := changeStage(tx, app, StageEnrolled, fmt.Sprintf("student %d created", student.Id), actor); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	go LogActivity("CONVERT_ADMISSION", actor)
+	go AuditLog("CREATE", "STUDENT", fmt.Sprintf("%d from application %d", student.Id, id), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"application_id": id, "student": student})
+}
+
+// GetStudentAdmissionHandler returns the admission application and history a student was created from
+func (a *HybridHandler) GetStudentAdmissionHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, studentID) {
+		return
+	}
+
+	var id int
+	err = a.MySQL.db.QueryRow("SELECT application_id FROM admission_applications WHERE student_id=?", studentID).Scan(&id)
+	if err == sql.ErrNoRows {
+		writeAdmissionError(w, ErrAdmissionNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	app, err := loadAdmission(a.MySQL.db, id)
+	if err == nil {
+		err = loadAdmissionDetails(a.MySQL.db, app)
+	}
+	if err != nil {
+		writeAdmissionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
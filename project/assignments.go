@@ -0,0 +1,552 @@
+package project
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Assignment is coursework published to a section with a due date and late policy
+type Assignment struct {
+	Assignmentid      int       `json:"assignment_id"`
+	Sectionid         int       `json:"section_id"`
+	Title             string    `json:"title"`
+	Description       string    `json:"description"`
+	Dueat             time.Time `json:"due_at"`
+	Maxscore          float64   `json:"max_score"`
+	Latepenaltyperday float64   `json:"late_penalty_per_day"`
+	Maxlatedays       int       `json:"max_late_days"`
+	Createdby         string    `json:"created_by,omitempty"`
+	Createdat         time.Time `json:"created_at"`
+}
+
+// SubmissionGrade is a lecturer's score and feedback on one submission version
+type SubmissionGrade struct {
+	Rawscore       float64   `json:"raw_score"`
+	Penaltypercent float64   `json:"penalty_percent"`
+	Finalscore     float64   `json:"final_score"`
+	Feedback       string    `json:"feedback"`
+	Gradedby       string    `json:"graded_by"`
+	Gradedat       time.Time `json:"graded_at"`
+}
+
+// Submission is one uploaded version of a student's work, resubmitting adds a new version
+type Submission struct {
+	Submissionid int              `json:"submission_id"`
+	Assignmentid int              `json:"assignment_id"`
+	Studentid    int              `json:"student_id"`
+	Version      int              `json:"version"`
+	Filename     string           `json:"filename"`
+	Contenttype  string           `json:"content_type"`
+	Size         int64            `json:"size_bytes"`
+	Checksum     string           `json:"checksum"`
+	Submittedat  time.Time        `json:"submitted_at"`
+	Latedays     int              `json:"late_days"`
+	Grade        *SubmissionGrade `json:"grade,omitempty"`
+}
+
+// errors returned by the assignment helpers
+var (
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	ErrSubmissionNotFound = errors.New("submission not found")
+	ErrSubmissionClosed   = errors.New("submissions are closed for this assignment")
+)
+
+// ValidateAssignment validates incoming assignment data
+func ValidateAssignment(as Assignment) error {
+	if strings.TrimSpace(as.Title) == "" {
+		return fmt.Errorf("title cannot be empty")
+	}
+	if as.Dueat.IsZero() {
+		return fmt.Errorf("due_at is required")
+	}
+	if as.Maxscore <= 0 {
+		return fmt.Errorf("max_score must be greater than 0")
+	}
+	if as.Latepenaltyperday < 0 || as.Latepenaltyperday > 100 {
+		return fmt.Errorf("late_penalty_per_day must be between 0 and 100")
+	}
+	if as.Maxlatedays < 0 {
+		return fmt.Errorf("max_late_days cannot be negative")
+	}
+	return nil
+}
+
+// lateDays counts started days past the due date, zero when on time
+func lateDays(due, submitted time.Time) int {
+	if !submitted.After(due) {
+		return 0
+	}
+	return int(math.Ceil(submitted.Sub(due).Hours() / 24))
+}
+
+// latePenalty is the percentage deducted for a late submission, capped at 100
+func latePenalty(days int, perDay float64) float64 {
+	return math.Min(100, float64(days)*perDay)
+}
+
+// penalisedScore applies a late penalty percentage to a raw score
+func penalisedScore(raw, penaltyPercent float64) float64 {
+	return round2(raw * (100 - penaltyPercent) / 100)
+}
+
+// maxUploadBytes reads the upload limit from MAX_UPLOAD_MB, default 20 MB
+func maxUploadBytes() int64 {
+	if mb, err := strconv.Atoi(os.Getenv("MAX_UPLOAD_MB")); err == nil && mb > 0 {
+		return int64(mb) << 20
+	}
+	return 20 << 20
+}
+
+// loadAssignment fetches one assignment
+func loadAssignment(q dbtx, id int) (*Assignment, error) {
+	var as Assignment
+	var description sql.NullString
+	err := q.QueryRow("SELECT assignment_id , section_id , title , description , due_at , max_score , late_penalty_per_day , max_late_days , created_by , created_at FROM assignments WHERE assignment_id=?", id).
+		Scan(&as.Assignmentid, &as.Sectionid, &as.Title, &description, &as.Dueat, &as.Maxscore, &as.Latepenaltyperday, &as.Maxlatedays, &as.Createdby, &as.Createdat)
+	if err == sql.ErrNoRows {
+		return nil, ErrAssignmentNotFound
+	}
+	as.Description = description.String
+	return &as, err
+}
+
+// writeAssignmentError maps assignment errors to HTTP responses
+func writeAssignmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrAssignmentNotFound), errors.Is(err, ErrSubmissionNotFound), errors.Is(err, ErrBlobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrSubmissionClosed), errors.Is(err, ErrNotEnrolledInSec):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// CreateAssignmentHandler publishes an assignment to a section, section staff only
+func (a *HybridHandler) CreateAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var as Assignment
+	if err := json.NewDecoder(r.Body).Decode(&as); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// Validate assignment data
+	if err := ValidateAssignment(as); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	if _, _, err := sectionTerm(a.MySQL.db, sectionID); err != nil {
+		writeEnrollmentError(w, err)
+		return
+	}
+
+	as.Sectionid = sectionID
+	as.Createdby = currentUser(r)
+	as.Createdat = time.Now()
+	res, err := a.MySQL.db.Exec("INSERT INTO assignments (section_id , title , description , due_at , max_score , late_penalty_per_day , max_late_days , created_by , created_at) VALUES (? , ? , ? , ? , ? , ? , ? , ? , ?)",
+		as.Sectionid, as.Title, as.Description, as.Dueat, as.Maxscore, as.Latepenaltyperday, as.Maxlatedays, as.Createdby, as.Createdat)
+	if err != nil {
+		http.Error(w, "failed to create assignment", http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+	as.Assignmentid = int(id)
+
+	go LogActivity("CREATE_ASSIGNMENT", as.Createdby)
+	go AuditLog("CREATE", "ASSIGNMENT", as.Assignmentid, as.Createdby)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(as)
+}
+
+// GetSectionAssignmentsHandler lists the assignments of a section by due date
+func (a *HybridHandler) GetSectionAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT assignment_id , section_id , title , description , due_at , max_score , late_penalty_per_day , max_late_days , created_at FROM assignments WHERE section_id=? ORDER BY due_at", sectionID)
+	if err != nil {
+		http.Error(w, "unable to fetch assignments", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	assignments := []Assignment{}
+	for rows.Next() {
+		var as Assignment
+		var description sql.NullString
+		if err := rows.Scan(&as.Assignmentid, &as.Sectionid, &as.Title, &description, &as.Dueat, &as.Maxscore, &as.Latepenaltyperday, &as.Maxlatedays, &as.Createdat); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		as.Description = description.String
+		assignments = append(assignments, as)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignments)
+}
+
+// GetAssignmentByIDHandler returns one assignment
+func (a *HybridHandler) GetAssignmentByIDHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	as, err := loadAssignment(a.MySQL.db, id)
+	if err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(as)
+}
+
+// SubmitAssignmentHandler uploads a new submission version as multipart form data (file, student_id).
+// The file is stored first and removed again if the submission cannot be recorded.
+func (a *HybridHandler) SubmitAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract assignment id from URL
+	assignmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Parse the multipart form within the upload limit
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes())
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, "invalid or too large upload", http.StatusBadRequest)
+		return
+	}
+	studentID, err := strconv.Atoi(r.FormValue("student_id"))
+	if err != nil || studentID <= 0 {
+		http.Error(w, "invalid student_id", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, studentID) {
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	as, err := loadAssignment(a.MySQL.db, assignmentID)
+	if err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+	var enrolled int
+	if err := a.MySQL.db.QueryRow("SELECT COUNT(*) FROM enrollments WHERE section_id=? AND student_id=? AND status=?", as.Sectionid, studentID, EnrollmentEnrolled).Scan(&enrolled); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if enrolled == 0 {
+		writeAssignmentError(w, ErrNotEnrolledInSec)
+		return
+	}
+
+	// reject submissions past the late window before storing anything
+	now := time.Now()
+	late := lateDays(as.Dueat, now)
+	if late > as.Maxlatedays {
+		writeAssignmentError(w, ErrSubmissionClosed)
+		return
+	}
+
+	// Store the file, hashing it on the way through
+	code, err := newVerificationCode()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filename := filepath.Base(header.Filename)
+	key := fmt.Sprintf("assignments/%d/%d/%s-%s", assignmentID, studentID, strings.ToLower(code), filename)
+	hash := sha256.New()
+	ctx, cancel := context.WithTimeout(a.Ctx, 2*time.Minute)
+	defer cancel()
+	size, err := a.Blobs.Put(ctx, key, io.TeeReader(file, hash))
+	if err != nil {
+		http.Error(w, "failed to store file", http.StatusInternalServerError)
+		return
+	}
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	sub := Submission{
+		Assignmentid: assignmentID,
+		Studentid:    studentID,
+		Filename:     filename,
+		Contenttype:  contentType,
+		Size:         size,
+		Checksum:     hex.EncodeToString(hash.Sum(nil)),
+		Submittedat:  now,
+		Latedays:     late,
+	}
+
+	// Number the version inside a transaction so concurrent resubmissions get distinct versions
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) + 1 FROM submissions WHERE assignment_id=? AND student_id=? FOR UPDATE", assignmentID, studentID).Scan(&sub.Version); err != nil {
+			return err
+		}
+		res, err := tx.Exec("INSERT INTO submissions (assignment_id , student_id , version , blob_key , filename , content_type , size_bytes , checksum , submitted_at , late_days) VALUES (? , ? , ? , ? , ? , ? , ? , ? , ? , ?)",
+			sub.Assignmentid, sub.Studentid, sub.Version, key, sub.Filename, sub.Contenttype, sub.Size, sub.Checksum, sub.Submittedat, sub.Latedays)
+		if err != nil {
+			return err
+		}
+		id, _ := res.LastInsertId()
+		sub.Submissionid = int(id)
+		return tx.Commit()
+	}()
+	if err != nil {
+		a.Blobs.Delete(context.Background(), key)
+		http.Error(w, "failed to record submission", http.StatusInternalServerError)
+		return
+	}
+
+	actor := currentUser(r)
+	go LogActivity("SUBMIT_ASSIGNMENT", actor)
+	go AuditLog("SUBMIT", "SUBMISSION", fmt.Sprintf("%d assignment=%d student=%d version=%d late_days=%d", sub.Submissionid, assignmentID, studentID, sub.Version, late), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// GetAssignmentSubmissionsHandler lists submissions with grades; section staff see everyone,
+// a student sees only their own and must pass ?student_id=
+func (a *HybridHandler) GetAssignmentSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract assignment id from URL
+	assignmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	as, err := loadAssignment(a.MySQL.db, assignmentID)
+	if err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+
+	studentID, _ := strconv.Atoi(r.URL.Query().Get("student_id"))
+	staff, err := a.isSectionStaff(currentUser(r), as.Sectionid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !staff {
+		own, err := a.isStudent(currentUser(r), studentID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !own {
+			http.Error(w, "only section staff or the submitting student can view submissions", http.StatusForbidden)
+			return
+		}
+	}
+
+	query := `SELECT s.submission_id , s.assignment_id , s.student_id , s.version , s.filename , s.content_type , s.size_bytes , s.checksum , s.submitted_at , s.late_days ,
+		g.raw_score , g.penalty_percent , g.final_score , g.feedback , g.graded_by , g.graded_at
+		FROM submissions s LEFT JOIN submission_grades g ON g.submission_id=s.submission_id
+		WHERE s.assignment_id=?`
+	args := []interface{}{assignmentID}
+	if studentID > 0 {
+		query += " AND s.student_id=?"
+		args = append(args, studentID)
+	}
+	query += " ORDER BY s.student_id , s.version"
+
+	rows, err := a.MySQL.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "unable to fetch submissions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	submissions := []Submission{}
+	for rows.Next() {
+		var s Submission
+		var raw, penalty, final sql.NullFloat64
+		var feedback, gradedBy sql.NullString
+		var gradedAt sql.NullTime
+		if err := rows.Scan(&s.Submissionid, &s.Assignmentid, &s.Studentid, &s.Version, &s.Filename, &s.Contenttype, &s.Size, &s.Checksum, &s.Submittedat, &s.Latedays,
+			&raw, &penalty, &final, &feedback, &gradedBy, &gradedAt); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		if raw.Valid {
+			s.Grade = &SubmissionGrade{Rawscore: raw.Float64, Penaltypercent: penalty.Float64, Finalscore: final.Float64, Feedback: feedback.String, Gradedby: gradedBy.String, Gradedat: gradedAt.Time}
+		}
+		submissions = append(submissions, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submissions)
+}
+
+// DownloadSubmissionHandler streams a submitted file to section staff or the submitting student
+func (a *HybridHandler) DownloadSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	var key, filename, contentType string
+	var studentID, sectionID int
+	err = a.MySQL.db.QueryRow("SELECT s.blob_key , s.filename , s.content_type , s.student_id , a.section_id FROM submissions s JOIN assignments a ON a.assignment_id=s.assignment_id WHERE s.submission_id=?", id).
+		Scan(&key, &filename, &contentType, &studentID, &sectionID)
+	if err == sql.ErrNoRows {
+		writeAssignmentError(w, ErrSubmissionNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	allowed, err := a.isStudent(currentUser(r), studentID)
+	if err == nil && !allowed {
+		allowed, err = a.isSectionStaff(currentUser(r), sectionID)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "only section staff or the submitting student can download this file", http.StatusForbidden)
+		return
+	}
+
+	blob, err := a.Blobs.Get(r.Context(), key)
+	if err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	io.Copy(w, blob)
+}
+
+// GradeSubmissionHandler records a score and feedback, the late penalty is applied automatically
+func (a *HybridHandler) GradeSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Score    *float64 `json:"score"`
+		Feedback string   `json:"feedback"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	var assignmentID, late int
+	err = a.MySQL.db.QueryRow("SELECT assignment_id , late_days FROM submissions WHERE submission_id=?", id).Scan(&assignmentID, &late)
+	if err == sql.ErrNoRows {
+		writeAssignmentError(w, ErrSubmissionNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	as, err := loadAssignment(a.MySQL.db, assignmentID)
+	if err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+	if !a.requireSectionStaff(w, r, as.Sectionid) {
+		return
+	}
+	if req.Score == nil || *req.Score < 0 || *req.Score > as.Maxscore {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": fmt.Sprintf("score must be between 0 and %.2f", as.Maxscore)})
+		return
+	}
+
+	grade := SubmissionGrade{
+		Rawscore:       *req.Score,
+		Penaltypercent: latePenalty(late, as.Latepenaltyperday),
+		Feedback:       req.Feedback,
+		Gradedby:       currentUser(r),
+		Gradedat:       time.Now(),
+	}
+	grade.Finalscore = penalisedScore(grade.Rawscore, grade.Penaltypercent)
+
+	_, err = a.MySQL.db.Exec(`INSERT INTO submission_grades (submission_id , raw_score , penalty_percent , final_score , feedback , graded_by , graded_at) VALUES (? , ? , ? , ? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE raw_score=VALUES(raw_score) , penalty_percent=VALUES(penalty_percent) , final_score=VALUES(final_score) , feedback=VALUES(feedback) , graded_by=VALUES(graded_by) , graded_at=VALUES(graded_at)`,
+		id, grade.Rawscore, grade.Penaltypercent, grade.Finalscore, grade.Feedback, grade.Gradedby, grade.Gradedat)
+	if err != nil {
+		http.Error(w, "failed to save grade", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("GRADE_SUBMISSION", grade.Gradedby)
+	go AuditLog("GRADE", "SUBMISSION", fmt.Sprintf("%d raw=%.2f penalty=%.2f%% final=%.2f", id, grade.Rawscore, grade.Penaltypercent, grade.Finalscore), grade.Gradedby)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grade)
+}
@@ -0,0 +1,469 @@
+package project
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// attendance statuses
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// ClassSession is one dated occurrence of a section meeting
+type ClassSession struct {
+	Sessionid   int    `json:"session_id"`
+	Sectionid   int    `json:"section_id"`
+	Meetingid   *int   `json:"meeting_id,omitempty"`
+	Roomid      int    `json:"room_id"`
+	Sessiondate string `json:"session_date"`
+	Starttime   string `json:"start_time"`
+	Endtime     string `json:"end_time"`
+}
+
+// AttendanceEntry marks one student for a session, a reason is required when changing an existing mark
+type AttendanceEntry struct {
+	Studentid int    `json:"student_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AttendanceSummary is a student's attendance in one section
+type AttendanceSummary struct {
+	Studentid int     `json:"student_id"`
+	Sectionid int     `json:"section_id"`
+	Code      string  `json:"code"`
+	Sessions  int     `json:"sessions"`
+	Present   int     `json:"present"`
+	Late      int     `json:"late"`
+	Absent    int     `json:"absent"`
+	Excused   int     `json:"excused"`
+	Percent   float64 `json:"percent"`
+}
+
+// errors returned while marking attendance
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrReasonRequired   = errors.New("a reason is required to correct an existing attendance mark")
+	ErrNotEnrolledInSec = errors.New("student is not enrolled in this section")
+)
+
+// ValidateAttendanceEntry validates an attendance mark
+func ValidateAttendanceEntry(e AttendanceEntry) error {
+	if e.Studentid <= 0 {
+		return fmt.Errorf("invalid student_id")
+	}
+	switch e.Status {
+	case AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused:
+	default:
+		return fmt.Errorf("status must be present, absent, late or excused")
+	}
+	if len(e.Reason) > 255 {
+		return fmt.Errorf("reason cannot exceed 255 characters")
+	}
+	return nil
+}
+
+// attendancePercent counts late as attended and leaves excused sessions out
+func attendancePercent(present, late, absent int) float64 {
+	counted := present + late + absent
+	if counted == 0 {
+		return 100
+	}
+	return round2(float64(present+late) / float64(counted) * 100)
+}
+
+// attendanceThreshold reads the minimum attendance percentage from ?min= or ATTENDANCE_MIN_PERCENT, default 75
+func attendanceThreshold(r *http.Request) float64 {
+	for _, v := range []string{r.URL.Query().Get("min"), os.Getenv("ATTENDANCE_MIN_PERCENT")} {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 100 {
+			return f
+		}
+	}
+	return 75
+}
+
+// GenerateSessionsHandler creates dated class sessions from a section's weekly meetings between two dates
+func (a *HybridHandler) GenerateSessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	from, err1 := time.Parse("2006-01-02", req.From)
+	to, err2 := time.Parse("2006-01-02", req.To)
+	if err1 != nil || err2 != nil || to.Before(from) || to.Sub(from) > 366*24*time.Hour {
+		http.Error(w, "from and to must be YYYY-MM-DD dates at most a year apart", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT meeting_id , section_id , room_id , day_of_week , start_time , end_time FROM section_meetings WHERE section_id=?", sectionID)
+	if err != nil {
+		http.Error(w, "unable to fetch meetings", http.StatusInternalServerError)
+		return
+	}
+	meetings, err := scanMeetings(rows)
+	if err != nil {
+		http.Error(w, "rows scan failed", http.StatusInternalServerError)
+		return
+	}
+	if len(meetings) == 0 {
+		http.Error(w, "section has no scheduled meetings", http.StatusBadRequest)
+		return
+	}
+
+	// existing sessions are kept, so generation can be repeated safely
+	created := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, m := range meetings {
+			if m.Day%7 != int(day.Weekday()) {
+				continue
+			}
+			res, err := a.MySQL.db.Exec("INSERT IGNORE INTO class_sessions (section_id , meeting_id , room_id , session_date , start_time , end_time) VALUES (? , ? , ? , ? , ? , ?)",
+				sectionID, m.Meetingid, m.Roomid, day.Format("2006-01-02"), m.Starttime, m.Endtime)
+			if err != nil {
+				http.Error(w, "failed to create sessions", http.StatusInternalServerError)
+				return
+			}
+			n, _ := res.RowsAffected()
+			created += int(n)
+		}
+	}
+
+	go LogActivity("GENERATE_SESSIONS", currentUser(r))
+	go AuditLog("GENERATE", "CLASS_SESSIONS", fmt.Sprintf("section=%d from=%s to=%s created=%d", sectionID, req.From, req.To, created), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"section_id": sectionID, "created": created})
+}
+
+// GetSectionSessionsHandler lists the class sessions of a section to its lecturers and registrars
+func (a *HybridHandler) GetSectionSessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT session_id , section_id , meeting_id , room_id , session_date , start_time , end_time FROM class_sessions WHERE section_id=? ORDER BY session_date , start_time", sectionID)
+	if err != nil {
+		http.Error(w, "unable to fetch sessions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	sessions := []ClassSession{}
+	for rows.Next() {
+		var s ClassSession
+		var meetingID sql.NullInt64
+		var date time.Time
+		if err := rows.Scan(&s.Sessionid, &s.Sectionid, &meetingID, &s.Roomid, &date, &s.Starttime, &s.Endtime); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		if meetingID.Valid {
+			id := int(meetingID.Int64)
+			s.Meetingid = &id
+		}
+		s.Sessiondate = date.Format("2006-01-02")
+		s.Starttime, s.Endtime = trimClock(s.Starttime), trimClock(s.Endtime)
+		sessions = append(sessions, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// sessionSection returns the section a session belongs to
+func sessionSection(q dbtx, sessionID int) (int, error) {
+	var sectionID int
+	err := q.QueryRow("SELECT section_id FROM class_sessions WHERE session_id=?", sessionID).Scan(&sectionID)
+	if err == sql.ErrNoRows {
+		return 0, ErrSessionNotFound
+	}
+	return sectionID, err
+}
+
+// markAttendance records one mark, keeping a correction record when an existing mark changes.
+// It returns the previous status, empty when the student had not been marked.
+func markAttendance(tx *sql.Tx, sessionID, sectionID int, e AttendanceEntry, actor string) (string, error) {
+	var enrolled int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM enrollments WHERE section_id=? AND student_id=? AND status IN (? , ? , ?)", sectionID, e.Studentid, EnrollmentEnrolled, EnrollmentCompleted, EnrollmentFailed).Scan(&enrolled); err != nil {
+		return "", err
+	}
+	if enrolled == 0 {
+		return "", fmt.Errorf("student %d: %w", e.Studentid, ErrNotEnrolledInSec)
+	}
+
+	var old string
+	err := tx.QueryRow("SELECT status FROM attendance WHERE session_id=? AND student_id=? FOR UPDATE", sessionID, e.Studentid).Scan(&old)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	now := time.Now()
+	if err == sql.ErrNoRows {
+		_, err = tx.Exec("INSERT INTO attendance (session_id , student_id , status , marked_by , marked_at) VALUES (? , ? , ? , ? , ?)", sessionID, e.Studentid, e.Status, actor, now)
+		return "", err
+	}
+	if old == e.Status {
+		return old, nil
+	}
+	if strings.TrimSpace(e.Reason) == "" {
+		return "", fmt.Errorf("student %d: %w", e.Studentid, ErrReasonRequired)
+	}
+	if _, err := tx.Exec("INSERT INTO attendance_corrections (session_id , student_id , old_status , new_status , reason , corrected_by , corrected_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+		sessionID, e.Studentid, old, e.Status, e.Reason, actor, now); err != nil {
+		return "", err
+	}
+	_, err = tx.Exec("UPDATE attendance SET status=? , marked_by=? , marked_at=? WHERE session_id=? AND student_id=?", e.Status, actor, now, sessionID, e.Studentid)
+	return old, err
+}
+
+// writeAttendanceError maps attendance errors to HTTP responses
+func writeAttendanceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrReasonRequired), errors.Is(err, ErrNotEnrolledInSec):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// saveAttendance validates and stores marks for a session in one transaction, auditing every change
+func (a *HybridHandler) saveAttendance(w http.ResponseWriter, r *http.Request, sessionID int, entries []AttendanceEntry) {
+	sectionID, err := sessionSection(a.MySQL.db, sessionID)
+	if err != nil {
+		writeAttendanceError(w, err)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+	for _, e := range entries {
+		if err := ValidateAttendanceEntry(e); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"err": fmt.Sprintf("student %d: %v", e.Studentid, err)})
+			return
+		}
+	}
+
+	actor := currentUser(r)
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	marked, corrected := 0, 0
+	var audits []string
+	for _, e := range entries {
+		old, err := markAttendance(tx, sessionID, sectionID, e, actor)
+		if err != nil {
+			tx.Rollback()
+			writeAttendanceError(w, err)
+			return
+		}
+		switch {
+		case old == "":
+			marked++
+			audits = append(audits, fmt.Sprintf("MARK session=%d student=%d status=%s", sessionID, e.Studentid, e.Status))
+		case old != e.Status:
+			corrected++
+			audits = append(audits, fmt.Sprintf("CORRECT session=%d student=%d status=%s->%s reason=%q", sessionID, e.Studentid, old, e.Status, e.Reason))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("MARK_ATTENDANCE", actor)
+	for _, entry := range audits {
+		action, id, _ := strings.Cut(entry, " ")
+		go AuditLog(action, "ATTENDANCE", id, actor)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"session_id": sessionID, "marked": marked, "corrected": corrected})
+}
+
+// BulkAttendanceHandler marks attendance for many students in a session
+func (a *HybridHandler) BulkAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract session id from URL
+	sessionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var entries []AttendanceEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, "atleast one attendance entry is required", http.StatusBadRequest)
+		return
+	}
+
+	a.saveAttendance(w, r, sessionID, entries)
+}
+
+// CorrectAttendanceHandler changes one student's mark, a reason is required
+func (a *HybridHandler) CorrectAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	sessionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	studentID, err := strconv.Atoi(vars["studentId"])
+	if err != nil {
+		http.Error(w, "invalid student id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var entry AttendanceEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	entry.Studentid = studentID
+	if strings.TrimSpace(entry.Reason) == "" {
+		http.Error(w, ErrReasonRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.saveAttendance(w, r, sessionID, []AttendanceEntry{entry})
+}
+
+// attendanceSummaries aggregates attendance per student and section, filtered by section or term
+func attendanceSummaries(q dbtx, sectionID int, term string) ([]AttendanceSummary, error) {
+	query := `SELECT a.student_id , s.section_id , c.code ,
+		SUM(a.status='present') , SUM(a.status='late') , SUM(a.status='absent') , SUM(a.status='excused')
+		FROM attendance a
+		JOIN class_sessions cs ON cs.session_id=a.session_id
+		JOIN sections s ON s.section_id=cs.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		WHERE 1=1`
+	var args []interface{}
+	if sectionID > 0 {
+		query += " AND s.section_id=?"
+		args = append(args, sectionID)
+	}
+	if term != "" {
+		query += " AND s.term=?"
+		args = append(args, term)
+	}
+	query += " GROUP BY s.section_id , c.code , a.student_id ORDER BY s.section_id , a.student_id"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []AttendanceSummary{}
+	for rows.Next() {
+		var s AttendanceSummary
+		if err := rows.Scan(&s.Studentid, &s.Sectionid, &s.Code, &s.Present, &s.Late, &s.Absent, &s.Excused); err != nil {
+			return nil, err
+		}
+		s.Sessions = s.Present + s.Late + s.Absent + s.Excused
+		s.Percent = attendancePercent(s.Present, s.Late, s.Absent)
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// GetSectionAttendanceHandler returns attendance percentages for every student of a section to its lecturers and registrars
+func (a *HybridHandler) GetSectionAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	summaries, err := attendanceSummaries(a.MySQL.db, sectionID, "")
+	if err != nil {
+		http.Error(w, "unable to fetch attendance", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// GetAttendanceAlertsHandler lists students below the minimum attendance (?min=, ?term=, ?section_id=).
+// Lecturers may list the alerts of a section they teach, alerts across sections are for registrars.
+func (a *HybridHandler) GetAttendanceAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	threshold := attendanceThreshold(r)
+	sectionID, _ := strconv.Atoi(r.URL.Query().Get("section_id"))
+	if sectionID > 0 {
+		if !a.requireSectionStaff(w, r, sectionID) {
+			return
+		}
+	} else if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	summaries, err := attendanceSummaries(a.MySQL.db, sectionID, r.URL.Query().Get("term"))
+	if err != nil {
+		http.Error(w, "unable to fetch attendance", http.StatusInternalServerError)
+		return
+	}
+	alerts := []AttendanceSummary{}
+	for _, s := range summaries {
+		if s.Percent < threshold {
+			alerts = append(alerts, s)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"min_percent": threshold, "students": alerts})
+}
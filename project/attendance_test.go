@@ -0,0 +1,30 @@
+package project
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAttendanceReadsRequireSectionStaffOrRegistrar(t *testing.T) {
+	a := newMemoryHandler()
+	section := map[string]string{"id": "1"}
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		vars    map[string]string
+		user    string
+	}{
+		{"sessions anonymously", a.GetSectionSessionsHandler, "/sections/1/sessions", section, ""},
+		{"sessions as a stranger", a.GetSectionSessionsHandler, "/sections/1/sessions", section, "someone@gmail.com"},
+		{"section attendance anonymously", a.GetSectionAttendanceHandler, "/sections/1/attendance", section, ""},
+		{"section attendance as a stranger", a.GetSectionAttendanceHandler, "/sections/1/attendance", section, "someone@gmail.com"},
+		{"section alerts as a stranger", a.GetAttendanceAlertsHandler, "/attendance/alerts?section_id=1", nil, "someone@gmail.com"},
+		{"all alerts as a librarian", a.GetAttendanceAlertsHandler, "/attendance/alerts", nil, testLibrarian},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(t, tc.handler, "GET", tc.target, tc.vars, tc.user, nil)
+			wantStatus(t, w, http.StatusForbidden)
+		})
+	}
+}
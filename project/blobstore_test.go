@@ -0,0 +1,38 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStoreKeys(t *testing.T) {
+	s, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"assignments/1/2/ab-report..v2.pdf", "admissions/3/id-cd-..hidden", "a/b/c.txt"} {
+		if _, err := s.Put(ctx, key, strings.NewReader(key)); err != nil {
+			t.Fatalf("put %q: %v", key, err)
+		}
+		r, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("get %q: %v", key, err)
+		}
+		got, _ := io.ReadAll(r)
+		r.Close()
+		if string(got) != key {
+			t.Fatalf("get %q returned %q", key, got)
+		}
+	}
+
+	for _, key := range []string{"", "/", "../escape", "a/../../escape", `a\..\escape`, "a/.."} {
+		if _, err := s.Put(ctx, key, strings.NewReader("x")); !errors.Is(err, ErrInvalidBlobKey) {
+			t.Errorf("put %q: got %v, want %v", key, err, ErrInvalidBlobKey)
+		}
+	}
+}
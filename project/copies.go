@@ -0,0 +1,227 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// status of a physical copy, only available copies can be borrowed, and on_hold copies by the reader holding them
+const (
+	CopyAvailable = "available"
+	CopyOnLoan    = "on_loan"
+	CopyOnHold    = "on_hold"
+	CopyLost      = "lost"
+	CopyDamaged   = "damaged"
+	CopyWithdrawn = "withdrawn"
+)
+
+// condition of a physical copy
+const (
+	ConditionNew  = "new"
+	ConditionGood = "good"
+	ConditionFair = "fair"
+	ConditionPoor = "poor"
+)
+
+// Copy is one physical copy of a book, identified by the barcode on its label
+type Copy struct {
+	Copyid        int       `json:"copy_id"`
+	Barcode       string    `json:"barcode"`
+	Bookid        int       `json:"book_id"`
+	Shelflocation string    `json:"shelf_location"`
+	Condition     string    `json:"condition"`
+	Status        string    `json:"status"`
+	Createdat     time.Time `json:"created_at"`
+	Updatedat     time.Time `json:"updated_at"`
+}
+
+var barcodePattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// circulationStatus reports whether a copy status is only set by borrowing, returning and holds
+func circulationStatus(status string) bool {
+	return status == CopyOnLoan || status == CopyOnHold
+}
+
+// ValidateCopy checks a copy before it is stored
+func ValidateCopy(c Copy) error {
+	if !barcodePattern.MatchString(c.Barcode) {
+		return fmt.Errorf("barcode must be 1 to 64 letters, digits or dashes")
+	}
+	if len(c.Shelflocation) > 100 {
+		return fmt.Errorf("shelf_location must be at most 100 characters")
+	}
+	switch c.Condition {
+	case ConditionNew, ConditionGood, ConditionFair, ConditionPoor:
+	default:
+		return fmt.Errorf("condition must be new, good, fair or poor")
+	}
+	switch c.Status {
+	case CopyAvailable, CopyOnLoan, CopyOnHold, CopyLost, CopyDamaged, CopyWithdrawn:
+	default:
+		return fmt.Errorf("status must be available, on_loan, on_hold, lost, damaged or withdrawn")
+	}
+	return nil
+}
+
+// GetCopiesHandler lists the copies of a library's book
+func (a *HybridHandler) GetCopiesHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	bookID, err := strconv.Atoi(vars["bookId"])
+	if err != nil {
+		http.Error(w, "invalid book id format", http.StatusBadRequest)
+		return
+	}
+
+	copies, err := a.Libraries.Copies(r.Context(), id, bookID)
+	if err != nil {
+		writeLibraryError(w, err, "failed to fetch copies")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(copies)
+}
+
+// AddCopyHandler registers a new copy of a library's book (librarian only)
+func (a *HybridHandler) AddCopyHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	bookID, err := strconv.Atoi(vars["bookId"])
+	if err != nil {
+		http.Error(w, "invalid book id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var c Copy
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	c.Barcode = strings.TrimSpace(c.Barcode)
+	if c.Condition == "" {
+		c.Condition = ConditionGood
+	}
+	if c.Status == "" {
+		c.Status = CopyAvailable
+	}
+	if err := ValidateCopy(c); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	if circulationStatus(c.Status) {
+		http.Error(w, "copies go on loan through /borrow and on hold through /holds", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Libraries.AddCopy(r.Context(), id, bookID, &c); err != nil {
+		writeLibraryError(w, err, "failed to add copy")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("ADD_COPY", currentUser(r))
+	go AuditLog("CREATE", "COPY", c.Copyid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// GetCopyHandler looks a copy up by barcode
+func (a *HybridHandler) GetCopyHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := a.Libraries.GetCopy(r.Context(), mux.Vars(r)["barcode"])
+	if err != nil {
+		writeLibraryError(w, err, "failed to fetch copy")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// UpdateCopyHandler moves, regrades or changes the status of a copy. A copy on loan keeps its status until returned,
+// and a copy on hold until the hold is picked up, cancelled or expires (librarian only).
+func (a *HybridHandler) UpdateCopyHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Fetch the copy, fields missing from the body are kept
+	c, err := a.Libraries.GetCopy(r.Context(), mux.Vars(r)["barcode"])
+	if err != nil {
+		writeLibraryError(w, err, "failed to fetch copy")
+		return
+	}
+	var body struct {
+		Shelflocation *string `json:"shelf_location"`
+		Condition     string  `json:"condition"`
+		Status        string  `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	current := c.Status
+	if body.Shelflocation != nil {
+		c.Shelflocation = strings.TrimSpace(*body.Shelflocation)
+	}
+	if body.Condition != "" {
+		c.Condition = body.Condition
+	}
+	if body.Status != "" {
+		c.Status = body.Status
+	}
+	if err := ValidateCopy(*c); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	switch {
+	case current == CopyOnLoan && c.Status != CopyOnLoan:
+		writeLibraryError(w, ErrCopyOnLoan, "failed to update copy")
+		return
+	case current == CopyOnHold && c.Status != CopyOnHold:
+		writeLibraryError(w, ErrCopyOnHold, "failed to update copy")
+		return
+	case current != c.Status && circulationStatus(c.Status):
+		http.Error(w, "copies go on loan through /borrow and on hold through /holds", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Libraries.UpdateCopy(r.Context(), c); err != nil {
+		writeLibraryError(w, err, "failed to update copy")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("UPDATE_COPY", currentUser(r))
+	go AuditLog("UPDATE", "COPY", c.Copyid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
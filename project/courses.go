@@ -0,0 +1,254 @@
+package project
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Course represents a course in the catalogue stored in mysql
+type Course struct {
+	Courseid      int    `json:"course_id"`
+	Code          string `json:"code"`
+	Title         string `json:"title"`
+	Credits       int    `json:"credits"`
+	Dept          string `json:"dept"`
+	Prerequisites []int  `json:"prerequisites"`
+}
+
+// Section represents one offering of a course in a term
+type Section struct {
+	Sectionid int    `json:"section_id"`
+	Courseid  int    `json:"course_id"`
+	Term      string `json:"term"`
+	Capacity  int    `json:"capacity"`
+	Enrolled  int    `json:"enrolled"`
+	Waitlist  int    `json:"waitlist"`
+
+	// weekly teaching demand used by the timetable generator
+	Weeklymeetings int `json:"weekly_meetings"`
+	Meetingminutes int `json:"meeting_minutes"`
+}
+
+// ValidateCourse validates incoming course data
+func ValidateCourse(course Course) error {
+	if strings.TrimSpace(course.Code) == "" {
+		return fmt.Errorf("course code cannot be empty")
+	}
+	if strings.TrimSpace(course.Title) == "" {
+		return fmt.Errorf("course title cannot be empty")
+	}
+	if strings.TrimSpace(course.Dept) == "" {
+		return fmt.Errorf("empty dept or invalid dept")
+	}
+	if course.Credits <= 0 {
+		return fmt.Errorf("credits must be greater than 0")
+	}
+	for _, p := range course.Prerequisites {
+		if p <= 0 {
+			return fmt.Errorf("invalid prerequisite id: %d", p)
+		}
+	}
+	return nil
+}
+
+// ValidateSection validates incoming section data
+func ValidateSection(section Section) error {
+	if section.Courseid <= 0 {
+		return fmt.Errorf("invalid course_id")
+	}
+	if strings.TrimSpace(section.Term) == "" {
+		return fmt.Errorf("term cannot be empty")
+	}
+	if section.Capacity <= 0 {
+		return fmt.Errorf("capacity must be greater than 0")
+	}
+	if section.Weeklymeetings < 0 || section.Weeklymeetings > 7 {
+		return fmt.Errorf("weekly_meetings must be between 0 and 7")
+	}
+	if section.Meetingminutes < 0 {
+		return fmt.Errorf("meeting_minutes cannot be negative")
+	}
+	return nil
+}
+
+// CreateCourseHandler handles creation of a course with its prerequisites
+func (a *HybridHandler) CreateCourseHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Decode incoming JSON request body
+	var course Course
+	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// validate input
+	if err := ValidateCourse(course); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	// Begin transaction
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Insert course record
+	res, err := tx.Exec("INSERT INTO courses (code , title , credits , dept) VALUES (? , ? , ? , ?)", course.Code, course.Title, course.Credits, course.Dept)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, fmt.Sprintf("failed to insert course: %v", err), http.StatusInternalServerError)
+		return
+	}
+	courseID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "failed to fetch course id", http.StatusInternalServerError)
+		return
+	}
+	course.Courseid = int(courseID)
+
+	// Insert prerequisites
+	for _, p := range course.Prerequisites {
+		if _, err := tx.Exec("INSERT INTO course_prerequisites (course_id , prerequisite_id) VALUES (? , ?)", courseID, p); err != nil {
+			tx.Rollback()
+			http.Error(w, fmt.Sprintf("invalid prerequisite %d", p), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("CREATE_COURSE", "system")
+	go AuditLog("CREATE", "COURSE", course.Courseid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(course)
+}
+
+// GetCourseByIDHandler retrives a course with its prerequisites
+func (a *HybridHandler) GetCourseByIDHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id := mux.Vars(r)["id"]
+
+	var course Course
+	err := a.MySQL.db.QueryRow("SELECT course_id , code , title , credits , dept FROM courses WHERE course_id=?", id).Scan(&course.Courseid, &course.Code, &course.Title, &course.Credits, &course.Dept)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "course not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to fetch course: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Fetch prerequisites
+	rows, err := a.MySQL.db.Query("SELECT prerequisite_id FROM course_prerequisites WHERE course_id=?", id)
+	if err != nil {
+		http.Error(w, "failed to fetch prerequisites", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p int
+		if err := rows.Scan(&p); err != nil {
+			http.Error(w, "failed to scan prerequisites", http.StatusInternalServerError)
+			return
+		}
+		course.Prerequisites = append(course.Prerequisites, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(course)
+}
+
+// CreateSectionHandler handles creation of a new section for a course
+func (a *HybridHandler) CreateSectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Decode incoming JSON request body
+	var section Section
+	if err := json.NewDecoder(r.Body).Decode(&section); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// validate input
+	if err := ValidateSection(section); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	// default to two one-hour meetings a week
+	if section.Weeklymeetings == 0 {
+		section.Weeklymeetings = 2
+	}
+	if section.Meetingminutes == 0 {
+		section.Meetingminutes = 60
+	}
+
+	// Insert section record
+	res, err := a.MySQL.db.Exec("INSERT INTO sections (course_id , term , capacity , weekly_meetings , meeting_minutes) VALUES (? , ? , ? , ? , ?)", section.Courseid, section.Term, section.Capacity, section.Weeklymeetings, section.Meetingminutes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to insert section: %v", err), http.StatusInternalServerError)
+		return
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	section.Sectionid = int(id)
+
+	go LogActivity("CREATE_SECTION", "system")
+	go AuditLog("CREATE", "SECTION", section.Sectionid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(section)
+}
+
+// GetSectionByIDHandler retrives a section with its seat and waitlist counts
+func (a *HybridHandler) GetSectionByIDHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	var section Section
+	err = a.MySQL.db.QueryRow(`SELECT s.section_id , s.course_id , s.term , s.capacity , s.weekly_meetings , s.meeting_minutes ,
+		(SELECT COUNT(*) FROM enrollments e WHERE e.section_id=s.section_id AND e.status=?) ,
+		(SELECT COUNT(*) FROM enrollments e WHERE e.section_id=s.section_id AND e.status=?)
+		FROM sections s WHERE s.section_id=?`, EnrollmentEnrolled, EnrollmentWaitlisted, id).
+		Scan(&section.Sectionid, &section.Courseid, &section.Term, &section.Capacity, &section.Weeklymeetings, &section.Meetingminutes, &section.Enrolled, &section.Waitlist)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "section not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to fetch section: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
@@ -0,0 +1,384 @@
+package project
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// enrollment statuses
+const (
+	EnrollmentEnrolled   = "enrolled"
+	EnrollmentWaitlisted = "waitlisted"
+	EnrollmentDropped    = "dropped"
+	EnrollmentCompleted  = "completed"
+	EnrollmentFailed     = "failed"
+)
+
+// Enrollment represents a student's place in a section, either a seat or a waitlist slot
+type Enrollment struct {
+	Enrollmentid     int        `json:"enrollment_id"`
+	Sectionid        int        `json:"section_id"`
+	Studentid        int        `json:"student_id"`
+	Status           string     `json:"status"`
+	Waitlistposition *int       `json:"waitlist_position,omitempty"`
+	Enrolledat       *time.Time `json:"enrolled_at,omitempty"`
+}
+
+// errors returned by the enrollment helpers so handlers can pick a status code
+var (
+	ErrSectionNotFound    = errors.New("section not found")
+	ErrStudentNotFound    = errors.New("student not found")
+	ErrAlreadyEnrolled    = errors.New("student is already enrolled or waitlisted in this section")
+	ErrEnrollmentNotFound = errors.New("no active enrollment found")
+)
+
+// MissingPrerequisitesError lists the prerequisite course codes a student has not completed
+type MissingPrerequisitesError struct {
+	Codes []string
+}
+
+func (e *MissingPrerequisitesError) Error() string {
+	return fmt.Sprintf("missing completed prerequisites: %v", e.Codes)
+}
+
+// lockSection locks the section row for the rest of the transaction and returns its course and capacity
+func lockSection(tx *sql.Tx, sectionID int) (courseID, capacity int, err error) {
+	err = tx.QueryRow("SELECT course_id , capacity FROM sections WHERE section_id=? FOR UPDATE", sectionID).Scan(&courseID, &capacity)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrSectionNotFound
+	}
+	return courseID, capacity, err
+}
+
+// missingPrerequisites returns the codes of prerequisite courses the student has not completed
+func missingPrerequisites(tx *sql.Tx, courseID, studentID int) ([]string, error) {
+	rows, err := tx.Query(`SELECT c.code FROM course_prerequisites p
+		JOIN courses c ON c.course_id=p.prerequisite_id
+		WHERE p.course_id=? AND NOT EXISTS (
+			SELECT 1 FROM enrollments e JOIN sections s ON s.section_id=e.section_id
+			WHERE e.student_id=? AND e.status=? AND s.course_id=p.prerequisite_id)`, courseID, studentID, EnrollmentCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// enrollStudent places a student in a section, or on its waitlist when every seat is taken.
+// The section row stays locked until the caller commits so concurrent requests cannot oversell seats.
+func enrollStudent(tx *sql.Tx, sectionID, studentID int) (*Enrollment, error) {
+	courseID, capacity, err := lockSection(tx, sectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// check student exists
+	var exists int
+	if err := tx.QueryRow("SELECT 1 FROM students WHERE id=?", studentID).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStudentNotFound
+		}
+		return nil, err
+	}
+
+	// check for an existing enrollment row, a dropped one can be reused
+	var existingID int
+	var existingStatus string
+	err = tx.QueryRow("SELECT enrollment_id , status FROM enrollments WHERE section_id=? AND student_id=? FOR UPDATE", sectionID, studentID).Scan(&existingID, &existingStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil && existingStatus != EnrollmentDropped {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	// verify completed prerequisites
+	missing, err := missingPrerequisites(tx, courseID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, &MissingPrerequisitesError{Codes: missing}
+	}
+
+	// reject timetable clashes with the student's other sections
+	clashes, err := sectionClashes(tx, "student", strconv.Itoa(studentID), sectionID, "SELECT section_id FROM enrollments WHERE student_id=? AND status IN ('enrolled' , 'waitlisted')", studentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(clashes) > 0 {
+		return nil, &ScheduleConflictError{Conflicts: clashes}
+	}
+
+	// count taken seats
+	var enrolled int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM enrollments WHERE section_id=? AND status=?", sectionID, EnrollmentEnrolled).Scan(&enrolled); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	enrollment := &Enrollment{Sectionid: sectionID, Studentid: studentID, Status: EnrollmentEnrolled, Enrolledat: &now}
+	if enrolled >= capacity {
+		var last int
+		if err := tx.QueryRow("SELECT COALESCE(MAX(waitlist_position), 0) FROM enrollments WHERE section_id=? AND status=?", sectionID, EnrollmentWaitlisted).Scan(&last); err != nil {
+			return nil, err
+		}
+		position := last + 1
+		enrollment.Status = EnrollmentWaitlisted
+		enrollment.Waitlistposition = &position
+	}
+
+	if existingID != 0 {
+		_, err = tx.Exec("UPDATE enrollments SET status=? , waitlist_position=? , enrolled_at=? , updated_at=? WHERE enrollment_id=?", enrollment.Status, enrollment.Waitlistposition, now, now, existingID)
+		enrollment.Enrollmentid = existingID
+		return enrollment, err
+	}
+	res, err := tx.Exec("INSERT INTO enrollments (section_id , student_id , status , waitlist_position , enrolled_at , updated_at) VALUES (? , ? , ? , ? , ? , ?)", sectionID, studentID, enrollment.Status, enrollment.Waitlistposition, now, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	enrollment.Enrollmentid = int(id)
+	return enrollment, nil
+}
+
+// promoteWaitlist moves waitlisted students into free seats in waitlist order.
+// The caller must hold the section lock. It returns the ids of the promoted students.
+func promoteWaitlist(tx *sql.Tx, sectionID, capacity int) ([]int, error) {
+	var enrolled int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM enrollments WHERE section_id=? AND status=?", sectionID, EnrollmentEnrolled).Scan(&enrolled); err != nil {
+		return nil, err
+	}
+
+	var promoted []int
+	for ; enrolled < capacity; enrolled++ {
+		var enrollmentID, studentID, position int
+		err := tx.QueryRow("SELECT enrollment_id , student_id , waitlist_position FROM enrollments WHERE section_id=? AND status=? ORDER BY waitlist_position LIMIT 1 FOR UPDATE", sectionID, EnrollmentWaitlisted).Scan(&enrollmentID, &studentID, &position)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("UPDATE enrollments SET status=? , waitlist_position=NULL , updated_at=? WHERE enrollment_id=?", EnrollmentEnrolled, time.Now(), enrollmentID); err != nil {
+			return nil, err
+		}
+		if err := closeWaitlistGap(tx, sectionID, position); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, studentID)
+	}
+	return promoted, nil
+}
+
+// closeWaitlistGap shifts everyone behind position one place forward
+func closeWaitlistGap(tx *sql.Tx, sectionID, position int) error {
+	_, err := tx.Exec("UPDATE enrollments SET waitlist_position=waitlist_position-1 WHERE section_id=? AND status=? AND waitlist_position>?", sectionID, EnrollmentWaitlisted, position)
+	return err
+}
+
+// dropStudent drops a student's seat or waitlist slot and promotes the next waitlisted students
+func dropStudent(tx *sql.Tx, sectionID, studentID int) (string, []int, error) {
+	_, capacity, err := lockSection(tx, sectionID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var enrollmentID int
+	var status string
+	var position sql.NullInt64
+	err = tx.QueryRow("SELECT enrollment_id , status , waitlist_position FROM enrollments WHERE section_id=? AND student_id=? FOR UPDATE", sectionID, studentID).Scan(&enrollmentID, &status, &position)
+	if err == sql.ErrNoRows || (err == nil && status != EnrollmentEnrolled && status != EnrollmentWaitlisted) {
+		return "", nil, ErrEnrollmentNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE enrollments SET status=? , waitlist_position=NULL , updated_at=? WHERE enrollment_id=?", EnrollmentDropped, time.Now(), enrollmentID); err != nil {
+		return "", nil, err
+	}
+
+	if status == EnrollmentWaitlisted {
+		return status, nil, closeWaitlistGap(tx, sectionID, int(position.Int64))
+	}
+	promoted, err := promoteWaitlist(tx, sectionID, capacity)
+	return status, promoted, err
+}
+
+// writeEnrollmentError maps enrollment helper errors to HTTP responses
+func writeEnrollmentError(w http.ResponseWriter, err error) {
+	var missing *MissingPrerequisitesError
+	var clash *ScheduleConflictError
+	switch {
+	case errors.Is(err, ErrSectionNotFound), errors.Is(err, ErrStudentNotFound), errors.Is(err, ErrEnrollmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAlreadyEnrolled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &missing):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{"err": "missing completed prerequisites", "missing": missing.Codes})
+	case errors.As(err, &clash):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"err": "schedule conflict", "conflicts": clash.Conflicts})
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// EnrollStudentHandler enrolls a student in a section or places them on the waitlist
+func (a *HybridHandler) EnrollStudentHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Studentid int `json:"student_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Studentid <= 0 {
+		http.Error(w, "invalid student_id", http.StatusBadRequest)
+		return
+	}
+
+	// Begin transaction, the section row lock serialises concurrent enrollments
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	enrollment, err := enrollStudent(tx, sectionID, req.Studentid)
+	if err != nil {
+		tx.Rollback()
+		writeEnrollmentError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Log activity and audit trail
+	action := "ENROLL"
+	if enrollment.Status == EnrollmentWaitlisted {
+		action = "WAITLIST"
+	}
+	go LogActivity(action+"_STUDENT", "system")
+	go AuditLog(action, "ENROLLMENT", enrollment.Enrollmentid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// DropEnrollmentHandler drops a student from a section and promotes the waitlist
+func (a *HybridHandler) DropEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	sectionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	studentID, err := strconv.Atoi(vars["studentId"])
+	if err != nil {
+		http.Error(w, "invalid student id format", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	previous, promoted, err := dropStudent(tx, sectionID, studentID)
+	if err != nil {
+		tx.Rollback()
+		writeEnrollmentError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Log activity and audit trail for the drop and every promotion
+	go LogActivity("DROP_STUDENT", "system")
+	go AuditLog("DROP", "ENROLLMENT", fmt.Sprintf("section=%d student=%d was=%s", sectionID, studentID, previous), "system")
+	for _, p := range promoted {
+		go AuditLog("PROMOTE", "ENROLLMENT", fmt.Sprintf("section=%d student=%d", sectionID, p), "system")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "student dropped", "promoted": promoted})
+}
+
+// GetSectionEnrollmentsHandler lists enrolled students followed by the waitlist in order
+func (a *HybridHandler) GetSectionEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query(`SELECT enrollment_id , section_id , student_id , status , waitlist_position , enrolled_at
+		FROM enrollments WHERE section_id=? AND status IN (? , ?)
+		ORDER BY status , waitlist_position , enrolled_at`, sectionID, EnrollmentEnrolled, EnrollmentWaitlisted)
+	if err != nil {
+		http.Error(w, "unable to fetch enrollments", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	enrollments := []Enrollment{}
+	for rows.Next() {
+		var e Enrollment
+		var position sql.NullInt64
+		var enrolledAt time.Time
+		if err := rows.Scan(&e.Enrollmentid, &e.Sectionid, &e.Studentid, &e.Status, &position, &enrolledAt); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		if position.Valid {
+			p := int(position.Int64)
+			e.Waitlistposition = &p
+		}
+		e.Enrolledat = &enrolledAt
+		enrollments = append(enrollments, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollments)
+}
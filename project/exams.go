@@ -0,0 +1,864 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ExamSlot is a dated exam sitting within a term
+type ExamSlot struct {
+	Slotid    int    `json:"slot_id"`
+	Term      string `json:"term"`
+	Examdate  string `json:"exam_date"`
+	Starttime string `json:"start_time"`
+	Endtime   string `json:"end_time"`
+}
+
+// ExamClash is a student who would sit two overlapping exams
+type ExamClash struct {
+	Studentid int    `json:"student_id"`
+	Sectionid int    `json:"section_id"`
+	Code      string `json:"code"`
+	Slotid    int    `json:"slot_id"`
+}
+
+// ExamClashError is returned when scheduling an exam would double-book students
+type ExamClashError struct {
+	Clashes []ExamClash
+}
+
+func (e *ExamClashError) Error() string {
+	return fmt.Sprintf("exam clashes for %d students", len(e.Clashes))
+}
+
+// ExamSeat is one allocated seat in a seating plan
+type ExamSeat struct {
+	Roomid    int    `json:"room_id"`
+	Room      string `json:"room,omitempty"`
+	Row       int    `json:"row"`
+	Column    int    `json:"column"`
+	Examid    int    `json:"exam_id"`
+	Code      string `json:"code,omitempty"`
+	Studentid int    `json:"student_id"`
+}
+
+// Invigilator is a lecturer supervising a room during a slot
+type Invigilator struct {
+	Roomid     int    `json:"room_id"`
+	Lecturerid string `json:"lecturer_id"`
+	Name       string `json:"name,omitempty"`
+}
+
+// HallTicketExam is one exam printed on a student's hall ticket
+type HallTicketExam struct {
+	Code      string `json:"code"`
+	Title     string `json:"title"`
+	Examdate  string `json:"exam_date"`
+	Starttime string `json:"start_time"`
+	Endtime   string `json:"end_time"`
+	Room      string `json:"room,omitempty"`
+	Building  string `json:"building,omitempty"`
+	Row       int    `json:"row,omitempty"`
+	Column    int    `json:"column,omitempty"`
+}
+
+// HallTicket lists a student's exams for a term with their seats
+type HallTicket struct {
+	Student Student          `json:"student"`
+	Term    string           `json:"term"`
+	Exams   []HallTicketExam `json:"exams"`
+}
+
+// examGroup is the set of students sitting one exam in a slot
+type examGroup struct {
+	Examid   int
+	Students []int
+}
+
+// errors returned by the exam helpers
+var (
+	ErrExamSlotNotFound       = errors.New("exam slot not found")
+	ErrExamTermMismatch       = errors.New("exam slot belongs to a different term than the section")
+	ErrNotEnoughSeats         = errors.New("not enough free room capacity for this slot")
+	ErrNoSeatingPlan          = errors.New("slot has no seating plan yet")
+	ErrNotEnoughInvigilators  = errors.New("not enough free lecturers to invigilate this slot")
+	ErrSeatingColumnsRequired = errors.New("columns must be between 1 and 50")
+)
+
+// ValidateExamSlot validates incoming exam slot data
+func ValidateExamSlot(slot ExamSlot) error {
+	if slot.Term == "" {
+		return fmt.Errorf("term cannot be empty")
+	}
+	if _, err := time.Parse("2006-01-02", slot.Examdate); err != nil {
+		return fmt.Errorf("exam_date must be YYYY-MM-DD")
+	}
+	start, err := parseClock(slot.Starttime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(slot.Endtime)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("end_time must be after start_time")
+	}
+	return nil
+}
+
+// loadExamSlot fetches one exam slot
+func loadExamSlot(q dbtx, id int) (*ExamSlot, error) {
+	var slot ExamSlot
+	var date time.Time
+	err := q.QueryRow("SELECT slot_id , term , exam_date , start_time , end_time FROM exam_slots WHERE slot_id=?", id).
+		Scan(&slot.Slotid, &slot.Term, &date, &slot.Starttime, &slot.Endtime)
+	if err == sql.ErrNoRows {
+		return nil, ErrExamSlotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	slot.Examdate = date.Format("2006-01-02")
+	slot.Starttime, slot.Endtime = trimClock(slot.Starttime), trimClock(slot.Endtime)
+	return &slot, nil
+}
+
+// overlappingSlots is a subquery selecting slots of the same term that overlap slot ?, excluding it
+const overlappingSlots = `SELECT o.slot_id FROM exam_slots o JOIN exam_slots m ON m.slot_id=?
+	WHERE o.term=m.term AND o.exam_date=m.exam_date AND o.start_time<m.end_time AND o.end_time>m.start_time AND o.slot_id<>m.slot_id`
+
+// examClashes returns students of a section who already sit another exam overlapping the slot
+func examClashes(q dbtx, sectionID, slotID int) ([]ExamClash, error) {
+	rows, err := q.Query(`SELECT mine.student_id , ox.section_id , c.code , ox.slot_id
+		FROM enrollments mine
+		JOIN enrollments other ON other.student_id=mine.student_id AND other.section_id<>mine.section_id AND other.status=?
+		JOIN exams ox ON ox.section_id=other.section_id
+		JOIN sections s ON s.section_id=ox.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		WHERE mine.section_id=? AND mine.status=? AND (ox.slot_id=? OR ox.slot_id IN (`+overlappingSlots+`))
+		ORDER BY mine.student_id`, EnrollmentEnrolled, sectionID, EnrollmentEnrolled, slotID, slotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clashes := []ExamClash{}
+	for rows.Next() {
+		var c ExamClash
+		if err := rows.Scan(&c.Studentid, &c.Sectionid, &c.Code, &c.Slotid); err != nil {
+			return nil, err
+		}
+		clashes = append(clashes, c)
+	}
+	return clashes, rows.Err()
+}
+
+// writeExamError maps exam errors to HTTP responses
+func writeExamError(w http.ResponseWriter, err error) {
+	var clash *ExamClashError
+	switch {
+	case errors.Is(err, ErrExamSlotNotFound), errors.Is(err, ErrSectionNotFound), errors.Is(err, ErrStudentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrExamTermMismatch), errors.Is(err, ErrSeatingColumnsRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNotEnoughSeats), errors.Is(err, ErrNoSeatingPlan), errors.Is(err, ErrNotEnoughInvigilators):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.As(err, &clash):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"err": "exam clash", "clashes": clash.Clashes})
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// planSeating seats every student of the groups into the rooms, largest room first.
+// Each row holds a single exam and consecutive rows never hold the same exam, so when
+// only one exam is left a row is left empty between its rows.
+func planSeating(rooms []Room, columns int, groups []examGroup) ([]ExamSeat, error) {
+	remaining := make([][]int, len(groups))
+	left := 0
+	for i, g := range groups {
+		remaining[i] = g.Students
+		left += len(g.Students)
+	}
+	rooms = append([]Room(nil), rooms...)
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].Capacity > rooms[j].Capacity })
+
+	seats := []ExamSeat{}
+	for _, room := range rooms {
+		if left == 0 {
+			break
+		}
+		last := -1
+		rows := (room.Capacity + columns - 1) / columns
+		for row := 1; row <= rows && left > 0; row++ {
+			// pick the exam with most students left that did not sit the previous row
+			pick := -1
+			for i := range remaining {
+				if i != last && len(remaining[i]) > 0 && (pick < 0 || len(remaining[i]) > len(remaining[pick])) {
+					pick = i
+				}
+			}
+			if pick < 0 {
+				last = -1
+				continue
+			}
+			width := columns
+			if row == rows && room.Capacity%columns != 0 {
+				width = room.Capacity % columns
+			}
+			n := min(width, len(remaining[pick]))
+			for col := 1; col <= n; col++ {
+				seats = append(seats, ExamSeat{Roomid: room.Roomid, Room: room.Name, Row: row, Column: col, Examid: groups[pick].Examid, Studentid: remaining[pick][col-1]})
+			}
+			remaining[pick] = remaining[pick][n:]
+			left -= n
+			last = pick
+		}
+	}
+	if left > 0 {
+		return nil, fmt.Errorf("%w: %d students without a seat", ErrNotEnoughSeats, left)
+	}
+	return seats, nil
+}
+
+// CreateExamSlotHandler adds an exam slot to a term, registrar only
+func (a *HybridHandler) CreateExamSlotHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var slot ExamSlot
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// Validate slot data
+	if err := ValidateExamSlot(slot); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	res, err := a.MySQL.db.Exec("INSERT INTO exam_slots (term , exam_date , start_time , end_time) VALUES (? , ? , ? , ?)", slot.Term, slot.Examdate, slot.Starttime, slot.Endtime)
+	if err != nil {
+		http.Error(w, "failed to create exam slot", http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+	slot.Slotid = int(id)
+
+	go LogActivity("CREATE_EXAM_SLOT", currentUser(r))
+	go AuditLog("CREATE", "EXAM_SLOT", slot.Slotid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(slot)
+}
+
+// GetExamSlotsHandler lists exam slots, optionally for one term (?term=)
+func (a *HybridHandler) GetExamSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT slot_id , term , exam_date , start_time , end_time FROM exam_slots"
+	var args []interface{}
+	if term := r.URL.Query().Get("term"); term != "" {
+		query += " WHERE term=?"
+		args = append(args, term)
+	}
+	rows, err := a.MySQL.db.Query(query+" ORDER BY exam_date , start_time", args...)
+	if err != nil {
+		http.Error(w, "unable to fetch exam slots", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	slots := []ExamSlot{}
+	for rows.Next() {
+		var slot ExamSlot
+		var date time.Time
+		if err := rows.Scan(&slot.Slotid, &slot.Term, &date, &slot.Starttime, &slot.Endtime); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		slot.Examdate = date.Format("2006-01-02")
+		slot.Starttime, slot.Endtime = trimClock(slot.Starttime), trimClock(slot.Endtime)
+		slots = append(slots, slot)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// ScheduleExamHandler places a section's exam in a slot, rejecting clashes for enrolled students.
+// Moving an exam drops its seats, the slot's seating plan must be generated again.
+func (a *HybridHandler) ScheduleExamHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Slotid int `json:"slot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, _, err := lockSection(tx, sectionID); err != nil {
+			return err
+		}
+		term, _, err := sectionTerm(tx, sectionID)
+		if err != nil {
+			return err
+		}
+		slot, err := loadExamSlot(tx, req.Slotid)
+		if err != nil {
+			return err
+		}
+		if slot.Term != term {
+			return ErrExamTermMismatch
+		}
+		clashes, err := examClashes(tx, sectionID, req.Slotid)
+		if err != nil {
+			return err
+		}
+		if len(clashes) > 0 {
+			return &ExamClashError{Clashes: clashes}
+		}
+		if _, err := tx.Exec("DELETE es FROM exam_seats es JOIN exams e ON e.exam_id=es.exam_id WHERE e.section_id=? AND e.slot_id<>?", sectionID, req.Slotid); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO exams (section_id , slot_id , scheduled_by , scheduled_at) VALUES (? , ? , ? , ?)
+			ON DUPLICATE KEY UPDATE slot_id=VALUES(slot_id) , scheduled_by=VALUES(scheduled_by) , scheduled_at=VALUES(scheduled_at)`,
+			sectionID, req.Slotid, currentUser(r), time.Now())
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeExamError(w, err)
+		return
+	}
+
+	go LogActivity("SCHEDULE_EXAM", currentUser(r))
+	go AuditLog("SCHEDULE", "EXAM", fmt.Sprintf("section=%d slot=%d", sectionID, req.Slotid), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"section_id": sectionID, "slot_id": req.Slotid})
+}
+
+// GetExamClashesHandler lists every student clash between scheduled exams of a term (?term=)
+func (a *HybridHandler) GetExamClashesHandler(w http.ResponseWriter, r *http.Request) {
+	term := r.URL.Query().Get("term")
+	if term == "" {
+		http.Error(w, "term is required", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query(`SELECT e1.student_id , x1.section_id , x1.slot_id , x2.section_id , x2.slot_id
+		FROM exams x1
+		JOIN exam_slots s1 ON s1.slot_id=x1.slot_id
+		JOIN exams x2 ON x2.section_id>x1.section_id
+		JOIN exam_slots s2 ON s2.slot_id=x2.slot_id AND s2.term=s1.term AND s2.exam_date=s1.exam_date AND s2.start_time<s1.end_time AND s2.end_time>s1.start_time
+		JOIN enrollments e1 ON e1.section_id=x1.section_id AND e1.status=?
+		JOIN enrollments e2 ON e2.section_id=x2.section_id AND e2.student_id=e1.student_id AND e2.status=?
+		WHERE s1.term=? ORDER BY e1.student_id`, EnrollmentEnrolled, EnrollmentEnrolled, term)
+	if err != nil {
+		http.Error(w, "unable to fetch clashes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type clash struct {
+		Studentid int `json:"student_id"`
+		Sectiona  int `json:"section_a"`
+		Slota     int `json:"slot_a"`
+		Sectionb  int `json:"section_b"`
+		Slotb     int `json:"slot_b"`
+	}
+	clashes := []clash{}
+	for rows.Next() {
+		var c clash
+		if err := rows.Scan(&c.Studentid, &c.Sectiona, &c.Slota, &c.Sectionb, &c.Slotb); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		clashes = append(clashes, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clashes)
+}
+
+// GenerateSeatingHandler replaces a slot's seating plan, registrar only.
+// Rooms already used by an overlapping slot are skipped; columns defaults to 6 seats per row.
+func (a *HybridHandler) GenerateSeatingHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract slot id from URL
+	slotID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	req := struct {
+		Columns int `json:"columns"`
+	}{Columns: 6}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "failed to decode response", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.Columns < 1 || req.Columns > 50 {
+		writeExamError(w, ErrSeatingColumnsRequired)
+		return
+	}
+
+	var seats []ExamSeat
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// lock the slot so concurrent generations do not interleave
+		err = tx.QueryRow("SELECT slot_id FROM exam_slots WHERE slot_id=? FOR UPDATE", slotID).Scan(&slotID)
+		if err == sql.ErrNoRows {
+			return ErrExamSlotNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// students of every exam in the slot
+		rows, err := tx.Query(`SELECT x.exam_id , e.student_id FROM exams x
+			JOIN enrollments e ON e.section_id=x.section_id AND e.status=?
+			WHERE x.slot_id=? ORDER BY x.exam_id , e.student_id`, EnrollmentEnrolled, slotID)
+		if err != nil {
+			return err
+		}
+		var groups []examGroup
+		for rows.Next() {
+			var examID, studentID int
+			if err := rows.Scan(&examID, &studentID); err != nil {
+				rows.Close()
+				return err
+			}
+			if len(groups) == 0 || groups[len(groups)-1].Examid != examID {
+				groups = append(groups, examGroup{Examid: examID})
+			}
+			groups[len(groups)-1].Students = append(groups[len(groups)-1].Students, studentID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// rooms not taken by an overlapping slot
+		all, err := loadRooms(tx)
+		if err != nil {
+			return err
+		}
+		taken := map[int]bool{}
+		rows, err = tx.Query("SELECT DISTINCT room_id FROM exam_seats WHERE slot_id IN ("+overlappingSlots+")", slotID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			taken[id] = true
+		}
+		rows.Close()
+		var free []Room
+		for _, room := range all {
+			if !taken[room.Roomid] {
+				free = append(free, room)
+			}
+		}
+
+		seats, err = planSeating(free, req.Columns, groups)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM exam_invigilators WHERE slot_id=?", slotID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM exam_seats WHERE slot_id=?", slotID); err != nil {
+			return err
+		}
+		for _, s := range seats {
+			if _, err := tx.Exec("INSERT INTO exam_seats (slot_id , room_id , seat_row , seat_col , exam_id , student_id) VALUES (? , ? , ? , ? , ? , ?)",
+				slotID, s.Roomid, s.Row, s.Column, s.Examid, s.Studentid); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeExamError(w, err)
+		return
+	}
+
+	go LogActivity("GENERATE_SEATING", currentUser(r))
+	go AuditLog("GENERATE", "EXAM_SEATING", fmt.Sprintf("slot=%d seats=%d", slotID, len(seats)), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(seats)
+}
+
+// GetSeatingHandler returns a slot's seating plan by room, row and column
+func (a *HybridHandler) GetSeatingHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract slot id from URL
+	slotID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query(`SELECT es.room_id , rm.name , es.seat_row , es.seat_col , es.exam_id , c.code , es.student_id
+		FROM exam_seats es
+		JOIN rooms rm ON rm.room_id=es.room_id
+		JOIN exams x ON x.exam_id=es.exam_id
+		JOIN sections s ON s.section_id=x.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		WHERE es.slot_id=? ORDER BY rm.name , es.seat_row , es.seat_col`, slotID)
+	if err != nil {
+		http.Error(w, "unable to fetch seating plan", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	seats := []ExamSeat{}
+	for rows.Next() {
+		var s ExamSeat
+		if err := rows.Scan(&s.Roomid, &s.Room, &s.Row, &s.Column, &s.Examid, &s.Code, &s.Studentid); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		seats = append(seats, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seats)
+}
+
+// AssignInvigilatorsHandler assigns lecturers to every seated room of a slot, registrar only.
+// Each room gets one invigilator per started block of per_students students (default 30);
+// lecturers busy in an overlapping slot are skipped and those with fewest duties this term go first.
+func (a *HybridHandler) AssignInvigilatorsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract slot id from URL
+	slotID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	req := struct {
+		Perstudents int `json:"per_students"`
+	}{Perstudents: 30}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "failed to decode response", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.Perstudents <= 0 {
+		http.Error(w, "per_students must be greater than 0", http.StatusBadRequest)
+		return
+	}
+
+	// all lecturers from the lecturer store
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturers, err := a.Lecturers.FindAll(ctx, "")
+	if err != nil {
+		http.Error(w, "failed to fetch lecturers", http.StatusInternalServerError)
+		return
+	}
+
+	var assigned []Invigilator
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		slot, err := loadExamSlot(tx, slotID)
+		if err != nil {
+			return err
+		}
+
+		// students seated per room
+		rows, err := tx.Query("SELECT room_id , COUNT(*) FROM exam_seats WHERE slot_id=? GROUP BY room_id ORDER BY room_id", slotID)
+		if err != nil {
+			return err
+		}
+		type roomLoad struct{ room, students int }
+		var loads []roomLoad
+		for rows.Next() {
+			var l roomLoad
+			if err := rows.Scan(&l.room, &l.students); err != nil {
+				rows.Close()
+				return err
+			}
+			loads = append(loads, l)
+		}
+		rows.Close()
+		if len(loads) == 0 {
+			return ErrNoSeatingPlan
+		}
+
+		// lecturers already busy at the same time, and duty counts for the term
+		busy := map[string]bool{}
+		duties := map[string]int{}
+		rows, err = tx.Query(`SELECT i.lecturer_id , i.slot_id IN (`+overlappingSlots+`) FROM exam_invigilators i
+			JOIN exam_slots s ON s.slot_id=i.slot_id WHERE s.term=? AND i.slot_id<>?`, slotID, slot.Term, slotID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id string
+			var overlapping bool
+			if err := rows.Scan(&id, &overlapping); err != nil {
+				rows.Close()
+				return err
+			}
+			duties[id]++
+			if overlapping {
+				busy[id] = true
+			}
+		}
+		rows.Close()
+
+		var free []Lecturer
+		for _, l := range lecturers {
+			if !busy[l.Id.Hex()] {
+				free = append(free, l)
+			}
+		}
+		sort.SliceStable(free, func(i, j int) bool {
+			if di, dj := duties[free[i].Id.Hex()], duties[free[j].Id.Hex()]; di != dj {
+				return di < dj
+			}
+			return free[i].Name < free[j].Name
+		})
+
+		if _, err := tx.Exec("DELETE FROM exam_invigilators WHERE slot_id=?", slotID); err != nil {
+			return err
+		}
+		next := 0
+		for _, l := range loads {
+			need := (l.students + req.Perstudents - 1) / req.Perstudents
+			for i := 0; i < need; i++ {
+				if next >= len(free) {
+					return ErrNotEnoughInvigilators
+				}
+				lecturer := free[next]
+				next++
+				if _, err := tx.Exec("INSERT INTO exam_invigilators (slot_id , room_id , lecturer_id) VALUES (? , ? , ?)", slotID, l.room, lecturer.Id.Hex()); err != nil {
+					return err
+				}
+				assigned = append(assigned, Invigilator{Roomid: l.room, Lecturerid: lecturer.Id.Hex(), Name: lecturer.Name})
+			}
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeExamError(w, err)
+		return
+	}
+
+	go LogActivity("ASSIGN_INVIGILATORS", currentUser(r))
+	go AuditLog("ASSIGN", "EXAM_INVIGILATORS", fmt.Sprintf("slot=%d count=%d", slotID, len(assigned)), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assigned)
+}
+
+// GetInvigilatorsHandler lists the invigilators of a slot
+func (a *HybridHandler) GetInvigilatorsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract slot id from URL
+	slotID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT room_id , lecturer_id FROM exam_invigilators WHERE slot_id=? ORDER BY room_id", slotID)
+	if err != nil {
+		http.Error(w, "unable to fetch invigilators", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	invigilators := []Invigilator{}
+	for rows.Next() {
+		var inv Invigilator
+		if err := rows.Scan(&inv.Roomid, &inv.Lecturerid); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		invigilators = append(invigilators, inv)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invigilators)
+}
+
+// buildHallTicket lists a student's exams in a term with any allocated seats
+func buildHallTicket(q dbtx, studentID int, term string) (*HallTicket, error) {
+	ticket := &HallTicket{Term: term, Exams: []HallTicketExam{}}
+	err := q.QueryRow("SELECT id , name , age , email , dept FROM students WHERE id=?", studentID).
+		Scan(&ticket.Student.Id, &ticket.Student.Name, &ticket.Student.Age, &ticket.Student.Email, &ticket.Student.Dept)
+	if err == sql.ErrNoRows {
+		return nil, ErrStudentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`SELECT c.code , c.title , sl.exam_date , sl.start_time , sl.end_time , rm.name , rm.building , es.seat_row , es.seat_col
+		FROM enrollments e
+		JOIN exams x ON x.section_id=e.section_id
+		JOIN exam_slots sl ON sl.slot_id=x.slot_id
+		JOIN sections s ON s.section_id=x.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		LEFT JOIN exam_seats es ON es.exam_id=x.exam_id AND es.student_id=e.student_id
+		LEFT JOIN rooms rm ON rm.room_id=es.room_id
+		WHERE e.student_id=? AND e.status=? AND sl.term=?
+		ORDER BY sl.exam_date , sl.start_time`, studentID, EnrollmentEnrolled, term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ex HallTicketExam
+		var date time.Time
+		var room, building sql.NullString
+		var row, col sql.NullInt64
+		if err := rows.Scan(&ex.Code, &ex.Title, &date, &ex.Starttime, &ex.Endtime, &room, &building, &row, &col); err != nil {
+			return nil, err
+		}
+		ex.Examdate = date.Format("2006-01-02")
+		ex.Starttime, ex.Endtime = trimClock(ex.Starttime), trimClock(ex.Endtime)
+		ex.Room, ex.Building = room.String, building.String
+		ex.Row, ex.Column = int(row.Int64), int(col.Int64)
+		ticket.Exams = append(ticket.Exams, ex)
+	}
+	return ticket, rows.Err()
+}
+
+// hallTicketLines renders a hall ticket as plain text lines for the PDF
+func hallTicketLines(t *HallTicket) []string {
+	lines := []string{
+		"EXAMINATION HALL TICKET - " + t.Term,
+		"",
+		fmt.Sprintf("Student:    %s (ID %d)", t.Student.Name, t.Student.Id),
+		fmt.Sprintf("Department: %s", t.Student.Dept),
+		"",
+		fmt.Sprintf("%-10s %-10s %-11s %-20s %s", "Code", "Date", "Time", "Room", "Seat"),
+	}
+	for _, ex := range t.Exams {
+		room, seat := "TBA", "TBA"
+		if ex.Room != "" {
+			room = ex.Building + " " + ex.Room
+			seat = fmt.Sprintf("R%d C%d", ex.Row, ex.Column)
+		}
+		if len(room) > 20 {
+			room = room[:20]
+		}
+		lines = append(lines, fmt.Sprintf("%-10s %-10s %-11s %-20s %s", ex.Code, ex.Examdate, ex.Starttime+"-"+ex.Endtime, room, seat))
+	}
+	return append(lines, "", "Bring this ticket and photo ID to every exam.")
+}
+
+// GetHallTicketHandler exports a student's hall ticket for a term as PDF (default) or JSON (?format=json)
+func (a *HybridHandler) GetHallTicketHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	term := r.URL.Query().Get("term")
+	if term == "" {
+		http.Error(w, "term is required", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, studentID) {
+		return
+	}
+
+	ticket, err := buildHallTicket(a.MySQL.db, studentID, term)
+	if err != nil {
+		writeExamError(w, err)
+		return
+	}
+
+	go LogActivity("EXPORT_HALL_TICKET", currentUser(r))
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ticket)
+		return
+	}
+
+	pdf := renderPDF(hallTicketLines(ticket), map[string]string{
+		"Title":   "Hall Ticket - " + ticket.Student.Name,
+		"Subject": "Exams " + term,
+		"Creator": "College Management System",
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=hall-ticket-%d-%s.pdf", studentID, term))
+	w.Write(pdf)
+}
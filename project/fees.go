@@ -0,0 +1,1054 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ledger transaction kinds
+const (
+	LedgerCharge  = "charge"
+	LedgerPayment = "payment"
+	LedgerWaiver  = "waiver"
+	LedgerRefund  = "refund"
+	LedgerAward   = "award"
+)
+
+// ledger accounts, receivable is what students owe
+const (
+	AccountReceivable = "receivable"
+	AccountRevenue    = "fee_revenue"
+	AccountCash       = "cash"
+	AccountWaivers    = "fee_waivers"
+	AccountAwards     = "scholarships"
+)
+
+// ledgerPostings gives the debit and credit account of every transaction kind
+var ledgerPostings = map[string][2]string{
+	LedgerCharge:  {AccountReceivable, AccountRevenue},
+	LedgerPayment: {AccountCash, AccountReceivable},
+	LedgerWaiver:  {AccountWaivers, AccountReceivable},
+	LedgerRefund:  {AccountReceivable, AccountCash},
+	LedgerAward:   {AccountAwards, AccountReceivable},
+}
+
+// invoice and payment statuses
+const (
+	InvoiceOpen = "open"
+	InvoicePaid = "paid"
+
+	PaymentPending   = "pending"
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+)
+
+// FeePlanItem is one fee of a plan, per-credit items are multiplied by the credits taken in the term
+type FeePlanItem struct {
+	Itemid      int    `json:"item_id"`
+	Description string `json:"description"`
+	Amountcents int64  `json:"amount_cents"`
+	Percredit   bool   `json:"per_credit"`
+}
+
+// FeePlan is the fee structure of a department, optionally narrowed to a program
+type FeePlan struct {
+	Planid    int           `json:"plan_id"`
+	Name      string        `json:"name"`
+	Dept      string        `json:"dept"`
+	Program   string        `json:"program"`
+	Currency  string        `json:"currency"`
+	Items     []FeePlanItem `json:"items"`
+	Createdat time.Time     `json:"created_at"`
+}
+
+// InvoiceLine is one billed fee
+type InvoiceLine struct {
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	Unitcents   int64  `json:"unit_cents"`
+	Amountcents int64  `json:"amount_cents"`
+}
+
+// Invoice is a student's bill for a term
+type Invoice struct {
+	Invoiceid    int           `json:"invoice_id"`
+	Studentid    int           `json:"student_id"`
+	Term         string        `json:"term"`
+	Planid       *int          `json:"plan_id,omitempty"`
+	Currency     string        `json:"currency"`
+	Totalcents   int64         `json:"total_cents"`
+	Balancecents int64         `json:"balance_cents"`
+	Status       string        `json:"status"`
+	Issuedat     time.Time     `json:"issued_at"`
+	Dueat        time.Time     `json:"due_at"`
+	Lines        []InvoiceLine `json:"lines,omitempty"`
+}
+
+// LedgerEntry is one side of a ledger transaction
+type LedgerEntry struct {
+	Account     string `json:"account"`
+	Debitcents  int64  `json:"debit_cents"`
+	Creditcents int64  `json:"credit_cents"`
+}
+
+// LedgerTransaction is a balanced set of ledger entries for one financial event
+type LedgerTransaction struct {
+	Txnid       int           `json:"txn_id"`
+	Studentid   int           `json:"student_id"`
+	Invoiceid   *int          `json:"invoice_id,omitempty"`
+	Kind        string        `json:"kind"`
+	Amountcents int64         `json:"amount_cents"`
+	Memo        string        `json:"memo"`
+	Createdby   string        `json:"created_by"`
+	Createdat   time.Time     `json:"created_at"`
+	Entries     []LedgerEntry `json:"entries"`
+}
+
+// Payment is an attempt to collect money through the payment gateway
+type Payment struct {
+	Paymentid     int       `json:"payment_id"`
+	Invoiceid     int       `json:"invoice_id"`
+	Studentid     int       `json:"student_id"`
+	Amountcents   int64     `json:"amount_cents"`
+	Refundedcents int64     `json:"refunded_cents"`
+	Gateway       string    `json:"gateway"`
+	Gatewayref    string    `json:"gateway_ref,omitempty"`
+	Status        string    `json:"status"`
+	Failurereason string    `json:"failure_reason,omitempty"`
+	Createdat     time.Time `json:"created_at"`
+}
+
+// StudentAccount is a student's financial position
+type StudentAccount struct {
+	Studentid    int                 `json:"student_id"`
+	Balancecents int64               `json:"balance_cents"`
+	Invoices     []Invoice           `json:"invoices"`
+	Payments     []Payment           `json:"payments"`
+	Transactions []LedgerTransaction `json:"transactions"`
+}
+
+// errors returned by the fee helpers
+var (
+	ErrFeePlanNotFound    = errors.New("no fee plan found for this student")
+	ErrInvoiceExists      = errors.New("student already has an invoice for this term")
+	ErrInvoiceNotFound    = errors.New("invoice not found")
+	ErrPaymentNotFound    = errors.New("payment not found")
+	ErrAmountOverBalance  = errors.New("amount exceeds the outstanding balance")
+	ErrRefundOverPayment  = errors.New("refund exceeds the refundable amount of the payment")
+	ErrPaymentNotSettled  = errors.New("only succeeded payments can be refunded")
+	ErrNothingToInvoice   = errors.New("student has no billable credits in this term")
+	ErrInvalidAmountCents = errors.New("amount_cents must be greater than 0")
+)
+
+// ValidateFeePlan validates incoming fee plan data
+func ValidateFeePlan(plan FeePlan) error {
+	if strings.TrimSpace(plan.Name) == "" {
+		return fmt.Errorf("plan name cannot be empty")
+	}
+	if strings.TrimSpace(plan.Dept) == "" {
+		return fmt.Errorf("empty dept or invalid dept")
+	}
+	if len(plan.Currency) != 3 {
+		return fmt.Errorf("currency must be a 3 letter code")
+	}
+	if len(plan.Items) == 0 {
+		return fmt.Errorf("atleast one fee item is required")
+	}
+	for _, item := range plan.Items {
+		if strings.TrimSpace(item.Description) == "" {
+			return fmt.Errorf("fee item description cannot be empty")
+		}
+		if item.Amountcents <= 0 {
+			return fmt.Errorf("fee item %q must have a positive amount_cents", item.Description)
+		}
+	}
+	return nil
+}
+
+// postLedger records a balanced transaction, debiting and crediting the accounts of its kind
+func postLedger(q dbtx, studentID int, invoiceID *int, kind string, amount int64, memo, actor string) (int, error) {
+	accounts, ok := ledgerPostings[kind]
+	if !ok {
+		return 0, fmt.Errorf("unknown ledger transaction kind %q", kind)
+	}
+	if amount <= 0 {
+		return 0, ErrInvalidAmountCents
+	}
+	res, err := q.Exec("INSERT INTO ledger_transactions (student_id , invoice_id , kind , amount_cents , memo , created_by , created_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+		studentID, invoiceID, kind, amount, memo, actor, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	if _, err := q.Exec("INSERT INTO ledger_entries (txn_id , account , debit_cents , credit_cents) VALUES (? , ? , ? , 0) , (? , ? , 0 , ?)",
+		id, accounts[0], amount, id, accounts[1], amount); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// receivableBalance sums what is owed on the receivable account, by invoice or by student
+func receivableBalance(q dbtx, column string, id int) (int64, error) {
+	var balance int64
+	err := q.QueryRow(`SELECT COALESCE(SUM(e.debit_cents - e.credit_cents), 0) FROM ledger_entries e
+		JOIN ledger_transactions t ON t.txn_id=e.txn_id
+		WHERE e.account=? AND t.`+column+`=?`, AccountReceivable, id).Scan(&balance)
+	return balance, err
+}
+
+// payableBalance is what can still be paid or waived on an invoice: the receivable balance less
+// the gateway payments already pending against it, so two payments cannot both claim the same balance
+func payableBalance(q dbtx, invoiceID int) (int64, error) {
+	balance, err := receivableBalance(q, "invoice_id", invoiceID)
+	if err != nil {
+		return 0, err
+	}
+	var pending int64
+	err = q.QueryRow("SELECT COALESCE(SUM(amount_cents), 0) FROM payments WHERE invoice_id=? AND status=?", invoiceID, PaymentPending).Scan(&pending)
+	return balance - pending, err
+}
+
+// refreshInvoiceStatus marks an invoice paid once nothing is owed on it, and open again after a refund
+func refreshInvoiceStatus(q dbtx, invoiceID int) (int64, error) {
+	balance, err := receivableBalance(q, "invoice_id", invoiceID)
+	if err != nil {
+		return 0, err
+	}
+	status := InvoiceOpen
+	if balance <= 0 {
+		status = InvoicePaid
+	}
+	_, err = q.Exec("UPDATE invoices SET status=? WHERE invoice_id=?", status, invoiceID)
+	return balance, err
+}
+
+// loadFeePlan fetches a plan with its items
+func loadFeePlan(q dbtx, planID int) (*FeePlan, error) {
+	var plan FeePlan
+	err := q.QueryRow("SELECT plan_id , name , dept , program , currency , created_at FROM fee_plans WHERE plan_id=?", planID).
+		Scan(&plan.Planid, &plan.Name, &plan.Dept, &plan.Program, &plan.Currency, &plan.Createdat)
+	if err == sql.ErrNoRows {
+		return nil, ErrFeePlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query("SELECT item_id , description , amount_cents , per_credit FROM fee_plan_items WHERE plan_id=? ORDER BY item_id", planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	plan.Items = []FeePlanItem{}
+	for rows.Next() {
+		var item FeePlanItem
+		if err := rows.Scan(&item.Itemid, &item.Description, &item.Amountcents, &item.Percredit); err != nil {
+			return nil, err
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return &plan, rows.Err()
+}
+
+// matchFeePlan finds the plan for a department and program, falling back to the department-wide plan
+func matchFeePlan(q dbtx, dept, program string) (*FeePlan, error) {
+	var planID int
+	err := q.QueryRow("SELECT plan_id FROM fee_plans WHERE dept=? AND program IN (? , '') ORDER BY program DESC LIMIT 1", dept, program).Scan(&planID)
+	if err == sql.ErrNoRows {
+		return nil, ErrFeePlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return loadFeePlan(q, planID)
+}
+
+// termCredits sums the credits of the sections a student takes in a term
+func termCredits(q dbtx, studentID int, term string) (int, error) {
+	var credits int
+	err := q.QueryRow(`SELECT COALESCE(SUM(c.credits), 0) FROM enrollments e
+		JOIN sections s ON s.section_id=e.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		WHERE e.student_id=? AND s.term=? AND e.status IN (? , ? , ?)`,
+		studentID, term, EnrollmentEnrolled, EnrollmentCompleted, EnrollmentFailed).Scan(&credits)
+	return credits, err
+}
+
+// generateInvoice bills a student for a term from a fee plan and posts the charge.
+// planID 0 picks the plan matching the student's department and program.
+func generateInvoice(tx *sql.Tx, studentID int, term, program string, planID int, due time.Time, actor string) (*Invoice, error) {
+	var dept sql.NullString
+	err := tx.QueryRow("SELECT dept FROM students WHERE id=? FOR UPDATE", studentID).Scan(&dept)
+	if err == sql.ErrNoRows {
+		return nil, ErrStudentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM invoices WHERE student_id=? AND term=?", studentID, term).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, ErrInvoiceExists
+	}
+
+	var plan *FeePlan
+	if planID > 0 {
+		plan, err = loadFeePlan(tx, planID)
+	} else {
+		plan, err = matchFeePlan(tx, dept.String, program)
+	}
+	if err != nil {
+		return nil, err
+	}
+	credits, err := termCredits(tx, studentID, term)
+	if err != nil {
+		return nil, err
+	}
+	if credits == 0 {
+		return nil, ErrNothingToInvoice
+	}
+
+	inv := &Invoice{Studentid: studentID, Term: term, Planid: &plan.Planid, Currency: plan.Currency, Status: InvoiceOpen, Issuedat: time.Now(), Dueat: due}
+	for _, item := range plan.Items {
+		qty := 1
+		if item.Percredit {
+			qty = credits
+		}
+		line := InvoiceLine{Description: item.Description, Quantity: qty, Unitcents: item.Amountcents, Amountcents: int64(qty) * item.Amountcents}
+		inv.Lines = append(inv.Lines, line)
+		inv.Totalcents += line.Amountcents
+	}
+
+	res, err := tx.Exec("INSERT INTO invoices (student_id , term , plan_id , currency , total_cents , status , issued_at , due_at) VALUES (? , ? , ? , ? , ? , ? , ? , ?)",
+		inv.Studentid, inv.Term, plan.Planid, inv.Currency, inv.Totalcents, inv.Status, inv.Issuedat, inv.Dueat)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	inv.Invoiceid = int(id)
+	for _, line := range inv.Lines {
+		if _, err := tx.Exec("INSERT INTO invoice_lines (invoice_id , description , quantity , unit_cents , amount_cents) VALUES (? , ? , ? , ? , ?)",
+			inv.Invoiceid, line.Description, line.Quantity, line.Unitcents, line.Amountcents); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := postLedger(tx, studentID, &inv.Invoiceid, LedgerCharge, inv.Totalcents, fmt.Sprintf("%s fees for %s", plan.Name, term), actor); err != nil {
+		return nil, err
+	}
+
+	// credit scholarships already awarded for the term
+	if err := applyTermAwards(tx, studentID, term, inv.Invoiceid, actor); err != nil {
+		return nil, err
+	}
+	if inv.Balancecents, err = refreshInvoiceStatus(tx, inv.Invoiceid); err != nil {
+		return nil, err
+	}
+	if inv.Balancecents <= 0 {
+		inv.Status = InvoicePaid
+	}
+	return inv, nil
+}
+
+// loadInvoice fetches an invoice with its lines and outstanding balance
+func loadInvoice(q dbtx, invoiceID int) (*Invoice, error) {
+	var inv Invoice
+	var planID sql.NullInt64
+	err := q.QueryRow("SELECT invoice_id , student_id , term , plan_id , currency , total_cents , status , issued_at , due_at FROM invoices WHERE invoice_id=?", invoiceID).
+		Scan(&inv.Invoiceid, &inv.Studentid, &inv.Term, &planID, &inv.Currency, &inv.Totalcents, &inv.Status, &inv.Issuedat, &inv.Dueat)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if planID.Valid {
+		id := int(planID.Int64)
+		inv.Planid = &id
+	}
+	rows, err := q.Query("SELECT description , quantity , unit_cents , amount_cents FROM invoice_lines WHERE invoice_id=? ORDER BY line_id", invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line InvoiceLine
+		if err := rows.Scan(&line.Description, &line.Quantity, &line.Unitcents, &line.Amountcents); err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	inv.Balancecents, err = receivableBalance(q, "invoice_id", invoiceID)
+	return &inv, err
+}
+
+// writeFeeError maps fee and payment errors to HTTP responses
+func writeFeeError(w http.ResponseWriter, err error) {
+	var declined *PaymentDeclinedError
+	switch {
+	case errors.Is(err, ErrFeePlanNotFound), errors.Is(err, ErrInvoiceNotFound), errors.Is(err, ErrPaymentNotFound), errors.Is(err, ErrStudentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvoiceExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrAmountOverBalance), errors.Is(err, ErrRefundOverPayment), errors.Is(err, ErrPaymentNotSettled), errors.Is(err, ErrNothingToInvoice):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrInvalidAmountCents):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &declined):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// CreateFeePlanHandler creates a fee plan with its items, registrar only
+func (a *HybridHandler) CreateFeePlanHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var plan FeePlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// Validate fee plan data
+	if err := ValidateFeePlan(plan); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	plan.Currency = strings.ToUpper(plan.Currency)
+	plan.Createdat = time.Now()
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT INTO fee_plans (name , dept , program , currency , created_at) VALUES (? , ? , ? , ? , ?)", plan.Name, plan.Dept, plan.Program, plan.Currency, plan.Createdat)
+	if err != nil {
+		http.Error(w, "failed to create fee plan, a plan may already exist for this dept and program", http.StatusConflict)
+		return
+	}
+	id, _ := res.LastInsertId()
+	plan.Planid = int(id)
+	for i, item := range plan.Items {
+		res, err := tx.Exec("INSERT INTO fee_plan_items (plan_id , description , amount_cents , per_credit) VALUES (? , ? , ? , ?)", plan.Planid, item.Description, item.Amountcents, item.Percredit)
+		if err != nil {
+			http.Error(w, "failed to create fee item", http.StatusInternalServerError)
+			return
+		}
+		itemID, _ := res.LastInsertId()
+		plan.Items[i].Itemid = int(itemID)
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("CREATE_FEE_PLAN", currentUser(r))
+	go AuditLog("CREATE", "FEE_PLAN", plan.Planid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GetFeePlansHandler lists fee plans, optionally for one department (?dept=)
+func (a *HybridHandler) GetFeePlansHandler(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT plan_id FROM fee_plans"
+	var args []interface{}
+	if dept := r.URL.Query().Get("dept"); dept != "" {
+		query += " WHERE dept=?"
+		args = append(args, dept)
+	}
+	rows, err := a.MySQL.db.Query(query+" ORDER BY dept , program", args...)
+	if err != nil {
+		http.Error(w, "unable to fetch fee plans", http.StatusInternalServerError)
+		return
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	plans := []FeePlan{}
+	for _, id := range ids {
+		plan, err := loadFeePlan(a.MySQL.db, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		plans = append(plans, *plan)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
+
+// invoiceRequest is the body of the invoice generation endpoints
+type invoiceRequest struct {
+	Term    string    `json:"term"`
+	Program string    `json:"program"`
+	Planid  int       `json:"plan_id"`
+	Dueat   time.Time `json:"due_at"`
+}
+
+// CreateInvoiceHandler bills one student for a term, registrar only
+func (a *HybridHandler) CreateInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract student id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req invoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if strings.TrimSpace(req.Term) == "" || req.Dueat.IsZero() {
+		http.Error(w, "term and due_at are required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	inv, err := generateInvoice(tx, studentID, req.Term, req.Program, req.Planid, req.Dueat, currentUser(r))
+	if err != nil {
+		tx.Rollback()
+		writeFeeError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("CREATE_INVOICE", currentUser(r))
+	go AuditLog("CREATE", "INVOICE", fmt.Sprintf("%d student=%d term=%s total=%d", inv.Invoiceid, studentID, req.Term, inv.Totalcents), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(inv)
+}
+
+// GenerateTermInvoicesHandler bills every student enrolled in a term who has no invoice yet, registrar only
+func (a *HybridHandler) GenerateTermInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req invoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if strings.TrimSpace(req.Term) == "" || req.Dueat.IsZero() {
+		http.Error(w, "term and due_at are required", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query(`SELECT DISTINCT e.student_id FROM enrollments e
+		JOIN sections s ON s.section_id=e.section_id
+		LEFT JOIN invoices i ON i.student_id=e.student_id AND i.term=s.term
+		WHERE s.term=? AND e.status=? AND i.invoice_id IS NULL ORDER BY e.student_id`, req.Term, EnrollmentEnrolled)
+	if err != nil {
+		http.Error(w, "unable to fetch students", http.StatusInternalServerError)
+		return
+	}
+	var students []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		students = append(students, id)
+	}
+	rows.Close()
+
+	// each student is billed in its own transaction so one failure does not block the rest
+	actor := currentUser(r)
+	created := []int{}
+	skipped := map[int]string{}
+	for _, studentID := range students {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+			return
+		}
+		inv, err := generateInvoice(tx, studentID, req.Term, req.Program, req.Planid, req.Dueat, actor)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			skipped[studentID] = err.Error()
+			continue
+		}
+		created = append(created, inv.Invoiceid)
+	}
+
+	go LogActivity("GENERATE_TERM_INVOICES", actor)
+	go AuditLog("GENERATE", "INVOICES", fmt.Sprintf("term=%s created=%d skipped=%d", req.Term, len(created), len(skipped)), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"term": req.Term, "created": created, "skipped": skipped})
+}
+
+// GetInvoiceHandler returns an invoice with its lines and balance to the student or a registrar
+func (a *HybridHandler) GetInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := loadInvoice(a.MySQL.db, id)
+	if err != nil {
+		writeFeeError(w, err)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, inv.Studentid) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
+
+// PayInvoiceHandler charges the payment gateway and posts the payment to the ledger.
+// The attempt is recorded as pending under a lock on the invoice before the gateway is called, so no charge
+// goes unrecorded and the pending amount is reserved against concurrent payments and waivers.
+func (a *HybridHandler) PayInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	invoiceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Amountcents int64  `json:"amount_cents"`
+		Token       string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Amountcents <= 0 {
+		writeFeeError(w, ErrInvalidAmountCents)
+		return
+	}
+
+	inv, err := loadInvoice(a.MySQL.db, invoiceID)
+	if err != nil {
+		writeFeeError(w, err)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, inv.Studentid) {
+		return
+	}
+
+	// Reserve the amount as a pending payment while the invoice row is locked
+	actor := currentUser(r)
+	payment := Payment{Invoiceid: invoiceID, Studentid: inv.Studentid, Amountcents: req.Amountcents, Gateway: a.Payments.Name(), Status: PaymentPending, Createdat: time.Now()}
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := tx.QueryRow("SELECT invoice_id FROM invoices WHERE invoice_id=? FOR UPDATE", invoiceID).Scan(&invoiceID); err != nil {
+			return err
+		}
+		payable, err := payableBalance(tx, invoiceID)
+		if err != nil {
+			return err
+		}
+		if req.Amountcents > payable {
+			return ErrAmountOverBalance
+		}
+		res, err := tx.Exec("INSERT INTO payments (invoice_id , student_id , amount_cents , gateway , status , created_by , created_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+			payment.Invoiceid, payment.Studentid, payment.Amountcents, payment.Gateway, payment.Status, actor, payment.Createdat)
+		if err != nil {
+			return err
+		}
+		id, _ := res.LastInsertId()
+		payment.Paymentid = int(id)
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeFeeError(w, err)
+		return
+	}
+
+	// Charge through the gateway
+	ctx, cancel := context.WithTimeout(a.Ctx, 30*time.Second)
+	defer cancel()
+	ref, err := a.Payments.Charge(ctx, ChargeRequest{Amountcents: req.Amountcents, Currency: inv.Currency, Token: req.Token, Description: fmt.Sprintf("Invoice %d", invoiceID), Reference: fmt.Sprintf("payment-%d", payment.Paymentid)})
+	var declined *PaymentDeclinedError
+	if errors.As(err, &declined) {
+		a.MySQL.db.Exec("UPDATE payments SET status=? , failure_reason=? WHERE payment_id=?", PaymentFailed, declined.Reason, payment.Paymentid)
+		go AuditLog("DECLINE", "PAYMENT", payment.Paymentid, actor)
+		writeFeeError(w, err)
+		return
+	}
+	if err != nil {
+		// outcome unknown, the payment stays pending for reconciliation
+		log.Printf("payment %d left pending: %v", payment.Paymentid, err)
+		http.Error(w, "payment gateway error, payment left pending", http.StatusBadGateway)
+		return
+	}
+
+	// Record the settled payment in the ledger, re-checking the balance under the invoice lock
+	payment.Gatewayref, payment.Status = ref, PaymentSucceeded
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := tx.QueryRow("SELECT invoice_id FROM invoices WHERE invoice_id=? FOR UPDATE", invoiceID).Scan(&invoiceID); err != nil {
+			return err
+		}
+		balance, err := receivableBalance(tx, "invoice_id", invoiceID)
+		if err != nil {
+			return err
+		}
+		if req.Amountcents > balance {
+			return ErrAmountOverBalance
+		}
+		if _, err := tx.Exec("UPDATE payments SET status=? , gateway_ref=? WHERE payment_id=?", payment.Status, ref, payment.Paymentid); err != nil {
+			return err
+		}
+		if _, err := postLedger(tx, inv.Studentid, &invoiceID, LedgerPayment, req.Amountcents, fmt.Sprintf("payment %d via %s %s", payment.Paymentid, payment.Gateway, ref), actor); err != nil {
+			return err
+		}
+		if _, err := refreshInvoiceStatus(tx, invoiceID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if errors.Is(err, ErrAmountOverBalance) {
+		// the balance went down while charging, give the money back rather than overpay the invoice
+		payment.Gatewayref, payment.Status, payment.Failurereason = ref, PaymentFailed, ErrAmountOverBalance.Error()
+		if err := a.Payments.Refund(ctx, ref, req.Amountcents); err != nil {
+			log.Printf("payment %d charged as %s over the balance and not refunded: %v", payment.Paymentid, ref, err)
+			http.Error(w, "payment exceeds the balance and could not be refunded, it will be reconciled", http.StatusInternalServerError)
+			return
+		}
+		a.MySQL.db.Exec("UPDATE payments SET status=? , gateway_ref=? , failure_reason=? WHERE payment_id=?", payment.Status, ref, payment.Failurereason, payment.Paymentid)
+		go AuditLog("REFUND", "PAYMENT", fmt.Sprintf("%d over balance ref=%s", payment.Paymentid, ref), actor)
+		writeFeeError(w, ErrAmountOverBalance)
+		return
+	}
+	if err != nil {
+		log.Printf("payment %d charged as %s but not posted: %v", payment.Paymentid, ref, err)
+		http.Error(w, "payment taken but not recorded, it will be reconciled", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("PAY_INVOICE", actor)
+	go AuditLog("PAY", "INVOICE", fmt.Sprintf("%d payment=%d amount=%d ref=%s", invoiceID, payment.Paymentid, req.Amountcents, ref), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// WaiveInvoiceHandler writes off part of an invoice, registrar only
+func (a *HybridHandler) WaiveInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract id from URL
+	invoiceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Amountcents int64  `json:"amount_cents"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	actor := currentUser(r)
+	var balance int64
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		var studentID int
+		err = tx.QueryRow("SELECT student_id FROM invoices WHERE invoice_id=? FOR UPDATE", invoiceID).Scan(&studentID)
+		if err == sql.ErrNoRows {
+			return ErrInvoiceNotFound
+		}
+		if err != nil {
+			return err
+		}
+		outstanding, err := payableBalance(tx, invoiceID)
+		if err != nil {
+			return err
+		}
+		if req.Amountcents > outstanding {
+			return ErrAmountOverBalance
+		}
+		if _, err := postLedger(tx, studentID, &invoiceID, LedgerWaiver, req.Amountcents, "waiver: "+req.Reason, actor); err != nil {
+			return err
+		}
+		if balance, err = refreshInvoiceStatus(tx, invoiceID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeFeeError(w, err)
+		return
+	}
+
+	go LogActivity("WAIVE_INVOICE", actor)
+	go AuditLog("WAIVE", "INVOICE", fmt.Sprintf("%d amount=%d reason=%q", invoiceID, req.Amountcents, req.Reason), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"invoice_id": invoiceID, "waived_cents": req.Amountcents, "balance_cents": balance})
+}
+
+// RefundPaymentHandler refunds part or all of a settled payment through the gateway, registrar only.
+// The payment row stays locked while the gateway is called so a payment cannot be refunded twice.
+func (a *HybridHandler) RefundPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract id from URL
+	paymentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Amountcents int64  `json:"amount_cents"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Amountcents <= 0 {
+		writeFeeError(w, ErrInvalidAmountCents)
+		return
+	}
+
+	actor := currentUser(r)
+	var p Payment
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		var ref sql.NullString
+		err = tx.QueryRow("SELECT payment_id , invoice_id , student_id , amount_cents , refunded_cents , gateway , gateway_ref , status , created_at FROM payments WHERE payment_id=? FOR UPDATE", paymentID).
+			Scan(&p.Paymentid, &p.Invoiceid, &p.Studentid, &p.Amountcents, &p.Refundedcents, &p.Gateway, &ref, &p.Status, &p.Createdat)
+		if err == sql.ErrNoRows {
+			return ErrPaymentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		p.Gatewayref = ref.String
+		if p.Status != PaymentSucceeded {
+			return ErrPaymentNotSettled
+		}
+		if p.Refundedcents+req.Amountcents > p.Amountcents {
+			return ErrRefundOverPayment
+		}
+
+		ctx, cancel := context.WithTimeout(a.Ctx, 30*time.Second)
+		defer cancel()
+		if err := a.Payments.Refund(ctx, p.Gatewayref, req.Amountcents); err != nil {
+			return err
+		}
+		p.Refundedcents += req.Amountcents
+		if _, err := tx.Exec("UPDATE payments SET refunded_cents=? WHERE payment_id=?", p.Refundedcents, paymentID); err != nil {
+			return err
+		}
+		if _, err := postLedger(tx, p.Studentid, &p.Invoiceid, LedgerRefund, req.Amountcents, fmt.Sprintf("refund of payment %d: %s", paymentID, req.Reason), actor); err != nil {
+			return err
+		}
+		if _, err := refreshInvoiceStatus(tx, p.Invoiceid); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			log.Printf("payment %d refunded %d at gateway but not posted: %v", paymentID, req.Amountcents, err)
+			return err
+		}
+		return nil
+	}()
+	if err != nil {
+		writeFeeError(w, err)
+		return
+	}
+
+	go LogActivity("REFUND_PAYMENT", actor)
+	go AuditLog("REFUND", "PAYMENT", fmt.Sprintf("%d amount=%d reason=%q", paymentID, req.Amountcents, req.Reason), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// GetStudentAccountHandler returns a student's balance, invoices, payments and ledger to the student or a registrar
+func (a *HybridHandler) GetStudentAccountHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, studentID) {
+		return
+	}
+
+	account, err := studentAccount(a.MySQL.db, studentID)
+	if err != nil {
+		writeFeeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// studentAccount assembles a student's financial position from the ledger
+func studentAccount(q dbtx, studentID int) (*StudentAccount, error) {
+	account := &StudentAccount{Studentid: studentID, Invoices: []Invoice{}, Payments: []Payment{}, Transactions: []LedgerTransaction{}}
+	var err error
+	if account.Balancecents, err = receivableBalance(q, "student_id", studentID); err != nil {
+		return nil, err
+	}
+
+	// invoices with their outstanding balances
+	rows, err := q.Query("SELECT invoice_id FROM invoices WHERE student_id=? ORDER BY issued_at", studentID)
+	if err != nil {
+		return nil, err
+	}
+	var invoiceIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		invoiceIDs = append(invoiceIDs, id)
+	}
+	rows.Close()
+	for _, id := range invoiceIDs {
+		inv, err := loadInvoice(q, id)
+		if err != nil {
+			return nil, err
+		}
+		account.Invoices = append(account.Invoices, *inv)
+	}
+
+	// gateway payments
+	rows, err = q.Query("SELECT payment_id , invoice_id , student_id , amount_cents , refunded_cents , gateway , gateway_ref , status , failure_reason , created_at FROM payments WHERE student_id=? ORDER BY created_at", studentID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p Payment
+		var ref, reason sql.NullString
+		if err := rows.Scan(&p.Paymentid, &p.Invoiceid, &p.Studentid, &p.Amountcents, &p.Refundedcents, &p.Gateway, &ref, &p.Status, &reason, &p.Createdat); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Gatewayref, p.Failurereason = ref.String, reason.String
+		account.Payments = append(account.Payments, p)
+	}
+	rows.Close()
+
+	// ledger transactions with their entries
+	rows, err = q.Query(`SELECT t.txn_id , t.student_id , t.invoice_id , t.kind , t.amount_cents , t.memo , t.created_by , t.created_at , e.account , e.debit_cents , e.credit_cents
+		FROM ledger_transactions t JOIN ledger_entries e ON e.txn_id=t.txn_id
+		WHERE t.student_id=? ORDER BY t.txn_id , e.entry_id`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t LedgerTransaction
+		var invoiceID sql.NullInt64
+		var e LedgerEntry
+		if err := rows.Scan(&t.Txnid, &t.Studentid, &invoiceID, &t.Kind, &t.Amountcents, &t.Memo, &t.Createdby, &t.Createdat, &e.Account, &e.Debitcents, &e.Creditcents); err != nil {
+			return nil, err
+		}
+		if n := len(account.Transactions); n == 0 || account.Transactions[n-1].Txnid != t.Txnid {
+			if invoiceID.Valid {
+				id := int(invoiceID.Int64)
+				t.Invoiceid = &id
+			}
+			account.Transactions = append(account.Transactions, t)
+		}
+		last := &account.Transactions[len(account.Transactions)-1]
+		last.Entries = append(last.Entries, e)
+	}
+	return account, rows.Err()
+}
@@ -0,0 +1,158 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRegistrar holds the registrar role in handlers built by newFeeHandler
+const testRegistrar = "registrar@gmail.com"
+
+// newFeeHandler returns a handler over the test database charging through a fake gateway
+func newFeeHandler(t *testing.T) *HybridHandler {
+	t.Helper()
+	a := newMemoryHandler()
+	a.MySQL = testMySQL(t)
+	a.Payments = NewFakeGateway()
+	a.Roles = func(email, role string) (bool, error) {
+		return email == testRegistrar && role == RoleRegistrar, nil
+	}
+	return a
+}
+
+// newTestInvoice inserts a student owing totalCents on one open invoice and returns the invoice id
+func newTestInvoice(t *testing.T, m *MySQLInstance, totalCents int64) int {
+	t.Helper()
+	now := time.Now()
+	res, err := m.db.Exec("INSERT INTO students (name , age , email , dept) VALUES (? , ? , ? , ?)", "Payer", 20, fmt.Sprintf("payer-%d@gmail.com", now.UnixNano()), "CS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	studentID, _ := res.LastInsertId()
+	res, err = m.db.Exec("INSERT INTO invoices (student_id , term , currency , total_cents , status , issued_at , due_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+		studentID, "2026-FALL", "USD", totalCents, InvoiceOpen, now, now.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := res.LastInsertId()
+	invoiceID := int(id)
+	if _, err := postLedger(m.db, int(studentID), &invoiceID, LedgerCharge, totalCents, "tuition", "test"); err != nil {
+		t.Fatal(err)
+	}
+	return invoiceID
+}
+
+// pay posts a payment for an invoice as the registrar
+func pay(t *testing.T, a *HybridHandler, invoiceID int, amount int64, token string) (int, Payment) {
+	t.Helper()
+	id := fmt.Sprint(invoiceID)
+	w := serve(t, a.PayInvoiceHandler, "POST", "/invoices/"+id+"/payments", map[string]string{"id": id}, testRegistrar,
+		map[string]interface{}{"amount_cents": amount, "token": token})
+	var p Payment
+	if w.Code == http.StatusCreated {
+		decode(t, w, &p)
+	}
+	return w.Code, p
+}
+
+// wantInvoice fails the test unless the invoice has the balance and status
+func wantInvoice(t *testing.T, a *HybridHandler, invoiceID int, balance int64, status string) {
+	t.Helper()
+	inv, err := loadInvoice(a.MySQL.db, invoiceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Balancecents != balance || inv.Status != status {
+		t.Fatalf("invoice %d has balance %d and status %s, want %d and %s", invoiceID, inv.Balancecents, inv.Status, balance, status)
+	}
+}
+
+func TestPayAndRefundInvoiceHandlers(t *testing.T) {
+	a := newFeeHandler(t)
+	invoiceID := newTestInvoice(t, a.MySQL, 1000)
+	id := fmt.Sprint(invoiceID)
+
+	w := serve(t, a.PayInvoiceHandler, "POST", "/invoices/"+id+"/payments", map[string]string{"id": id}, "", map[string]interface{}{"amount_cents": 100, "token": "tok"})
+	wantStatus(t, w, http.StatusForbidden)
+
+	code, first := pay(t, a, invoiceID, 600, "tok")
+	if code != http.StatusCreated || first.Status != PaymentSucceeded || first.Gatewayref == "" {
+		t.Fatalf("first payment: status %d, %+v", code, first)
+	}
+	wantInvoice(t, a, invoiceID, 400, InvoiceOpen)
+
+	for _, tc := range []struct {
+		name   string
+		amount int64
+		token  string
+		want   int
+	}{
+		{"over the balance", 500, "tok", http.StatusUnprocessableEntity},
+		{"declined card", 100, "decline", http.StatusPaymentRequired},
+		{"unknown outcome", 100, "error", http.StatusBadGateway},
+		{"over the balance less the pending payment", 400, "tok", http.StatusUnprocessableEntity},
+	} {
+		if code, _ := pay(t, a, invoiceID, tc.amount, tc.token); code != tc.want {
+			t.Fatalf("%s: status %d, want %d", tc.name, code, tc.want)
+		}
+	}
+	wantInvoice(t, a, invoiceID, 400, InvoiceOpen)
+
+	if code, _ := pay(t, a, invoiceID, 300, "tok"); code != http.StatusCreated {
+		t.Fatalf("rest of the payable balance: status %d", code)
+	}
+	wantInvoice(t, a, invoiceID, 100, InvoiceOpen)
+
+	// refunds
+	refund := func(p Payment, amount int64, user string) int {
+		id := fmt.Sprint(p.Paymentid)
+		w := serve(t, a.RefundPaymentHandler, "POST", "/payments/"+id+"/refunds", map[string]string{"id": id}, user,
+			map[string]interface{}{"amount_cents": amount, "reason": "dropped out"})
+		return w.Code
+	}
+	if code := refund(first, 100, "student@gmail.com"); code != http.StatusForbidden {
+		t.Fatalf("refund by a student: status %d, want 403", code)
+	}
+	if code := refund(first, 600, testRegistrar); code != http.StatusOK {
+		t.Fatalf("full refund: status %d", code)
+	}
+	if code := refund(first, 1, testRegistrar); code != http.StatusUnprocessableEntity {
+		t.Fatalf("refund past the payment: status %d, want 422", code)
+	}
+	wantInvoice(t, a, invoiceID, 700, InvoiceOpen)
+}
+
+func TestPayInvoiceInParallel(t *testing.T) {
+	const payers = 10
+	a := newFeeHandler(t)
+	invoiceID := newTestInvoice(t, a.MySQL, 1000)
+
+	var wg sync.WaitGroup
+	codes := make([]int, payers)
+	for i := 0; i < payers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i], _ = pay(t, a, invoiceID, 400, "tok")
+		}(i)
+	}
+	wg.Wait()
+
+	paid := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			paid++
+		case http.StatusUnprocessableEntity:
+		default:
+			t.Errorf("payer %d: status %d", i+1, code)
+		}
+	}
+	if paid != 2 {
+		t.Fatalf("%d payments of 400 went through on a balance of 1000, want 2", paid)
+	}
+	wantInvoice(t, a, invoiceID, 200, InvoiceOpen)
+}
@@ -0,0 +1,196 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// FineEntry is one line of a borrower's fine ledger. Charges come from overdue loans,
+// payments and waivers are recorded by librarians and reduce the balance.
+type FineEntry struct {
+	Entryid     int       `json:"entry_id"`
+	Userid      int       `json:"user_id"`
+	Usertype    string    `json:"usertype"`
+	Borrowid    *int      `json:"borrow_id,omitempty"`
+	Kind        string    `json:"kind"`
+	Amountcents int64     `json:"amount_cents"`
+	Memo        string    `json:"memo"`
+	Createdby   string    `json:"created_by"`
+	Createdat   time.Time `json:"created_at"`
+}
+
+// FineAccount is a borrower's fine ledger with its totals
+type FineAccount struct {
+	Userid       int         `json:"user_id"`
+	Usertype     string      `json:"usertype"`
+	Chargedcents int64       `json:"charged_cents"`
+	Paidcents    int64       `json:"paid_cents"`
+	Waivedcents  int64       `json:"waived_cents"`
+	Balancecents int64       `json:"balance_cents"`
+	Entries      []FineEntry `json:"entries"`
+}
+
+// fineFor is the fine of a loan returned, or still out, daysLate days after its due date.
+// Days inside the grace period are free, the rest cost fine_per_day_cents up to max_fine_cents.
+func fineFor(p LoanPolicy, daysLate int) int64 {
+	billable := daysLate - p.Gracedays
+	if billable <= 0 {
+		return 0
+	}
+	fine := int64(billable) * p.Fineperdaycents
+	if p.Maxfinecents > 0 && fine > p.Maxfinecents {
+		fine = p.Maxfinecents
+	}
+	return fine
+}
+
+// fineMemo describes the charge of an overdue loan
+func fineMemo(borrowID, daysLate int) string {
+	return fmt.Sprintf("overdue fine: loan %d, %d days late", borrowID, daysLate)
+}
+
+// finesBlockError explains why a borrow was refused for unpaid fines
+func finesBlockError(record Borrowrecords, balance int64, p LoanPolicy) error {
+	return fmt.Errorf("%w: %s %d owes %d cents, above the %d cents limit", ErrFinesUnpaid, record.Usertype, record.Userid, balance, p.Fineblockcents)
+}
+
+// addFineEntry adds an entry to the totals of an account
+func (f *FineAccount) addFineEntry(e FineEntry) {
+	switch e.Kind {
+	case LedgerCharge:
+		f.Chargedcents += e.Amountcents
+	case LedgerPayment:
+		f.Paidcents += e.Amountcents
+	case LedgerWaiver:
+		f.Waivedcents += e.Amountcents
+	}
+	f.Balancecents = f.Chargedcents - f.Paidcents - f.Waivedcents
+	f.Entries = append(f.Entries, e)
+}
+
+// borrowerFromURL reads {usertype} and {userId} from the URL
+func borrowerFromURL(r *http.Request) (string, int, error) {
+	vars := mux.Vars(r)
+	usertype := vars["usertype"]
+	if usertype != "student" && usertype != "lecturer" {
+		return "", 0, fmt.Errorf("usertype must be student or lecturer")
+	}
+	userID, err := strconv.Atoi(vars["userId"])
+	if err != nil || userID <= 0 {
+		return "", 0, fmt.Errorf("invalid user id")
+	}
+	return usertype, userID, nil
+}
+
+// writeFineError maps fine ledger errors to HTTP responses
+func writeFineError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrFineOverBalance), errors.Is(err, ErrInvalidAmountCents):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// GetFineAccountHandler returns a borrower's fine ledger and balance, librarian only
+func (a *HybridHandler) GetFineAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+	usertype, userID, err := borrowerFromURL(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := a.Libraries.FineAccount(r.Context(), usertype, userID)
+	if err != nil {
+		writeFineError(w, err, "unable to fetch fines")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// PayFineHandler records a fine payment taken at the desk, librarian only
+func (a *HybridHandler) PayFineHandler(w http.ResponseWriter, r *http.Request) {
+	a.postFineEntry(w, r, LedgerPayment)
+}
+
+// WaiveFineHandler writes off part of a borrower's fines, librarian only
+func (a *HybridHandler) WaiveFineHandler(w http.ResponseWriter, r *http.Request) {
+	a.postFineEntry(w, r, LedgerWaiver)
+}
+
+// postFineEntry records a payment or waiver of at most the unpaid balance, waivers need a reason
+func (a *HybridHandler) postFineEntry(w http.ResponseWriter, r *http.Request, kind string) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+	usertype, userID, err := borrowerFromURL(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Amountcents int64  `json:"amount_cents"`
+		Memo        string `json:"memo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Amountcents <= 0 {
+		http.Error(w, ErrInvalidAmountCents.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Memo = strings.TrimSpace(req.Memo)
+	if kind == LedgerWaiver && req.Memo == "" {
+		http.Error(w, "memo with the reason is required", http.StatusBadRequest)
+		return
+	}
+	if req.Memo == "" {
+		req.Memo = "fine payment"
+	}
+
+	actor := currentUser(r)
+	entry := FineEntry{Userid: userID, Usertype: usertype, Kind: kind, Amountcents: req.Amountcents, Memo: req.Memo, Createdby: actor}
+	balance, err := a.Libraries.PostFineEntry(r.Context(), &entry)
+	if err != nil {
+		writeFineError(w, err, "failed to record "+kind)
+		return
+	}
+
+	go LogActivity(strings.ToUpper(kind)+"_FINE", actor)
+	go AuditLog(strings.ToUpper(kind), "FINE", fmt.Sprintf("%s %d amount=%d memo=%q", usertype, userID, req.Amountcents, req.Memo), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"entry": entry, "balance_cents": balance})
+}
+
+// AssessFinesHandler charges the fines accrued by open overdue loans now instead of waiting for the nightly run, librarian only
+func (a *HybridHandler) AssessFinesHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+	charged, err := a.Libraries.AssessOverdue(r.Context())
+	if err != nil {
+		http.Error(w, "failed to assess fines", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("ASSESS_FINES", currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"loans_charged": charged})
+}
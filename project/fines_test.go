@@ -0,0 +1,88 @@
+package project
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFineFor(t *testing.T) {
+	policy := LoanPolicy{Fineperdaycents: 25, Gracedays: 2, Maxfinecents: 1000}
+	uncapped := policy
+	uncapped.Maxfinecents = 0
+	for _, tc := range []struct {
+		name     string
+		policy   LoanPolicy
+		daysLate int
+		want     int64
+	}{
+		{"on time", policy, 0, 0},
+		{"inside the grace period", policy, 1, 0},
+		{"last day of grace", policy, 2, 0},
+		{"first day after grace", policy, 3, 25},
+		{"a week late", policy, 7, 125},
+		{"just under the cap", policy, 41, 975},
+		{"at the cap", policy, 42, 1000},
+		{"past the cap", policy, 100, 1000},
+		{"no cap", uncapped, 100, 2450},
+		{"no grace", LoanPolicy{Fineperdaycents: 10}, 1, 10},
+		{"free usertype", LoanPolicy{Gracedays: 2}, 30, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fineFor(tc.policy, tc.daysLate); got != tc.want {
+				t.Fatalf("fineFor(%d days) = %d, want %d", tc.daysLate, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMemoryAssessOverdueChargesOnlyTheIncrease(t *testing.T) {
+	ctx := context.Background()
+	s := newLendingLibrary(t, "CL-1")
+	if err := s.Borrow(ctx, &Borrowrecords{Userid: 1, Usertype: "student", Barcode: "CL-1"}); err != nil {
+		t.Fatal(err)
+	}
+	policy := s.policies["student"]
+
+	// dueDaysAgo moves the loan's due date so it is that many days overdue today
+	dueDaysAgo := func(days int) {
+		due := today().AddDate(0, 0, -days)
+		s.borrows[0].Duedate = &due
+	}
+	for _, step := range []struct {
+		name        string
+		daysLate    int
+		wantCharged int
+		wantBalance int64
+	}{
+		{"inside the grace period", policy.Gracedays, 0, 0},
+		{"first assessment", 5, 1, fineFor(policy, 5)},
+		{"same day again", 5, 0, fineFor(policy, 5)},
+		{"three days later", 8, 1, fineFor(policy, 8)},
+		{"past the cap", 100, 1, policy.Maxfinecents},
+		{"capped loan later", 120, 0, policy.Maxfinecents},
+	} {
+		dueDaysAgo(step.daysLate)
+		charged, err := s.AssessOverdue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		account, err := s.FineAccount(ctx, "student", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if charged != step.wantCharged || account.Balancecents != step.wantBalance {
+			t.Fatalf("%s: charged %d loans to a balance of %d, want %d loans and %d", step.name, charged, account.Balancecents, step.wantCharged, step.wantBalance)
+		}
+	}
+
+	account, _ := s.FineAccount(ctx, "student", 1)
+	want := []int64{fineFor(policy, 5), fineFor(policy, 8) - fineFor(policy, 5), policy.Maxfinecents - fineFor(policy, 8)}
+	if len(account.Entries) != len(want) {
+		t.Fatalf("got %d ledger entries, want %d", len(account.Entries), len(want))
+	}
+	for k, e := range account.Entries {
+		if e.Kind != LedgerCharge || e.Amountcents != want[k] {
+			t.Errorf("entry %d is %s of %d, want a charge of %d", k, e.Kind, e.Amountcents, want[k])
+		}
+	}
+}
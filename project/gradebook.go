@@ -0,0 +1,862 @@
+package project
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GradeBand maps a minimum percentage to a letter grade and grade points
+type GradeBand struct {
+	Letter      string  `json:"letter"`
+	Minpercent  float64 `json:"min_percent"`
+	Gradepoints float64 `json:"grade_points"`
+}
+
+// GradeScale is a named set of grade bands, sections use the default scale unless one is set
+type GradeScale struct {
+	Scaleid   int         `json:"scale_id"`
+	Name      string      `json:"name"`
+	Isdefault bool        `json:"is_default"`
+	Bands     []GradeBand `json:"bands"`
+}
+
+// Assessment is a weighted piece of work in a section
+type Assessment struct {
+	Assessmentid int     `json:"assessment_id"`
+	Sectionid    int     `json:"section_id"`
+	Name         string  `json:"name"`
+	Weight       float64 `json:"weight"`
+	Maxscore     float64 `json:"max_score"`
+}
+
+// ScoreEntry is a student's mark on an assessment
+type ScoreEntry struct {
+	Studentid int     `json:"student_id"`
+	Score     float64 `json:"score"`
+}
+
+// StudentGrade is a student's computed grade in a section
+type StudentGrade struct {
+	Studentid   int     `json:"student_id"`
+	Percent     float64 `json:"percent"`
+	Letter      string  `json:"letter"`
+	Gradepoints float64 `json:"grade_points"`
+}
+
+// TermGPA is the grade point average of one term
+type TermGPA struct {
+	Term    string  `json:"term"`
+	Credits int     `json:"credits"`
+	GPA     float64 `json:"gpa"`
+}
+
+// StudentGPA holds per-term and cumulative GPA for a student
+type StudentGPA struct {
+	Studentid  int       `json:"student_id"`
+	Terms      []TermGPA `json:"terms"`
+	Credits    int       `json:"credits"`
+	Cumulative float64   `json:"cumulative_gpa"`
+}
+
+// DefaultGradeBands is used when neither the section nor the database defines a scale
+var DefaultGradeBands = []GradeBand{
+	{Letter: "A", Minpercent: 90, Gradepoints: 4},
+	{Letter: "B", Minpercent: 80, Gradepoints: 3},
+	{Letter: "C", Minpercent: 70, Gradepoints: 2},
+	{Letter: "D", Minpercent: 60, Gradepoints: 1},
+	{Letter: "F", Minpercent: 0, Gradepoints: 0},
+}
+
+// ErrTermLocked is returned when grades of a finalized term are changed
+var ErrTermLocked = errors.New("grades are locked, the term has been finalized")
+
+// ValidateGradeScale validates a grade scale before DB operations
+func ValidateGradeScale(scale GradeScale) error {
+	if strings.TrimSpace(scale.Name) == "" {
+		return fmt.Errorf("scale name cannot be empty")
+	}
+	if len(scale.Bands) == 0 {
+		return fmt.Errorf("atleast one band is required")
+	}
+	letters := map[string]bool{}
+	hasZero := false
+	for _, b := range scale.Bands {
+		if strings.TrimSpace(b.Letter) == "" {
+			return fmt.Errorf("band letter cannot be empty")
+		}
+		if letters[b.Letter] {
+			return fmt.Errorf("duplicate band letter %s", b.Letter)
+		}
+		letters[b.Letter] = true
+		if b.Minpercent < 0 || b.Minpercent > 100 {
+			return fmt.Errorf("min_percent must be between 0 and 100")
+		}
+		if b.Gradepoints < 0 || b.Gradepoints > 9.99 {
+			return fmt.Errorf("grade_points must be between 0 and 9.99")
+		}
+		if b.Minpercent == 0 {
+			hasZero = true
+		}
+	}
+	if !hasZero {
+		return fmt.Errorf("a band starting at 0 percent is required")
+	}
+	return nil
+}
+
+// ValidateAssessment validates an assessment before DB operations
+func ValidateAssessment(as Assessment) error {
+	if strings.TrimSpace(as.Name) == "" {
+		return fmt.Errorf("assessment name cannot be empty")
+	}
+	if as.Weight <= 0 || as.Weight > 100 {
+		return fmt.Errorf("weight must be between 0 and 100")
+	}
+	if as.Maxscore <= 0 {
+		return fmt.Errorf("max_score must be greater than 0")
+	}
+	return nil
+}
+
+// round2 rounds to two decimal places
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// letterFor returns the band a percentage falls into
+func letterFor(bands []GradeBand, percent float64) GradeBand {
+	sorted := append([]GradeBand{}, bands...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Minpercent > sorted[j].Minpercent })
+	for _, b := range sorted {
+		if percent >= b.Minpercent {
+			return b
+		}
+	}
+	return sorted[len(sorted)-1]
+}
+
+// weightedPercent combines assessment scores into a percentage, missing scores count as zero
+func weightedPercent(assessments []Assessment, scores map[int]float64) float64 {
+	var total, weights float64
+	for _, as := range assessments {
+		weights += as.Weight
+		total += scores[as.Assessmentid] / as.Maxscore * as.Weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return round2(total / weights * 100)
+}
+
+// gradePointAverage is the credit-weighted mean of grade points
+func gradePointAverage(points []float64, credits []int) (float64, int) {
+	var sum float64
+	var total int
+	for i := range points {
+		sum += points[i] * float64(credits[i])
+		total += credits[i]
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return round2(sum / float64(total)), total
+}
+
+// termLocked reports whether a term's grades have been finalized
+func termLocked(q dbtx, term string) (bool, error) {
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM term_locks WHERE term=?", term).Scan(&n)
+	return n > 0, err
+}
+
+// sectionTerm returns the term of a section and the credits of its course
+func sectionTerm(q dbtx, sectionID int) (string, int, error) {
+	var term string
+	var credits int
+	err := q.QueryRow("SELECT s.term , c.credits FROM sections s JOIN courses c ON c.course_id=s.course_id WHERE s.section_id=?", sectionID).Scan(&term, &credits)
+	if err == sql.ErrNoRows {
+		return "", 0, ErrSectionNotFound
+	}
+	return term, credits, err
+}
+
+// loadScaleBands loads the bands of one grade scale
+func loadScaleBands(q dbtx, scaleID int) ([]GradeBand, error) {
+	rows, err := q.Query("SELECT letter , min_percent , grade_points FROM grade_scale_bands WHERE scale_id=? ORDER BY min_percent DESC", scaleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bands []GradeBand
+	for rows.Next() {
+		var b GradeBand
+		if err := rows.Scan(&b.Letter, &b.Minpercent, &b.Gradepoints); err != nil {
+			return nil, err
+		}
+		bands = append(bands, b)
+	}
+	return bands, rows.Err()
+}
+
+// sectionBands returns the section's scale, else the default scale, else DefaultGradeBands
+func sectionBands(q dbtx, sectionID int) ([]GradeBand, error) {
+	var scaleID sql.NullInt64
+	err := q.QueryRow("SELECT COALESCE(s.grade_scale_id , (SELECT scale_id FROM grade_scales WHERE is_default LIMIT 1)) FROM sections s WHERE s.section_id=?", sectionID).Scan(&scaleID)
+	if err == sql.ErrNoRows {
+		return nil, ErrSectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !scaleID.Valid {
+		return DefaultGradeBands, nil
+	}
+	bands, err := loadScaleBands(q, int(scaleID.Int64))
+	if err != nil || len(bands) == 0 {
+		return DefaultGradeBands, err
+	}
+	return bands, nil
+}
+
+// loadAssessments lists a section's assessments
+func loadAssessments(q dbtx, sectionID int) ([]Assessment, error) {
+	rows, err := q.Query("SELECT assessment_id , section_id , name , weight , max_score FROM assessments WHERE section_id=? ORDER BY assessment_id", sectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	assessments := []Assessment{}
+	for rows.Next() {
+		var as Assessment
+		if err := rows.Scan(&as.Assessmentid, &as.Sectionid, &as.Name, &as.Weight, &as.Maxscore); err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, as)
+	}
+	return assessments, rows.Err()
+}
+
+// computeSectionGrades computes the current grade of every enrolled student in a section
+func computeSectionGrades(q dbtx, sectionID int) ([]StudentGrade, error) {
+	bands, err := sectionBands(q, sectionID)
+	if err != nil {
+		return nil, err
+	}
+	assessments, err := loadAssessments(q, sectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// scores per student
+	rows, err := q.Query(`SELECT e.student_id , sc.assessment_id , sc.score FROM enrollments e
+		LEFT JOIN assessments a ON a.section_id=e.section_id
+		LEFT JOIN assessment_scores sc ON sc.assessment_id=a.assessment_id AND sc.student_id=e.student_id
+		WHERE e.section_id=? AND e.status IN (? , ? , ?)
+		ORDER BY e.student_id`, sectionID, EnrollmentEnrolled, EnrollmentCompleted, EnrollmentFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var order []int
+	scores := map[int]map[int]float64{}
+	for rows.Next() {
+		var studentID int
+		var assessmentID sql.NullInt64
+		var score sql.NullFloat64
+		if err := rows.Scan(&studentID, &assessmentID, &score); err != nil {
+			return nil, err
+		}
+		if _, ok := scores[studentID]; !ok {
+			scores[studentID] = map[int]float64{}
+			order = append(order, studentID)
+		}
+		if assessmentID.Valid && score.Valid {
+			scores[studentID][int(assessmentID.Int64)] = score.Float64
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	grades := []StudentGrade{}
+	for _, studentID := range order {
+		percent := weightedPercent(assessments, scores[studentID])
+		band := letterFor(bands, percent)
+		grades = append(grades, StudentGrade{Studentid: studentID, Percent: percent, Letter: band.Letter, Gradepoints: band.Gradepoints})
+	}
+	return grades, nil
+}
+
+// writeGradebookError maps gradebook errors to HTTP responses
+func writeGradebookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrTermLocked):
+		http.Error(w, err.Error(), http.StatusLocked)
+	default:
+		writeEnrollmentError(w, err)
+	}
+}
+
+// CreateGradeScaleHandler creates a letter-grade scale, registrar only
+func (a *HybridHandler) CreateGradeScaleHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var scale GradeScale
+	if err := json.NewDecoder(r.Body).Decode(&scale); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// validate input
+	if err := ValidateGradeScale(scale); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	if scale.Isdefault {
+		if _, err := tx.Exec("UPDATE grade_scales SET is_default=FALSE"); err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	res, err := tx.Exec("INSERT INTO grade_scales (name , is_default) VALUES (? , ?)", scale.Name, scale.Isdefault)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, fmt.Sprintf("failed to insert grade scale: %v", err), http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+	scale.Scaleid = int(id)
+	for _, b := range scale.Bands {
+		if _, err := tx.Exec("INSERT INTO grade_scale_bands (scale_id , letter , min_percent , grade_points) VALUES (? , ? , ? , ?)", id, b.Letter, b.Minpercent, b.Gradepoints); err != nil {
+			tx.Rollback()
+			http.Error(w, "failed to insert grade bands", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("CREATE_GRADE_SCALE", currentUser(r))
+	go AuditLog("CREATE", "GRADE_SCALE", scale.Scaleid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scale)
+}
+
+// GetGradeScalesHandler lists grade scales with their bands
+func (a *HybridHandler) GetGradeScalesHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.MySQL.db.Query("SELECT scale_id , name , is_default FROM grade_scales ORDER BY scale_id")
+	if err != nil {
+		http.Error(w, "unable to fetch grade scales", http.StatusInternalServerError)
+		return
+	}
+	scales := []GradeScale{}
+	for rows.Next() {
+		var s GradeScale
+		if err := rows.Scan(&s.Scaleid, &s.Name, &s.Isdefault); err != nil {
+			rows.Close()
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		scales = append(scales, s)
+	}
+	rows.Close()
+
+	for i := range scales {
+		if scales[i].Bands, err = loadScaleBands(a.MySQL.db, scales[i].Scaleid); err != nil {
+			http.Error(w, "unable to fetch grade bands", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scales)
+}
+
+// SetSectionGradeScaleHandler chooses the grade scale of a section
+func (a *HybridHandler) SetSectionGradeScaleHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	var req struct {
+		Scaleid int `json:"scale_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	term, _, err := sectionTerm(a.MySQL.db, sectionID)
+	if err != nil {
+		writeGradebookError(w, err)
+		return
+	}
+	if locked, err := termLocked(a.MySQL.db, term); err != nil || locked {
+		if err == nil {
+			err = ErrTermLocked
+		}
+		writeGradebookError(w, err)
+		return
+	}
+
+	if _, err := a.MySQL.db.Exec("UPDATE sections SET grade_scale_id=? WHERE section_id=?", req.Scaleid, sectionID); err != nil {
+		http.Error(w, "invalid grade scale", http.StatusBadRequest)
+		return
+	}
+
+	go AuditLog("UPDATE", "SECTION_GRADE_SCALE", fmt.Sprintf("section=%d scale=%d", sectionID, req.Scaleid), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"section_id": sectionID, "scale_id": req.Scaleid})
+}
+
+// CreateAssessmentHandler adds a weighted assessment to a section
+func (a *HybridHandler) CreateAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var as Assessment
+	if err := json.NewDecoder(r.Body).Decode(&as); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	as.Sectionid = sectionID
+
+	// validate input
+	if err := ValidateAssessment(as); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	if _, _, err := lockSection(tx, sectionID); err != nil {
+		tx.Rollback()
+		writeGradebookError(w, err)
+		return
+	}
+	term, _, err := sectionTerm(tx, sectionID)
+	if err == nil {
+		var locked bool
+		if locked, err = termLocked(tx, term); err == nil && locked {
+			err = ErrTermLocked
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		writeGradebookError(w, err)
+		return
+	}
+
+	// weights of a section may not exceed 100
+	var weights float64
+	if err := tx.QueryRow("SELECT COALESCE(SUM(weight) , 0) FROM assessments WHERE section_id=?", sectionID).Scan(&weights); err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if weights+as.Weight > 100 {
+		tx.Rollback()
+		http.Error(w, fmt.Sprintf("total weight would be %.2f, it cannot exceed 100", weights+as.Weight), http.StatusBadRequest)
+		return
+	}
+
+	res, err := tx.Exec("INSERT INTO assessments (section_id , name , weight , max_score) VALUES (? , ? , ? , ?)", sectionID, as.Name, as.Weight, as.Maxscore)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "failed to insert assessment", http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+	as.Assessmentid = int(id)
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("CREATE_ASSESSMENT", currentUser(r))
+	go AuditLog("CREATE", "ASSESSMENT", as.Assessmentid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(as)
+}
+
+// GetSectionAssessmentsHandler lists the assessments of a section
+func (a *HybridHandler) GetSectionAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	assessments, err := loadAssessments(a.MySQL.db, sectionID)
+	if err != nil {
+		http.Error(w, "unable to fetch assessments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assessments)
+}
+
+// RecordScoresHandler records marks for an assessment, only lecturers of the section may enter them
+func (a *HybridHandler) RecordScoresHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract assessment id from URL
+	assessmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	var as Assessment
+	err = a.MySQL.db.QueryRow("SELECT assessment_id , section_id , name , weight , max_score FROM assessments WHERE assessment_id=?", assessmentID).
+		Scan(&as.Assessmentid, &as.Sectionid, &as.Name, &as.Weight, &as.Maxscore)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "assessment not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !a.requireSectionStaff(w, r, as.Sectionid) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var entries []ScoreEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	for _, e := range entries {
+		if e.Score < 0 || e.Score > as.Maxscore {
+			http.Error(w, fmt.Sprintf("score for student %d must be between 0 and %.2f", e.Studentid, as.Maxscore), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	term, _, err := sectionTerm(tx, as.Sectionid)
+	if err == nil {
+		var locked bool
+		if locked, err = termLocked(tx, term); err == nil && locked {
+			err = ErrTermLocked
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		writeGradebookError(w, err)
+		return
+	}
+
+	actor := currentUser(r)
+	type change struct {
+		student  int
+		old, new interface{}
+	}
+	var changes []change
+	for _, e := range entries {
+		var enrolled int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM enrollments WHERE section_id=? AND student_id=? AND status=?", as.Sectionid, e.Studentid, EnrollmentEnrolled).Scan(&enrolled); err != nil || enrolled == 0 {
+			tx.Rollback()
+			http.Error(w, fmt.Sprintf("student %d is not enrolled in this section", e.Studentid), http.StatusBadRequest)
+			return
+		}
+		var old sql.NullFloat64
+		err := tx.QueryRow("SELECT score FROM assessment_scores WHERE assessment_id=? AND student_id=? FOR UPDATE", assessmentID, e.Studentid).Scan(&old)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, err = tx.Exec(`INSERT INTO assessment_scores (assessment_id , student_id , score , updated_by , updated_at) VALUES (? , ? , ? , ? , ?)
+			ON DUPLICATE KEY UPDATE score=VALUES(score) , updated_by=VALUES(updated_by) , updated_at=VALUES(updated_at)`, assessmentID, e.Studentid, e.Score, actor, time.Now())
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, "failed to record score", http.StatusInternalServerError)
+			return
+		}
+		var previous interface{} = "none"
+		if old.Valid {
+			previous = old.Float64
+		}
+		changes = append(changes, change{e.Studentid, previous, e.Score})
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Audit every score change
+	go LogActivity("RECORD_SCORES", actor)
+	for _, c := range changes {
+		go AuditLog("UPDATE", "ASSESSMENT_SCORE", fmt.Sprintf("assessment=%d student=%d score=%v->%v", assessmentID, c.student, c.old, c.new), actor)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"assessment_id": assessmentID, "recorded": len(entries)})
+}
+
+// GetSectionGradesHandler returns computed grades for a section, final grades once the term is finalized
+func (a *HybridHandler) GetSectionGradesHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireSectionStaff(w, r, sectionID) {
+		return
+	}
+
+	term, _, err := sectionTerm(a.MySQL.db, sectionID)
+	if err != nil {
+		writeGradebookError(w, err)
+		return
+	}
+	locked, err := termLocked(a.MySQL.db, term)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var grades []StudentGrade
+	if locked {
+		rows, err := a.MySQL.db.Query("SELECT student_id , percent , letter , grade_points FROM final_grades WHERE section_id=? ORDER BY student_id", sectionID)
+		if err != nil {
+			http.Error(w, "unable to fetch grades", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		grades = []StudentGrade{}
+		for rows.Next() {
+			var g StudentGrade
+			if err := rows.Scan(&g.Studentid, &g.Percent, &g.Letter, &g.Gradepoints); err != nil {
+				http.Error(w, "rows scan failed", http.StatusInternalServerError)
+				return
+			}
+			grades = append(grades, g)
+		}
+	} else if grades, err = computeSectionGrades(a.MySQL.db, sectionID); err != nil {
+		writeGradebookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"section_id": sectionID, "term": term, "final": locked, "grades": grades})
+}
+
+// FinalizeTermHandler stores final grades for every section of a term, completes enrollments and locks grades, registrar only
+func (a *HybridHandler) FinalizeTermHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract term from URL
+	term := mux.Vars(r)["term"]
+	actor := currentUser(r)
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// the primary key on term makes finalization happen once
+	now := time.Now()
+	if _, err := tx.Exec("INSERT INTO term_locks (term , finalized_at , finalized_by) VALUES (? , ? , ?)", term, now, actor); err != nil {
+		tx.Rollback()
+		http.Error(w, "term is already finalized", http.StatusConflict)
+		return
+	}
+
+	rows, err := tx.Query("SELECT section_id FROM sections WHERE term=? ORDER BY section_id FOR UPDATE", term)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var sections []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sections = append(sections, id)
+	}
+	rows.Close()
+
+	finalized := 0
+	for _, sectionID := range sections {
+		_, credits, err := sectionTerm(tx, sectionID)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		grades, err := computeSectionGrades(tx, sectionID)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, g := range grades {
+			if _, err := tx.Exec("INSERT INTO final_grades (section_id , student_id , percent , letter , grade_points , credits , finalized_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+				sectionID, g.Studentid, g.Percent, g.Letter, g.Gradepoints, credits, now); err != nil {
+				tx.Rollback()
+				http.Error(w, "failed to store final grades", http.StatusInternalServerError)
+				return
+			}
+
+			// passing grades complete the course for prerequisite checks
+			status := EnrollmentCompleted
+			if g.Gradepoints == 0 {
+				status = EnrollmentFailed
+			}
+			if _, err := tx.Exec("UPDATE enrollments SET status=? , updated_at=? WHERE section_id=? AND student_id=? AND status=?", status, now, sectionID, g.Studentid, EnrollmentEnrolled); err != nil {
+				tx.Rollback()
+				http.Error(w, "failed to update enrollments", http.StatusInternalServerError)
+				return
+			}
+			finalized++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("FINALIZE_TERM", actor)
+	go AuditLog("FINALIZE", "TERM", term, actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"term": term, "sections": len(sections), "grades": finalized, "locked": true})
+}
+
+// studentGPA computes per-term and cumulative GPA from final grades
+func studentGPA(q dbtx, studentID int) (*StudentGPA, error) {
+	rows, err := q.Query(`SELECT s.term , fg.grade_points , fg.credits FROM final_grades fg
+		JOIN sections s ON s.section_id=fg.section_id
+		JOIN term_locks tl ON tl.term=s.term
+		WHERE fg.student_id=? ORDER BY tl.finalized_at , s.term`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &StudentGPA{Studentid: studentID, Terms: []TermGPA{}}
+	var allPoints []float64
+	var allCredits []int
+	termPoints := map[string][]float64{}
+	termCredits := map[string][]int{}
+	var terms []string
+	for rows.Next() {
+		var term string
+		var points float64
+		var credits int
+		if err := rows.Scan(&term, &points, &credits); err != nil {
+			return nil, err
+		}
+		if _, ok := termPoints[term]; !ok {
+			terms = append(terms, term)
+		}
+		termPoints[term] = append(termPoints[term], points)
+		termCredits[term] = append(termCredits[term], credits)
+		allPoints = append(allPoints, points)
+		allCredits = append(allCredits, credits)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, term := range terms {
+		gpa, credits := gradePointAverage(termPoints[term], termCredits[term])
+		result.Terms = append(result.Terms, TermGPA{Term: term, Credits: credits, GPA: gpa})
+	}
+	result.Cumulative, result.Credits = gradePointAverage(allPoints, allCredits)
+	return result, nil
+}
+
+// GetStudentGPAHandler returns a student's per-term and cumulative GPA to the student or a registrar
+func (a *HybridHandler) GetStudentGPAHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, studentID) {
+		return
+	}
+
+	gpa, err := studentGPA(a.MySQL.db, studentID)
+	if err != nil {
+		http.Error(w, "unable to compute gpa", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gpa)
+}
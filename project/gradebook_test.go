@@ -0,0 +1,12 @@
+package project
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStudentGPARequiresStudentOrRegistrar(t *testing.T) {
+	a := newMemoryHandler()
+	w := serve(t, a.GetStudentGPAHandler, "GET", "/students/1/gpa", map[string]string{"id": "1"}, "", nil)
+	wantStatus(t, w, http.StatusForbidden)
+}
@@ -0,0 +1,170 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// status of a hold. A waiting hold queues for the next copy of its book, a ready hold has a copy
+// set aside until pickup_by, the others are closed.
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldExpired   = "expired"
+	HoldCancelled = "cancelled"
+)
+
+// Hold is a reader's place in the queue of a book whose copies are all out.
+// Position counts from 1 among the waiting holds of the book, Barcode is the copy set aside for a ready hold.
+type Hold struct {
+	Holdid    int        `json:"hold_id"`
+	Bookid    int        `json:"book_id"`
+	Userid    int        `json:"user_id"`
+	Usertype  string     `json:"usertype"`
+	Status    string     `json:"status"`
+	Position  int        `json:"position,omitempty"`
+	Barcode   string     `json:"barcode,omitempty"`
+	Createdat time.Time  `json:"created_at"`
+	Readyat   *time.Time `json:"ready_at,omitempty"`
+	Pickupby  *time.Time `json:"pickup_by,omitempty"`
+	Closedat  *time.Time `json:"closed_at,omitempty"`
+}
+
+// activeHold reports whether a hold still waits for or keeps a copy
+func activeHold(status string) bool {
+	return status == HoldWaiting || status == HoldReady
+}
+
+// writeHoldError maps hold errors to HTTP responses
+func writeHoldError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrHoldNotFound), errors.Is(err, ErrBookNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrHoldExists), errors.Is(err, ErrCopiesAvailable), errors.Is(err, ErrHoldClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrNoLoanPolicy):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// PlaceHoldHandler queues a reader for a book that has no copy available
+func (a *HybridHandler) PlaceHoldHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Decode incoming JSON request body
+	var hold Hold
+	if err := json.NewDecoder(r.Body).Decode(&hold); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	// validate
+	if hold.Usertype != "student" && hold.Usertype != "lecturer" {
+		http.Error(w, "Invalid user type , user must be student or lecturer", http.StatusBadRequest)
+		return
+	}
+	if hold.Userid <= 0 || hold.Bookid <= 0 {
+		http.Error(w, "user_id and book_id are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Libraries.PlaceHold(r.Context(), &hold); err != nil {
+		writeHoldError(w, err, "failed to place hold")
+		return
+	}
+
+	go LogActivity("PLACE_HOLD", "system")
+	go AuditLog("CREATE", "HOLD", hold.Holdid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// GetHoldHandler returns a hold with its queue position or the copy waiting for pickup
+func (a *HybridHandler) GetHoldHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := a.Libraries.GetHold(r.Context(), id)
+	if err != nil {
+		writeHoldError(w, err, "failed to fetch hold")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// CancelHoldHandler cancels a waiting or ready hold, a copy set aside for it goes to the next hold or back on the shelf
+func (a *HybridHandler) CancelHoldHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := a.Libraries.CancelHold(r.Context(), id)
+	if err != nil {
+		writeHoldError(w, err, "failed to cancel hold")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("CANCEL_HOLD", "system")
+	go AuditLog("CANCEL", "HOLD", hold.Holdid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// RunLibraryJobs expires unclaimed holds and charges overdue fines at startup and then every night shortly after midnight,
+// until ctx is done. Both jobs only act on what changed since their last run, so a missed or repeated run is harmless.
+func (a *HybridHandler) RunLibraryJobs(ctx context.Context) {
+	for {
+		expired, err := a.Libraries.ExpireHolds(ctx)
+		if err != nil {
+			log.Println("hold expiry failed:", err)
+		} else {
+			log.Printf("hold expiry closed %d unclaimed holds\n", expired)
+		}
+		if expired > 0 {
+			a.invalidateLibraries()
+		}
+
+		charged, err := a.Libraries.AssessOverdue(ctx)
+		if err != nil {
+			log.Println("fine assessment failed:", err)
+		} else {
+			log.Printf("fine assessment charged %d overdue loans\n", charged)
+		}
+
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 5, 0, 0, now.Location())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+	}
+}
+
+// holdPickupError explains why a borrow of a copy set aside for another reader was refused
+func holdPickupError(c Copy) error {
+	return fmt.Errorf("%w: copy %s is held for another reader", ErrCopyUnavailable, c.Barcode)
+}
@@ -0,0 +1,281 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// instructor roles
+const (
+	InstructorPrimary   = "primary"
+	InstructorAssistant = "assistant"
+)
+
+// SectionInstructor links a MySQL section to a lecturer by hex ObjectID
+type SectionInstructor struct {
+	Sectionid  int        `json:"section_id"`
+	Lecturerid string     `json:"lecturer_id"`
+	Role       string     `json:"role"`
+	Assignedat *time.Time `json:"assigned_at,omitempty"`
+}
+
+// LecturerSection is a section taught by a lecturer along with course details
+type LecturerSection struct {
+	Sectionid int    `json:"section_id"`
+	Courseid  int    `json:"course_id"`
+	Code      string `json:"code"`
+	Title     string `json:"title"`
+	Credits   int    `json:"credits"`
+	Term      string `json:"term"`
+	Role      string `json:"role"`
+}
+
+// ValidateSectionInstructor validates an instructor assignment before DB operations
+func ValidateSectionInstructor(si SectionInstructor) error {
+	if len(si.Lecturerid) != 24 {
+		return fmt.Errorf("lecturer_id must be a 24 character hex ObjectID")
+	}
+	if si.Role != InstructorPrimary && si.Role != InstructorAssistant {
+		return fmt.Errorf("role must be primary or assistant")
+	}
+	return nil
+}
+
+// writeLecturerLookupError maps findLecturer errors to HTTP responses
+func writeLecturerLookupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidLecturerID):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrLecturerNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// AssignInstructorHandler assigns a lecturer to a section as primary or assistant instructor
+func (a *HybridHandler) AssignInstructorHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var si SectionInstructor
+	if err := json.NewDecoder(r.Body).Decode(&si); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	si.Sectionid = sectionID
+
+	// validate input
+	if err := ValidateSectionInstructor(si); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	// Lecturer must exist in the lecturer store at assignment time
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	if _, err := a.findLecturer(ctx, si.Lecturerid); err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+
+	// Begin transaction, the section lock keeps a single primary instructor
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	if _, _, err := lockSection(tx, sectionID); err != nil {
+		tx.Rollback()
+		writeEnrollmentError(w, err)
+		return
+	}
+	if si.Role == InstructorPrimary {
+		var current string
+		err := tx.QueryRow("SELECT lecturer_id FROM section_instructors WHERE section_id=? AND role=?", sectionID, InstructorPrimary).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err == nil && current != si.Lecturerid {
+			tx.Rollback()
+			http.Error(w, "section already has a primary instructor", http.StatusConflict)
+			return
+		}
+	}
+
+	// reject timetable clashes with the lecturer's other sections
+	clashes, err := sectionClashes(tx, "lecturer", si.Lecturerid, sectionID, "SELECT section_id FROM section_instructors WHERE lecturer_id=?", si.Lecturerid)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(clashes) > 0 {
+		tx.Rollback()
+		writeEnrollmentError(w, &ScheduleConflictError{Conflicts: clashes})
+		return
+	}
+
+	now := time.Now()
+	si.Assignedat = &now
+	_, err = tx.Exec("INSERT INTO section_instructors (section_id , lecturer_id , role , assigned_at) VALUES (? , ? , ? , ?) ON DUPLICATE KEY UPDATE role=VALUES(role)", sectionID, si.Lecturerid, si.Role, now)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "failed to assign instructor", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("ASSIGN_INSTRUCTOR", "system")
+	go AuditLog("ASSIGN", "SECTION_INSTRUCTOR", fmt.Sprintf("section=%d lecturer=%s role=%s", sectionID, si.Lecturerid, si.Role), "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(si)
+}
+
+// RemoveInstructorHandler removes a lecturer from a section
+func (a *HybridHandler) RemoveInstructorHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	sectionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	lecturerID := vars["lecturerId"]
+
+	res, err := a.MySQL.db.Exec("DELETE FROM section_instructors WHERE section_id=? AND lecturer_id=?", sectionID, lecturerID)
+	if err != nil {
+		http.Error(w, "unable to delete", http.StatusInternalServerError)
+		return
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "instructor assignment not found", http.StatusNotFound)
+		return
+	}
+
+	go LogActivity("REMOVE_INSTRUCTOR", "system")
+	go AuditLog("REMOVE", "SECTION_INSTRUCTOR", fmt.Sprintf("section=%d lecturer=%s", sectionID, lecturerID), "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("instructor removed!"))
+}
+
+// GetSectionInstructorsHandler lists the instructors of a section, primary first
+func (a *HybridHandler) GetSectionInstructorsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT section_id , lecturer_id , role , assigned_at FROM section_instructors WHERE section_id=? ORDER BY role DESC , assigned_at", sectionID)
+	if err != nil {
+		http.Error(w, "unable to fetch instructors", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	instructors := []SectionInstructor{}
+	for rows.Next() {
+		var si SectionInstructor
+		var assignedAt time.Time
+		if err := rows.Scan(&si.Sectionid, &si.Lecturerid, &si.Role, &assignedAt); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		si.Assignedat = &assignedAt
+		instructors = append(instructors, si)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instructors)
+}
+
+// lecturerSections returns the sections a lecturer teaches, optionally limited to one term
+func (a *HybridHandler) lecturerSections(lecturerID, term string) ([]LecturerSection, error) {
+	query := `SELECT s.section_id , s.course_id , c.code , c.title , c.credits , s.term , si.role
+		FROM section_instructors si
+		JOIN sections s ON s.section_id=si.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		WHERE si.lecturer_id=?`
+	args := []interface{}{lecturerID}
+	if term != "" {
+		query += " AND s.term=?"
+		args = append(args, term)
+	}
+	query += " ORDER BY s.term , c.code"
+
+	rows, err := a.MySQL.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []LecturerSection{}
+	for rows.Next() {
+		var ls LecturerSection
+		if err := rows.Scan(&ls.Sectionid, &ls.Courseid, &ls.Code, &ls.Title, &ls.Credits, &ls.Term, &ls.Role); err != nil {
+			return nil, err
+		}
+		sections = append(sections, ls)
+	}
+	return sections, rows.Err()
+}
+
+// GetLecturerSectionsHandler returns a lecturer from the lecturer store together with the sections they teach from MySQL
+func (a *HybridHandler) GetLecturerSectionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id := mux.Vars(r)["id"]
+
+	// Fetch lecturer from the lecturer store
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, id)
+	if err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+
+	// Fetch sections from MySQL
+	sections, err := a.lecturerSections(id, r.URL.Query().Get("term"))
+	if err != nil {
+		http.Error(w, "unable to fetch sections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"lecturer": lecturer, "sections": sections})
+}
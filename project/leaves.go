@@ -0,0 +1,1025 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// leave request statuses
+const (
+	LeavePending   = "pending"
+	LeaveApproved  = "approved"
+	LeaveRejected  = "rejected"
+	LeaveCancelled = "cancelled"
+)
+
+// LeaveType is a kind of leave with its default yearly entitlement
+type LeaveType struct {
+	Leavetype  string `json:"leave_type"`
+	Name       string `json:"name"`
+	Annualdays int    `json:"annual_days"`
+	Paid       bool   `json:"paid"`
+}
+
+// LeaveBalance is a lecturer's entitlement and usage of one leave type for a year.
+// Pending requests are held against the balance until they are decided.
+type LeaveBalance struct {
+	Leavetype string `json:"leave_type"`
+	Year      int    `json:"year"`
+	Entitled  int    `json:"entitled_days"`
+	Used      int    `json:"used_days"`
+	Pending   int    `json:"pending_days"`
+	Available int    `json:"available_days"`
+}
+
+// LeaveRequest is a lecturer's request for leave over an inclusive date range
+type LeaveRequest struct {
+	Leaveid      int             `json:"leave_id"`
+	Lecturerid   string          `json:"lecturer_id"`
+	Leavetype    string          `json:"leave_type"`
+	Startdate    string          `json:"start_date"`
+	Enddate      string          `json:"end_date"`
+	Days         int             `json:"days"`
+	Reason       string          `json:"reason,omitempty"`
+	Status       string          `json:"status"`
+	Requestedby  string          `json:"requested_by"`
+	Decidedby    string          `json:"decided_by,omitempty"`
+	Decidedat    *time.Time      `json:"decided_at,omitempty"`
+	Decisionnote string          `json:"decision_note,omitempty"`
+	Createdat    time.Time       `json:"created_at"`
+	Affected     []AffectedClass `json:"affected_classes,omitempty"`
+}
+
+// AffectedClass is a class the lecturer on leave would have taught. Classes on dates without
+// generated sessions come from the weekly meeting and carry its meeting id instead of a session id.
+type AffectedClass struct {
+	Sessionid   int                    `json:"session_id,omitempty"`
+	Meetingid   int                    `json:"meeting_id,omitempty"`
+	Sectionid   int                    `json:"section_id"`
+	Courseid    int                    `json:"course_id"`
+	Code        string                 `json:"code"`
+	Date        string                 `json:"date"`
+	Starttime   string                 `json:"start_time"`
+	Endtime     string                 `json:"end_time"`
+	Room        string                 `json:"room"`
+	Substitutes []SubstituteSuggestion `json:"substitutes"`
+}
+
+// SubstituteSuggestion is a free lecturer of the same department who could take a class
+type SubstituteSuggestion struct {
+	Lecturerid   string `json:"lecturer_id"`
+	Name         string `json:"name"`
+	Taughtcourse bool   `json:"taught_course"`
+	Classesonday int    `json:"classes_that_day"`
+}
+
+// InsufficientLeaveError is returned when a request exceeds the available balance
+type InsufficientLeaveError struct {
+	Leavetype            string
+	Requested, Available int
+}
+
+func (e *InsufficientLeaveError) Error() string {
+	return fmt.Sprintf("requested %d days of %s leave but only %d are available", e.Requested, e.Leavetype, e.Available)
+}
+
+// errors returned by the leave helpers
+var (
+	ErrLeaveNotFound    = errors.New("leave request not found")
+	ErrUnknownLeaveType = errors.New("unknown leave type")
+	ErrLeaveOverlap     = errors.New("leave overlaps an existing request")
+	ErrLeaveDecided     = errors.New("leave request has already been decided")
+	ErrLeaveStarted     = errors.New("leave has already started")
+	ErrOwnLeave         = errors.New("cannot decide your own leave request")
+	ErrOtherDepartment  = errors.New("leave request belongs to another department")
+)
+
+// maxSubstitutes is the number of suggestions returned per affected class
+const maxSubstitutes = 3
+
+// parseLeaveDates validates an inclusive leave range, leave may not cross a calendar year
+func parseLeaveDates(start, end string) (time.Time, time.Time, error) {
+	from, err1 := time.Parse("2006-01-02", start)
+	to, err2 := time.Parse("2006-01-02", end)
+	if err1 != nil || err2 != nil {
+		return from, to, fmt.Errorf("start_date and end_date must be YYYY-MM-DD dates")
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("end_date cannot be before start_date")
+	}
+	if to.Year() != from.Year() {
+		return from, to, fmt.Errorf("leave cannot cross a calendar year, split it into two requests")
+	}
+	return from, to, nil
+}
+
+// leaveDays counts the calendar days of an inclusive range
+func leaveDays(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24) + 1
+}
+
+// leaveBalances returns a lecturer's balances for every leave type in a year.
+// A leave_balances row overrides the type's annual_days for that lecturer.
+func leaveBalances(q dbtx, lecturerID string, year int) ([]LeaveBalance, error) {
+	rows, err := q.Query(`SELECT lt.leave_type , COALESCE(lb.entitled_days , lt.annual_days) ,
+		COALESCE(SUM(CASE WHEN lr.status=? THEN lr.days END) , 0) ,
+		COALESCE(SUM(CASE WHEN lr.status=? THEN lr.days END) , 0)
+		FROM leave_types lt
+		LEFT JOIN leave_balances lb ON lb.leave_type=lt.leave_type AND lb.lecturer_id=? AND lb.year=?
+		LEFT JOIN leave_requests lr ON lr.leave_type=lt.leave_type AND lr.lecturer_id=? AND YEAR(lr.start_date)=?
+		GROUP BY lt.leave_type , lb.entitled_days , lt.annual_days ORDER BY lt.leave_type`,
+		LeaveApproved, LeavePending, lecturerID, year, lecturerID, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []LeaveBalance{}
+	for rows.Next() {
+		b := LeaveBalance{Year: year}
+		if err := rows.Scan(&b.Leavetype, &b.Entitled, &b.Used, &b.Pending); err != nil {
+			return nil, err
+		}
+		b.Available = b.Entitled - b.Used - b.Pending
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// leaveColumns is the column list scanned by scanLeave
+const leaveColumns = "leave_id , lecturer_id , leave_type , start_date , end_date , days , reason , status , requested_by , decided_by , decided_at , decision_note , created_at"
+
+// scanLeave reads one leave request row selected with leaveColumns
+func scanLeave(row interface{ Scan(...interface{}) error }) (*LeaveRequest, error) {
+	var l LeaveRequest
+	var start, end time.Time
+	var reason, decidedBy, note sql.NullString
+	var decidedAt sql.NullTime
+	if err := row.Scan(&l.Leaveid, &l.Lecturerid, &l.Leavetype, &start, &end, &l.Days, &reason, &l.Status, &l.Requestedby, &decidedBy, &decidedAt, &note, &l.Createdat); err != nil {
+		return nil, err
+	}
+	l.Startdate, l.Enddate = start.Format("2006-01-02"), end.Format("2006-01-02")
+	l.Reason, l.Decidedby, l.Decisionnote = reason.String, decidedBy.String, note.String
+	if decidedAt.Valid {
+		l.Decidedat = &decidedAt.Time
+	}
+	return &l, nil
+}
+
+// loadLeave fetches a leave request by id
+func loadLeave(q dbtx, id int) (*LeaveRequest, error) {
+	l, err := scanLeave(q.QueryRow("SELECT "+leaveColumns+" FROM leave_requests WHERE leave_id=?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrLeaveNotFound
+	}
+	return l, err
+}
+
+// scanLeaves reads every leave request row selected with leaveColumns
+func scanLeaves(rows *sql.Rows) ([]LeaveRequest, error) {
+	defer rows.Close()
+	leaves := []LeaveRequest{}
+	for rows.Next() {
+		l, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *l)
+	}
+	return leaves, rows.Err()
+}
+
+// weeklyClass is a weekly meeting of a section a lecturer teaches, before it is laid out on dates
+type weeklyClass struct {
+	AffectedClass
+	Lecturerid string
+	Day        int
+}
+
+// expandMeetings lays weekly meetings out on the dates between from and to and merges them with the
+// generated sessions. A session replaces the meeting occurrence of the same section, date and start time,
+// since it carries the real room and session id. The result is ordered by date and start time.
+func expandMeetings(sessions []AffectedClass, meetings []weeklyClass, from, to time.Time) []AffectedClass {
+	type slot struct {
+		section     int
+		date, start string
+	}
+	classes := append([]AffectedClass{}, sessions...)
+	generated := map[slot]bool{}
+	for _, c := range sessions {
+		generated[slot{c.Sectionid, c.Date, c.Starttime}] = true
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, m := range meetings {
+			if m.Day%7 != int(day.Weekday()) {
+				continue
+			}
+			c := m.AffectedClass
+			c.Date = day.Format("2006-01-02")
+			if generated[slot{c.Sectionid, c.Date, c.Starttime}] {
+				continue
+			}
+			c.Substitutes = []SubstituteSuggestion{}
+			classes = append(classes, c)
+		}
+	}
+	sort.SliceStable(classes, func(i, j int) bool {
+		if classes[i].Date != classes[j].Date {
+			return classes[i].Date < classes[j].Date
+		}
+		return classes[i].Starttime < classes[j].Starttime
+	})
+	return classes
+}
+
+// lecturerClasses lists the classes each lecturer teaches between two dates. Generated class sessions are
+// used where they exist, and the weekly meetings of sections in terms not yet finalized cover the dates
+// no sessions have been generated for.
+func lecturerClasses(q dbtx, lecturerIDs []string, from, to string) (map[string][]AffectedClass, error) {
+	classes := map[string][]AffectedClass{}
+	if len(lecturerIDs) == 0 {
+		return classes, nil
+	}
+	start, err1 := time.Parse("2006-01-02", from)
+	end, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("dates must be YYYY-MM-DD")
+	}
+	ids := make([]interface{}, len(lecturerIDs))
+	for i, id := range lecturerIDs {
+		ids[i] = id
+	}
+	in := "(?" + strings.Repeat(" , ?", len(ids)-1) + ")"
+
+	// generated sessions
+	sessions := map[string][]AffectedClass{}
+	rows, err := q.Query(`SELECT si.lecturer_id , cs.session_id , cs.section_id , s.course_id , c.code , cs.session_date , cs.start_time , cs.end_time , rm.name
+		FROM class_sessions cs
+		JOIN section_instructors si ON si.section_id=cs.section_id
+		JOIN sections s ON s.section_id=cs.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		JOIN rooms rm ON rm.room_id=cs.room_id
+		WHERE cs.session_date BETWEEN ? AND ? AND si.lecturer_id IN `+in,
+		append([]interface{}{from, to}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		var c AffectedClass
+		var date time.Time
+		if err := rows.Scan(&id, &c.Sessionid, &c.Sectionid, &c.Courseid, &c.Code, &date, &c.Starttime, &c.Endtime, &c.Room); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Date = date.Format("2006-01-02")
+		c.Starttime, c.Endtime = trimClock(c.Starttime), trimClock(c.Endtime)
+		c.Substitutes = []SubstituteSuggestion{}
+		sessions[id] = append(sessions[id], c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// weekly meetings of sections whose term is still running
+	meetings := map[string][]weeklyClass{}
+	rows, err = q.Query(`SELECT si.lecturer_id , m.meeting_id , m.section_id , s.course_id , c.code , m.day_of_week , m.start_time , m.end_time , rm.name
+		FROM section_meetings m
+		JOIN section_instructors si ON si.section_id=m.section_id
+		JOIN sections s ON s.section_id=m.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		JOIN rooms rm ON rm.room_id=m.room_id
+		WHERE s.term NOT IN (SELECT term FROM term_locks) AND si.lecturer_id IN `+in, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m weeklyClass
+		if err := rows.Scan(&m.Lecturerid, &m.Meetingid, &m.Sectionid, &m.Courseid, &m.Code, &m.Day, &m.Starttime, &m.Endtime, &m.Room); err != nil {
+			return nil, err
+		}
+		m.Starttime, m.Endtime = trimClock(m.Starttime), trimClock(m.Endtime)
+		meetings[m.Lecturerid] = append(meetings[m.Lecturerid], m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range lecturerIDs {
+		if len(sessions[id]) > 0 || len(meetings[id]) > 0 {
+			classes[id] = expandMeetings(sessions[id], meetings[id], start, end)
+		}
+	}
+	return classes, nil
+}
+
+// affectedClasses lists the classes a lecturer teaches between two dates
+func affectedClasses(q dbtx, lecturerID, from, to string) ([]AffectedClass, error) {
+	classes, err := lecturerClasses(q, []string{lecturerID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if classes[lecturerID] == nil {
+		return []AffectedClass{}, nil
+	}
+	return classes[lecturerID], nil
+}
+
+// suggestSubstitutes fills in substitutes for each affected class from the lecturer's department.
+// A candidate must not be teaching at an overlapping time that day or be on pending or approved leave;
+// lecturers who have taught the course come first, then those with fewer classes that day.
+func (a *HybridHandler) suggestSubstitutes(ctx context.Context, lecturer *Lecturer, from, to string, classes []AffectedClass) error {
+	if lecturer.Department == "" || len(classes) == 0 {
+		return nil
+	}
+	staff, err := a.Lecturers.FindAll(ctx, lecturer.Department)
+	if err != nil {
+		return err
+	}
+	var candidates []Lecturer
+	for _, c := range staff {
+		if c.Id != lecturer.Id {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	candidateIDs := make([]string, len(candidates))
+	ids := make([]interface{}, len(candidates))
+	for i, c := range candidates {
+		candidateIDs[i] = c.Id.Hex()
+		ids[i] = c.Id.Hex()
+	}
+	in := "(?" + strings.Repeat(" , ?", len(ids)-1) + ")"
+
+	// classes the candidates teach in the range
+	teaching, err := lecturerClasses(a.MySQL.db, candidateIDs, from, to)
+	if err != nil {
+		return err
+	}
+
+	// candidates' own leave in the range
+	type dateRange struct{ from, to string }
+	away := map[string][]dateRange{}
+	args := append([]interface{}{LeavePending, LeaveApproved, to, from}, ids...)
+	rows, err := a.MySQL.db.Query("SELECT lecturer_id , start_date , end_date FROM leave_requests WHERE status IN (? , ?) AND start_date<=? AND end_date>=? AND lecturer_id IN "+in, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		var start, end time.Time
+		if err := rows.Scan(&id, &start, &end); err != nil {
+			rows.Close()
+			return err
+		}
+		away[id] = append(away[id], dateRange{start.Format("2006-01-02"), end.Format("2006-01-02")})
+	}
+	rows.Close()
+
+	// courses each candidate has taught
+	taught := map[string]map[int]bool{}
+	rows, err = a.MySQL.db.Query("SELECT DISTINCT si.lecturer_id , s.course_id FROM section_instructors si JOIN sections s ON s.section_id=si.section_id WHERE si.lecturer_id IN "+in, ids...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		var course int
+		if err := rows.Scan(&id, &course); err != nil {
+			rows.Close()
+			return err
+		}
+		if taught[id] == nil {
+			taught[id] = map[int]bool{}
+		}
+		taught[id][course] = true
+	}
+	rows.Close()
+
+	for i := range classes {
+		c := &classes[i]
+		var suggestions []SubstituteSuggestion
+	candidates:
+		for _, l := range candidates {
+			id := l.Id.Hex()
+			for _, d := range away[id] {
+				if d.from <= c.Date && c.Date <= d.to {
+					continue candidates
+				}
+			}
+			sameDay := 0
+			for _, s := range teaching[id] {
+				if s.Date != c.Date {
+					continue
+				}
+				if s.Starttime < c.Endtime && c.Starttime < s.Endtime {
+					continue candidates
+				}
+				sameDay++
+			}
+			suggestions = append(suggestions, SubstituteSuggestion{Lecturerid: id, Name: l.Name, Taughtcourse: taught[id][c.Courseid], Classesonday: sameDay})
+		}
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			si, sj := suggestions[i], suggestions[j]
+			if si.Taughtcourse != sj.Taughtcourse {
+				return si.Taughtcourse
+			}
+			if si.Classesonday != sj.Classesonday {
+				return si.Classesonday < sj.Classesonday
+			}
+			return si.Name < sj.Name
+		})
+		if len(suggestions) > maxSubstitutes {
+			suggestions = suggestions[:maxSubstitutes]
+		}
+		if suggestions != nil {
+			c.Substitutes = suggestions
+		}
+	}
+	return nil
+}
+
+// leaveImpact loads the affected classes of a leave request with substitute suggestions
+func (a *HybridHandler) leaveImpact(ctx context.Context, l *LeaveRequest) error {
+	lecturer, err := a.findLecturer(ctx, l.Lecturerid)
+	if err != nil {
+		return err
+	}
+	if l.Affected, err = affectedClasses(a.MySQL.db, l.Lecturerid, l.Startdate, l.Enddate); err != nil {
+		return err
+	}
+	return a.suggestSubstitutes(ctx, lecturer, l.Startdate, l.Enddate, l.Affected)
+}
+
+// requireLecturerOrHR writes a 403 and returns false unless the current user is the lecturer or holds the hr role
+func (a *HybridHandler) requireLecturerOrHR(w http.ResponseWriter, r *http.Request, lecturer *Lecturer) bool {
+	if email := currentUser(r); email != "" && strings.EqualFold(email, lecturer.Email) {
+		return true
+	}
+	return a.requireRole(w, r, RoleHR)
+}
+
+// writeLeaveError maps leave errors to HTTP responses
+func writeLeaveError(w http.ResponseWriter, err error) {
+	var insufficient *InsufficientLeaveError
+	switch {
+	case errors.Is(err, ErrLeaveNotFound), errors.Is(err, ErrUnknownLeaveType):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrLeaveOverlap), errors.Is(err, ErrLeaveDecided), errors.Is(err, ErrLeaveStarted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrOwnLeave), errors.Is(err, ErrOtherDepartment):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &insufficient):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrLecturerNotFound), errors.Is(err, ErrInvalidLecturerID):
+		writeLecturerLookupError(w, err)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetLeaveTypesHandler lists the leave types
+func (a *HybridHandler) GetLeaveTypesHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.MySQL.db.Query("SELECT leave_type , name , annual_days , paid FROM leave_types ORDER BY leave_type")
+	if err != nil {
+		http.Error(w, "unable to fetch leave types", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	types := []LeaveType{}
+	for rows.Next() {
+		var t LeaveType
+		if err := rows.Scan(&t.Leavetype, &t.Name, &t.Annualdays, &t.Paid); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		types = append(types, t)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types)
+}
+
+// SaveLeaveTypeHandler creates or updates a leave type, hr only
+func (a *HybridHandler) SaveLeaveTypeHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var t LeaveType
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	t.Leavetype = strings.ToLower(strings.TrimSpace(t.Leavetype))
+	if t.Leavetype == "" || strings.TrimSpace(t.Name) == "" || t.Annualdays < 0 || t.Annualdays > 366 {
+		http.Error(w, "leave_type and name are required and annual_days must be between 0 and 366", http.StatusBadRequest)
+		return
+	}
+
+	_, err := a.MySQL.db.Exec(`INSERT INTO leave_types (leave_type , name , annual_days , paid) VALUES (? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE name=VALUES(name) , annual_days=VALUES(annual_days) , paid=VALUES(paid)`, t.Leavetype, t.Name, t.Annualdays, t.Paid)
+	if err != nil {
+		http.Error(w, "failed to save leave type", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("SAVE_LEAVE_TYPE", currentUser(r))
+	go AuditLog("SAVE", "LEAVE_TYPE", t.Leavetype, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// GetLeaveBalancesHandler returns a lecturer's leave balances for ?year= (default this year)
+func (a *HybridHandler) GetLeaveBalancesHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract lecturer id from URL
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+	if !a.requireLecturerOrHR(w, r, lecturer) {
+		return
+	}
+
+	year := time.Now().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		if year, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+	balances, err := leaveBalances(a.MySQL.db, lecturer.Id.Hex(), year)
+	if err != nil {
+		http.Error(w, "unable to fetch leave balances", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balances)
+}
+
+// SetLeaveBalanceHandler overrides a lecturer's entitlement of one leave type for a year, hr only
+func (a *HybridHandler) SetLeaveBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Extract lecturer id from URL
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Leavetype    string `json:"leave_type"`
+		Year         int    `json:"year"`
+		Entitleddays int    `json:"entitled_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Year < 2000 || req.Entitleddays < 0 || req.Entitleddays > 366 {
+		http.Error(w, "year is required and entitled_days must be between 0 and 366", http.StatusBadRequest)
+		return
+	}
+
+	var exists int
+	if err := a.MySQL.db.QueryRow("SELECT COUNT(*) FROM leave_types WHERE leave_type=?", req.Leavetype).Scan(&exists); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if exists == 0 {
+		writeLeaveError(w, ErrUnknownLeaveType)
+		return
+	}
+	_, err = a.MySQL.db.Exec(`INSERT INTO leave_balances (lecturer_id , leave_type , year , entitled_days) VALUES (? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE entitled_days=VALUES(entitled_days)`, lecturer.Id.Hex(), req.Leavetype, req.Year, req.Entitleddays)
+	if err != nil {
+		http.Error(w, "failed to save leave balance", http.StatusInternalServerError)
+		return
+	}
+	balances, err := leaveBalances(a.MySQL.db, lecturer.Id.Hex(), req.Year)
+	if err != nil {
+		http.Error(w, "unable to fetch leave balances", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("SET_LEAVE_BALANCE", currentUser(r))
+	go AuditLog("SET", "LEAVE_BALANCE", fmt.Sprintf("%s %s %d=%d", lecturer.Id.Hex(), req.Leavetype, req.Year, req.Entitleddays), currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balances)
+}
+
+// RequestLeaveHandler files a leave request for a lecturer, by the lecturer or hr.
+// The response lists the classes the leave would affect with substitute suggestions.
+func (a *HybridHandler) RequestLeaveHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract lecturer id from URL
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+	if !a.requireLecturerOrHR(w, r, lecturer) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var leave LeaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&leave); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	from, to, err := parseLeaveDates(leave.Startdate, leave.Enddate)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	leave.Lecturerid = lecturer.Id.Hex()
+	leave.Days = leaveDays(from, to)
+	leave.Status = LeavePending
+	leave.Requestedby = currentUser(r)
+	leave.Createdat = time.Now()
+
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// lock the lecturer's requests so concurrent requests cannot both pass the balance check
+		locked, err := tx.Query("SELECT leave_id FROM leave_requests WHERE lecturer_id=? FOR UPDATE", leave.Lecturerid)
+		if err != nil {
+			return err
+		}
+		locked.Close()
+		var overlapping int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM leave_requests WHERE lecturer_id=? AND status IN (? , ?) AND start_date<=? AND end_date>=?",
+			leave.Lecturerid, LeavePending, LeaveApproved, leave.Enddate, leave.Startdate).Scan(&overlapping); err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrLeaveOverlap
+		}
+
+		balances, err := leaveBalances(tx, leave.Lecturerid, from.Year())
+		if err != nil {
+			return err
+		}
+		var balance *LeaveBalance
+		for i := range balances {
+			if balances[i].Leavetype == leave.Leavetype {
+				balance = &balances[i]
+			}
+		}
+		if balance == nil {
+			return ErrUnknownLeaveType
+		}
+		if leave.Days > balance.Available {
+			return &InsufficientLeaveError{Leavetype: leave.Leavetype, Requested: leave.Days, Available: balance.Available}
+		}
+
+		res, err := tx.Exec("INSERT INTO leave_requests (lecturer_id , leave_type , start_date , end_date , days , reason , status , requested_by , created_at) VALUES (? , ? , ? , ? , ? , ? , ? , ? , ?)",
+			leave.Lecturerid, leave.Leavetype, leave.Startdate, leave.Enddate, leave.Days, leave.Reason, leave.Status, leave.Requestedby, leave.Createdat)
+		if err != nil {
+			return err
+		}
+		id, _ := res.LastInsertId()
+		leave.Leaveid = int(id)
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+	// the leave is saved, so a failed impact lookup only leaves the affected classes out
+	if err := a.leaveImpact(ctx, &leave); err != nil {
+		log.Printf("leave %d saved without its affected classes: %v", leave.Leaveid, err)
+		leave.Affected = nil
+	}
+
+	go LogActivity("REQUEST_LEAVE", leave.Requestedby)
+	go AuditLog("REQUEST", "LEAVE", fmt.Sprintf("%d %s %s..%s", leave.Leaveid, leave.Lecturerid, leave.Startdate, leave.Enddate), leave.Requestedby)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(leave)
+}
+
+// GetLecturerLeavesHandler lists a lecturer's leave requests (?status=)
+func (a *HybridHandler) GetLecturerLeavesHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract lecturer id from URL
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+	if !a.requireLecturerOrHR(w, r, lecturer) {
+		return
+	}
+
+	query := "SELECT " + leaveColumns + " FROM leave_requests WHERE lecturer_id=?"
+	args := []interface{}{lecturer.Id.Hex()}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND status=?"
+		args = append(args, status)
+	}
+	rows, err := a.MySQL.db.Query(query+" ORDER BY start_date DESC", args...)
+	if err != nil {
+		http.Error(w, "unable to fetch leave requests", http.StatusInternalServerError)
+		return
+	}
+	leaves, err := scanLeaves(rows)
+	if err != nil {
+		http.Error(w, "rows scan failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leaves)
+}
+
+// GetLeavesHandler lists leave requests (?status=, default pending) for heads of department and hr.
+// A head of department who is also a lecturer only sees their own department.
+func (a *HybridHandler) GetLeavesHandler(w http.ResponseWriter, r *http.Request) {
+	email := currentUser(r)
+	hr, err := a.hasRole(email, RoleHR)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !hr && !a.requireRole(w, r, RoleHOD) {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = LeavePending
+	}
+	rows, err := a.MySQL.db.Query("SELECT "+leaveColumns+" FROM leave_requests WHERE status=? ORDER BY start_date", status)
+	if err != nil {
+		http.Error(w, "unable to fetch leave requests", http.StatusInternalServerError)
+		return
+	}
+	leaves, err := scanLeaves(rows)
+	if err != nil {
+		http.Error(w, "rows scan failed", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	if !hr {
+		hod, err := a.findLecturerByEmail(ctx, email)
+		if err != nil && err != ErrLecturerNotFound {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if hod != nil && hod.Department != "" {
+			staff, err := a.Lecturers.FindAll(ctx, hod.Department)
+			if err != nil {
+				http.Error(w, "failed to fetch lecturers", http.StatusInternalServerError)
+				return
+			}
+			inDept := map[string]bool{}
+			for _, l := range staff {
+				inDept[l.Id.Hex()] = true
+			}
+			filtered := []LeaveRequest{}
+			for _, l := range leaves {
+				if inDept[l.Lecturerid] {
+					filtered = append(filtered, l)
+				}
+			}
+			leaves = filtered
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leaves)
+}
+
+// GetLeaveHandler returns a leave request with its affected classes and substitute suggestions
+func (a *HybridHandler) GetLeaveHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	leave, err := loadLeave(a.MySQL.db, id)
+	if err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, leave.Lecturerid)
+	if err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+	if !strings.EqualFold(currentUser(r), lecturer.Email) {
+		hod, err := a.hasRole(currentUser(r), RoleHOD)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !hod && !a.requireRole(w, r, RoleHR) {
+			return
+		}
+	}
+	if err := a.leaveImpact(ctx, leave); err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leave)
+}
+
+// DecideLeaveHandler approves or rejects a pending leave request, heads of department only.
+// A head of department cannot decide their own leave, nor leave of another department when their own is known.
+func (a *HybridHandler) DecideLeaveHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHOD) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Approve bool   `json:"approve"`
+		Note    string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	actor := currentUser(r)
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	hod, err := a.findLecturerByEmail(ctx, actor)
+	if err != nil && err != ErrLecturerNotFound {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var leave *LeaveRequest
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var locked int
+		err = tx.QueryRow("SELECT leave_id FROM leave_requests WHERE leave_id=? FOR UPDATE", id).Scan(&locked)
+		if err == sql.ErrNoRows {
+			return ErrLeaveNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if leave, err = loadLeave(tx, id); err != nil {
+			return err
+		}
+		if leave.Status != LeavePending {
+			return ErrLeaveDecided
+		}
+		if hod != nil {
+			if hod.Id.Hex() == leave.Lecturerid {
+				return ErrOwnLeave
+			}
+			lecturer, err := a.findLecturer(ctx, leave.Lecturerid)
+			if err != nil {
+				return err
+			}
+			if hod.Department != "" && lecturer.Department != hod.Department {
+				return ErrOtherDepartment
+			}
+		}
+
+		leave.Status = LeaveRejected
+		if req.Approve {
+			leave.Status = LeaveApproved
+		}
+		now := time.Now()
+		if _, err := tx.Exec("UPDATE leave_requests SET status=? , decided_by=? , decided_at=? , decision_note=? WHERE leave_id=?", leave.Status, actor, now, req.Note, id); err != nil {
+			return err
+		}
+		leave.Decidedby, leave.Decidedat, leave.Decisionnote = actor, &now, req.Note
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+	if leave.Status == LeaveApproved {
+		if err := a.leaveImpact(ctx, leave); err != nil {
+			log.Printf("leave %d decided without its affected classes: %v", leave.Leaveid, err)
+			leave.Affected = nil
+		}
+	}
+
+	go LogActivity("DECIDE_LEAVE", actor)
+	go AuditLog(strings.ToUpper(leave.Status), "LEAVE", id, actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leave)
+}
+
+// CancelLeaveHandler cancels a pending leave, or an approved one that has not started, by the lecturer or hr
+func (a *HybridHandler) CancelLeaveHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	leave, err := loadLeave(a.MySQL.db, id)
+	if err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, leave.Lecturerid)
+	if err != nil {
+		writeLeaveError(w, err)
+		return
+	}
+	if !a.requireLecturerOrHR(w, r, lecturer) {
+		return
+	}
+
+	switch {
+	case leave.Status != LeavePending && leave.Status != LeaveApproved:
+		writeLeaveError(w, ErrLeaveDecided)
+		return
+	case leave.Status == LeaveApproved && leave.Startdate <= time.Now().Format("2006-01-02"):
+		writeLeaveError(w, ErrLeaveStarted)
+		return
+	}
+	res, err := a.MySQL.db.Exec("UPDATE leave_requests SET status=? WHERE leave_id=? AND status=?", LeaveCancelled, id, leave.Status)
+	if err != nil {
+		http.Error(w, "failed to cancel leave", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeLeaveError(w, ErrLeaveDecided)
+		return
+	}
+	leave.Status = LeaveCancelled
+
+	go LogActivity("CANCEL_LEAVE", currentUser(r))
+	go AuditLog("CANCEL", "LEAVE", id, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leave)
+}
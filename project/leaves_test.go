@@ -0,0 +1,42 @@
+package project
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandMeetingsCoversDatesWithoutSessions(t *testing.T) {
+	monday := weeklyClass{AffectedClass: AffectedClass{Meetingid: 1, Sectionid: 10, Code: "CS101", Starttime: "09:00", Endtime: "10:00", Room: "A1"}, Day: 1}
+	sunday := weeklyClass{AffectedClass: AffectedClass{Meetingid: 2, Sectionid: 11, Code: "CS201", Starttime: "14:00", Endtime: "15:00", Room: "B2"}, Day: 7}
+	// the first Monday was generated, into another room
+	sessions := []AffectedClass{{Sessionid: 5, Sectionid: 10, Code: "CS101", Date: "2026-03-02", Starttime: "09:00", Endtime: "10:00", Room: "Hall", Substitutes: []SubstituteSuggestion{}}}
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC) // a Sunday
+	to := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	got := expandMeetings(sessions, []weeklyClass{monday, sunday}, from, to)
+
+	want := []struct {
+		date      string
+		session   int
+		meeting   int
+		room      string
+		startTime string
+	}{
+		{"2026-03-01", 0, 2, "B2", "14:00"},
+		{"2026-03-02", 5, 0, "Hall", "09:00"},
+		{"2026-03-08", 0, 2, "B2", "14:00"},
+		{"2026-03-09", 0, 1, "A1", "09:00"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d classes, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		c := got[i]
+		if c.Date != w.date || c.Sessionid != w.session || c.Meetingid != w.meeting || c.Room != w.room || c.Starttime != w.startTime {
+			t.Errorf("class %d is %+v, want %+v", i, c, w)
+		}
+		if c.Substitutes == nil {
+			t.Errorf("class %d has nil substitutes", i)
+		}
+	}
+}
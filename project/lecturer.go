@@ -3,24 +3,29 @@ package project
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Lecturer represents a lecturer entity in MongoDB and exchange via json in API requests/responses
+// Lecturer represents a lecturer entity stored through the LecturerRepository and exchange via json in API requests/responses
 type Lecturer struct {
 	Id          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	Name        string             `json:"name" bson:"name"`
 	Age         int                `json:"age" bson:"age"`
 	Email       string             `json:"email" bson:"email"`
 	Designation string             `json:"designation" bson:"designation"`
+	Department  string             `json:"department,omitempty" bson:"department,omitempty"`
 }
 
 // Validatelecturer validates lecturer input before DB operations
@@ -75,16 +80,12 @@ func (a *HybridHandler) CreateLecturerHandler(w http.ResponseWriter, r *http.Req
 	ctx, cancel := context.WithTimeout(a.Ctx, 15*time.Second)
 	defer cancel()
 
-	// Insert lecturer into MongoDB
-	res, err := a.MongoDB.Lecturer.InsertOne(ctx, lecturers)
-	if err != nil {
-		http.Error(w, "unable to connect mongoDB", http.StatusInternalServerError)
+	// Insert lecturer through the configured store, which assigns its id
+	if err := a.Lecturers.Create(ctx, &lecturers); err != nil {
+		writeLecturerWriteError(w, err, "unable to insert lecturer")
 		return
 	}
 
-	// Assign generated MongoDB ID
-	lecturers.Id = res.InsertedID.(primitive.ObjectID)
-
 	// Marshal lecturer data for redis caching
 	jsonData, err := json.Marshal(lecturers)
 	if err != nil {
@@ -92,8 +93,9 @@ func (a *HybridHandler) CreateLecturerHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// store lecturer in redis
-	go a.Redis.Client.Set(a.Ctx, lecturers.Id.Hex(), jsonData, 10*time.Minute)
+	// store lecturer in redis and drop cached lists
+	go a.Redis.Set(a.Ctx, lecturers.Id.Hex(), jsonData, 10*time.Minute)
+	a.invalidateLecturerLists()
 
 	//  Logactivity and audit trail
 	go LogActivity("CREATE_LECTURER", "system")
@@ -105,52 +107,145 @@ func (a *HybridHandler) CreateLecturerHandler(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(lecturers)
 }
 
-// GetLecturerHandler Fetches all lecturer
+// lecturerListVersionKey holds the version embedded in every cached lecturer list key.
+// Writes bump it, so lists cached before the write are never read again and simply expire.
+const lecturerListVersionKey = "lecturers:version"
+
+// LecturerListQuery is a parsed lecturer listing request
+type LecturerListQuery struct {
+	Page        int
+	Limit       int
+	Designation string
+	Department  string
+	Minage      int
+	Maxage      int
+	Sort        string
+}
+
+// LecturerPage is one page of the lecturer listing
+type LecturerPage struct {
+	Lecturers  []Lecturer `json:"lecturers"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	Total      int64      `json:"total"`
+	Totalpages int        `json:"total_pages"`
+}
+
+// lecturerSortFields are the fields the listing can be sorted by
+var lecturerSortFields = map[string]bool{"name": true, "age": true, "designation": true, "department": true, "email": true}
+
+// parseLecturerListQuery reads paging, filters and sort from the query string
+func parseLecturerListQuery(v url.Values) (LecturerListQuery, error) {
+	q := LecturerListQuery{Page: 1, Limit: 20, Sort: "name"}
+	ints := []struct {
+		name string
+		dst  *int
+	}{{"page", &q.Page}, {"limit", &q.Limit}, {"min_age", &q.Minage}, {"max_age", &q.Maxage}}
+	for _, p := range ints {
+		if s := v.Get(p.name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return q, fmt.Errorf("%s must be a number", p.name)
+			}
+			*p.dst = n
+		}
+	}
+	if q.Page < 1 {
+		return q, fmt.Errorf("page must be at least 1")
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		return q, fmt.Errorf("limit must be between 1 and 100")
+	}
+	if q.Minage < 0 || q.Maxage < 0 || (q.Maxage > 0 && q.Minage > q.Maxage) {
+		return q, fmt.Errorf("min_age and max_age must be positive and min_age cannot exceed max_age")
+	}
+	if s := v.Get("sort"); s != "" {
+		q.Sort = s
+	}
+	if !lecturerSortFields[strings.TrimPrefix(q.Sort, "-")] {
+		return q, fmt.Errorf("sort must be one of name, age, designation, department or email, prefixed with - for descending")
+	}
+	q.Designation = strings.TrimSpace(v.Get("designation"))
+	q.Department = strings.TrimSpace(v.Get("department"))
+	return q, nil
+}
+
+// cacheKey is the Redis key of this query's page under a list version
+func (q LecturerListQuery) cacheKey(version int64) string {
+	return fmt.Sprintf("lecturers:v%d:page=%d:limit=%d:designation=%s:department=%s:min_age=%d:max_age=%d:sort=%s",
+		version, q.Page, q.Limit, strings.ToLower(q.Designation), strings.ToLower(q.Department), q.Minage, q.Maxage, q.Sort)
+}
+
+// lecturerListVersion returns the current list version, ok is false when Redis is unavailable
+func (a *HybridHandler) lecturerListVersion(ctx context.Context) (int64, bool) {
+	version, err := a.Redis.Get(ctx, lecturerListVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, true
+	}
+	return version, err == nil
+}
+
+// invalidateLecturerLists bumps the list version so every cached lecturer list goes stale at once
+func (a *HybridHandler) invalidateLecturerLists() {
+	if err := a.Redis.Incr(a.Ctx, lecturerListVersionKey).Err(); err != nil {
+		log.Println("failed to invalidate lecturer lists:", err)
+	}
+}
+
+// GetLecturerHandler Fetches a page of lecturers (?page=, ?limit=, ?designation=, ?department=, ?min_age=, ?max_age=, ?sort=)
 func (a *HybridHandler) GetLecturerHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Log Activity
 	go LogActivity("GET_ALL_LECTURERS", "system")
 
-	// Redis cache for all lecturers
-	cacheKey := "all_lecturers"
-
-	// Attempt to fetch from redis cache
-	value, err := a.Redis.Client.Get(a.Ctx, cacheKey).Result()
-	if err == nil {
-		log.Println("cache hit...")
+	// Parse paging, filters and sort
+	query, err := parseLecturerListQuery(r.URL.Query())
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(value))
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
 		return
 	}
-	fmt.Println("Cache miss querying MongoDB...")
+
+	// Redis cache key for this query under the current list version
+	version, cacheable := a.lecturerListVersion(a.Ctx)
+	cacheKey := query.cacheKey(version)
+
+	// Attempt to fetch from redis cache
+	if cacheable {
+		value, err := a.Redis.Get(a.Ctx, cacheKey).Result()
+		if err == nil {
+			log.Println("cache hit...")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(value))
+			return
+		}
+	}
+	fmt.Println("Cache miss querying lecturer store...")
 
 	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
 	defer cancel()
 
-	// cursor for all lecturers documents
-	cursor, err := a.MongoDB.Lecturer.Find(ctx, bson.M{})
+	// fetch the requested page and the count of matching lecturers
+	lecturers, total, err := a.Lecturers.List(ctx, query)
 	if err != nil {
 		http.Error(w, "failed to fetch lecturers", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	// Decode all lecturers into slice
-	var lecturers []Lecturer
-	if err := cursor.All(ctx, &lecturers); err != nil {
-		http.Error(w, "failed to decode lecturers", http.StatusInternalServerError)
-		return
-	}
+	page := LecturerPage{Lecturers: lecturers, Page: query.Page, Limit: query.Limit, Total: total}
+	page.Totalpages = int((total + int64(query.Limit) - 1) / int64(query.Limit))
 
 	// marshal lecturers to json
-	jsondata, err := json.Marshal(lecturers)
+	jsondata, err := json.Marshal(page)
 	if err != nil {
 		http.Error(w, "failed to marshal lecturers", http.StatusInternalServerError)
 		return
 	}
 
 	// cache result in redis for 10 minutes
-	go a.Redis.Client.Set(a.Ctx, cacheKey, jsondata, 10*time.Minute)
+	if cacheable {
+		go a.Redis.Set(a.Ctx, cacheKey, jsondata, 10*time.Minute)
+	}
 
 	// send response
 	w.Header().Set("Content-Type", "application/json")
@@ -168,30 +263,24 @@ func (a *HybridHandler) GetLecturerByIDHandler(w http.ResponseWriter, r *http.Re
 	go LogActivity("GET_LECTURER", "system")
 
 	// Attempt to fetch from redis cache
-	value, err := a.Redis.Client.Get(a.Ctx, id).Result()
+	value, err := a.Redis.Get(a.Ctx, id).Result()
 	if err == nil {
 		log.Println("cache Hit...")
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(value))
 		return
 	}
-	// Cache miss fetch from MongoDB
-	fmt.Println("cache miss querying mongodb...")
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		http.Error(w, "invalid id format", http.StatusBadRequest)
-		return
-	}
+	// Cache miss fetch from the lecturer store
+	fmt.Println("cache miss querying lecturer store...")
 
 	// Create context with timeout to avoid hanging DB calls
-	var lecturers Lecturer
 	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
 	defer cancel()
 
-	// Fetch lecturer from MongoDB
-	err = a.MongoDB.Lecturer.FindOne(ctx, bson.M{"_id": objectID}).Decode(&lecturers)
+	// Fetch lecturer
+	lecturers, err := a.Lecturers.Get(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeLecturerLookupError(w, err)
 		return
 	}
 
@@ -201,7 +290,7 @@ func (a *HybridHandler) GetLecturerByIDHandler(w http.ResponseWriter, r *http.Re
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	go a.Redis.Client.Set(a.Ctx, id, jsonData, 10*time.Minute)
+	go a.Redis.Set(a.Ctx, id, jsonData, 10*time.Minute)
 
 	// Return lecturer
 	w.Header().Set("Content-Type", "application/json")
@@ -228,37 +317,22 @@ func (a *HybridHandler) UpdateLecturerHandler(w http.ResponseWriter, r *http.Req
 		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
 		return
 	}
-	objID, err := primitive.ObjectIDFromHex(id)
+	objID, err := parseLecturerID(id)
 	if err != nil {
 		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
 	}
+	lecturers.Id = objID
 
 	// create context with timeout to avoid DB calls
 	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Minute)
 	defer cancel()
 
-	// update feilds
-	update := bson.M{
-		"$set": bson.M{
-			"name":        lecturers.Name,
-			"age":         lecturers.Age,
-			"email":       lecturers.Email,
-			"designation": lecturers.Designation,
-		},
-	}
-
-	res, err := a.MongoDB.Lecturer.UpdateOne(ctx, bson.M{"_id": objID}, update)
-	if err != nil {
-		http.Error(w, "unable to update", http.StatusInternalServerError)
-		return
-	}
-
-	// Handle record not found
-	if res.MatchedCount == 0 {
-		http.Error(w, "lecturer not found", http.StatusNotFound)
+	// update feilds, handling record not found
+	if err := a.Lecturers.Update(ctx, &lecturers); err != nil {
+		writeLecturerWriteError(w, err, "unable to update")
 		return
 	}
-	lecturers.Id = objID
 
 	// update redis cache
 	jsonData, err := json.Marshal(lecturers)
@@ -266,7 +340,8 @@ func (a *HybridHandler) UpdateLecturerHandler(w http.ResponseWriter, r *http.Req
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	go a.Redis.Client.Set(a.Ctx, id, jsonData, 10*time.Minute)
+	go a.Redis.Set(a.Ctx, id, jsonData, 10*time.Minute)
+	a.invalidateLecturerLists()
 
 	// Log update activity
 	go LogActivity("UPDATE_LECTURER", "system")
@@ -284,35 +359,84 @@ func (a *HybridHandler) DeleteLecturerHandler(w http.ResponseWriter, r *http.Req
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	objID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		http.Error(w, "invalid id format", http.StatusBadRequest)
-		return
-	}
-
 	// create context withtimeout to avoid DB calls
 	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Minute)
 	defer cancel()
 
-	res, err := a.MongoDB.Lecturer.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
-		http.Error(w, "unable to delete", http.StatusInternalServerError)
+	err := a.Lecturers.Delete(ctx, id)
+	switch {
+	case errors.Is(err, ErrInvalidLecturerID):
+		http.Error(w, "invalid id format", http.StatusBadRequest)
 		return
-	}
-	// Handle record not found
-	if res.DeletedCount == 0 {
+	case errors.Is(err, ErrLecturerNotFound):
 		http.Error(w, "Lecturer not found", http.StatusNotFound)
 		return
+	case err != nil:
+		http.Error(w, "unable to delete", http.StatusInternalServerError)
+		return
 	}
-	// remove cache entry
-	a.Redis.Client.Del(a.Ctx, id)
+	// remove cache entry and drop cached lists
+	a.Redis.Del(a.Ctx, id)
+	a.invalidateLecturerLists()
 
 	// Log delete activity
 	go LogActivity("DELETE_LECTURER", "system")
-	go AuditLog("DELETE", "LECTURER", objID.Hex(), "system")
+	go AuditLog("DELETE", "LECTURER", id, "system")
 
 	// send response
 	w.Header().Set("Content-Type", "system")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Lecturer deleted!"))
 }
+
+// errors returned by findLecturer
+var (
+	ErrLecturerNotFound  = errors.New("lecturer not found")
+	ErrInvalidLecturerID = errors.New("invalid lecturer id format")
+)
+
+// findLecturer loads a lecturer by its hex id from the configured store
+func (a *HybridHandler) findLecturer(ctx context.Context, id string) (*Lecturer, error) {
+	return a.Lecturers.Get(ctx, id)
+}
+
+// findLecturerByEmail loads a lecturer by email address from the configured store
+func (a *HybridHandler) findLecturerByEmail(ctx context.Context, email string) (*Lecturer, error) {
+	return a.Lecturers.GetByEmail(ctx, email)
+}
+
+// errors returned by lecturerWriteError
+var (
+	ErrLecturerEmailTaken = errors.New("a lecturer with this email already exists")
+	ErrLecturerSchema     = errors.New("lecturer does not match the collection schema")
+)
+
+// lecturerWriteError translates MongoDB unique index and validator failures into errors for the client
+func lecturerWriteError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLecturerEmailTaken
+	}
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == 121 {
+				return fmt.Errorf("%w: %s", ErrLecturerSchema, e.Message)
+			}
+		}
+	}
+	return err
+}
+
+// writeLecturerWriteError maps failed lecturer inserts and updates to HTTP responses
+func writeLecturerWriteError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrLecturerNotFound):
+		http.Error(w, "lecturer not found", http.StatusNotFound)
+	case errors.Is(err, ErrLecturerEmailTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrLecturerSchema):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,51 @@
+package project
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLecturerCRUDHandlers(t *testing.T) {
+	a := newMemoryHandler()
+
+	w := serve(t, a.CreateLecturerHandler, "POST", "/lecturers", nil, "", Lecturer{Name: "Grace", Age: 45, Email: "grace@gmail.com", Designation: "Professor", Department: "CS"})
+	wantStatus(t, w, http.StatusCreated)
+	var created Lecturer
+	decode(t, w, &created)
+	if created.Id.IsZero() {
+		t.Fatal("created lecturer has no id")
+	}
+	id := created.Id.Hex()
+
+	w = serve(t, a.CreateLecturerHandler, "POST", "/lecturers", nil, "", Lecturer{Name: "Copy", Age: 40, Email: "grace@gmail.com", Designation: "Lecturer"})
+	wantStatus(t, w, http.StatusConflict)
+	w = serve(t, a.CreateLecturerHandler, "POST", "/lecturers", nil, "", Lecturer{Name: "Old", Age: 120, Email: "old@gmail.com", Designation: "Lecturer"})
+	wantStatus(t, w, http.StatusBadRequest)
+
+	w = serve(t, a.GetLecturerByIDHandler, "GET", "/lecturers/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	var got Lecturer
+	decode(t, w, &got)
+	if got != created {
+		t.Fatalf("got %+v, want %+v", got, created)
+	}
+
+	created.Designation = "Dean"
+	w = serve(t, a.UpdateLecturerHandler, "PUT", "/lecturers/"+id, map[string]string{"id": id}, "", created)
+	wantStatus(t, w, http.StatusOK)
+
+	w = serve(t, a.GetLecturerHandler, "GET", "/lecturers?designation=Dean", nil, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	var page LecturerPage
+	decode(t, w, &page)
+	if page.Total != 1 || len(page.Lecturers) != 1 || page.Lecturers[0].Id != created.Id {
+		t.Fatalf("got %+v, want the updated lecturer", page)
+	}
+
+	w = serve(t, a.DeleteLecturerHandler, "DELETE", "/lecturers/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	w = serve(t, a.GetLecturerByIDHandler, "GET", "/lecturers/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusNotFound)
+	w = serve(t, a.DeleteLecturerHandler, "DELETE", "/lecturers/not-hex", map[string]string{"id": "not-hex"}, "", nil)
+	wantStatus(t, w, http.StatusBadRequest)
+}
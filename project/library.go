@@ -1,40 +1,107 @@
 package project
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
 )
 
-// Library represents a library entity
+// Library represents a library entity, Availablecopies counts the available copies of its books
 type Library struct {
-	Libraryid int `json:"library_id"`
-	Book      []struct {
-		Bookid   int    `json:"book_id"`
-		Bookname string `json:"book_name"`
-	} `json:"book"`
-	Title  string `json:"title"`
-	Author []struct {
-		Authorid   int    `json:"author_id"`
-		Authorname string `json:"author_name"`
-	} `json:"author"`
-	Availablecopies int `json:"available_copies"`
-}
-
-// borrowrecords represents borrowing transactions
+	Libraryid       int             `json:"library_id"`
+	Book            []LibraryBook   `json:"book"`
+	Title           string          `json:"title"`
+	Author          []LibraryAuthor `json:"author"`
+	Availablecopies int             `json:"available_copies"`
+}
+
+// LibraryBook is a book held by a library, the copy counts are derived and ignored on input.
+// Totalcopies leaves out withdrawn copies.
+type LibraryBook struct {
+	Bookid          int    `json:"book_id"`
+	Bookname        string `json:"book_name"`
+	Totalcopies     int    `json:"total_copies"`
+	Availablecopies int    `json:"available_copies"`
+}
+
+// LibraryAuthor is an author listed by a library
+type LibraryAuthor struct {
+	Authorid   int    `json:"author_id"`
+	Authorname string `json:"author_name"`
+}
+
+// Author is an author served by /authors, together with the library that lists them
+type Author struct {
+	Authorid   int    `json:"author_id"`
+	Authorname string `json:"author_name"`
+	Libraryid  int    `json:"library_id"`
+}
+
+// LibraryListQuery is a parsed page of libraries or books, Title filters by a case-insensitive substring
+type LibraryListQuery struct {
+	Page  int
+	Limit int
+	Title string
+}
+
+// AuthorListQuery is a parsed page of authors
+type AuthorListQuery struct {
+	Page      int
+	Limit     int
+	Libraryid int
+	Name      string
+}
+
+// LibraryPage is one page of the library listing
+type LibraryPage struct {
+	Libraries  []Library `json:"libraries"`
+	Page       int       `json:"page"`
+	Limit      int       `json:"limit"`
+	Total      int64     `json:"total"`
+	Totalpages int       `json:"total_pages"`
+}
+
+// BookPage is one page of a library's books
+type BookPage struct {
+	Books      []LibraryBook `json:"books"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	Total      int64         `json:"total"`
+	Totalpages int           `json:"total_pages"`
+}
+
+// AuthorPage is one page of the author listing
+type AuthorPage struct {
+	Authors    []Author `json:"authors"`
+	Page       int      `json:"page"`
+	Limit      int      `json:"limit"`
+	Total      int64    `json:"total"`
+	Totalpages int      `json:"total_pages"`
+}
+
+// borrowrecords represents borrowing transactions of one copy
 type Borrowrecords struct {
 	Borrowid   int        `json:"borrow_id"`
 	Userid     int        `json:"user_id"`
 	Usertype   string     `json:"usertype"`
 	Bookid     int        `json:"book_id"`
+	Copyid     int        `json:"copy_id,omitempty"`
+	Barcode    string     `json:"barcode"`
 	Borrowdate *time.Time `json:"bowwow_date"`
+	Duedate    *time.Time `json:"due_date"`
 	Returndate *time.Time `json:"return_date"`
+	Renewals   int        `json:"renewals"`
+	Finecents  int64      `json:"fine_cents"`
 }
 
 // validate library ensures that library input data is valid before DB operations
@@ -47,29 +114,41 @@ func ValidateLibrary(library *Library) error {
 	if len(library.Book) == 0 {
 		return fmt.Errorf("atleast one book is required")
 	}
-	// validate availablecopies
-	if library.Availablecopies < 0 {
-		return fmt.Errorf("availabe copies cannot be negative")
-	}
 	for _, b := range library.Book {
-		if b.Bookid <= 0 {
-			return fmt.Errorf("invalid Book_id: %d", b.Bookid)
-		}
-		if b.Bookname == "" {
-			return fmt.Errorf("book name cannot be empty")
+		if err := ValidateLibraryBook(b); err != nil {
+			return err
 		}
 	}
 	for _, a := range library.Author {
-		if a.Authorid <= 0 {
-			return fmt.Errorf("invalid author_id: %d", a.Authorid)
-		}
-		if a.Authorname == "" {
-			return fmt.Errorf("author_name cannot be empty")
+		if err := ValidateLibraryAuthor(a); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// ValidateLibraryBook checks a book before it is added to a library
+func ValidateLibraryBook(b LibraryBook) error {
+	if b.Bookid <= 0 {
+		return fmt.Errorf("invalid Book_id: %d", b.Bookid)
+	}
+	if strings.TrimSpace(b.Bookname) == "" {
+		return fmt.Errorf("book name cannot be empty")
+	}
+	return nil
+}
+
+// ValidateLibraryAuthor checks an author before it is added to a library
+func ValidateLibraryAuthor(a LibraryAuthor) error {
+	if a.Authorid <= 0 {
+		return fmt.Errorf("invalid author_id: %d", a.Authorid)
+	}
+	if strings.TrimSpace(a.Authorname) == "" {
+		return fmt.Errorf("author_name cannot be empty")
+	}
+	return nil
+}
+
 // validateBorrowRecords ensures borrow record input is valid
 func ValidateBorrowRecords(BR Borrowrecords) error {
 	if BR.Bookid <= 0 {
@@ -108,58 +187,22 @@ func (a *HybridHandler) CreateLibraryHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Begin transaction
-	tx, err := a.MySQL.db.Begin()
-	if err != nil {
-		http.Error(w, "failed to start transcation", http.StatusInternalServerError)
+	// Insert library with its books and authors in one transaction
+	if err := a.Libraries.Create(r.Context(), &libraries); err != nil {
+		writeLibraryError(w, err, "failed to create library")
 		return
 	}
+	a.invalidateLibraries()
 
-	// Insert library records
-	res, err := tx.Exec("INSERT INTO libraries (title , available_copies) VALUES (? , ?)", libraries.Title, libraries.Availablecopies)
-	if err != nil {
-		tx.Rollback()
-		http.Error(w, fmt.Sprintf("failed to insert libraries: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Get generated library ID
-	libraryID, err := res.LastInsertId()
-	if err != nil {
-		tx.Rollback()
-		http.Error(w, "Failed to fetch libraryID", http.StatusInternalServerError)
-		return
-	}
-	// Insert BOOKS
-	for _, b := range libraries.Book {
-		_, err := tx.Exec("INSERT INTO books (book_id, book_name , library_id) VALUES (? , ? , ?)", b.Bookid, b.Bookname, libraryID)
-		if err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to insert books", http.StatusInternalServerError)
-			return
-		}
-	}
-	// Insert authors
-	for _, a := range libraries.Author {
-		_, err := tx.Exec("INSERT INTO authors (author_id , author_name , library_id) VALUES (? , ? ,?)", a.Authorid, a.Authorname, libraryID)
-		if err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to insert authors", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
-		return
-	}
+	// Log activity and audit trail
+	go LogActivity("CREATE_LIBRARY", "system")
+	go AuditLog("CREATE", "LIBRARY", libraries.Libraryid, "system")
 
 	// success response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "library created succesfully", "library_id": libraryID,
+		"message": "library created succesfully", "library_id": libraries.Libraryid,
 	})
 }
 
@@ -170,69 +213,36 @@ func (a *HybridHandler) GetLibraryByIDHandler(w http.ResponseWriter, r *http.Req
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Try redis cache
-	value, err := a.Redis.Client.Get(a.Ctx, id).Result()
-	if err == nil {
-		log.Println("cache Hit...")
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(value))
-		return
-	}
-
-	// cache miss querying MySQL
-	fmt.Println("cache miss querying MySQL...")
-	var lib Library
-
-	// Fetch library details
-	err = a.MySQL.db.QueryRow("SELECT library_id, title , available_copies FROM libraries WHERE library_id=?", id).Scan(&lib.Libraryid, &lib.Title, &lib.Availablecopies)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "library not found", http.StatusNotFound)
+	// Try redis cache under the current library version
+	version, cacheable := a.libraryCacheVersion(a.Ctx)
+	cacheKey := libraryCacheKey(version, "library:"+id)
+	if cacheable {
+		value, err := a.Redis.Get(a.Ctx, cacheKey).Result()
+		if err == nil {
+			log.Println("cache Hit...")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(value))
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to fetch library: %v", err), http.StatusInternalServerError)
-		return
 	}
-	// Fetch Books
-	booksRows, err := a.MySQL.db.Query("SELECT book_id , book_name FROM books WHERE library_id=?", id)
+
+	// cache miss querying the library store
+	fmt.Println("cache miss querying library store...")
+	libraryID, err := strconv.Atoi(id)
 	if err != nil {
-		log.Printf("Failed to fetch books: %v", err)
-		http.Error(w, "failed to fetch books", http.StatusInternalServerError)
+		http.Error(w, "library not found", http.StatusNotFound)
 		return
 	}
-	defer booksRows.Close()
-
-	for booksRows.Next() {
-		var b struct {
-			Bookid   int    `json:"book_id"`
-			Bookname string `json:"book_name"`
-		}
-		if err := booksRows.Scan(&b.Bookid, &b.Bookname); err != nil {
-			http.Error(w, "Failed to scan books", http.StatusInternalServerError)
-			return
-		}
-		lib.Book = append(lib.Book, b)
+	lib, err := a.Libraries.Get(r.Context(), libraryID)
+	if err == ErrLibraryNotFound {
+		http.Error(w, "library not found", http.StatusNotFound)
+		return
 	}
-	// Fetch authors
-	authorrows, err := a.MySQL.db.Query("SELECT author_id , author_name FROM authors WHERE library_id=?", id)
 	if err != nil {
-		http.Error(w, "failed to fetch authors", http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to fetch library: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer authorrows.Close()
 
-	for authorrows.Next() {
-		var aObj struct {
-			Authorid   int    `json:"author_id"`
-			Authorname string `json:"author_name"`
-		}
-		if err := authorrows.Scan(&aObj.Authorid, &aObj.Authorname); err != nil {
-			http.Error(w, "Failed to scan author", http.StatusInternalServerError)
-			return
-		}
-		lib.Author = append(lib.Author, aObj)
-
-	}
 	//  marshal results to json
 
 	responseJson, err := json.Marshal(lib)
@@ -241,7 +251,9 @@ func (a *HybridHandler) GetLibraryByIDHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	// cache in redis
-	a.Redis.Client.Set(a.Ctx, id, string(responseJson), 10*time.Minute)
+	if cacheable {
+		a.Redis.Set(a.Ctx, cacheKey, string(responseJson), 10*time.Minute)
+	}
 
 	// return response
 	w.Header().Set("Content-Type", "application/json")
@@ -262,34 +274,24 @@ func (a *HybridHandler) Borrowbooks(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid user type , user must be student or lecturer", http.StatusBadRequest)
 		return
 	}
-	//  check book is available
-	var available int
-	err := a.MySQL.db.QueryRow("SELECT available_copies FROM libraries WHERE library_id=(SELECT library_id FROM books WHERE book_id=?)", records.Bookid).Scan(&available)
-	if err != nil {
-		http.Error(w, "Book not found", http.StatusNotFound)
+	if strings.TrimSpace(records.Barcode) == "" {
+		http.Error(w, "barcode is required", http.StatusBadRequest)
 		return
 	}
-	if available <= 0 {
-		http.Error(w, "book not available", http.StatusBadRequest)
-		return
-	}
-	// Insert borrow records
-	_, err = a.MySQL.db.Exec("INSERT INTO borrow_records(user_id , usertype , book_id , borrow_date) VALUES (? , ? , ? ,CURDATE())", records.Userid, records.Usertype, records.Bookid)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	// Decrement availables copies
-	_, err = a.MySQL.db.Exec("UPDATE books SET available_copies= available_copies-1 WHERE book_id=?", records.Bookid)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// borrow the copy if it is available and the user's loan policy allows it
+	if err := a.Libraries.Borrow(r.Context(), &records); err != nil {
+		writeLoanError(w, err, "failed to borrow")
 		return
 	}
 
+	a.invalidateLibraries()
+
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"status": "book borrowed"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "book borrowed", "borrow_id": records.Borrowid, "due_date": records.Duedate,
+	})
 }
 
 // Return books
@@ -307,28 +309,519 @@ func (a *HybridHandler) ReturnBooksHandler(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "invalid user type", http.StatusInternalServerError)
 		return
 	}
+	if strings.TrimSpace(records.Barcode) == "" {
+		http.Error(w, "barcode is required", http.StatusBadRequest)
+		return
+	}
 
-	// Execute update query
-	res, err := a.MySQL.db.Exec("UPDATE borrow_records SET return_date=CURDATE() WHERE user_id=? AND book_id=? AND return_date is NULL", records.Userid, records.Bookid)
-	if err != nil {
+	// close the open borrow record, charge any overdue fine and pass the copy on to the next hold or the shelf
+	hold, err := a.Libraries.Return(r.Context(), &records)
+	switch {
+	case err == ErrNoOpenBorrow:
+		http.Error(w, "no borrow records found", http.StatusInternalServerError)
+		return
+	case err == ErrCopyNotFound:
+		http.Error(w, "copy not found", http.StatusNotFound)
+		return
+	case err != nil:
 		http.Error(w, "failed to update", http.StatusInternalServerError)
 		return
 	}
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		http.Error(w, "no borrow records found", http.StatusInternalServerError)
+
+	a.invalidateLibraries()
+
+	// send response, naming the hold the copy was set aside for
+	response := map[string]interface{}{
+		"status": "book returned", "borrow_id": records.Borrowid, "due_date": records.Duedate, "fine_cents": records.Finecents,
+	}
+	if hold != nil {
+		response["hold_id"], response["pickup_by"] = hold.Holdid, hold.Pickupby
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// libraryCacheVersionKey holds the version embedded in every cached library, book and author response.
+// Every library write bumps it, since a write can change a library, its book list and the author list at once.
+const libraryCacheVersionKey = "libraries:version"
+
+// libraryCacheKey is the Redis key of a library response under a cache version
+func libraryCacheKey(version int64, suffix string) string {
+	return fmt.Sprintf("libraries:v%d:%s", version, suffix)
+}
+
+// libraryCacheVersion returns the current library cache version, ok is false when Redis is unavailable
+func (a *HybridHandler) libraryCacheVersion(ctx context.Context) (int64, bool) {
+	version, err := a.Redis.Get(ctx, libraryCacheVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, true
+	}
+	return version, err == nil
+}
+
+// invalidateLibraries bumps the cache version so every cached library response goes stale at once
+func (a *HybridHandler) invalidateLibraries() {
+	if err := a.Redis.Incr(a.Ctx, libraryCacheVersionKey).Err(); err != nil {
+		log.Println("failed to invalidate library cache:", err)
+	}
+}
+
+// serveLibraryCached answers from Redis under the current version, or loads the response and caches it
+func (a *HybridHandler) serveLibraryCached(w http.ResponseWriter, suffix string, load func(ctx context.Context) (any, error)) {
+	version, cacheable := a.libraryCacheVersion(a.Ctx)
+	cacheKey := libraryCacheKey(version, suffix)
+	if cacheable {
+		if value, err := a.Redis.Get(a.Ctx, cacheKey).Result(); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(value))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	resp, err := load(ctx)
+	if err != nil {
+		writeLibraryError(w, err, "failed to fetch library data")
+		return
+	}
+	jsonData, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+	if cacheable {
+		go a.Redis.Set(a.Ctx, cacheKey, jsonData, 10*time.Minute)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// parsePaging reads ?page= (default 1) and ?limit= (default 20, max 100)
+func parsePaging(v url.Values) (int, int, error) {
+	page, limit := 1, 20
+	if s := v.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("page must be a number of at least 1")
+		}
+		page = n
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 100 {
+			return 0, 0, fmt.Errorf("limit must be a number between 1 and 100")
+		}
+		limit = n
+	}
+	return page, limit, nil
+}
+
+// totalPages is the number of pages of limit items needed for total items
+func totalPages(total int64, limit int) int {
+	return int((total + int64(limit) - 1) / int64(limit))
+}
+
+// writeLibraryError maps library repository errors to HTTP responses
+func writeLibraryError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrLibraryNotFound), errors.Is(err, ErrBookNotFound), errors.Is(err, ErrAuthorNotFound), errors.Is(err, ErrCopyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrBookExists), errors.Is(err, ErrAuthorExists), errors.Is(err, ErrBookOnLoan), errors.Is(err, ErrLibraryHasLoans),
+		errors.Is(err, ErrCopyExists), errors.Is(err, ErrCopyOnLoan), errors.Is(err, ErrCopyOnHold):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// GetLibrariesHandler lists libraries with their books and authors (?page=, ?limit=, ?title=)
+func (a *HybridHandler) GetLibrariesHandler(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := parsePaging(r.URL.Query())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	q := LibraryListQuery{Page: page, Limit: limit, Title: strings.TrimSpace(r.URL.Query().Get("title"))}
+
+	suffix := fmt.Sprintf("list:page=%d:limit=%d:title=%s", q.Page, q.Limit, strings.ToLower(q.Title))
+	a.serveLibraryCached(w, suffix, func(ctx context.Context) (any, error) {
+		libs, total, err := a.Libraries.List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return LibraryPage{Libraries: libs, Page: q.Page, Limit: q.Limit, Total: total, Totalpages: totalPages(total, q.Limit)}, nil
+	})
+}
+
+// UpdateLibraryHandler replaces a library's title, books and authors (librarian only)
+func (a *HybridHandler) UpdateLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var library Library
+	if err := json.NewDecoder(r.Body).Decode(&library); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if err := ValidateLibrary(&library); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	library.Libraryid = id
+
+	if err := a.Libraries.Update(r.Context(), &library); err != nil {
+		writeLibraryError(w, err, "failed to update library")
+		return
+	}
+	a.invalidateLibraries()
+
+	updated, err := a.Libraries.Get(r.Context(), id)
+	if err != nil {
+		writeLibraryError(w, err, "failed to fetch library")
+		return
+	}
+
+	go LogActivity("UPDATE_LIBRARY", currentUser(r))
+	go AuditLog("UPDATE", "LIBRARY", id, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteLibraryHandler deletes a library with its books and authors, refused while books are on loan (librarian only)
+func (a *HybridHandler) DeleteLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Libraries.Delete(r.Context(), id); err != nil {
+		writeLibraryError(w, err, "failed to delete library")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("DELETE_LIBRARY", currentUser(r))
+	go AuditLog("DELETE", "LIBRARY", id, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "library deleted"})
+}
+
+// GetLibraryBooksHandler lists the books of a library (?page=, ?limit=, ?title=)
+func (a *HybridHandler) GetLibraryBooksHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	page, limit, err := parsePaging(r.URL.Query())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	q := LibraryListQuery{Page: page, Limit: limit, Title: strings.TrimSpace(r.URL.Query().Get("title"))}
+
+	suffix := fmt.Sprintf("library:%d:books:page=%d:limit=%d:title=%s", id, q.Page, q.Limit, strings.ToLower(q.Title))
+	a.serveLibraryCached(w, suffix, func(ctx context.Context) (any, error) {
+		books, total, err := a.Libraries.Books(ctx, id, q)
+		if err != nil {
+			return nil, err
+		}
+		return BookPage{Books: books, Page: q.Page, Limit: q.Limit, Total: total, Totalpages: totalPages(total, q.Limit)}, nil
+	})
+}
+
+// AddLibraryBookHandler adds a book to an existing library (librarian only)
+func (a *HybridHandler) AddLibraryBookHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var book LibraryBook
+	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if err := ValidateLibraryBook(book); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	if err := a.Libraries.AddBook(r.Context(), id, book); err != nil {
+		writeLibraryError(w, err, "failed to add book")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("ADD_BOOK", currentUser(r))
+	go AuditLog("CREATE", "BOOK", book.Bookid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(book)
+}
+
+// UpdateLibraryBookHandler renames a book of a library (librarian only)
+func (a *HybridHandler) UpdateLibraryBookHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	bookID, err := strconv.Atoi(vars["bookId"])
+	if err != nil {
+		http.Error(w, "invalid book id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var book LibraryBook
+	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	book.Bookid = bookID
+	if err := ValidateLibraryBook(book); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	if err := a.Libraries.UpdateBook(r.Context(), id, book); err != nil {
+		writeLibraryError(w, err, "failed to update book")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("UPDATE_BOOK", currentUser(r))
+	go AuditLog("UPDATE", "BOOK", bookID, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
+// DeleteLibraryBookHandler removes a book from a library, refused while it is on loan (librarian only)
+func (a *HybridHandler) DeleteLibraryBookHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract ids from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	bookID, err := strconv.Atoi(vars["bookId"])
+	if err != nil {
+		http.Error(w, "invalid book id format", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Libraries.DeleteBook(r.Context(), id, bookID); err != nil {
+		writeLibraryError(w, err, "failed to delete book")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("DELETE_BOOK", currentUser(r))
+	go AuditLog("DELETE", "BOOK", bookID, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "book deleted"})
+}
+
+// ValidateAuthor checks an author before it is stored
+func ValidateAuthor(author Author) error {
+	if err := ValidateLibraryAuthor(LibraryAuthor{Authorid: author.Authorid, Authorname: author.Authorname}); err != nil {
+		return err
+	}
+	if author.Libraryid <= 0 {
+		return fmt.Errorf("library_id is required")
+	}
+	return nil
+}
+
+// GetAuthorsHandler lists authors (?page=, ?limit=, ?library_id=, ?name=)
+func (a *HybridHandler) GetAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	page, limit, err := parsePaging(v)
+	q := AuthorListQuery{Page: page, Limit: limit, Name: strings.TrimSpace(v.Get("name"))}
+	if err == nil && v.Get("library_id") != "" {
+		if q.Libraryid, err = strconv.Atoi(v.Get("library_id")); err != nil {
+			err = fmt.Errorf("library_id must be a number")
+		}
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
 		return
 	}
 
-	// increment available copies
-	_, err = a.MySQL.db.Exec("UPDATE books SET available_copies=available_copies+1 WHERE book_id=?", records.Bookid)
+	suffix := fmt.Sprintf("authors:page=%d:limit=%d:library=%d:name=%s", q.Page, q.Limit, q.Libraryid, strings.ToLower(q.Name))
+	a.serveLibraryCached(w, suffix, func(ctx context.Context) (any, error) {
+		authors, total, err := a.Libraries.Authors(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return AuthorPage{Authors: authors, Page: q.Page, Limit: q.Limit, Total: total, Totalpages: totalPages(total, q.Limit)}, nil
+	})
+}
+
+// GetAuthorHandler returns one author
+func (a *HybridHandler) GetAuthorHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	a.serveLibraryCached(w, fmt.Sprintf("author:%d", id), func(ctx context.Context) (any, error) {
+		return a.Libraries.GetAuthor(ctx, id)
+	})
+}
+
+// CreateAuthorHandler adds an author to a library (librarian only)
+func (a *HybridHandler) CreateAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var author Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if err := ValidateAuthor(author); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
 		return
 	}
 
-	// send response
+	if err := a.Libraries.CreateAuthor(r.Context(), author); err != nil {
+		writeLibraryError(w, err, "failed to create author")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("CREATE_AUTHOR", currentUser(r))
+	go AuditLog("CREATE", "AUTHOR", author.Authorid, currentUser(r))
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"status": "book returned"})
+	json.NewEncoder(w).Encode(author)
+}
+
+// UpdateAuthorHandler renames an author or moves them to another library (librarian only)
+func (a *HybridHandler) UpdateAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var author Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	author.Authorid = id
+	if err := ValidateAuthor(author); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	if err := a.Libraries.UpdateAuthor(r.Context(), author); err != nil {
+		writeLibraryError(w, err, "failed to update author")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("UPDATE_AUTHOR", currentUser(r))
+	go AuditLog("UPDATE", "AUTHOR", id, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(author)
+}
+
+// DeleteAuthorHandler deletes an author (librarian only)
+func (a *HybridHandler) DeleteAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.Libraries.DeleteAuthor(r.Context(), id); err != nil {
+		writeLibraryError(w, err, "failed to delete author")
+		return
+	}
+	a.invalidateLibraries()
+
+	go LogActivity("DELETE_AUTHOR", currentUser(r))
+	go AuditLog("DELETE", "AUTHOR", id, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "author deleted"})
 }
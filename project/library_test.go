@@ -0,0 +1,54 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestLibraryCRUDHandlers(t *testing.T) {
+	a := newMemoryHandler()
+
+	lib := Library{Title: "Central", Book: []LibraryBook{{Bookid: 1, Bookname: "Go"}}, Author: []LibraryAuthor{{Authorid: 1, Authorname: "Rob"}}}
+	w := serve(t, a.CreateLibraryHandler, "POST", "/libraries", nil, "", lib)
+	wantStatus(t, w, http.StatusCreated)
+	var created struct {
+		Libraryid int `json:"library_id"`
+	}
+	decode(t, w, &created)
+	id := fmt.Sprint(created.Libraryid)
+
+	w = serve(t, a.CreateLibraryHandler, "POST", "/libraries", nil, "", lib)
+	wantStatus(t, w, http.StatusConflict)
+
+	w = serve(t, a.GetLibraryByIDHandler, "GET", "/libraries/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	var got Library
+	decode(t, w, &got)
+	if got.Title != "Central" || len(got.Book) != 1 || len(got.Author) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+
+	update := Library{Title: "Main", Book: []LibraryBook{{Bookid: 1, Bookname: "Go"}, {Bookid: 2, Bookname: "SQL"}}}
+	w = serve(t, a.UpdateLibraryHandler, "PUT", "/libraries/"+id, map[string]string{"id": id}, "student@gmail.com", update)
+	wantStatus(t, w, http.StatusForbidden)
+	w = serve(t, a.UpdateLibraryHandler, "PUT", "/libraries/"+id, map[string]string{"id": id}, testLibrarian, update)
+	wantStatus(t, w, http.StatusOK)
+	decode(t, w, &got)
+	if got.Title != "Main" || len(got.Book) != 2 || len(got.Author) != 0 {
+		t.Fatalf("got %+v after update", got)
+	}
+
+	w = serve(t, a.GetLibrariesHandler, "GET", "/libraries?title=main", nil, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	var page LibraryPage
+	decode(t, w, &page)
+	if page.Total != 1 || page.Libraries[0].Libraryid != created.Libraryid {
+		t.Fatalf("got %+v", page)
+	}
+
+	w = serve(t, a.DeleteLibraryHandler, "DELETE", "/libraries/"+id, map[string]string{"id": id}, testLibrarian, nil)
+	wantStatus(t, w, http.StatusOK)
+	w = serve(t, a.GetLibraryByIDHandler, "GET", "/libraries/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusNotFound)
+}
@@ -0,0 +1,156 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// LoanPolicy is how long and how much a user type may borrow, and what it pays for late returns.
+// The fine of one loan is capped at max_fine_cents (0 is no cap), and borrowing stops once unpaid fines exceed fine_block_cents.
+type LoanPolicy struct {
+	Usertype        string    `json:"usertype"`
+	Loandays        int       `json:"loan_days"`
+	Maxloans        int       `json:"max_loans"`
+	Maxrenewals     int       `json:"max_renewals"`
+	Fineperdaycents int64     `json:"fine_per_day_cents"`
+	Gracedays       int       `json:"grace_days"`
+	Maxfinecents    int64     `json:"max_fine_cents"`
+	Fineblockcents  int64     `json:"fine_block_cents"`
+	Holdpickupdays  int       `json:"hold_pickup_days"`
+	Updatedby       string    `json:"updated_by,omitempty"`
+	Updatedat       time.Time `json:"updated_at"`
+}
+
+// defaultLoanPolicies are the policies migrations 000019 to 000021 seed, the memory store starts with them too
+var defaultLoanPolicies = []LoanPolicy{
+	{Usertype: "student", Loandays: 14, Maxloans: 5, Maxrenewals: 2, Fineperdaycents: 25, Gracedays: 2, Maxfinecents: 1000, Fineblockcents: 500, Holdpickupdays: 3, Updatedby: "system"},
+	{Usertype: "lecturer", Loandays: 30, Maxloans: 15, Maxrenewals: 3, Fineperdaycents: 10, Gracedays: 3, Maxfinecents: 500, Fineblockcents: 1000, Holdpickupdays: 3, Updatedby: "system"},
+}
+
+// ValidateLoanPolicy validates a loan policy before it is saved, max_loans 0 suspends borrowing
+func ValidateLoanPolicy(p LoanPolicy) error {
+	if p.Usertype != "student" && p.Usertype != "lecturer" {
+		return fmt.Errorf("usertype must be student or lecturer")
+	}
+	if p.Loandays <= 0 || p.Loandays > 365 {
+		return fmt.Errorf("loan_days must be between 1 and 365")
+	}
+	if p.Maxloans < 0 {
+		return fmt.Errorf("max_loans cannot be negative")
+	}
+	if p.Maxrenewals < 0 {
+		return fmt.Errorf("max_renewals cannot be negative")
+	}
+	if p.Fineperdaycents < 0 || p.Maxfinecents < 0 || p.Fineblockcents < 0 {
+		return fmt.Errorf("fine amounts cannot be negative")
+	}
+	if p.Gracedays < 0 || p.Gracedays > 365 {
+		return fmt.Errorf("grace_days must be between 0 and 365")
+	}
+	if p.Holdpickupdays <= 0 || p.Holdpickupdays > 30 {
+		return fmt.Errorf("hold_pickup_days must be between 1 and 30")
+	}
+	return nil
+}
+
+// loanLimitError explains why a borrow was refused by the borrower's policy
+func loanLimitError(record Borrowrecords, open int, p LoanPolicy) error {
+	return fmt.Errorf("%w: %s %d already has %d of %d loans open", ErrLoanLimit, record.Usertype, record.Userid, open, p.Maxloans)
+}
+
+// renewalLimitError explains why a renewal was refused by the borrower's policy
+func renewalLimitError(loan Borrowrecords, p LoanPolicy) error {
+	return fmt.Errorf("%w: loan %d was renewed %d of %d times", ErrRenewalLimit, loan.Borrowid, loan.Renewals, p.Maxrenewals)
+}
+
+// writeLoanError maps borrow and renewal errors to HTTP responses, refusals carry the reason
+func writeLoanError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrCopyNotFound), errors.Is(err, ErrNoOpenBorrow):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrCopyUnavailable), errors.Is(err, ErrLoanLimit), errors.Is(err, ErrRenewalLimit), errors.Is(err, ErrFinesUnpaid),
+		errors.Is(err, ErrLoanOverdue), errors.Is(err, ErrHoldsWaiting):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrNoLoanPolicy):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// GetLoanPoliciesHandler lists the loan policies
+func (a *HybridHandler) GetLoanPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	policies, err := a.Libraries.LoanPolicies(r.Context())
+	if err != nil {
+		http.Error(w, "unable to fetch loan policies", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// SaveLoanPolicyHandler creates or replaces the policy of a user type, librarian only.
+// Open loans keep their due dates, the new policy applies from the next borrow or renewal.
+func (a *HybridHandler) SaveLoanPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleLibrarian) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var policy LoanPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	policy.Usertype = mux.Vars(r)["usertype"]
+
+	// validate policy data
+	if err := ValidateLoanPolicy(policy); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	policy.Updatedby, policy.Updatedat = currentUser(r), time.Now()
+	if err := a.Libraries.SaveLoanPolicy(r.Context(), policy); err != nil {
+		http.Error(w, "failed to save loan policy", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("SAVE_LOAN_POLICY", policy.Updatedby)
+	go AuditLog("SAVE", "LOAN_POLICY", policy.Usertype, policy.Updatedby)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// RenewLoanHandler extends an open loan that is not overdue and that nobody holds the book for,
+// by the loan period of its user type, up to max_renewals times
+func (a *HybridHandler) RenewLoanHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	loan, err := a.Libraries.Renew(r.Context(), id)
+	if err != nil {
+		writeLoanError(w, err, "failed to renew loan")
+		return
+	}
+
+	go LogActivity("RENEW_LOAN", "system")
+	go AuditLog("RENEW", "LOAN", loan.Borrowid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loan)
+}
@@ -0,0 +1,137 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"project/db/migrations"
+
+	"github.com/joho/godotenv"
+)
+
+// migrateUsage describes the migrate subcommand
+const migrateUsage = `usage: migrate <command>
+  up         apply every pending migration
+  down [N]   revert the last N migrations (default 1)
+  goto V     apply or revert migrations until version V, 0 reverts everything
+  force V    record version V as applied without running SQL, after a manual repair or to baseline a database
+  status     list migrations and whether they are applied`
+
+// migrateOnStart reports whether pending MySQL migrations run when the server starts
+func migrateOnStart() bool {
+	on, err := strconv.ParseBool(os.Getenv("MIGRATE_ON_START"))
+	return err != nil || on
+}
+
+// MigrateMySQL applies every pending embedded migration
+func MigrateMySQL(ctx context.Context, m *MySQLInstance) error {
+	runner, err := migrations.New(m.db)
+	if err != nil {
+		return err
+	}
+	runner.Logf = log.Printf
+	return runner.Up(ctx)
+}
+
+// MigrateCommand runs the migrate subcommand and returns the process exit code
+func MigrateCommand(args []string) int {
+
+	// Load environment variables from .env file
+	godotenv.Load()
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	// parse the optional numeric argument
+	num := -1
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		num = n
+	}
+
+	mysqlinstance, err := ConnectMySQL()
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	defer mysqlinstance.db.Close()
+
+	runner, err := migrations.New(mysqlinstance.db)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	runner.Logf = log.Printf
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		err = runner.Up(ctx)
+	case "down":
+		if num < 0 {
+			num = 1
+		}
+		err = runner.Down(ctx, num)
+	case "goto", "force":
+		if num < 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if args[0] == "goto" {
+			err = runner.Goto(ctx, num)
+		} else {
+			err = runner.Force(ctx, num)
+		}
+	case "status":
+		err = printMigrationStatus(ctx, runner)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// printMigrationStatus writes the migration table to stdout
+func printMigrationStatus(ctx context.Context, runner *migrations.Runner) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state, at := "pending", ""
+		switch {
+		case s.Dirty:
+			state = "dirty"
+		case s.Unknown:
+			state = "unknown"
+		case s.Modified:
+			state = "modified"
+		case s.Applied:
+			state = "applied"
+		}
+		if s.Appliedat != nil {
+			at = s.Appliedat.Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%06d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,211 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoMigrationsCollection records applied MongoDB migrations, like schema_migrations for MySQL
+const mongoMigrationsCollection = "schema_migrations"
+
+// how long to wait for another instance applying a migration, how often to check on it, and how old
+// a dirty record must be before it is taken for a migration that stopped midway
+var (
+	mongoClaimWait  = 2 * time.Minute
+	mongoClaimPoll  = time.Second
+	mongoStaleClaim = 10 * time.Minute
+)
+
+// errors returned by MigrateMongoDB
+var (
+	ErrStaleMongoMigration   = errors.New("mongo migration stopped midway")
+	ErrMongoMigrationTimeout = errors.New("timed out waiting for another instance to apply a mongo migration")
+	ErrDuplicateEmails       = errors.New("lecturers share an email, remove the duplicates before the unique index can be built")
+)
+
+// MongoMigration is one versioned change to the MongoDB schema.
+// Up must be idempotent, a failed migration is retried from the start on the next boot.
+type MongoMigration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// appliedMigration is the record kept in schema_migrations
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	Dirty       bool      `bson:"dirty"`
+	Appliedat   time.Time `bson:"applied_at"`
+}
+
+// lecturerSchema mirrors ValidateLecturer so documents written around the API are checked too
+var lecturerSchema = bson.M{
+	"bsonType": "object",
+	"required": bson.A{"name", "age", "email", "designation"},
+	"properties": bson.M{
+		"name":        bson.M{"bsonType": "string", "minLength": 1},
+		"age":         bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1, "maximum": 99},
+		"email":       bson.M{"bsonType": "string", "pattern": `^[^@\s]+@gmail\.com$`},
+		"designation": bson.M{"bsonType": "string", "minLength": 1},
+		"department":  bson.M{"bsonType": "string"},
+	},
+}
+
+// mongoMigrations lists every MongoDB migration in version order
+var mongoMigrations = []MongoMigration{
+	{
+		Version:     1,
+		Description: "create lecturers collection with a $jsonSchema validator",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return ensureCollection(ctx, db, "lecturers", lecturerSchema)
+		},
+	},
+	{
+		Version:     2,
+		Description: "index lecturers by unique email, designation and text",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := checkUniqueEmails(ctx, db.Collection("lecturers")); err != nil {
+				return err
+			}
+			return ensureIndexes(ctx, db.Collection("lecturers"), []mongo.IndexModel{
+				{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("uq_lecturer_email").SetUnique(true)},
+				{Keys: bson.D{{Key: "designation", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("idx_lecturer_designation")},
+				{Keys: bson.D{{Key: "department", Value: 1}}, Options: options.Index().SetName("idx_lecturer_department")},
+				{
+					Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "designation", Value: "text"}, {Key: "department", Value: "text"}},
+					Options: options.Index().SetName("txt_lecturer").SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "designation", Value: 2}, {Key: "department", Value: 1}}),
+				},
+			})
+		},
+	},
+}
+
+// ensureCollection creates a collection with a validator, or updates the validator of an existing one.
+// Existing documents are only checked when they are next updated.
+func ensureCollection(ctx context.Context, db *mongo.Database, name string, schema bson.M) error {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	validator := bson.M{"$jsonSchema": schema}
+	if len(names) == 0 {
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate").SetValidationAction("error")
+		return db.CreateCollection(ctx, name, opts)
+	}
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: name},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}).Err()
+}
+
+// checkUniqueEmails lists emails held by more than one document, so a unique index
+// that would fail on them is reported with the documents to fix
+func checkUniqueEmails(ctx context.Context, coll *mongo.Collection) error {
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$email", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$limit", Value: 20}},
+	})
+	if err != nil {
+		return err
+	}
+	var dupes []struct {
+		Email string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &dupes); err != nil {
+		return err
+	}
+	if len(dupes) == 0 {
+		return nil
+	}
+	listed := make([]string, len(dupes))
+	for i, d := range dupes {
+		listed[i] = fmt.Sprintf("%s (%d)", d.Email, d.Count)
+	}
+	return fmt.Errorf("%w: %s", ErrDuplicateEmails, strings.Join(listed, ", "))
+}
+
+// ensureIndexes creates indexes, those that already exist with the same definition are left alone
+func ensureIndexes(ctx context.Context, coll *mongo.Collection, models []mongo.IndexModel) error {
+	_, err := coll.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// checkClaim decides what to do about a migration another instance has claimed: keep waiting (nil),
+// give up because the claim is older than mongoStaleClaim, or give up because the wait passed its deadline
+func checkClaim(claim appliedMigration, now, deadline time.Time) error {
+	if now.Sub(claim.Appliedat) > mongoStaleClaim {
+		return fmt.Errorf("%w: migration %d has been dirty since %s, delete its %s record to retry",
+			ErrStaleMongoMigration, claim.Version, claim.Appliedat.Format(time.RFC3339), mongoMigrationsCollection)
+	}
+	if now.After(deadline) {
+		return fmt.Errorf("%w: migration %d", ErrMongoMigrationTimeout, claim.Version)
+	}
+	return nil
+}
+
+// MigrateMongoDB applies every migration missing from schema_migrations, in version order.
+// Each migration is claimed with a dirty record first, so two instances booting together never run the same one;
+// the instance that loses the claim waits for the other to finish, like the MySQL runner waits on its lock.
+func MigrateMongoDB(ctx context.Context, db *mongo.Database) error {
+	records := db.Collection(mongoMigrationsCollection)
+	for _, m := range mongoMigrations {
+		deadline := time.Now().Add(mongoClaimWait)
+		for {
+			var applied appliedMigration
+			err := records.FindOne(ctx, bson.M{"_id": m.Version}).Decode(&applied)
+			if err == nil && !applied.Dirty {
+				break
+			}
+			if err == nil {
+				// another instance holds the claim, wait for it to finish or release it
+				if err := checkClaim(applied, time.Now(), deadline); err != nil {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(mongoClaimPoll):
+				}
+				continue
+			}
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				return err
+			}
+
+			// claim the migration, losing the race means waiting like above
+			claim := appliedMigration{Version: m.Version, Description: m.Description, Dirty: true, Appliedat: time.Now()}
+			if _, err := records.InsertOne(ctx, claim); err != nil {
+				if mongo.IsDuplicateKeyError(err) {
+					continue
+				}
+				return err
+			}
+
+			if err := m.Up(ctx, db); err != nil {
+				// release the claim so the migration is retried once the cause is fixed
+				records.DeleteOne(ctx, bson.M{"_id": m.Version})
+				return fmt.Errorf("mongo migration %d (%s): %w", m.Version, m.Description, err)
+			}
+			if _, err := records.UpdateOne(ctx, bson.M{"_id": m.Version}, bson.M{"$set": bson.M{"dirty": false, "applied_at": time.Now()}}); err != nil {
+				return err
+			}
+			log.Printf("applied mongo migration %d: %s\n", m.Version, m.Description)
+			break
+		}
+	}
+	return nil
+}
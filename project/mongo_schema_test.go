@@ -0,0 +1,29 @@
+package project
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckClaim(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		claimed  time.Time
+		deadline time.Time
+		want     error
+	}{
+		{"fresh claim", now.Add(-time.Second), now.Add(time.Minute), nil},
+		{"claim just inside the stale age", now.Add(-mongoStaleClaim), now.Add(time.Minute), nil},
+		{"wait past the deadline", now.Add(-time.Minute), now.Add(-time.Second), ErrMongoMigrationTimeout},
+		{"stale claim", now.Add(-mongoStaleClaim - time.Second), now.Add(time.Minute), ErrStaleMongoMigration},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkClaim(appliedMigration{Version: 2, Dirty: true, Appliedat: tc.claimed}, now, tc.deadline)
+			if tc.want == nil && err != nil || tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChargeRequest asks a gateway to collect money with a tokenised payment method
+// Reference is our id for the attempt, gateways use it to make a retried charge idempotent.
+type ChargeRequest struct {
+	Amountcents int64
+	Currency    string
+	Token       string
+	Description string
+	Reference   string
+}
+
+// PaymentGateway collects and refunds card payments. Declines are returned as *PaymentDeclinedError,
+// any other error means the outcome is unknown.
+type PaymentGateway interface {
+	Name() string
+	Charge(ctx context.Context, req ChargeRequest) (ref string, err error)
+	Refund(ctx context.Context, ref string, amountCents int64) error
+}
+
+// PaymentDeclinedError is a definite refusal from the gateway
+type PaymentDeclinedError struct {
+	Reason string
+}
+
+func (e *PaymentDeclinedError) Error() string {
+	return "payment declined: " + e.Reason
+}
+
+// errors returned by NewPaymentGateway
+var (
+	ErrUnknownGateway    = errors.New("unknown payment gateway")
+	ErrGatewayKeyMissing = errors.New("payment gateway secret key is not set")
+)
+
+// NewPaymentGateway returns the gateway PAYMENT_GATEWAY names at startup. An empty name returns nil,
+// which leaves card payments and refunds switched off rather than accepting payments nobody collects.
+func NewPaymentGateway(name string) (PaymentGateway, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "stripe":
+		key := os.Getenv("STRIPE_SECRET_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("%w: STRIPE_SECRET_KEY", ErrGatewayKeyMissing)
+		}
+		return NewStripeGateway(key), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownGateway, name)
+	}
+}
+
+// StripeGateway charges tokenised cards through the Stripe PaymentIntents API.
+// The token is a Stripe payment method id collected by the client.
+type StripeGateway struct {
+	SecretKey string
+	BaseURL   string
+	Client    *http.Client
+}
+
+// NewStripeGateway returns a gateway for the Stripe API authenticated with a secret key
+func NewStripeGateway(secretKey string) *StripeGateway {
+	return &StripeGateway{SecretKey: secretKey, BaseURL: "https://api.stripe.com", Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name identifies the gateway in payment records
+func (g *StripeGateway) Name() string {
+	return "stripe"
+}
+
+// stripeObject is the part of a Stripe payment intent or refund the gateway reads
+type stripeObject struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// post sends a form to the Stripe API. Card errors and rejected requests are declines,
+// since Stripe took no money, anything else leaves the outcome unknown.
+func (g *StripeGateway) post(ctx context.Context, path, idempotencyKey string, form url.Values) (*stripeObject, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(g.SecretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	res, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var obj stripeObject
+	if err := json.NewDecoder(res.Body).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("stripe %s: status %d: %w", path, res.StatusCode, err)
+	}
+	if res.StatusCode == http.StatusOK {
+		return &obj, nil
+	}
+	reason := res.Status
+	if obj.Error != nil {
+		reason = obj.Error.Message
+	}
+	if res.StatusCode == http.StatusPaymentRequired || res.StatusCode == http.StatusBadRequest {
+		return nil, &PaymentDeclinedError{Reason: reason}
+	}
+	return nil, fmt.Errorf("stripe %s: %s", path, reason)
+}
+
+// Charge creates and confirms a payment intent, only a succeeded intent counts as collected
+func (g *StripeGateway) Charge(ctx context.Context, req ChargeRequest) (string, error) {
+	form := url.Values{
+		"amount":                             {strconv.FormatInt(req.Amountcents, 10)},
+		"currency":                           {strings.ToLower(req.Currency)},
+		"payment_method":                     {req.Token},
+		"description":                        {req.Description},
+		"confirm":                            {"true"},
+		"automatic_payment_methods[enabled]": {"true"},
+		"automatic_payment_methods[allow_redirects]": {"never"},
+	}
+	intent, err := g.post(ctx, "/v1/payment_intents", req.Reference, form)
+	if err != nil {
+		return "", err
+	}
+	switch intent.Status {
+	case "succeeded":
+		return intent.ID, nil
+	case "requires_payment_method", "requires_action", "canceled":
+		return "", &PaymentDeclinedError{Reason: "payment intent " + intent.ID + " is " + intent.Status}
+	default:
+		return "", fmt.Errorf("payment intent %s is %s", intent.ID, intent.Status)
+	}
+}
+
+// Refund returns part or all of a succeeded payment intent
+func (g *StripeGateway) Refund(ctx context.Context, ref string, amountCents int64) error {
+	form := url.Values{
+		"payment_intent": {ref},
+		"amount":         {strconv.FormatInt(amountCents, 10)},
+	}
+	refund, err := g.post(ctx, "/v1/refunds", "", form)
+	if err != nil {
+		return err
+	}
+	if refund.Status == "failed" || refund.Status == "canceled" {
+		return &PaymentDeclinedError{Reason: "refund " + refund.ID + " is " + refund.Status}
+	}
+	return nil
+}
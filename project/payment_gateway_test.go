@@ -0,0 +1,196 @@
+package project
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// FakeGateway is an in-memory gateway for tests.
+// The token "decline" is refused, "error" fails with an unknown outcome, anything else succeeds.
+type FakeGateway struct {
+	mu      sync.Mutex
+	charges map[string]int64
+}
+
+// NewFakeGateway returns an empty fake gateway
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{charges: map[string]int64{}}
+}
+
+// Name identifies the gateway in payment records
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+// Charge records the amount against a new reference
+func (g *FakeGateway) Charge(ctx context.Context, req ChargeRequest) (string, error) {
+	switch req.Token {
+	case "decline":
+		return "", &PaymentDeclinedError{Reason: "card declined"}
+	case "error":
+		return "", errors.New("gateway unavailable")
+	}
+	if req.Amountcents <= 0 {
+		return "", &PaymentDeclinedError{Reason: "invalid amount"}
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ref := "fake_" + hex.EncodeToString(b)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.charges[ref] = req.Amountcents
+	return ref, nil
+}
+
+// Refund returns part or all of a recorded charge
+func (g *FakeGateway) Refund(ctx context.Context, ref string, amountCents int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	charged, ok := g.charges[ref]
+	if !ok {
+		return &PaymentDeclinedError{Reason: "unknown charge " + ref}
+	}
+	if amountCents <= 0 || amountCents > charged {
+		return &PaymentDeclinedError{Reason: fmt.Sprintf("refund of %d exceeds refundable %d", amountCents, charged)}
+	}
+	g.charges[ref] = charged - amountCents
+	return nil
+}
+
+func TestNewPaymentGateway(t *testing.T) {
+	g, err := NewPaymentGateway("")
+	if err != nil || g != nil {
+		t.Fatalf("unset gateway: got %v, %v, want nil, nil", g, err)
+	}
+	for _, name := range []string{"fake", "paypal"} {
+		if _, err := NewPaymentGateway(name); !errors.Is(err, ErrUnknownGateway) {
+			t.Errorf("%s: got %v, want %v", name, err, ErrUnknownGateway)
+		}
+	}
+
+	t.Setenv("STRIPE_SECRET_KEY", "")
+	if _, err := NewPaymentGateway("stripe"); !errors.Is(err, ErrGatewayKeyMissing) {
+		t.Fatalf("stripe without a key: got %v, want %v", err, ErrGatewayKeyMissing)
+	}
+	t.Setenv("STRIPE_SECRET_KEY", "sk_test_123")
+	g, err = NewPaymentGateway("stripe")
+	if err != nil || g == nil || g.Name() != "stripe" {
+		t.Fatalf("stripe: got %v, %v", g, err)
+	}
+}
+
+// newStripeServer serves the Stripe API from handle and returns a gateway pointed at it
+func newStripeServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request)) *StripeGateway {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, _, _ := r.BasicAuth(); key != "sk_test_123" {
+			t.Errorf("%s authenticated as %q", r.URL.Path, key)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		handle(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	g := NewStripeGateway("sk_test_123")
+	g.BaseURL = srv.URL
+	return g
+}
+
+func TestStripeGatewayCharge(t *testing.T) {
+	g := newStripeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/payment_intents" {
+			t.Errorf("charge posted to %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Idempotency-Key"); got != "payment-7" {
+			t.Errorf("idempotency key %q, want payment-7", got)
+		}
+		for field, want := range map[string]string{"amount": "1250", "currency": "usd", "confirm": "true"} {
+			if got := r.PostForm.Get(field); got != want {
+				t.Errorf("%s = %q, want %q", field, got, want)
+			}
+		}
+		switch r.PostForm.Get("payment_method") {
+		case "pm_card_visa":
+			fmt.Fprint(w, `{"id":"pi_1","status":"succeeded"}`)
+		case "pm_card_3ds":
+			fmt.Fprint(w, `{"id":"pi_2","status":"requires_action"}`)
+		case "pm_card_declined":
+			w.WriteHeader(http.StatusPaymentRequired)
+			fmt.Fprint(w, `{"error":{"type":"card_error","message":"Your card was declined."}}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error":{"type":"api_error","message":"try again"}}`)
+		}
+	})
+	ctx := context.Background()
+	charge := func(token string) (string, error) {
+		return g.Charge(ctx, ChargeRequest{Amountcents: 1250, Currency: "USD", Token: token, Reference: "payment-7"})
+	}
+
+	ref, err := charge("pm_card_visa")
+	if err != nil || ref != "pi_1" {
+		t.Fatalf("good card: got %q, %v, want pi_1", ref, err)
+	}
+	var declined *PaymentDeclinedError
+	for _, token := range []string{"pm_card_declined", "pm_card_3ds"} {
+		if _, err := charge(token); !errors.As(err, &declined) {
+			t.Errorf("%s: got %v, want a decline", token, err)
+		}
+	}
+	if _, err := charge("pm_server_error"); err == nil || errors.As(err, &declined) {
+		t.Fatalf("server error: got %v, want an unknown outcome", err)
+	}
+}
+
+func TestStripeGatewayRefund(t *testing.T) {
+	g := newStripeServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/refunds" || r.PostForm.Get("payment_intent") != "pi_1" {
+			t.Errorf("refund posted to %s for %q", r.URL.Path, r.PostForm.Get("payment_intent"))
+		}
+		if r.PostForm.Get("amount") == "9999" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"Refund amount is greater than unrefunded amount"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"re_1","status":"succeeded"}`)
+	})
+	ctx := context.Background()
+	if err := g.Refund(ctx, "pi_1", 500); err != nil {
+		t.Fatal(err)
+	}
+	var declined *PaymentDeclinedError
+	if err := g.Refund(ctx, "pi_1", 9999); !errors.As(err, &declined) {
+		t.Fatalf("refund above the charge: got %v, want a decline", err)
+	}
+}
+
+func TestFakeGatewayRefundsUpToTheCharge(t *testing.T) {
+	var g PaymentGateway = NewFakeGateway()
+	ctx := context.Background()
+	ref, err := g.Charge(ctx, ChargeRequest{Amountcents: 1000, Currency: "USD", Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Refund(ctx, ref, 600); err != nil {
+		t.Fatal(err)
+	}
+	var declined *PaymentDeclinedError
+	if err := g.Refund(ctx, ref, 500); !errors.As(err, &declined) {
+		t.Fatalf("refund above the rest: got %v, want a decline", err)
+	}
+	if _, err := g.Charge(ctx, ChargeRequest{Amountcents: 1000, Token: "decline"}); !errors.As(err, &declined) {
+		t.Fatalf("declined token: got %v, want a decline", err)
+	}
+}
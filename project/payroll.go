@@ -0,0 +1,920 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// pay component kinds and calculations
+const (
+	ComponentAllowance = "allowance"
+	ComponentDeduction = "deduction"
+
+	CalcFixed   = "fixed"
+	CalcPercent = "percent"
+)
+
+// payslip line kinds, in the order they are printed
+const (
+	LineBasic       = "basic"
+	LineAllowance   = "allowance"
+	LineUnpaidLeave = "unpaid_leave"
+	LineDeduction   = "deduction"
+)
+
+// PayGrade maps a lecturer designation to a grade and monthly basic pay
+type PayGrade struct {
+	Designation string    `json:"designation"`
+	Grade       string    `json:"grade"`
+	Basiccents  int64     `json:"basic_cents"`
+	Currency    string    `json:"currency"`
+	Updatedby   string    `json:"updated_by,omitempty"`
+	Updatedat   time.Time `json:"updated_at"`
+}
+
+// PayComponent is a monthly allowance or deduction, for every designation or just one.
+// Percent allowances are taken of basic pay, percent deductions of gross pay.
+type PayComponent struct {
+	Componentid int       `json:"component_id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Calc        string    `json:"calc"`
+	Amountcents int64     `json:"amount_cents"`
+	Percent     float64   `json:"percent"`
+	Designation string    `json:"designation,omitempty"`
+	Active      bool      `json:"active"`
+	Createdat   time.Time `json:"created_at"`
+}
+
+// PayslipLine is one earning or deduction on a payslip
+type PayslipLine struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Amountcents int64  `json:"amount_cents"`
+}
+
+// Payslip is a lecturer's pay for one month. It copies everything it was computed from and is never changed.
+type Payslip struct {
+	Payslipid        int           `json:"payslip_id"`
+	Runid            int           `json:"run_id"`
+	Lecturerid       string        `json:"lecturer_id"`
+	Period           string        `json:"period"`
+	Name             string        `json:"name"`
+	Email            string        `json:"email"`
+	Designation      string        `json:"designation"`
+	Grade            string        `json:"grade"`
+	Currency         string        `json:"currency"`
+	Basiccents       int64         `json:"basic_cents"`
+	Allowancescents  int64         `json:"allowances_cents"`
+	Unpaidleavedays  int           `json:"unpaid_leave_days"`
+	Unpaidleavecents int64         `json:"unpaid_leave_cents"`
+	Grosscents       int64         `json:"gross_cents"`
+	Deductionscents  int64         `json:"deductions_cents"`
+	Netcents         int64         `json:"net_cents"`
+	Reference        string        `json:"reference"`
+	Createdat        time.Time     `json:"created_at"`
+	Lines            []PayslipLine `json:"lines,omitempty"`
+}
+
+// PayrollRun is the monthly payroll of every lecturer
+type PayrollRun struct {
+	Runid    int              `json:"run_id"`
+	Period   string           `json:"period"`
+	Payslips int              `json:"payslips"`
+	Skipped  int              `json:"skipped"`
+	Totals   []PayrollTotal   `json:"totals"`
+	Runby    string           `json:"run_by"`
+	Runat    time.Time        `json:"run_at"`
+	Slips    []Payslip        `json:"slips,omitempty"`
+	Skips    []SkippedPayslip `json:"skipped_lecturers,omitempty"`
+}
+
+// PayrollTotal is what a run pays in one currency, pay grades may use different currencies
+type PayrollTotal struct {
+	Currency   string `json:"currency"`
+	Grosscents int64  `json:"gross_cents"`
+	Netcents   int64  `json:"net_cents"`
+}
+
+// SkippedPayslip is a lecturer left out of a run because their designation has no pay grade
+type SkippedPayslip struct {
+	Lecturerid  string `json:"lecturer_id"`
+	Name        string `json:"name"`
+	Designation string `json:"designation"`
+}
+
+// errors returned by the payroll helpers
+var (
+	ErrPayrollRunExists   = errors.New("payroll has already been run for this period")
+	ErrPayrollRunNotFound = errors.New("payroll run not found")
+	ErrPayslipNotFound    = errors.New("payslip not found")
+	ErrComponentNotFound  = errors.New("pay component not found")
+	ErrPeriodNotEnded     = errors.New("payroll can only be run for a month that has ended")
+	ErrNoPayGrades        = errors.New("no lecturer has a pay grade for their designation")
+)
+
+// ValidatePayGrade validates a pay grade before DB operations
+func ValidatePayGrade(g PayGrade) error {
+	if strings.TrimSpace(g.Designation) == "" || strings.TrimSpace(g.Grade) == "" {
+		return fmt.Errorf("designation and grade cannot be empty")
+	}
+	if g.Basiccents <= 0 {
+		return fmt.Errorf("basic_cents must be greater than 0")
+	}
+	if len(g.Currency) != 3 {
+		return fmt.Errorf("currency must be a 3 letter code")
+	}
+	return nil
+}
+
+// ValidatePayComponent validates a pay component before DB operations
+func ValidatePayComponent(c PayComponent) error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if c.Kind != ComponentAllowance && c.Kind != ComponentDeduction {
+		return fmt.Errorf("kind must be allowance or deduction")
+	}
+	switch c.Calc {
+	case CalcFixed:
+		if c.Amountcents <= 0 {
+			return fmt.Errorf("fixed components need amount_cents greater than 0")
+		}
+	case CalcPercent:
+		if c.Percent <= 0 || c.Percent > 100 {
+			return fmt.Errorf("percent components need a percent between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("calc must be fixed or percent")
+	}
+	return nil
+}
+
+// parsePeriod parses a YYYY-MM payroll period into the first day of the month
+func parsePeriod(period string) (time.Time, error) {
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return start, fmt.Errorf("period must be YYYY-MM")
+	}
+	return start, nil
+}
+
+// periodEnded reports whether the last day of the month starting at monthStart has passed.
+// Leave approved later in a month changes its pay, so a month is only paid once it is over.
+func periodEnded(monthStart, now time.Time) bool {
+	next := time.Date(monthStart.Year(), monthStart.Month()+1, 1, 0, 0, 0, 0, now.Location())
+	return !now.Before(next)
+}
+
+// addPayrollTotal adds a payslip to the run total of its currency
+func addPayrollTotal(totals []PayrollTotal, slip Payslip) []PayrollTotal {
+	for i := range totals {
+		if totals[i].Currency == slip.Currency {
+			totals[i].Grosscents += slip.Grosscents
+			totals[i].Netcents += slip.Netcents
+			return totals
+		}
+	}
+	return append(totals, PayrollTotal{Currency: slip.Currency, Grosscents: slip.Grosscents, Netcents: slip.Netcents})
+}
+
+// formatCents renders an amount in cents as a decimal
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// percentOf returns pct percent of cents, rounded to the nearest cent
+func percentOf(cents int64, pct float64) int64 {
+	return int64(math.Round(float64(cents) * pct / 100))
+}
+
+// computePayslip works out a lecturer's pay for a month.
+// Unpaid leave is docked pro rata from basic pay plus allowances before deductions are applied.
+// Deductions stop at gross pay, the one that would take net pay below zero is cut short and later ones are left out.
+func computePayslip(l Lecturer, grade PayGrade, components []PayComponent, unpaidDays, daysInMonth int) Payslip {
+	slip := Payslip{
+		Lecturerid:  l.Id.Hex(),
+		Name:        l.Name,
+		Email:       l.Email,
+		Designation: l.Designation,
+		Grade:       grade.Grade,
+		Currency:    grade.Currency,
+		Basiccents:  grade.Basiccents,
+		Lines:       []PayslipLine{{Kind: LineBasic, Name: "Basic pay (" + grade.Grade + ")", Amountcents: grade.Basiccents}},
+	}
+	applies := func(c PayComponent) bool {
+		return c.Active && (c.Designation == "" || strings.EqualFold(c.Designation, l.Designation))
+	}
+
+	for _, c := range components {
+		if c.Kind != ComponentAllowance || !applies(c) {
+			continue
+		}
+		amount := c.Amountcents
+		if c.Calc == CalcPercent {
+			amount = percentOf(slip.Basiccents, c.Percent)
+		}
+		slip.Allowancescents += amount
+		slip.Lines = append(slip.Lines, PayslipLine{Kind: LineAllowance, Name: c.Name, Amountcents: amount})
+	}
+
+	earnings := slip.Basiccents + slip.Allowancescents
+	if unpaidDays > daysInMonth {
+		unpaidDays = daysInMonth
+	}
+	if unpaidDays > 0 {
+		slip.Unpaidleavedays = unpaidDays
+		slip.Unpaidleavecents = int64(math.Round(float64(earnings) * float64(unpaidDays) / float64(daysInMonth)))
+		slip.Lines = append(slip.Lines, PayslipLine{Kind: LineUnpaidLeave, Name: fmt.Sprintf("Unpaid leave (%d days)", unpaidDays), Amountcents: slip.Unpaidleavecents})
+	}
+	slip.Grosscents = earnings - slip.Unpaidleavecents
+
+	for _, c := range components {
+		if c.Kind != ComponentDeduction || !applies(c) {
+			continue
+		}
+		amount := c.Amountcents
+		if c.Calc == CalcPercent {
+			amount = percentOf(slip.Grosscents, c.Percent)
+		}
+		if left := slip.Grosscents - slip.Deductionscents; amount > left {
+			amount = left
+		}
+		if amount <= 0 {
+			continue
+		}
+		slip.Deductionscents += amount
+		slip.Lines = append(slip.Lines, PayslipLine{Kind: LineDeduction, Name: c.Name, Amountcents: amount})
+	}
+	slip.Netcents = slip.Grosscents - slip.Deductionscents
+	return slip
+}
+
+// loadPayGrades returns the pay grades keyed by lower-cased designation
+func loadPayGrades(q dbtx) (map[string]PayGrade, error) {
+	rows, err := q.Query("SELECT designation , grade , basic_cents , currency , updated_by , updated_at FROM pay_grades ORDER BY designation")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grades := map[string]PayGrade{}
+	for rows.Next() {
+		var g PayGrade
+		if err := rows.Scan(&g.Designation, &g.Grade, &g.Basiccents, &g.Currency, &g.Updatedby, &g.Updatedat); err != nil {
+			return nil, err
+		}
+		grades[strings.ToLower(g.Designation)] = g
+	}
+	return grades, rows.Err()
+}
+
+// loadPayComponents returns the pay components, only active ones unless all is set
+func loadPayComponents(q dbtx, all bool) ([]PayComponent, error) {
+	query := "SELECT component_id , name , kind , calc , amount_cents , percent , designation , active , created_at FROM pay_components"
+	if !all {
+		query += " WHERE active=TRUE"
+	}
+	rows, err := q.Query(query + " ORDER BY kind , component_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := []PayComponent{}
+	for rows.Next() {
+		var c PayComponent
+		var designation sql.NullString
+		if err := rows.Scan(&c.Componentid, &c.Name, &c.Kind, &c.Calc, &c.Amountcents, &c.Percent, &designation, &c.Active, &c.Createdat); err != nil {
+			return nil, err
+		}
+		c.Designation = designation.String
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// unpaidLeaveDays counts each lecturer's approved unpaid leave days falling inside a month
+func unpaidLeaveDays(q dbtx, monthStart, monthEnd time.Time) (map[string]int, error) {
+	rows, err := q.Query(`SELECT lr.lecturer_id , lr.start_date , lr.end_date FROM leave_requests lr
+		JOIN leave_types lt ON lt.leave_type=lr.leave_type
+		WHERE lr.status=? AND lt.paid=FALSE AND lr.start_date<=? AND lr.end_date>=?`,
+		LeaveApproved, monthEnd.Format("2006-01-02"), monthStart.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[string]int{}
+	for rows.Next() {
+		var id string
+		var from, to time.Time
+		if err := rows.Scan(&id, &from, &to); err != nil {
+			return nil, err
+		}
+		if from.Before(monthStart) {
+			from = monthStart
+		}
+		if to.After(monthEnd) {
+			to = monthEnd
+		}
+		days[id] += leaveDays(from, to)
+	}
+	return days, rows.Err()
+}
+
+// payrollTotals sums the payslips of the runs matching a where clause by run and currency
+func payrollTotals(q dbtx, where string, args ...interface{}) (map[int][]PayrollTotal, error) {
+	rows, err := q.Query("SELECT run_id , currency , SUM(gross_cents) , SUM(net_cents) FROM payslips WHERE "+where+" GROUP BY run_id , currency ORDER BY run_id , currency", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := map[int][]PayrollTotal{}
+	for rows.Next() {
+		var runID int
+		var t PayrollTotal
+		if err := rows.Scan(&runID, &t.Currency, &t.Grosscents, &t.Netcents); err != nil {
+			return nil, err
+		}
+		totals[runID] = append(totals[runID], t)
+	}
+	return totals, rows.Err()
+}
+
+// payslipColumns is the column list scanned by scanPayslip
+const payslipColumns = `payslip_id , run_id , lecturer_id , period , name , email , designation , grade , currency , basic_cents , allowances_cents ,
+	unpaid_leave_days , unpaid_leave_cents , gross_cents , deductions_cents , net_cents , reference , created_at`
+
+// scanPayslip reads one payslip row selected with payslipColumns
+func scanPayslip(row interface{ Scan(...interface{}) error }) (*Payslip, error) {
+	var p Payslip
+	err := row.Scan(&p.Payslipid, &p.Runid, &p.Lecturerid, &p.Period, &p.Name, &p.Email, &p.Designation, &p.Grade, &p.Currency, &p.Basiccents, &p.Allowancescents,
+		&p.Unpaidleavedays, &p.Unpaidleavecents, &p.Grosscents, &p.Deductionscents, &p.Netcents, &p.Reference, &p.Createdat)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// queryPayslips lists payslips matching a where clause, without their lines
+func queryPayslips(q dbtx, where string, args ...interface{}) ([]Payslip, error) {
+	rows, err := q.Query("SELECT "+payslipColumns+" FROM payslips WHERE "+where+" ORDER BY period DESC , name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slips := []Payslip{}
+	for rows.Next() {
+		p, err := scanPayslip(rows)
+		if err != nil {
+			return nil, err
+		}
+		slips = append(slips, *p)
+	}
+	return slips, rows.Err()
+}
+
+// loadPayslip fetches a payslip with its lines
+func loadPayslip(q dbtx, id int) (*Payslip, error) {
+	p, err := scanPayslip(q.QueryRow("SELECT "+payslipColumns+" FROM payslips WHERE payslip_id=?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPayslipNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query("SELECT kind , name , amount_cents FROM payslip_lines WHERE payslip_id=? ORDER BY line_no", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l PayslipLine
+		if err := rows.Scan(&l.Kind, &l.Name, &l.Amountcents); err != nil {
+			return nil, err
+		}
+		p.Lines = append(p.Lines, l)
+	}
+	return p, rows.Err()
+}
+
+// payslipLines renders a payslip as plain text lines for the PDF
+func payslipLines(p *Payslip) []string {
+	period, _ := parsePeriod(p.Period)
+	lines := []string{
+		"PAYSLIP - " + strings.ToUpper(period.Format("January 2006")),
+		"",
+		"Reference:   " + p.Reference,
+		"Name:        " + p.Name,
+		"Lecturer ID: " + p.Lecturerid,
+		"Designation: " + p.Designation + " (grade " + p.Grade + ")",
+		"",
+		fmt.Sprintf("%-40s %15s", "Earnings", p.Currency),
+	}
+	for _, l := range p.Lines {
+		if l.Kind == LineBasic || l.Kind == LineAllowance {
+			lines = append(lines, fmt.Sprintf("  %-38s %15s", l.Name, formatCents(l.Amountcents)))
+		}
+	}
+	for _, l := range p.Lines {
+		if l.Kind == LineUnpaidLeave {
+			lines = append(lines, fmt.Sprintf("  %-38s %15s", l.Name, formatCents(-l.Amountcents)))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%-40s %15s", "Gross pay", formatCents(p.Grosscents)), "", "Deductions")
+	for _, l := range p.Lines {
+		if l.Kind == LineDeduction {
+			lines = append(lines, fmt.Sprintf("  %-38s %15s", l.Name, formatCents(l.Amountcents)))
+		}
+	}
+	lines = append(lines,
+		fmt.Sprintf("%-40s %15s", "Total deductions", formatCents(p.Deductionscents)),
+		"",
+		fmt.Sprintf("%-40s %15s", "NET PAY", formatCents(p.Netcents)),
+		"",
+		"Issued "+p.Createdat.Format("2006-01-02"),
+	)
+	return lines
+}
+
+// requirePayslipAccess writes a 403 and returns false unless the current user is the payslip's lecturer or holds the hr role
+func (a *HybridHandler) requirePayslipAccess(w http.ResponseWriter, r *http.Request, p *Payslip) bool {
+	email := currentUser(r)
+	if email != "" && strings.EqualFold(email, p.Email) {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	if lecturer, err := a.findLecturer(ctx, p.Lecturerid); err == nil && email != "" && strings.EqualFold(email, lecturer.Email) {
+		return true
+	}
+	return a.requireRole(w, r, RoleHR)
+}
+
+// writePayrollError maps payroll errors to HTTP responses
+func writePayrollError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPayrollRunNotFound), errors.Is(err, ErrPayslipNotFound), errors.Is(err, ErrComponentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPayrollRunExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrPeriodNotEnded):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNoPayGrades):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrLecturerNotFound), errors.Is(err, ErrInvalidLecturerID):
+		writeLecturerLookupError(w, err)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SavePayGradeHandler creates or replaces the pay grade of a designation, hr only
+func (a *HybridHandler) SavePayGradeHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var grade PayGrade
+	if err := json.NewDecoder(r.Body).Decode(&grade); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	grade.Designation = mux.Vars(r)["designation"]
+	grade.Currency = strings.ToUpper(grade.Currency)
+
+	// validate grade data
+	if err := ValidatePayGrade(grade); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	grade.Updatedby, grade.Updatedat = currentUser(r), time.Now()
+	_, err := a.MySQL.db.Exec(`INSERT INTO pay_grades (designation , grade , basic_cents , currency , updated_by , updated_at) VALUES (? , ? , ? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE grade=VALUES(grade) , basic_cents=VALUES(basic_cents) , currency=VALUES(currency) , updated_by=VALUES(updated_by) , updated_at=VALUES(updated_at)`,
+		grade.Designation, grade.Grade, grade.Basiccents, grade.Currency, grade.Updatedby, grade.Updatedat)
+	if err != nil {
+		http.Error(w, "failed to save pay grade", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("SAVE_PAY_GRADE", grade.Updatedby)
+	go AuditLog("SAVE", "PAY_GRADE", fmt.Sprintf("%s grade=%s basic=%d", grade.Designation, grade.Grade, grade.Basiccents), grade.Updatedby)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grade)
+}
+
+// GetPayGradesHandler lists the pay grades, hr only
+func (a *HybridHandler) GetPayGradesHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+	rows, err := a.MySQL.db.Query("SELECT designation , grade , basic_cents , currency , updated_by , updated_at FROM pay_grades ORDER BY grade , designation")
+	if err != nil {
+		http.Error(w, "unable to fetch pay grades", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	grades := []PayGrade{}
+	for rows.Next() {
+		var g PayGrade
+		if err := rows.Scan(&g.Designation, &g.Grade, &g.Basiccents, &g.Currency, &g.Updatedby, &g.Updatedat); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		grades = append(grades, g)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grades)
+}
+
+// CreatePayComponentHandler adds an allowance or deduction, hr only
+func (a *HybridHandler) CreatePayComponentHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var c PayComponent
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// validate component data
+	if err := ValidatePayComponent(c); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	if c.Calc == CalcFixed {
+		c.Percent = 0
+	} else {
+		c.Amountcents = 0
+	}
+
+	var designation interface{}
+	if c.Designation != "" {
+		designation = c.Designation
+	}
+	c.Active, c.Createdat = true, time.Now()
+	res, err := a.MySQL.db.Exec("INSERT INTO pay_components (name , kind , calc , amount_cents , percent , designation , active , created_at) VALUES (? , ? , ? , ? , ? , ? , ? , ?)",
+		c.Name, c.Kind, c.Calc, c.Amountcents, c.Percent, designation, c.Active, c.Createdat)
+	if err != nil {
+		http.Error(w, "failed to create pay component", http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+	c.Componentid = int(id)
+
+	go LogActivity("CREATE_PAY_COMPONENT", currentUser(r))
+	go AuditLog("CREATE", "PAY_COMPONENT", c.Componentid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// GetPayComponentsHandler lists active pay components (?all=true includes retired ones), hr only
+func (a *HybridHandler) GetPayComponentsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+	components, err := loadPayComponents(a.MySQL.db, r.URL.Query().Get("all") == "true")
+	if err != nil {
+		http.Error(w, "unable to fetch pay components", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(components)
+}
+
+// RetirePayComponentHandler stops a component applying to future runs, hr only.
+// Components are never deleted since payslips already name them.
+func (a *HybridHandler) RetirePayComponentHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	res, err := a.MySQL.db.Exec("UPDATE pay_components SET active=FALSE WHERE component_id=?", id)
+	if err != nil {
+		http.Error(w, "failed to retire pay component", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists int
+		a.MySQL.db.QueryRow("SELECT COUNT(*) FROM pay_components WHERE component_id=?", id).Scan(&exists)
+		if exists == 0 {
+			writePayrollError(w, ErrComponentNotFound)
+			return
+		}
+	}
+
+	go LogActivity("RETIRE_PAY_COMPONENT", currentUser(r))
+	go AuditLog("RETIRE", "PAY_COMPONENT", id, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"component_id": id, "active": false})
+}
+
+// RunPayrollHandler computes and stores the payslips of every lecturer for a month, hr only.
+// A period can be run once; lecturers whose designation has no pay grade are skipped and listed.
+func (a *HybridHandler) RunPayrollHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Period string `json:"period"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	monthStart, err := parsePeriod(req.Period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !periodEnded(monthStart, time.Now()) {
+		writePayrollError(w, ErrPeriodNotEnded)
+		return
+	}
+	monthEnd := monthStart.AddDate(0, 1, -1)
+
+	// all lecturers from the lecturer store
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturers, err := a.Lecturers.FindAll(ctx, "")
+	if err != nil {
+		http.Error(w, "failed to fetch lecturers", http.StatusInternalServerError)
+		return
+	}
+
+	actor := currentUser(r)
+	run := &PayrollRun{Period: req.Period, Runby: actor, Runat: time.Now(), Totals: []PayrollTotal{}, Slips: []Payslip{}, Skips: []SkippedPayslip{}}
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		res, err := tx.Exec("INSERT IGNORE INTO payroll_runs (period , payslips , skipped , run_by , run_at) VALUES (? , 0 , 0 , ? , ?)", run.Period, run.Runby, run.Runat)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrPayrollRunExists
+		}
+		id, _ := res.LastInsertId()
+		run.Runid = int(id)
+
+		grades, err := loadPayGrades(tx)
+		if err != nil {
+			return err
+		}
+		components, err := loadPayComponents(tx, false)
+		if err != nil {
+			return err
+		}
+		unpaid, err := unpaidLeaveDays(tx, monthStart, monthEnd)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range lecturers {
+			grade, ok := grades[strings.ToLower(strings.TrimSpace(l.Designation))]
+			if !ok {
+				run.Skips = append(run.Skips, SkippedPayslip{Lecturerid: l.Id.Hex(), Name: l.Name, Designation: l.Designation})
+				continue
+			}
+			slip := computePayslip(l, grade, components, unpaid[l.Id.Hex()], monthEnd.Day())
+			slip.Runid, slip.Period, slip.Createdat = run.Runid, run.Period, run.Runat
+			if slip.Reference, err = newVerificationCode(); err != nil {
+				return err
+			}
+			res, err := tx.Exec(`INSERT INTO payslips (run_id , lecturer_id , period , name , email , designation , grade , currency , basic_cents , allowances_cents ,
+				unpaid_leave_days , unpaid_leave_cents , gross_cents , deductions_cents , net_cents , reference , created_at)
+				VALUES (? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ?)`,
+				slip.Runid, slip.Lecturerid, slip.Period, slip.Name, slip.Email, slip.Designation, slip.Grade, slip.Currency, slip.Basiccents, slip.Allowancescents,
+				slip.Unpaidleavedays, slip.Unpaidleavecents, slip.Grosscents, slip.Deductionscents, slip.Netcents, slip.Reference, slip.Createdat)
+			if err != nil {
+				return err
+			}
+			id, _ := res.LastInsertId()
+			slip.Payslipid = int(id)
+			for i, line := range slip.Lines {
+				if _, err := tx.Exec("INSERT INTO payslip_lines (payslip_id , line_no , kind , name , amount_cents) VALUES (? , ? , ? , ? , ?)",
+					slip.Payslipid, i+1, line.Kind, line.Name, line.Amountcents); err != nil {
+					return err
+				}
+			}
+			run.Totals = addPayrollTotal(run.Totals, slip)
+			slip.Lines = nil
+			run.Slips = append(run.Slips, slip)
+		}
+		if len(run.Slips) == 0 {
+			return ErrNoPayGrades
+		}
+
+		run.Payslips, run.Skipped = len(run.Slips), len(run.Skips)
+		if _, err := tx.Exec("UPDATE payroll_runs SET payslips=? , skipped=? WHERE run_id=?", run.Payslips, run.Skipped, run.Runid); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writePayrollError(w, err)
+		return
+	}
+
+	go LogActivity("RUN_PAYROLL", actor)
+	go AuditLog("RUN", "PAYROLL", fmt.Sprintf("%d period=%s payslips=%d skipped=%d", run.Runid, run.Period, run.Payslips, run.Skipped), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(run)
+}
+
+// GetPayrollRunsHandler lists payroll runs, hr only
+func (a *HybridHandler) GetPayrollRunsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+	totals, err := payrollTotals(a.MySQL.db, "1=1")
+	if err != nil {
+		http.Error(w, "unable to fetch payroll totals", http.StatusInternalServerError)
+		return
+	}
+	rows, err := a.MySQL.db.Query("SELECT run_id , period , payslips , skipped , run_by , run_at FROM payroll_runs ORDER BY period DESC")
+	if err != nil {
+		http.Error(w, "unable to fetch payroll runs", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	runs := []PayrollRun{}
+	for rows.Next() {
+		var run PayrollRun
+		if err := rows.Scan(&run.Runid, &run.Period, &run.Payslips, &run.Skipped, &run.Runby, &run.Runat); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		run.Totals = totals[run.Runid]
+		if run.Totals == nil {
+			run.Totals = []PayrollTotal{}
+		}
+		runs = append(runs, run)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// GetPayrollRunHandler returns a payroll run with its payslips, hr only
+func (a *HybridHandler) GetPayrollRunHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	var run PayrollRun
+	err = a.MySQL.db.QueryRow("SELECT run_id , period , payslips , skipped , run_by , run_at FROM payroll_runs WHERE run_id=?", id).
+		Scan(&run.Runid, &run.Period, &run.Payslips, &run.Skipped, &run.Runby, &run.Runat)
+	if err == sql.ErrNoRows {
+		writePayrollError(w, ErrPayrollRunNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if run.Slips, err = queryPayslips(a.MySQL.db, "run_id=?", id); err != nil {
+		http.Error(w, "unable to fetch payslips", http.StatusInternalServerError)
+		return
+	}
+	run.Totals = []PayrollTotal{}
+	for _, slip := range run.Slips {
+		run.Totals = addPayrollTotal(run.Totals, slip)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// GetLecturerPayslipsHandler lists a lecturer's payslips, for the lecturer or hr
+func (a *HybridHandler) GetLecturerPayslipsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract lecturer id from URL
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturer(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+	if !a.requireLecturerOrHR(w, r, lecturer) {
+		return
+	}
+
+	slips, err := queryPayslips(a.MySQL.db, "lecturer_id=?", lecturer.Id.Hex())
+	if err != nil {
+		http.Error(w, "unable to fetch payslips", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slips)
+}
+
+// GetPayslipHandler returns a payslip with its lines, for the lecturer or hr
+func (a *HybridHandler) GetPayslipHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	slip, err := loadPayslip(a.MySQL.db, id)
+	if err != nil {
+		writePayrollError(w, err)
+		return
+	}
+	if !a.requirePayslipAccess(w, r, slip) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slip)
+}
+
+// DownloadPayslipHandler renders a payslip as PDF, for the lecturer or hr
+func (a *HybridHandler) DownloadPayslipHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	slip, err := loadPayslip(a.MySQL.db, id)
+	if err != nil {
+		writePayrollError(w, err)
+		return
+	}
+	if !a.requirePayslipAccess(w, r, slip) {
+		return
+	}
+
+	go LogActivity("DOWNLOAD_PAYSLIP", currentUser(r))
+	go AuditLog("DOWNLOAD", "PAYSLIP", id, currentUser(r))
+
+	pdf := renderPDF(payslipLines(slip), map[string]string{
+		"Title":   "Payslip " + slip.Period + " - " + slip.Name,
+		"Subject": "Reference " + slip.Reference,
+		"Creator": "College Management System",
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=payslip-%s-%s.pdf", slip.Period, slip.Reference))
+	w.Write(pdf)
+}
@@ -0,0 +1,75 @@
+package project
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodEnded(t *testing.T) {
+	loc := time.FixedZone("IST", 5*60*60+30*60)
+	march, _ := parsePeriod("2026-03")
+	for _, tc := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2026, 2, 20, 12, 0, 0, 0, loc), false},
+		{time.Date(2026, 3, 1, 0, 0, 0, 0, loc), false},
+		{time.Date(2026, 3, 31, 23, 59, 59, 0, loc), false},
+		{time.Date(2026, 4, 1, 0, 0, 0, 0, loc), true},
+		{time.Date(2026, 5, 10, 9, 0, 0, 0, loc), true},
+	} {
+		if got := periodEnded(march, tc.now); got != tc.want {
+			t.Errorf("2026-03 ended at %s: got %v, want %v", tc.now, got, tc.want)
+		}
+	}
+}
+
+func TestComputePayslipNeverPaysBelowZero(t *testing.T) {
+	grade := PayGrade{Designation: "Lecturer", Grade: "L1", Basiccents: 100000, Currency: "INR"}
+	components := []PayComponent{
+		{Name: "Tax", Kind: ComponentDeduction, Calc: CalcPercent, Percent: 10, Active: true},
+		{Name: "Housing loan", Kind: ComponentDeduction, Calc: CalcFixed, Amountcents: 40000, Active: true},
+		{Name: "Union dues", Kind: ComponentDeduction, Calc: CalcFixed, Amountcents: 500, Active: true},
+	}
+	l := Lecturer{Name: "Ravi", Designation: "Lecturer"}
+
+	full := computePayslip(l, grade, components, 0, 30)
+	if full.Grosscents != 100000 || full.Deductionscents != 50500 || full.Netcents != 49500 {
+		t.Fatalf("full month: gross %d, deductions %d, net %d", full.Grosscents, full.Deductionscents, full.Netcents)
+	}
+
+	// 20 of 30 days unpaid leaves 33333 gross, tax takes 3333 and the loan the remaining 30000
+	short := computePayslip(l, grade, components, 20, 30)
+	if short.Grosscents != 33333 || short.Deductionscents != 33333 || short.Netcents != 0 {
+		t.Fatalf("mostly unpaid month: gross %d, deductions %d, net %d", short.Grosscents, short.Deductionscents, short.Netcents)
+	}
+	var deducted []PayslipLine
+	for _, line := range short.Lines {
+		if line.Kind == LineDeduction {
+			deducted = append(deducted, line)
+		}
+	}
+	if len(deducted) != 2 || deducted[1].Name != "Housing loan" || deducted[1].Amountcents != 30000 {
+		t.Fatalf("deduction lines %+v, want tax and a housing loan cut to 30000", deducted)
+	}
+
+	unpaid := computePayslip(l, grade, components, 31, 30)
+	if unpaid.Grosscents != 0 || unpaid.Netcents != 0 || unpaid.Deductionscents != 0 {
+		t.Fatalf("unpaid month: gross %d, deductions %d, net %d", unpaid.Grosscents, unpaid.Deductionscents, unpaid.Netcents)
+	}
+}
+
+func TestAddPayrollTotalKeepsCurrenciesApart(t *testing.T) {
+	var totals []PayrollTotal
+	for _, slip := range []Payslip{
+		{Currency: "INR", Grosscents: 100000, Netcents: 90000},
+		{Currency: "USD", Grosscents: 5000, Netcents: 4500},
+		{Currency: "INR", Grosscents: 50000, Netcents: 45000},
+	} {
+		totals = addPayrollTotal(totals, slip)
+	}
+	want := []PayrollTotal{{"INR", 150000, 135000}, {"USD", 5000, 4500}}
+	if len(totals) != len(want) || totals[0] != want[0] || totals[1] != want[1] {
+		t.Fatalf("got %+v, want %+v", totals, want)
+	}
+}
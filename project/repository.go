@@ -0,0 +1,171 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storage backends a repository can be configured with
+const (
+	StoreMySQL   = "mysql"
+	StoreMongoDB = "mongodb"
+	StoreMemory  = "memory"
+)
+
+// errors returned by the repositories, students use ErrStudentNotFound and lecturers ErrLecturerNotFound
+var (
+	ErrLibraryNotFound  = errors.New("library not found")
+	ErrBookNotFound     = errors.New("book not found")
+	ErrBookExists       = errors.New("book_id is already used by a library")
+	ErrBookOnLoan       = errors.New("book is on loan and cannot be removed")
+	ErrLibraryHasLoans  = errors.New("library has books on loan and cannot be deleted")
+	ErrAuthorNotFound   = errors.New("author not found")
+	ErrAuthorExists     = errors.New("author_id is already used")
+	ErrCopyNotFound     = errors.New("copy not found")
+	ErrCopyExists       = errors.New("barcode is already used by another copy")
+	ErrCopyUnavailable  = errors.New("copy is not available for loan")
+	ErrCopyOnLoan       = errors.New("copy is on loan, return it before changing its status")
+	ErrCopyOnHold       = errors.New("copy is held for a reader, cancel the hold before changing its status")
+	ErrNoOpenBorrow     = errors.New("no borrow records found")
+	ErrNoLoanPolicy     = errors.New("no loan policy for usertype")
+	ErrLoanLimit        = errors.New("loan limit reached")
+	ErrRenewalLimit     = errors.New("renewal limit reached")
+	ErrLoanOverdue      = errors.New("loan is overdue and must be returned")
+	ErrFinesUnpaid      = errors.New("unpaid fines above the limit")
+	ErrFineOverBalance  = errors.New("amount exceeds the unpaid fines")
+	ErrHoldNotFound     = errors.New("hold not found")
+	ErrHoldExists       = errors.New("user already holds this book")
+	ErrCopiesAvailable  = errors.New("a copy is available, borrow it instead")
+	ErrHoldClosed       = errors.New("hold is no longer active")
+	ErrHoldsWaiting     = errors.New("other readers are waiting for this book")
+	ErrUnknownStore     = errors.New("unknown store")
+	ErrUnsupportedStore = errors.New("unsupported store")
+)
+
+// StudentRepository stores students
+type StudentRepository interface {
+	Create(ctx context.Context, student *Student) error
+	Get(ctx context.Context, id int) (*Student, error)
+	List(ctx context.Context) ([]Student, error)
+	Update(ctx context.Context, student *Student) error
+	Delete(ctx context.Context, id int) error
+}
+
+// LecturerRepository stores lecturers. Create and Update return ErrLecturerEmailTaken for a duplicate email,
+// lookups return ErrInvalidLecturerID and ErrLecturerNotFound.
+type LecturerRepository interface {
+	Create(ctx context.Context, lecturer *Lecturer) error
+	Get(ctx context.Context, id string) (*Lecturer, error)
+	GetByEmail(ctx context.Context, email string) (*Lecturer, error)
+	List(ctx context.Context, q LecturerListQuery) ([]Lecturer, int64, error)
+	FindAll(ctx context.Context, department string) ([]Lecturer, error)
+	Update(ctx context.Context, lecturer *Lecturer) error
+	Delete(ctx context.Context, id string) error
+}
+
+// LibraryRepository stores libraries with their books, authors and copies, and the borrow records against them.
+// Update replaces the whole book and author lists of a library in one transaction.
+// Availability is derived from copy status, Borrow and Return work on the copy with the record's barcode.
+// Borrow and Renew apply the loan policy of the borrower's usertype and set the due date.
+// Return and AssessOverdue charge overdue fines to the borrower's fine ledger.
+// A copy that is returned, or whose hold is cancelled or expires, goes to the next waiting hold on its book before the shelf.
+type LibraryRepository interface {
+	Create(ctx context.Context, library *Library) error
+	Get(ctx context.Context, id int) (*Library, error)
+	List(ctx context.Context, q LibraryListQuery) ([]Library, int64, error)
+	Update(ctx context.Context, library *Library) error
+	Delete(ctx context.Context, id int) error
+
+	Books(ctx context.Context, libraryID int, q LibraryListQuery) ([]LibraryBook, int64, error)
+	AddBook(ctx context.Context, libraryID int, book LibraryBook) error
+	UpdateBook(ctx context.Context, libraryID int, book LibraryBook) error
+	DeleteBook(ctx context.Context, libraryID, bookID int) error
+
+	Authors(ctx context.Context, q AuthorListQuery) ([]Author, int64, error)
+	GetAuthor(ctx context.Context, id int) (*Author, error)
+	CreateAuthor(ctx context.Context, author Author) error
+	UpdateAuthor(ctx context.Context, author Author) error
+	DeleteAuthor(ctx context.Context, id int) error
+
+	Copies(ctx context.Context, libraryID, bookID int) ([]Copy, error)
+	AddCopy(ctx context.Context, libraryID, bookID int, c *Copy) error
+	GetCopy(ctx context.Context, barcode string) (*Copy, error)
+	UpdateCopy(ctx context.Context, c *Copy) error
+
+	LoanPolicies(ctx context.Context) ([]LoanPolicy, error)
+	SaveLoanPolicy(ctx context.Context, policy LoanPolicy) error
+
+	Borrow(ctx context.Context, record *Borrowrecords) error
+	Return(ctx context.Context, record *Borrowrecords) (*Hold, error)
+	Renew(ctx context.Context, borrowID int) (*Borrowrecords, error)
+
+	PlaceHold(ctx context.Context, hold *Hold) error
+	GetHold(ctx context.Context, id int) (*Hold, error)
+	CancelHold(ctx context.Context, id int) (*Hold, error)
+	ExpireHolds(ctx context.Context) (int, error)
+
+	AssessOverdue(ctx context.Context) (int, error)
+	FineAccount(ctx context.Context, usertype string, userID int) (*FineAccount, error)
+	PostFineEntry(ctx context.Context, entry *FineEntry) (int64, error)
+}
+
+// storeFor reads the backend of one repository from the environment, def when unset
+func storeFor(env, def string) string {
+	store := strings.ToLower(strings.TrimSpace(os.Getenv(env)))
+	if store == "" {
+		return def
+	}
+	return store
+}
+
+// NewStudentRepository returns the student repository for a store, STUDENT_STORE picks it at startup.
+// Enrollments, fees, scholarships, transcripts, exams and roles still read the MySQL students table,
+// so the server refuses the other stores until those lookups go through StudentRepository.
+func NewStudentRepository(store string, m *MySQLInstance) (StudentRepository, error) {
+	switch store {
+	case StoreMySQL:
+		return NewMySQLStudentRepository(m.db), nil
+	case StoreMongoDB, StoreMemory:
+		return nil, fmt.Errorf("%w %q for students: other modules read students from MySQL", ErrUnsupportedStore, store)
+	}
+	return nil, fmt.Errorf("%w %q for students", ErrUnknownStore, store)
+}
+
+// NewLecturerRepository returns the lecturer repository for a store, LECTURER_STORE picks it at startup
+func NewLecturerRepository(store string, m *MySQLInstance, mg *MongoDBInstance) (LecturerRepository, error) {
+	switch store {
+	case StoreMySQL:
+		return NewMySQLLecturerRepository(m.db), nil
+	case StoreMongoDB:
+		return NewMongoLecturerRepository(mg.Lecturer), nil
+	case StoreMemory:
+		return NewMemoryLecturerRepository(), nil
+	}
+	return nil, fmt.Errorf("%w %q for lecturers", ErrUnknownStore, store)
+}
+
+// NewLibraryRepository returns the library repository for a store, LIBRARY_STORE picks it at startup.
+// Libraries are relational, so there is no MongoDB implementation.
+func NewLibraryRepository(store string, m *MySQLInstance) (LibraryRepository, error) {
+	switch store {
+	case StoreMySQL:
+		return NewMySQLLibraryRepository(m.db), nil
+	case StoreMemory:
+		return NewMemoryLibraryRepository(), nil
+	}
+	return nil, fmt.Errorf("%w %q for libraries", ErrUnknownStore, store)
+}
+
+// parseLecturerID converts a hex lecturer id, which every store uses
+func parseLecturerID(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return objID, ErrInvalidLecturerID
+	}
+	return objID, nil
+}
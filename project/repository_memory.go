@@ -0,0 +1,955 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStudentRepository keeps students in process memory, for development and tests
+type MemoryStudentRepository struct {
+	mu       sync.Mutex
+	nextID   int
+	students map[int]Student
+}
+
+// NewMemoryStudentRepository returns an empty in-memory student repository
+func NewMemoryStudentRepository() *MemoryStudentRepository {
+	return &MemoryStudentRepository{students: map[int]Student{}}
+}
+
+func (s *MemoryStudentRepository) Create(ctx context.Context, student *Student) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	student.Id = s.nextID
+	s.students[student.Id] = *student
+	return nil
+}
+
+func (s *MemoryStudentRepository) Get(ctx context.Context, id int) (*Student, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	student, ok := s.students[id]
+	if !ok {
+		return nil, ErrStudentNotFound
+	}
+	return &student, nil
+}
+
+func (s *MemoryStudentRepository) List(ctx context.Context) ([]Student, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	students := make([]Student, 0, len(s.students))
+	for _, st := range s.students {
+		students = append(students, st)
+	}
+	sort.Slice(students, func(i, j int) bool { return students[i].Id < students[j].Id })
+	return students, nil
+}
+
+func (s *MemoryStudentRepository) Update(ctx context.Context, student *Student) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.students[student.Id]; !ok {
+		return ErrStudentNotFound
+	}
+	s.students[student.Id] = *student
+	return nil
+}
+
+func (s *MemoryStudentRepository) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.students[id]; !ok {
+		return ErrStudentNotFound
+	}
+	delete(s.students, id)
+	return nil
+}
+
+// MemoryLecturerRepository keeps lecturers in process memory, for development and tests
+type MemoryLecturerRepository struct {
+	mu        sync.Mutex
+	lecturers map[primitive.ObjectID]Lecturer
+}
+
+// NewMemoryLecturerRepository returns an empty in-memory lecturer repository
+func NewMemoryLecturerRepository() *MemoryLecturerRepository {
+	return &MemoryLecturerRepository{lecturers: map[primitive.ObjectID]Lecturer{}}
+}
+
+// emailTaken reports whether another lecturer already uses the email, the caller holds mu
+func (s *MemoryLecturerRepository) emailTaken(email string, self primitive.ObjectID) bool {
+	for id, l := range s.lecturers {
+		if id != self && l.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns the lecturers matching keep, ordered by a sort field and then id, the caller holds mu
+func (s *MemoryLecturerRepository) sorted(sortBy string, keep func(Lecturer) bool) []Lecturer {
+	desc := strings.HasPrefix(sortBy, "-")
+	field := strings.TrimPrefix(sortBy, "-")
+	compare := func(a, b Lecturer) int {
+		switch field {
+		case "age":
+			return a.Age - b.Age
+		case "designation":
+			return strings.Compare(a.Designation, b.Designation)
+		case "department":
+			return strings.Compare(a.Department, b.Department)
+		case "email":
+			return strings.Compare(a.Email, b.Email)
+		}
+		return strings.Compare(a.Name, b.Name)
+	}
+
+	lecturers := []Lecturer{}
+	for _, l := range s.lecturers {
+		if keep(l) {
+			lecturers = append(lecturers, l)
+		}
+	}
+	sort.Slice(lecturers, func(i, j int) bool {
+		c := compare(lecturers[i], lecturers[j])
+		if c == 0 {
+			c = strings.Compare(lecturers[i].Id.Hex(), lecturers[j].Id.Hex())
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return lecturers
+}
+
+func (s *MemoryLecturerRepository) Create(ctx context.Context, lecturer *Lecturer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.emailTaken(lecturer.Email, primitive.NilObjectID) {
+		return ErrLecturerEmailTaken
+	}
+	lecturer.Id = primitive.NewObjectID()
+	s.lecturers[lecturer.Id] = *lecturer
+	return nil
+}
+
+func (s *MemoryLecturerRepository) Get(ctx context.Context, id string) (*Lecturer, error) {
+	objID, err := parseLecturerID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.lecturers[objID]
+	if !ok {
+		return nil, ErrLecturerNotFound
+	}
+	return &l, nil
+}
+
+func (s *MemoryLecturerRepository) GetByEmail(ctx context.Context, email string) (*Lecturer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.lecturers {
+		if l.Email == email {
+			return &l, nil
+		}
+	}
+	return nil, ErrLecturerNotFound
+}
+
+func (s *MemoryLecturerRepository) List(ctx context.Context, q LecturerListQuery) ([]Lecturer, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matches := s.sorted(q.Sort, func(l Lecturer) bool {
+		return (q.Designation == "" || strings.EqualFold(l.Designation, q.Designation)) &&
+			(q.Department == "" || strings.EqualFold(l.Department, q.Department)) &&
+			(q.Minage == 0 || l.Age >= q.Minage) &&
+			(q.Maxage == 0 || l.Age <= q.Maxage)
+	})
+	start, end := pageBounds(len(matches), q.Page, q.Limit)
+	return matches[start:end], int64(len(matches)), nil
+}
+
+func (s *MemoryLecturerRepository) FindAll(ctx context.Context, department string) ([]Lecturer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted("name", func(l Lecturer) bool { return department == "" || l.Department == department }), nil
+}
+
+func (s *MemoryLecturerRepository) Update(ctx context.Context, lecturer *Lecturer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lecturers[lecturer.Id]; !ok {
+		return ErrLecturerNotFound
+	}
+	if s.emailTaken(lecturer.Email, lecturer.Id) {
+		return ErrLecturerEmailTaken
+	}
+	s.lecturers[lecturer.Id] = *lecturer
+	return nil
+}
+
+func (s *MemoryLecturerRepository) Delete(ctx context.Context, id string) error {
+	objID, err := parseLecturerID(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lecturers[objID]; !ok {
+		return ErrLecturerNotFound
+	}
+	delete(s.lecturers, objID)
+	return nil
+}
+
+// MemoryLibraryRepository keeps libraries and borrow records in process memory, for development and tests
+type MemoryLibraryRepository struct {
+	mu         sync.Mutex
+	nextID     int
+	nextCopyID int
+	nextHoldID int
+	libraries  map[int]Library
+	books      map[int]memoryBook
+	authors    map[int]Author
+	copies     map[string]Copy
+	policies   map[string]LoanPolicy
+	borrows    []Borrowrecords
+	fines      []FineEntry
+	holds      []Hold
+}
+
+// memoryBook is a book with the library that holds it
+type memoryBook struct {
+	Libraryid int
+	Name      string
+}
+
+// NewMemoryLibraryRepository returns an in-memory library repository with no libraries and the default loan policies
+func NewMemoryLibraryRepository() *MemoryLibraryRepository {
+	s := &MemoryLibraryRepository{libraries: map[int]Library{}, books: map[int]memoryBook{}, authors: map[int]Author{}, copies: map[string]Copy{}, policies: map[string]LoanPolicy{}}
+	for _, p := range defaultLoanPolicies {
+		p.Updatedat = time.Now()
+		s.policies[p.Usertype] = p
+	}
+	return s
+}
+
+// assemble returns a library with its books, authors and available copies, the caller holds mu
+func (s *MemoryLibraryRepository) assemble(lib Library) Library {
+	lib.Book, lib.Author, lib.Availablecopies = []LibraryBook{}, []LibraryAuthor{}, 0
+	for id, b := range s.books {
+		if b.Libraryid == lib.Libraryid {
+			book := s.book(id, b)
+			lib.Book = append(lib.Book, book)
+			lib.Availablecopies += book.Availablecopies
+		}
+	}
+	for _, a := range s.authors {
+		if a.Libraryid == lib.Libraryid {
+			lib.Author = append(lib.Author, LibraryAuthor{Authorid: a.Authorid, Authorname: a.Authorname})
+		}
+	}
+	sort.Slice(lib.Book, func(i, j int) bool { return lib.Book[i].Bookid < lib.Book[j].Bookid })
+	sort.Slice(lib.Author, func(i, j int) bool { return lib.Author[i].Authorid < lib.Author[j].Authorid })
+	return lib
+}
+
+// book returns a book with its copy counts, the caller holds mu
+func (s *MemoryLibraryRepository) book(id int, b memoryBook) LibraryBook {
+	book := LibraryBook{Bookid: id, Bookname: b.Name}
+	for _, c := range s.copies {
+		if c.Bookid != id || c.Status == CopyWithdrawn {
+			continue
+		}
+		book.Totalcopies++
+		if c.Status == CopyAvailable {
+			book.Availablecopies++
+		}
+	}
+	return book
+}
+
+// dropBook deletes a book with its copies and holds, the caller holds mu
+func (s *MemoryLibraryRepository) dropBook(id int) {
+	delete(s.books, id)
+	for barcode, c := range s.copies {
+		if c.Bookid == id {
+			delete(s.copies, barcode)
+		}
+	}
+	holds := s.holds[:0]
+	for _, h := range s.holds {
+		if h.Bookid != id {
+			holds = append(holds, h)
+		}
+	}
+	s.holds = holds
+}
+
+// onLoan reports whether a book has an open borrow record, the caller holds mu
+func (s *MemoryLibraryRepository) onLoan(bookID int) bool {
+	for _, b := range s.borrows {
+		if b.Bookid == bookID && b.Returndate == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pageBounds returns the slice bounds of one page out of n sorted items
+func pageBounds(n, page, limit int) (int, int) {
+	start := (page - 1) * limit
+	if start > n {
+		start = n
+	}
+	end := start + limit
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// Create mirrors the book_id and author_id primary keys of the MySQL tables
+func (s *MemoryLibraryRepository) Create(ctx context.Context, library *Library) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := map[int]bool{}
+	for _, b := range library.Book {
+		if _, ok := s.books[b.Bookid]; ok || seen[b.Bookid] {
+			return fmt.Errorf("failed to insert books: %w: %d", ErrBookExists, b.Bookid)
+		}
+		seen[b.Bookid] = true
+	}
+	seen = map[int]bool{}
+	for _, a := range library.Author {
+		if _, ok := s.authors[a.Authorid]; ok || seen[a.Authorid] {
+			return fmt.Errorf("failed to insert authors: %w: %d", ErrAuthorExists, a.Authorid)
+		}
+		seen[a.Authorid] = true
+	}
+
+	s.nextID++
+	library.Libraryid = s.nextID
+	s.libraries[library.Libraryid] = Library{Libraryid: library.Libraryid, Title: library.Title}
+	for _, b := range library.Book {
+		s.books[b.Bookid] = memoryBook{Libraryid: library.Libraryid, Name: b.Bookname}
+	}
+	for _, a := range library.Author {
+		s.authors[a.Authorid] = Author{Authorid: a.Authorid, Authorname: a.Authorname, Libraryid: library.Libraryid}
+	}
+	return nil
+}
+
+func (s *MemoryLibraryRepository) Get(ctx context.Context, id int) (*Library, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lib, ok := s.libraries[id]
+	if !ok {
+		return nil, ErrLibraryNotFound
+	}
+	lib = s.assemble(lib)
+	return &lib, nil
+}
+
+func (s *MemoryLibraryRepository) List(ctx context.Context, q LibraryListQuery) ([]Library, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	libs := []Library{}
+	for _, lib := range s.libraries {
+		if q.Title == "" || strings.Contains(strings.ToLower(lib.Title), strings.ToLower(q.Title)) {
+			libs = append(libs, lib)
+		}
+	}
+	sort.Slice(libs, func(i, j int) bool { return libs[i].Libraryid < libs[j].Libraryid })
+	total := int64(len(libs))
+	start, end := pageBounds(len(libs), q.Page, q.Limit)
+	libs = libs[start:end]
+	for i := range libs {
+		libs[i] = s.assemble(libs[i])
+	}
+	return libs, total, nil
+}
+
+// Update checks every change before applying any, so a refused update leaves the library as it was
+func (s *MemoryLibraryRepository) Update(ctx context.Context, library *Library) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.libraries[library.Libraryid]; !ok {
+		return ErrLibraryNotFound
+	}
+	keep := map[int]bool{}
+	for _, b := range library.Book {
+		if existing, ok := s.books[b.Bookid]; (ok && existing.Libraryid != library.Libraryid) || keep[b.Bookid] {
+			return fmt.Errorf("%w: %d", ErrBookExists, b.Bookid)
+		}
+		keep[b.Bookid] = true
+	}
+	for id, b := range s.books {
+		if b.Libraryid == library.Libraryid && !keep[id] && s.onLoan(id) {
+			return fmt.Errorf("%w: %d", ErrBookOnLoan, id)
+		}
+	}
+	keepAuthors := map[int]bool{}
+	for _, a := range library.Author {
+		if existing, ok := s.authors[a.Authorid]; (ok && existing.Libraryid != library.Libraryid) || keepAuthors[a.Authorid] {
+			return fmt.Errorf("%w: %d", ErrAuthorExists, a.Authorid)
+		}
+		keepAuthors[a.Authorid] = true
+	}
+
+	s.libraries[library.Libraryid] = Library{Libraryid: library.Libraryid, Title: library.Title}
+	for id, b := range s.books {
+		if b.Libraryid == library.Libraryid && !keep[id] {
+			s.dropBook(id)
+		}
+	}
+	for _, b := range library.Book {
+		s.books[b.Bookid] = memoryBook{Libraryid: library.Libraryid, Name: b.Bookname}
+	}
+	for id, a := range s.authors {
+		if a.Libraryid == library.Libraryid && !keepAuthors[id] {
+			delete(s.authors, id)
+		}
+	}
+	for _, a := range library.Author {
+		s.authors[a.Authorid] = Author{Authorid: a.Authorid, Authorname: a.Authorname, Libraryid: library.Libraryid}
+	}
+	return nil
+}
+
+func (s *MemoryLibraryRepository) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.libraries[id]; !ok {
+		return ErrLibraryNotFound
+	}
+	for bookID, b := range s.books {
+		if b.Libraryid == id && s.onLoan(bookID) {
+			return ErrLibraryHasLoans
+		}
+	}
+	delete(s.libraries, id)
+	for bookID, b := range s.books {
+		if b.Libraryid == id {
+			s.dropBook(bookID)
+		}
+	}
+	for authorID, a := range s.authors {
+		if a.Libraryid == id {
+			delete(s.authors, authorID)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryLibraryRepository) Books(ctx context.Context, libraryID int, q LibraryListQuery) ([]LibraryBook, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.libraries[libraryID]; !ok {
+		return nil, 0, ErrLibraryNotFound
+	}
+	books := []LibraryBook{}
+	for id, b := range s.books {
+		if b.Libraryid == libraryID && (q.Title == "" || strings.Contains(strings.ToLower(b.Name), strings.ToLower(q.Title))) {
+			books = append(books, s.book(id, b))
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].Bookid < books[j].Bookid })
+	start, end := pageBounds(len(books), q.Page, q.Limit)
+	return books[start:end], int64(len(books)), nil
+}
+
+func (s *MemoryLibraryRepository) AddBook(ctx context.Context, libraryID int, book LibraryBook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.libraries[libraryID]; !ok {
+		return ErrLibraryNotFound
+	}
+	if _, ok := s.books[book.Bookid]; ok {
+		return fmt.Errorf("%w: %d", ErrBookExists, book.Bookid)
+	}
+	s.books[book.Bookid] = memoryBook{Libraryid: libraryID, Name: book.Bookname}
+	return nil
+}
+
+func (s *MemoryLibraryRepository) UpdateBook(ctx context.Context, libraryID int, book LibraryBook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[book.Bookid]; !ok || b.Libraryid != libraryID {
+		return ErrBookNotFound
+	}
+	s.books[book.Bookid] = memoryBook{Libraryid: libraryID, Name: book.Bookname}
+	return nil
+}
+
+func (s *MemoryLibraryRepository) DeleteBook(ctx context.Context, libraryID, bookID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[bookID]; !ok || b.Libraryid != libraryID {
+		return ErrBookNotFound
+	}
+	if s.onLoan(bookID) {
+		return ErrBookOnLoan
+	}
+	s.dropBook(bookID)
+	return nil
+}
+
+func (s *MemoryLibraryRepository) Authors(ctx context.Context, q AuthorListQuery) ([]Author, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authors := []Author{}
+	for _, a := range s.authors {
+		if (q.Libraryid == 0 || a.Libraryid == q.Libraryid) && (q.Name == "" || strings.Contains(strings.ToLower(a.Authorname), strings.ToLower(q.Name))) {
+			authors = append(authors, a)
+		}
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].Authorid < authors[j].Authorid })
+	start, end := pageBounds(len(authors), q.Page, q.Limit)
+	return authors[start:end], int64(len(authors)), nil
+}
+
+func (s *MemoryLibraryRepository) GetAuthor(ctx context.Context, id int) (*Author, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.authors[id]
+	if !ok {
+		return nil, ErrAuthorNotFound
+	}
+	return &a, nil
+}
+
+func (s *MemoryLibraryRepository) CreateAuthor(ctx context.Context, author Author) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.libraries[author.Libraryid]; !ok {
+		return ErrLibraryNotFound
+	}
+	if _, ok := s.authors[author.Authorid]; ok {
+		return fmt.Errorf("%w: %d", ErrAuthorExists, author.Authorid)
+	}
+	s.authors[author.Authorid] = author
+	return nil
+}
+
+func (s *MemoryLibraryRepository) UpdateAuthor(ctx context.Context, author Author) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.libraries[author.Libraryid]; !ok {
+		return ErrLibraryNotFound
+	}
+	if _, ok := s.authors[author.Authorid]; !ok {
+		return ErrAuthorNotFound
+	}
+	s.authors[author.Authorid] = author
+	return nil
+}
+
+func (s *MemoryLibraryRepository) DeleteAuthor(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.authors[id]; !ok {
+		return ErrAuthorNotFound
+	}
+	delete(s.authors, id)
+	return nil
+}
+
+func (s *MemoryLibraryRepository) Copies(ctx context.Context, libraryID, bookID int) ([]Copy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[bookID]; !ok || b.Libraryid != libraryID {
+		return nil, ErrBookNotFound
+	}
+	copies := []Copy{}
+	for _, c := range s.copies {
+		if c.Bookid == bookID {
+			copies = append(copies, c)
+		}
+	}
+	sort.Slice(copies, func(i, j int) bool { return copies[i].Copyid < copies[j].Copyid })
+	return copies, nil
+}
+
+func (s *MemoryLibraryRepository) AddCopy(ctx context.Context, libraryID, bookID int, c *Copy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[bookID]; !ok || b.Libraryid != libraryID {
+		return ErrBookNotFound
+	}
+	if _, ok := s.copies[c.Barcode]; ok {
+		return ErrCopyExists
+	}
+	now := time.Now().UTC()
+	s.nextCopyID++
+	c.Copyid, c.Bookid, c.Createdat, c.Updatedat = s.nextCopyID, bookID, now, now
+	if c.Status == CopyAvailable {
+		s.passCopyOn(c)
+	}
+	s.copies[c.Barcode] = *c
+	return nil
+}
+
+func (s *MemoryLibraryRepository) GetCopy(ctx context.Context, barcode string) (*Copy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.copies[barcode]
+	if !ok {
+		return nil, ErrCopyNotFound
+	}
+	return &c, nil
+}
+
+func (s *MemoryLibraryRepository) UpdateCopy(ctx context.Context, c *Copy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.copies[c.Barcode]
+	if !ok {
+		return ErrCopyNotFound
+	}
+	if stored.Status != c.Status && stored.Status == CopyOnHold {
+		return ErrCopyOnHold
+	}
+	if stored.Status != c.Status && (circulationStatus(stored.Status) || circulationStatus(c.Status)) {
+		return ErrCopyOnLoan
+	}
+	stored.Shelflocation, stored.Condition, stored.Status, stored.Updatedat = c.Shelflocation, c.Condition, c.Status, time.Now().UTC()
+	if stored.Status == CopyAvailable {
+		s.passCopyOn(&stored)
+	}
+	s.copies[c.Barcode] = stored
+	*c = stored
+	return nil
+}
+
+func (s *MemoryLibraryRepository) LoanPolicies(ctx context.Context) ([]LoanPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policies := []LoanPolicy{}
+	for _, p := range s.policies {
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Usertype < policies[j].Usertype })
+	return policies, nil
+}
+
+func (s *MemoryLibraryRepository) SaveLoanPolicy(ctx context.Context, policy LoanPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[policy.Usertype] = policy
+	return nil
+}
+
+// today is the start of the current day, loans are dated by day like the MySQL DATE columns
+func today() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func (s *MemoryLibraryRepository) Borrow(ctx context.Context, record *Borrowrecords) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.copies[record.Barcode]
+	if !ok {
+		return ErrCopyNotFound
+	}
+	switch c.Status {
+	case CopyAvailable:
+	case CopyOnHold:
+		if h := s.readyHold(c.Barcode); h == nil || h.Userid != record.Userid || h.Usertype != record.Usertype {
+			return holdPickupError(c)
+		}
+	default:
+		return ErrCopyUnavailable
+	}
+	policy, ok := s.policies[record.Usertype]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrNoLoanPolicy, record.Usertype)
+	}
+	open := 0
+	for _, b := range s.borrows {
+		if b.Userid == record.Userid && b.Usertype == record.Usertype && b.Returndate == nil {
+			open++
+		}
+	}
+	if open >= policy.Maxloans {
+		return loanLimitError(*record, open, policy)
+	}
+	if balance := s.fineAccount(record.Usertype, record.Userid).Balancecents; balance > policy.Fineblockcents {
+		return finesBlockError(*record, balance, policy)
+	}
+
+	borrowDate := today()
+	dueDate := borrowDate.AddDate(0, 0, policy.Loandays)
+	record.Borrowid, record.Bookid, record.Copyid = len(s.borrows)+1, c.Bookid, c.Copyid
+	record.Borrowdate, record.Duedate, record.Returndate, record.Renewals, record.Finecents = &borrowDate, &dueDate, nil, 0, 0
+	s.borrows = append(s.borrows, *record)
+	c.Status, c.Updatedat = CopyOnLoan, time.Now().UTC()
+	s.copies[c.Barcode] = c
+	for i := range s.holds {
+		h := &s.holds[i]
+		if h.Bookid == c.Bookid && h.Userid == record.Userid && h.Usertype == record.Usertype &&
+			(h.Status == HoldWaiting || h.Status == HoldReady && h.Barcode == c.Barcode) {
+			closed := time.Now().UTC()
+			h.Status, h.Closedat = HoldFulfilled, &closed
+		}
+	}
+	return nil
+}
+
+func (s *MemoryLibraryRepository) Return(ctx context.Context, record *Borrowrecords) (*Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.copies[record.Barcode]
+	if !ok {
+		return nil, ErrCopyNotFound
+	}
+	for i := range s.borrows {
+		b := &s.borrows[i]
+		if b.Userid == record.Userid && b.Copyid == c.Copyid && b.Returndate == nil {
+			returned := today()
+			b.Returndate = &returned
+			s.assessLoanFine(b)
+			hold := s.passCopyOn(&c)
+			s.copies[c.Barcode] = c
+			*record = *b
+			return hold, nil
+		}
+	}
+	return nil, ErrNoOpenBorrow
+}
+
+func (s *MemoryLibraryRepository) Renew(ctx context.Context, borrowID int) (*Borrowrecords, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if borrowID <= 0 || borrowID > len(s.borrows) || s.borrows[borrowID-1].Returndate != nil {
+		return nil, ErrNoOpenBorrow
+	}
+	loan := &s.borrows[borrowID-1]
+	policy, ok := s.policies[loan.Usertype]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrNoLoanPolicy, loan.Usertype)
+	}
+	if today().After(*loan.Duedate) {
+		return nil, ErrLoanOverdue
+	}
+	for _, h := range s.holds {
+		if h.Bookid == loan.Bookid && h.Status == HoldWaiting {
+			return nil, ErrHoldsWaiting
+		}
+	}
+	if loan.Renewals >= policy.Maxrenewals {
+		return nil, renewalLimitError(*loan, policy)
+	}
+	due := loan.Duedate.AddDate(0, 0, policy.Loandays)
+	loan.Duedate = &due
+	loan.Renewals++
+	renewed := *loan
+	return &renewed, nil
+}
+
+// assessLoanFine charges what a loan's fine grew by since it was last assessed and returns the amount, the caller holds mu
+func (s *MemoryLibraryRepository) assessLoanFine(loan *Borrowrecords) int64 {
+	policy, ok := s.policies[loan.Usertype]
+	if !ok {
+		return 0
+	}
+	end := today()
+	if loan.Returndate != nil {
+		end = *loan.Returndate
+	}
+	daysLate := lateDays(*loan.Duedate, end)
+	fine := fineFor(policy, daysLate)
+	if fine <= loan.Finecents {
+		return 0
+	}
+	borrowID := loan.Borrowid
+	entry := FineEntry{Entryid: len(s.fines) + 1, Userid: loan.Userid, Usertype: loan.Usertype, Borrowid: &borrowID, Kind: LedgerCharge,
+		Amountcents: fine - loan.Finecents, Memo: fineMemo(loan.Borrowid, daysLate), Createdby: "system", Createdat: time.Now().UTC()}
+	s.fines = append(s.fines, entry)
+	loan.Finecents = fine
+	return entry.Amountcents
+}
+
+// fineAccount returns a borrower's fine ledger, the caller holds mu
+func (s *MemoryLibraryRepository) fineAccount(usertype string, userID int) *FineAccount {
+	account := &FineAccount{Userid: userID, Usertype: usertype, Entries: []FineEntry{}}
+	for _, e := range s.fines {
+		if e.Userid == userID && e.Usertype == usertype {
+			account.addFineEntry(e)
+		}
+	}
+	return account
+}
+
+func (s *MemoryLibraryRepository) AssessOverdue(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	charged := 0
+	for i := range s.borrows {
+		if s.borrows[i].Returndate == nil && s.assessLoanFine(&s.borrows[i]) > 0 {
+			charged++
+		}
+	}
+	return charged, nil
+}
+
+func (s *MemoryLibraryRepository) FineAccount(ctx context.Context, usertype string, userID int) (*FineAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fineAccount(usertype, userID), nil
+}
+
+func (s *MemoryLibraryRepository) PostFineEntry(ctx context.Context, entry *FineEntry) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	balance := s.fineAccount(entry.Usertype, entry.Userid).Balancecents
+	if entry.Amountcents > balance {
+		return 0, ErrFineOverBalance
+	}
+	entry.Entryid, entry.Createdat = len(s.fines)+1, time.Now().UTC()
+	s.fines = append(s.fines, *entry)
+	return balance - entry.Amountcents, nil
+}
+
+// passCopyOn sets a copy aside for the oldest waiting hold on its book, or shelves it when nobody waits,
+// and returns the hold it went to. The caller holds mu and stores the copy.
+func (s *MemoryLibraryRepository) passCopyOn(c *Copy) *Hold {
+	c.Status, c.Updatedat = CopyAvailable, time.Now().UTC()
+	for i := range s.holds {
+		h := &s.holds[i]
+		if h.Bookid != c.Bookid || h.Status != HoldWaiting {
+			continue
+		}
+		ready, pickupBy := time.Now().UTC(), today().AddDate(0, 0, s.policies[h.Usertype].Holdpickupdays)
+		h.Status, h.Barcode, h.Readyat, h.Pickupby = HoldReady, c.Barcode, &ready, &pickupBy
+		c.Status = CopyOnHold
+		hold := s.holdView(*h)
+		return &hold
+	}
+	return nil
+}
+
+// readyHold returns the ready hold a copy is set aside for, the caller holds mu
+func (s *MemoryLibraryRepository) readyHold(barcode string) *Hold {
+	for i := range s.holds {
+		if s.holds[i].Status == HoldReady && s.holds[i].Barcode == barcode {
+			return &s.holds[i]
+		}
+	}
+	return nil
+}
+
+// holdView returns a hold with its queue position, the caller holds mu
+func (s *MemoryLibraryRepository) holdView(h Hold) Hold {
+	h.Position = 0
+	if h.Status != HoldWaiting {
+		return h
+	}
+	for _, other := range s.holds {
+		if other.Bookid == h.Bookid && other.Status == HoldWaiting && other.Holdid <= h.Holdid {
+			h.Position++
+		}
+	}
+	return h
+}
+
+// hold returns the stored hold with an id, the caller holds mu
+func (s *MemoryLibraryRepository) hold(id int) *Hold {
+	for i := range s.holds {
+		if s.holds[i].Holdid == id {
+			return &s.holds[i]
+		}
+	}
+	return nil
+}
+
+// closeHold closes an active hold and passes a copy set aside for it on, the caller holds mu
+func (s *MemoryLibraryRepository) closeHold(h *Hold, status string) {
+	closed := time.Now().UTC()
+	barcode := ""
+	if h.Status == HoldReady {
+		barcode = h.Barcode
+	}
+	h.Status, h.Closedat = status, &closed
+	if c, ok := s.copies[barcode]; ok && c.Status == CopyOnHold {
+		s.passCopyOn(&c)
+		s.copies[barcode] = c
+	}
+}
+
+func (s *MemoryLibraryRepository) PlaceHold(ctx context.Context, hold *Hold) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.policies[hold.Usertype]; !ok {
+		return fmt.Errorf("%w %q", ErrNoLoanPolicy, hold.Usertype)
+	}
+	b, ok := s.books[hold.Bookid]
+	if !ok {
+		return ErrBookNotFound
+	}
+	if s.book(hold.Bookid, b).Availablecopies > 0 {
+		return ErrCopiesAvailable
+	}
+	for _, h := range s.holds {
+		if h.Bookid == hold.Bookid && h.Userid == hold.Userid && h.Usertype == hold.Usertype && activeHold(h.Status) {
+			return ErrHoldExists
+		}
+	}
+	s.nextHoldID++
+	placed := Hold{Holdid: s.nextHoldID, Bookid: hold.Bookid, Userid: hold.Userid, Usertype: hold.Usertype, Status: HoldWaiting, Createdat: time.Now().UTC()}
+	s.holds = append(s.holds, placed)
+	*hold = s.holdView(placed)
+	return nil
+}
+
+func (s *MemoryLibraryRepository) GetHold(ctx context.Context, id int) (*Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.hold(id)
+	if h == nil {
+		return nil, ErrHoldNotFound
+	}
+	hold := s.holdView(*h)
+	return &hold, nil
+}
+
+func (s *MemoryLibraryRepository) CancelHold(ctx context.Context, id int) (*Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.hold(id)
+	if h == nil {
+		return nil, ErrHoldNotFound
+	}
+	if !activeHold(h.Status) {
+		return nil, ErrHoldClosed
+	}
+	s.closeHold(h, HoldCancelled)
+	hold := s.holdView(*h)
+	return &hold, nil
+}
+
+func (s *MemoryLibraryRepository) ExpireHolds(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := 0
+	for i := range s.holds {
+		h := &s.holds[i]
+		if h.Status == HoldReady && h.Pickupby.Before(today()) {
+			s.closeHold(h, HoldExpired)
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,97 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// newLendingLibrary returns a memory repository with one library holding book 1 with the given copies
+func newLendingLibrary(t *testing.T, barcodes ...string) *MemoryLibraryRepository {
+	t.Helper()
+	ctx := context.Background()
+	s := NewMemoryLibraryRepository()
+	lib := Library{Title: "Central", Book: []LibraryBook{{Bookid: 1, Bookname: "Go"}}}
+	if err := s.Create(ctx, &lib); err != nil {
+		t.Fatal(err)
+	}
+	for _, barcode := range barcodes {
+		c := Copy{Barcode: barcode, Condition: ConditionGood, Status: CopyAvailable}
+		if err := s.AddCopy(ctx, lib.Libraryid, 1, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestMemoryBorrowOneCopyInParallel(t *testing.T) {
+	const borrowers = 50
+	s := newLendingLibrary(t, "CL-1")
+
+	var wg sync.WaitGroup
+	errs := make([]error, borrowers)
+	for i := 0; i < borrowers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.Borrow(context.Background(), &Borrowrecords{Userid: i + 1, Usertype: "lecturer", Barcode: "CL-1"})
+		}(i)
+	}
+	wg.Wait()
+
+	lent := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			lent++
+		case !errors.Is(err, ErrCopyUnavailable):
+			t.Errorf("borrower %d: got %v, want %v", i+1, err, ErrCopyUnavailable)
+		}
+	}
+	if lent != 1 {
+		t.Fatalf("copy lent %d times, want 1", lent)
+	}
+	c, err := s.GetCopy(context.Background(), "CL-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status != CopyOnLoan {
+		t.Fatalf("copy status %q, want %q", c.Status, CopyOnLoan)
+	}
+}
+
+func TestMemoryBorrowKeepsLoanLimitInParallel(t *testing.T) {
+	const copies = 20
+	barcodes := make([]string, copies)
+	for i := range barcodes {
+		barcodes[i] = fmt.Sprintf("CL-%d", i+1)
+	}
+	s := newLendingLibrary(t, barcodes...)
+	maxLoans := defaultLoanPolicies[0].Maxloans
+
+	var wg sync.WaitGroup
+	errs := make([]error, copies)
+	for i, barcode := range barcodes {
+		wg.Add(1)
+		go func(i int, barcode string) {
+			defer wg.Done()
+			errs[i] = s.Borrow(context.Background(), &Borrowrecords{Userid: 1, Usertype: "student", Barcode: barcode})
+		}(i, barcode)
+	}
+	wg.Wait()
+
+	lent := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			lent++
+		case !errors.Is(err, ErrLoanLimit):
+			t.Errorf("copy %s: got %v, want %v", barcodes[i], err, ErrLoanLimit)
+		}
+	}
+	if lent != maxLoans {
+		t.Fatalf("student got %d loans, want max_loans %d", lent, maxLoans)
+	}
+}
@@ -0,0 +1,237 @@
+package project
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStudentRepository keeps students in the students collection.
+// Ids stay integers, allocated from a counter document so they look the same as MySQL ids.
+type MongoStudentRepository struct {
+	students *mongo.Collection
+	counters *mongo.Collection
+}
+
+// NewMongoStudentRepository returns a student repository over a MongoDB database
+func NewMongoStudentRepository(db *mongo.Database) *MongoStudentRepository {
+	return &MongoStudentRepository{students: db.Collection("students"), counters: db.Collection("counters")}
+}
+
+// nextID increments and returns the students counter
+func (s *MongoStudentRepository) nextID(ctx context.Context) (int, error) {
+	var counter struct {
+		Seq int `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := s.counters.FindOneAndUpdate(ctx, bson.M{"_id": "students"}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+	return counter.Seq, err
+}
+
+func (s *MongoStudentRepository) Create(ctx context.Context, student *Student) error {
+	id, err := s.nextID(ctx)
+	if err != nil {
+		return err
+	}
+	student.Id = id
+	if _, err := s.students.InsertOne(ctx, student); err != nil {
+		student.Id = 0
+		return err
+	}
+	return nil
+}
+
+func (s *MongoStudentRepository) Get(ctx context.Context, id int) (*Student, error) {
+	var student Student
+	err := s.students.FindOne(ctx, bson.M{"_id": id}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrStudentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (s *MongoStudentRepository) List(ctx context.Context) ([]Student, error) {
+	cursor, err := s.students.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	students := []Student{}
+	if err := cursor.All(ctx, &students); err != nil {
+		return nil, err
+	}
+	return students, nil
+}
+
+func (s *MongoStudentRepository) Update(ctx context.Context, student *Student) error {
+	res, err := s.students.ReplaceOne(ctx, bson.M{"_id": student.Id}, student)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStudentNotFound
+	}
+	return nil
+}
+
+func (s *MongoStudentRepository) Delete(ctx context.Context, id int) error {
+	res, err := s.students.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrStudentNotFound
+	}
+	return nil
+}
+
+// MongoLecturerRepository keeps lecturers in the lecturers collection set up by MigrateMongoDB
+type MongoLecturerRepository struct {
+	lecturers *mongo.Collection
+}
+
+// NewMongoLecturerRepository returns a lecturer repository over the lecturers collection
+func NewMongoLecturerRepository(lecturers *mongo.Collection) *MongoLecturerRepository {
+	return &MongoLecturerRepository{lecturers: lecturers}
+}
+
+// findOne decodes the lecturer matching a filter
+func (s *MongoLecturerRepository) findOne(ctx context.Context, filter bson.M) (*Lecturer, error) {
+	var lecturer Lecturer
+	err := s.lecturers.FindOne(ctx, filter).Decode(&lecturer)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrLecturerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lecturer, nil
+}
+
+// find decodes every lecturer matching a filter
+func (s *MongoLecturerRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]Lecturer, error) {
+	cursor, err := s.lecturers.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lecturers := []Lecturer{}
+	if err := cursor.All(ctx, &lecturers); err != nil {
+		return nil, err
+	}
+	return lecturers, nil
+}
+
+func (s *MongoLecturerRepository) Create(ctx context.Context, lecturer *Lecturer) error {
+	res, err := s.lecturers.InsertOne(ctx, lecturer)
+	if err != nil {
+		return lecturerWriteError(err)
+	}
+	lecturer.Id = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *MongoLecturerRepository) Get(ctx context.Context, id string) (*Lecturer, error) {
+	objID, err := parseLecturerID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.findOne(ctx, bson.M{"_id": objID})
+}
+
+func (s *MongoLecturerRepository) GetByEmail(ctx context.Context, email string) (*Lecturer, error) {
+	return s.findOne(ctx, bson.M{"email": email})
+}
+
+// filter builds the MongoDB filter, designation and department match case-insensitively
+func (q LecturerListQuery) filter() bson.M {
+	filter := bson.M{}
+	if q.Designation != "" {
+		filter["designation"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(q.Designation) + "$", Options: "i"}
+	}
+	if q.Department != "" {
+		filter["department"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(q.Department) + "$", Options: "i"}
+	}
+	age := bson.M{}
+	if q.Minage > 0 {
+		age["$gte"] = q.Minage
+	}
+	if q.Maxage > 0 {
+		age["$lte"] = q.Maxage
+	}
+	if len(age) > 0 {
+		filter["age"] = age
+	}
+	return filter
+}
+
+func (s *MongoLecturerRepository) List(ctx context.Context, q LecturerListQuery) ([]Lecturer, int64, error) {
+	// count matching lecturers for the page metadata
+	filter := q.filter()
+	total, err := s.lecturers.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// cursor for the requested page, _id breaks ties so pages never overlap
+	order := 1
+	field := q.Sort
+	if strings.HasPrefix(field, "-") {
+		order, field = -1, field[1:]
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}).
+		SetSkip(int64((q.Page - 1) * q.Limit)).
+		SetLimit(int64(q.Limit))
+	lecturers, err := s.find(ctx, filter, opts)
+	return lecturers, total, err
+}
+
+func (s *MongoLecturerRepository) FindAll(ctx context.Context, department string) ([]Lecturer, error) {
+	filter := bson.M{}
+	if department != "" {
+		filter["department"] = department
+	}
+	return s.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+func (s *MongoLecturerRepository) Update(ctx context.Context, lecturer *Lecturer) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":        lecturer.Name,
+			"age":         lecturer.Age,
+			"email":       lecturer.Email,
+			"designation": lecturer.Designation,
+			"department":  lecturer.Department,
+		},
+	}
+	res, err := s.lecturers.UpdateOne(ctx, bson.M{"_id": lecturer.Id}, update)
+	if err != nil {
+		return lecturerWriteError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrLecturerNotFound
+	}
+	return nil
+}
+
+func (s *MongoLecturerRepository) Delete(ctx context.Context, id string) error {
+	objID, err := parseLecturerID(id)
+	if err != nil {
+		return err
+	}
+	res, err := s.lecturers.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrLecturerNotFound
+	}
+	return nil
+}
@@ -0,0 +1,1569 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MySQLStudentRepository keeps students in the students table
+type MySQLStudentRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLStudentRepository returns a student repository over a MySQL database
+func NewMySQLStudentRepository(db *sql.DB) *MySQLStudentRepository {
+	return &MySQLStudentRepository{db: db}
+}
+
+func (s *MySQLStudentRepository) Create(ctx context.Context, student *Student) error {
+	return insertStudent(s.db, student)
+}
+
+func (s *MySQLStudentRepository) Get(ctx context.Context, id int) (*Student, error) {
+	var student Student
+	err := s.db.QueryRowContext(ctx, "SELECT id , name , age , email , dept FROM students WHERE id=?", id).
+		Scan(&student.Id, &student.Name, &student.Age, &student.Email, &student.Dept)
+	if err == sql.ErrNoRows {
+		return nil, ErrStudentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (s *MySQLStudentRepository) List(ctx context.Context) ([]Student, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id , name , age , email , dept FROM students ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []Student{}
+	for rows.Next() {
+		var st Student
+		if err := rows.Scan(&st.Id, &st.Name, &st.Age, &st.Email, &st.Dept); err != nil {
+			return nil, err
+		}
+		students = append(students, st)
+	}
+	return students, rows.Err()
+}
+
+func (s *MySQLStudentRepository) Update(ctx context.Context, student *Student) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE students SET name=? , age=? , email=? , dept=? WHERE id=?", student.Name, student.Age, student.Email, student.Dept, student.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL reports 0 affected rows when nothing changed, so check the row exists
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = s.Get(ctx, student.Id)
+	return err
+}
+
+func (s *MySQLStudentRepository) Delete(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM students WHERE id=?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStudentNotFound
+	}
+	return nil
+}
+
+// MySQLLecturerRepository keeps lecturers in the lecturers table, keyed by the same hex ids MongoDB generates
+type MySQLLecturerRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLLecturerRepository returns a lecturer repository over a MySQL database
+func NewMySQLLecturerRepository(db *sql.DB) *MySQLLecturerRepository {
+	return &MySQLLecturerRepository{db: db}
+}
+
+// lecturerColumns is the select list read by scanLecturer
+const lecturerColumns = "id , name , age , email , designation , department"
+
+// scanLecturer reads one lecturers row
+func scanLecturer(row interface{ Scan(...any) error }) (*Lecturer, error) {
+	var l Lecturer
+	var id string
+	var department sql.NullString
+	if err := row.Scan(&id, &l.Name, &l.Age, &l.Email, &l.Designation, &department); err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	l.Id, l.Department = objID, department.String
+	return &l, nil
+}
+
+// queryLecturers reads every row of a lecturers query
+func (s *MySQLLecturerRepository) queryLecturers(ctx context.Context, query string, args ...any) ([]Lecturer, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lecturers := []Lecturer{}
+	for rows.Next() {
+		l, err := scanLecturer(rows)
+		if err != nil {
+			return nil, err
+		}
+		lecturers = append(lecturers, *l)
+	}
+	return lecturers, rows.Err()
+}
+
+// mysqlLecturerError maps a duplicate email to ErrLecturerEmailTaken
+func mysqlLecturerError(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return ErrLecturerEmailTaken
+	}
+	return err
+}
+
+func (s *MySQLLecturerRepository) Create(ctx context.Context, lecturer *Lecturer) error {
+	lecturer.Id = primitive.NewObjectID()
+	_, err := s.db.ExecContext(ctx, "INSERT INTO lecturers ("+lecturerColumns+") VALUES (? , ? , ? , ? , ? , NULLIF(? , ''))",
+		lecturer.Id.Hex(), lecturer.Name, lecturer.Age, lecturer.Email, lecturer.Designation, lecturer.Department)
+	if err != nil {
+		lecturer.Id = primitive.NilObjectID
+		return mysqlLecturerError(err)
+	}
+	return nil
+}
+
+func (s *MySQLLecturerRepository) Get(ctx context.Context, id string) (*Lecturer, error) {
+	if _, err := parseLecturerID(id); err != nil {
+		return nil, err
+	}
+	l, err := scanLecturer(s.db.QueryRowContext(ctx, "SELECT "+lecturerColumns+" FROM lecturers WHERE id=?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrLecturerNotFound
+	}
+	return l, err
+}
+
+func (s *MySQLLecturerRepository) GetByEmail(ctx context.Context, email string) (*Lecturer, error) {
+	l, err := scanLecturer(s.db.QueryRowContext(ctx, "SELECT "+lecturerColumns+" FROM lecturers WHERE email=?", email))
+	if err == sql.ErrNoRows {
+		return nil, ErrLecturerNotFound
+	}
+	return l, err
+}
+
+// where builds the WHERE clause of a listing, comparisons follow the column collation and are case-insensitive
+func (q LecturerListQuery) where() (string, []any) {
+	var conds []string
+	var args []any
+	if q.Designation != "" {
+		conds, args = append(conds, "designation=?"), append(args, q.Designation)
+	}
+	if q.Department != "" {
+		conds, args = append(conds, "department=?"), append(args, q.Department)
+	}
+	if q.Minage > 0 {
+		conds, args = append(conds, "age>=?"), append(args, q.Minage)
+	}
+	if q.Maxage > 0 {
+		conds, args = append(conds, "age<=?"), append(args, q.Maxage)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *MySQLLecturerRepository) List(ctx context.Context, q LecturerListQuery) ([]Lecturer, int64, error) {
+	where, args := q.where()
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM lecturers"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// the sort field was checked against lecturerSortFields, id breaks ties so pages never overlap
+	order, field := "ASC", q.Sort
+	if strings.HasPrefix(field, "-") {
+		order, field = "DESC", field[1:]
+	}
+	if !lecturerSortFields[field] {
+		return nil, 0, fmt.Errorf("cannot sort lecturers by %s", field)
+	}
+	query := fmt.Sprintf("SELECT %s FROM lecturers%s ORDER BY %s %s , id %s LIMIT ? OFFSET ?", lecturerColumns, where, field, order, order)
+	lecturers, err := s.queryLecturers(ctx, query, append(args, q.Limit, (q.Page-1)*q.Limit)...)
+	return lecturers, total, err
+}
+
+func (s *MySQLLecturerRepository) FindAll(ctx context.Context, department string) ([]Lecturer, error) {
+	if department == "" {
+		return s.queryLecturers(ctx, "SELECT "+lecturerColumns+" FROM lecturers ORDER BY name")
+	}
+	return s.queryLecturers(ctx, "SELECT "+lecturerColumns+" FROM lecturers WHERE department=? ORDER BY name", department)
+}
+
+func (s *MySQLLecturerRepository) Update(ctx context.Context, lecturer *Lecturer) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE lecturers SET name=? , age=? , email=? , designation=? , department=NULLIF(? , '') WHERE id=?",
+		lecturer.Name, lecturer.Age, lecturer.Email, lecturer.Designation, lecturer.Department, lecturer.Id.Hex())
+	if err != nil {
+		return mysqlLecturerError(err)
+	}
+	// MySQL reports 0 affected rows when nothing changed, so check the row exists
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = s.Get(ctx, lecturer.Id.Hex())
+	return err
+}
+
+func (s *MySQLLecturerRepository) Delete(ctx context.Context, id string) error {
+	if _, err := parseLecturerID(id); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, "DELETE FROM lecturers WHERE id=?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLecturerNotFound
+	}
+	return nil
+}
+
+// MySQLLibraryRepository keeps libraries in the libraries, books, authors and borrow_records tables
+type MySQLLibraryRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLLibraryRepository returns a library repository over a MySQL database
+func NewMySQLLibraryRepository(db *sql.DB) *MySQLLibraryRepository {
+	return &MySQLLibraryRepository{db: db}
+}
+
+// Create inserts the library with its books and authors in one transaction and sets its id
+func (s *MySQLLibraryRepository) Create(ctx context.Context, library *Library) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Insert library records
+	res, err := tx.Exec("INSERT INTO libraries (title) VALUES (?)", library.Title)
+	if err != nil {
+		return fmt.Errorf("failed to insert libraries: %w", err)
+	}
+	libraryID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	// Insert books
+	for _, b := range library.Book {
+		if err := insertBook(tx, int(libraryID), b); err != nil {
+			return fmt.Errorf("failed to insert books: %w", err)
+		}
+	}
+	// Insert authors
+	for _, a := range library.Author {
+		if err := insertAuthor(tx, int(libraryID), a); err != nil {
+			return fmt.Errorf("failed to insert authors: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	library.Libraryid = int(libraryID)
+	return nil
+}
+
+func (s *MySQLLibraryRepository) Get(ctx context.Context, id int) (*Library, error) {
+	var lib Library
+	err := s.db.QueryRowContext(ctx, "SELECT library_id, title FROM libraries WHERE library_id=?", id).Scan(&lib.Libraryid, &lib.Title)
+	if err == sql.ErrNoRows {
+		return nil, ErrLibraryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	libs := []Library{lib}
+	if err := s.loadHoldings(ctx, libs); err != nil {
+		return nil, err
+	}
+	return &libs[0], nil
+}
+
+// bookColumns selects books with their copy counts, withdrawn copies are not counted
+const bookColumns = `SELECT b.library_id , b.book_id , b.book_name , COUNT(c.copy_id) , COALESCE(SUM(c.status='available') , 0)
+	FROM books b LEFT JOIN copies c ON c.book_id=b.book_id AND c.status<>'withdrawn'`
+
+// loadHoldings fills in the books and authors of libraries, and the available copies from the books
+func (s *MySQLLibraryRepository) loadHoldings(ctx context.Context, libs []Library) error {
+	if len(libs) == 0 {
+		return nil
+	}
+	index := map[int]int{}
+	ids := make([]any, len(libs))
+	for i := range libs {
+		libs[i].Book, libs[i].Author, libs[i].Availablecopies = []LibraryBook{}, []LibraryAuthor{}, 0
+		index[libs[i].Libraryid] = i
+		ids[i] = libs[i].Libraryid
+	}
+	in := "(?" + strings.Repeat(" , ?", len(ids)-1) + ")"
+
+	// Fetch books
+	bookRows, err := s.db.QueryContext(ctx, bookColumns+" WHERE b.library_id IN "+in+" GROUP BY b.book_id ORDER BY b.book_id", ids...)
+	if err != nil {
+		return err
+	}
+	defer bookRows.Close()
+	for bookRows.Next() {
+		var libraryID int
+		var b LibraryBook
+		if err := bookRows.Scan(&libraryID, &b.Bookid, &b.Bookname, &b.Totalcopies, &b.Availablecopies); err != nil {
+			return err
+		}
+		lib := &libs[index[libraryID]]
+		lib.Book = append(lib.Book, b)
+		lib.Availablecopies += b.Availablecopies
+	}
+	if err := bookRows.Err(); err != nil {
+		return err
+	}
+
+	// Fetch authors
+	authorRows, err := s.db.QueryContext(ctx, "SELECT library_id , author_id , author_name FROM authors WHERE library_id IN "+in+" ORDER BY author_id", ids...)
+	if err != nil {
+		return err
+	}
+	defer authorRows.Close()
+	for authorRows.Next() {
+		var libraryID int
+		var a LibraryAuthor
+		if err := authorRows.Scan(&libraryID, &a.Authorid, &a.Authorname); err != nil {
+			return err
+		}
+		lib := &libs[index[libraryID]]
+		lib.Author = append(lib.Author, a)
+	}
+	return authorRows.Err()
+}
+
+func (s *MySQLLibraryRepository) List(ctx context.Context, q LibraryListQuery) ([]Library, int64, error) {
+	where, args := "", []any{}
+	if q.Title != "" {
+		where, args = " WHERE title LIKE ?", append(args, "%"+q.Title+"%")
+	}
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM libraries"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT library_id , title FROM libraries"+where+" ORDER BY library_id LIMIT ? OFFSET ?",
+		append(args, q.Limit, (q.Page-1)*q.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	libs := []Library{}
+	for rows.Next() {
+		var lib Library
+		if err := rows.Scan(&lib.Libraryid, &lib.Title); err != nil {
+			return nil, 0, err
+		}
+		libs = append(libs, lib)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return libs, total, s.loadHoldings(ctx, libs)
+}
+
+// lockLibrary locks a library row for the rest of the transaction
+func lockLibrary(tx *sql.Tx, id int) error {
+	var locked int
+	err := tx.QueryRow("SELECT library_id FROM libraries WHERE library_id=? FOR UPDATE", id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return ErrLibraryNotFound
+	}
+	return err
+}
+
+// bookOnLoan reports whether a book has a borrow record that is not returned
+func bookOnLoan(q dbtx, bookID int) (bool, error) {
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM borrow_records WHERE book_id=? AND return_date IS NULL", bookID).Scan(&n)
+	return n > 0, err
+}
+
+// insertBook adds a book to a library, book ids are unique across libraries
+func insertBook(tx *sql.Tx, libraryID int, b LibraryBook) error {
+	var owner int
+	err := tx.QueryRow("SELECT library_id FROM books WHERE book_id=? FOR UPDATE", b.Bookid).Scan(&owner)
+	if err == nil {
+		return fmt.Errorf("%w: %d", ErrBookExists, b.Bookid)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO books (book_id, book_name , library_id) VALUES (? , ? , ?)", b.Bookid, b.Bookname, libraryID)
+	return err
+}
+
+// insertAuthor adds an author to a library, author ids are unique across libraries
+func insertAuthor(tx *sql.Tx, libraryID int, a LibraryAuthor) error {
+	var owner int
+	err := tx.QueryRow("SELECT library_id FROM authors WHERE author_id=? FOR UPDATE", a.Authorid).Scan(&owner)
+	if err == nil {
+		return fmt.Errorf("%w: %d", ErrAuthorExists, a.Authorid)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO authors (author_id , author_name , library_id) VALUES (? , ? ,?)", a.Authorid, a.Authorname, libraryID)
+	return err
+}
+
+// Update replaces the title, books and authors of a library. Books on loan cannot be dropped.
+func (s *MySQLLibraryRepository) Update(ctx context.Context, library *Library) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockLibrary(tx, library.Libraryid); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE libraries SET title=? WHERE library_id=?", library.Title, library.Libraryid); err != nil {
+		return err
+	}
+
+	// current books and authors of the library
+	current := map[int]bool{}
+	rows, err := tx.Query("SELECT book_id FROM books WHERE library_id=? FOR UPDATE", library.Libraryid)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current[id] = true
+	}
+	rows.Close()
+	currentAuthors := map[int]bool{}
+	rows, err = tx.Query("SELECT author_id FROM authors WHERE library_id=? FOR UPDATE", library.Libraryid)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		currentAuthors[id] = true
+	}
+	rows.Close()
+
+	// rename kept books, add new ones and drop the rest
+	for _, b := range library.Book {
+		if current[b.Bookid] {
+			delete(current, b.Bookid)
+			if _, err := tx.Exec("UPDATE books SET book_name=? WHERE book_id=?", b.Bookname, b.Bookid); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := insertBook(tx, library.Libraryid, b); err != nil {
+			return err
+		}
+	}
+	for id := range current {
+		onLoan, err := bookOnLoan(tx, id)
+		if err != nil {
+			return err
+		}
+		if onLoan {
+			return fmt.Errorf("%w: %d", ErrBookOnLoan, id)
+		}
+		if _, err := tx.Exec("DELETE FROM books WHERE book_id=?", id); err != nil {
+			return err
+		}
+	}
+
+	// same for authors
+	for _, a := range library.Author {
+		if currentAuthors[a.Authorid] {
+			delete(currentAuthors, a.Authorid)
+			if _, err := tx.Exec("UPDATE authors SET author_name=? WHERE author_id=?", a.Authorname, a.Authorid); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := insertAuthor(tx, library.Libraryid, a); err != nil {
+			return err
+		}
+	}
+	for id := range currentAuthors {
+		if _, err := tx.Exec("DELETE FROM authors WHERE author_id=?", id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete removes a library with its books and authors, unless one of its books is on loan
+func (s *MySQLLibraryRepository) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockLibrary(tx, id); err != nil {
+		return err
+	}
+	var onLoan int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM borrow_records br JOIN books b ON b.book_id=br.book_id
+		WHERE b.library_id=? AND br.return_date IS NULL`, id).Scan(&onLoan)
+	if err != nil {
+		return err
+	}
+	if onLoan > 0 {
+		return ErrLibraryHasLoans
+	}
+	if _, err := tx.Exec("DELETE FROM libraries WHERE library_id=?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLLibraryRepository) Books(ctx context.Context, libraryID int, q LibraryListQuery) ([]LibraryBook, int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(b.book_id) FROM libraries l LEFT JOIN books b ON b.library_id=l.library_id
+		WHERE l.library_id=? GROUP BY l.library_id`, libraryID).Scan(&total)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrLibraryNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := " WHERE b.library_id=?", []any{libraryID}
+	if q.Title != "" {
+		where, args = where+" AND b.book_name LIKE ?", append(args, "%"+q.Title+"%")
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books b"+where, args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+	rows, err := s.db.QueryContext(ctx, bookColumns+where+" GROUP BY b.book_id ORDER BY b.book_id LIMIT ? OFFSET ?",
+		append(args, q.Limit, (q.Page-1)*q.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	books := []LibraryBook{}
+	for rows.Next() {
+		var libraryID int
+		var b LibraryBook
+		if err := rows.Scan(&libraryID, &b.Bookid, &b.Bookname, &b.Totalcopies, &b.Availablecopies); err != nil {
+			return nil, 0, err
+		}
+		books = append(books, b)
+	}
+	return books, total, rows.Err()
+}
+
+func (s *MySQLLibraryRepository) AddBook(ctx context.Context, libraryID int, book LibraryBook) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockLibrary(tx, libraryID); err != nil {
+		return err
+	}
+	if err := insertBook(tx, libraryID, book); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLLibraryRepository) UpdateBook(ctx context.Context, libraryID int, book LibraryBook) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE books SET book_name=? WHERE book_id=? AND library_id=?", book.Bookname, book.Bookid, libraryID)
+	if err != nil {
+		return err
+	}
+	// MySQL reports 0 affected rows when nothing changed, so check the row exists
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books WHERE book_id=? AND library_id=?", book.Bookid, libraryID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBookNotFound
+	}
+	return nil
+}
+
+func (s *MySQLLibraryRepository) DeleteBook(ctx context.Context, libraryID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int
+	err = tx.QueryRow("SELECT library_id FROM books WHERE book_id=? AND library_id=? FOR UPDATE", bookID, libraryID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return ErrBookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	onLoan, err := bookOnLoan(tx, bookID)
+	if err != nil {
+		return err
+	}
+	if onLoan {
+		return ErrBookOnLoan
+	}
+	if _, err := tx.Exec("DELETE FROM books WHERE book_id=?", bookID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLLibraryRepository) Authors(ctx context.Context, q AuthorListQuery) ([]Author, int64, error) {
+	var conds []string
+	var args []any
+	if q.Libraryid > 0 {
+		conds, args = append(conds, "library_id=?"), append(args, q.Libraryid)
+	}
+	if q.Name != "" {
+		conds, args = append(conds, "author_name LIKE ?"), append(args, "%"+q.Name+"%")
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM authors"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT author_id , author_name , library_id FROM authors"+where+" ORDER BY author_id LIMIT ? OFFSET ?",
+		append(args, q.Limit, (q.Page-1)*q.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	authors := []Author{}
+	for rows.Next() {
+		var a Author
+		if err := rows.Scan(&a.Authorid, &a.Authorname, &a.Libraryid); err != nil {
+			return nil, 0, err
+		}
+		authors = append(authors, a)
+	}
+	return authors, total, rows.Err()
+}
+
+func (s *MySQLLibraryRepository) GetAuthor(ctx context.Context, id int) (*Author, error) {
+	var a Author
+	err := s.db.QueryRowContext(ctx, "SELECT author_id , author_name , library_id FROM authors WHERE author_id=?", id).Scan(&a.Authorid, &a.Authorname, &a.Libraryid)
+	if err == sql.ErrNoRows {
+		return nil, ErrAuthorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *MySQLLibraryRepository) CreateAuthor(ctx context.Context, author Author) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockLibrary(tx, author.Libraryid); err != nil {
+		return err
+	}
+	if err := insertAuthor(tx, author.Libraryid, LibraryAuthor{Authorid: author.Authorid, Authorname: author.Authorname}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLLibraryRepository) UpdateAuthor(ctx context.Context, author Author) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockLibrary(tx, author.Libraryid); err != nil {
+		return err
+	}
+	res, err := tx.Exec("UPDATE authors SET author_name=? , library_id=? WHERE author_id=?", author.Authorname, author.Libraryid, author.Authorid)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// MySQL reports 0 affected rows when nothing changed, so check the row exists
+	if n == 0 {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM authors WHERE author_id=?", author.Authorid).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrAuthorNotFound
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MySQLLibraryRepository) DeleteAuthor(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM authors WHERE author_id=?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAuthorNotFound
+	}
+	return nil
+}
+
+// copyColumns are the columns scanned by scanCopy
+const copyColumns = "copy_id , barcode , book_id , shelf_location , copy_condition , status , created_at , updated_at"
+
+// scanCopy reads a copy selected with copyColumns
+func scanCopy(row interface{ Scan(...any) error }) (*Copy, error) {
+	var c Copy
+	if err := row.Scan(&c.Copyid, &c.Barcode, &c.Bookid, &c.Shelflocation, &c.Condition, &c.Status, &c.Createdat, &c.Updatedat); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// mysqlCopyError maps a duplicate barcode to ErrCopyExists
+func mysqlCopyError(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return ErrCopyExists
+	}
+	return err
+}
+
+func (s *MySQLLibraryRepository) Copies(ctx context.Context, libraryID, bookID int) ([]Copy, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books WHERE book_id=? AND library_id=?", bookID, libraryID).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrBookNotFound
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT "+copyColumns+" FROM copies WHERE book_id=? ORDER BY copy_id", bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	copies := []Copy{}
+	for rows.Next() {
+		c, err := scanCopy(rows)
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, *c)
+	}
+	return copies, rows.Err()
+}
+
+func (s *MySQLLibraryRepository) AddCopy(ctx context.Context, libraryID, bookID int, c *Copy) error {
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books WHERE book_id=? AND library_id=?", bookID, libraryID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBookNotFound
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := s.db.ExecContext(ctx, "INSERT INTO copies (barcode , book_id , shelf_location , copy_condition , status , created_at , updated_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+		c.Barcode, bookID, c.Shelflocation, c.Condition, c.Status, now, now)
+	if err != nil {
+		return mysqlCopyError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.Copyid, c.Bookid, c.Createdat, c.Updatedat = int(id), bookID, now, now
+	return s.offerCopy(ctx, c)
+}
+
+func (s *MySQLLibraryRepository) GetCopy(ctx context.Context, barcode string) (*Copy, error) {
+	c, err := scanCopy(s.db.QueryRowContext(ctx, "SELECT "+copyColumns+" FROM copies WHERE barcode=?", barcode))
+	if err == sql.ErrNoRows {
+		return nil, ErrCopyNotFound
+	}
+	return c, err
+}
+
+// UpdateCopy saves shelf, condition and status. The status of a copy on loan or on hold only changes through circulation.
+func (s *MySQLLibraryRepository) UpdateCopy(ctx context.Context, c *Copy) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := s.db.ExecContext(ctx, `UPDATE copies SET shelf_location=? , copy_condition=? , status=? , updated_at=?
+		WHERE barcode=? AND (status=? OR (status NOT IN (? , ?) AND ? NOT IN (? , ?)))`,
+		c.Shelflocation, c.Condition, c.Status, now, c.Barcode, c.Status, CopyOnLoan, CopyOnHold, c.Status, CopyOnLoan, CopyOnHold)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		c.Updatedat = now
+		return s.offerCopy(ctx, c)
+	}
+	// the copy is gone or went into (or out of) circulation since it was read
+	var status string
+	err = s.db.QueryRowContext(ctx, "SELECT status FROM copies WHERE barcode=?", c.Barcode).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrCopyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == CopyOnHold {
+		return ErrCopyOnHold
+	}
+	return ErrCopyOnLoan
+}
+
+// lockCopy locks the copy with a barcode for the rest of the transaction
+func lockCopy(tx *sql.Tx, barcode string) (*Copy, error) {
+	c, err := scanCopy(tx.QueryRow("SELECT "+copyColumns+" FROM copies WHERE barcode=? FOR UPDATE", barcode))
+	if err == sql.ErrNoRows {
+		return nil, ErrCopyNotFound
+	}
+	return c, err
+}
+
+// loanPolicyColumns are the columns scanned by scanLoanPolicy
+const loanPolicyColumns = "usertype , loan_days , max_loans , max_renewals , fine_per_day_cents , grace_days , max_fine_cents , fine_block_cents , hold_pickup_days , updated_by , updated_at"
+
+// scanLoanPolicy reads a policy selected with loanPolicyColumns
+func scanLoanPolicy(row interface{ Scan(...any) error }) (LoanPolicy, error) {
+	var p LoanPolicy
+	err := row.Scan(&p.Usertype, &p.Loandays, &p.Maxloans, &p.Maxrenewals, &p.Fineperdaycents, &p.Gracedays, &p.Maxfinecents, &p.Fineblockcents, &p.Holdpickupdays, &p.Updatedby, &p.Updatedat)
+	return p, err
+}
+
+// loanPolicy returns the policy of a usertype
+func loanPolicy(q dbtx, usertype string) (LoanPolicy, error) {
+	p, err := scanLoanPolicy(q.QueryRow("SELECT "+loanPolicyColumns+" FROM loan_policies WHERE usertype=?", usertype))
+	if err == sql.ErrNoRows {
+		return p, fmt.Errorf("%w %q", ErrNoLoanPolicy, usertype)
+	}
+	return p, err
+}
+
+func (s *MySQLLibraryRepository) LoanPolicies(ctx context.Context) ([]LoanPolicy, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+loanPolicyColumns+" FROM loan_policies ORDER BY usertype")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []LoanPolicy{}
+	for rows.Next() {
+		p, err := scanLoanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+func (s *MySQLLibraryRepository) SaveLoanPolicy(ctx context.Context, policy LoanPolicy) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO loan_policies (`+loanPolicyColumns+`) VALUES (? , ? , ? , ? , ? , ? , ? , ? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE loan_days=VALUES(loan_days) , max_loans=VALUES(max_loans) , max_renewals=VALUES(max_renewals) ,
+		fine_per_day_cents=VALUES(fine_per_day_cents) , grace_days=VALUES(grace_days) , max_fine_cents=VALUES(max_fine_cents) ,
+		fine_block_cents=VALUES(fine_block_cents) , hold_pickup_days=VALUES(hold_pickup_days) , updated_by=VALUES(updated_by) , updated_at=VALUES(updated_at)`,
+		policy.Usertype, policy.Loandays, policy.Maxloans, policy.Maxrenewals, policy.Fineperdaycents, policy.Gracedays,
+		policy.Maxfinecents, policy.Fineblockcents, policy.Holdpickupdays, policy.Updatedby, policy.Updatedat)
+	return err
+}
+
+// Borrow checks and lends the copy in one transaction. The copy row stays locked until commit,
+// so concurrent borrowers of the same copy queue behind each other and only the first one gets it.
+// The user's open loans are counted with a locking read, which also locks the index range so two
+// parallel borrows by one user cannot both squeeze under max_loans.
+// A copy on hold is only lent to the reader of its ready hold, and borrowing fulfils the reader's hold on the book.
+func (s *MySQLLibraryRepository) Borrow(ctx context.Context, record *Borrowrecords) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the copy and check it is available, or set aside for this user
+	c, err := lockCopy(tx, record.Barcode)
+	if err != nil {
+		return err
+	}
+	switch c.Status {
+	case CopyAvailable:
+	case CopyOnHold:
+		var userID int
+		var usertype string
+		if err := tx.QueryRow("SELECT user_id , usertype FROM holds WHERE copy_id=? AND status=? FOR UPDATE", c.Copyid, HoldReady).Scan(&userID, &usertype); err != nil {
+			return err
+		}
+		if userID != record.Userid || usertype != record.Usertype {
+			return holdPickupError(*c)
+		}
+	default:
+		return ErrCopyUnavailable
+	}
+
+	// check the user's loan policy
+	policy, err := loanPolicy(tx, record.Usertype)
+	if err != nil {
+		return err
+	}
+	var open int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM borrow_records WHERE user_id=? AND usertype=? AND return_date IS NULL FOR UPDATE", record.Userid, record.Usertype).Scan(&open); err != nil {
+		return err
+	}
+	if open >= policy.Maxloans {
+		return loanLimitError(*record, open, policy)
+	}
+	balance, err := fineBalance(tx, record.Usertype, record.Userid)
+	if err != nil {
+		return err
+	}
+	if balance > policy.Fineblockcents {
+		return finesBlockError(*record, balance, policy)
+	}
+
+	// Insert borrow records, due after the policy's loan period
+	res, err := tx.Exec("INSERT INTO borrow_records(user_id , usertype , book_id , copy_id , borrow_date , due_date) VALUES (? , ? , ? , ? , CURDATE() , DATE_ADD(CURDATE() , INTERVAL ? DAY))",
+		record.Userid, record.Usertype, c.Bookid, c.Copyid, policy.Loandays)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	// Mark the copy on loan, the user's waiting hold on the book, or ready hold on this copy, is fulfilled
+	if _, err := tx.Exec("UPDATE copies SET status=? , updated_at=? WHERE copy_id=?", CopyOnLoan, time.Now().UTC(), c.Copyid); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE holds SET status=? , closed_at=? WHERE book_id=? AND user_id=? AND usertype=? AND (status=? OR (status=? AND copy_id=?))",
+		HoldFulfilled, time.Now().UTC(), c.Bookid, record.Userid, record.Usertype, HoldWaiting, HoldReady, c.Copyid); err != nil {
+		return err
+	}
+	var borrowDate, dueDate time.Time
+	if err := tx.QueryRow("SELECT borrow_date , due_date FROM borrow_records WHERE borrow_id=?", id).Scan(&borrowDate, &dueDate); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	record.Borrowid, record.Bookid, record.Copyid = int(id), c.Bookid, c.Copyid
+	record.Borrowdate, record.Duedate, record.Returndate, record.Renewals, record.Finecents = &borrowDate, &dueDate, nil, 0, 0
+	return nil
+}
+
+// Return closes the open loan, charges its overdue fine and passes the copy on in one transaction,
+// under the same copy lock as Borrow. It returns the hold the copy was set aside for, nil when it was shelved.
+func (s *MySQLLibraryRepository) Return(ctx context.Context, record *Borrowrecords) (*Hold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c, err := lockCopy(tx, record.Barcode)
+	if err != nil {
+		return nil, err
+	}
+	var borrowID int
+	err = tx.QueryRow("SELECT borrow_id FROM borrow_records WHERE user_id=? AND copy_id=? AND return_date is NULL FOR UPDATE", record.Userid, c.Copyid).Scan(&borrowID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenBorrow
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE borrow_records SET return_date=CURDATE() WHERE borrow_id=?", borrowID); err != nil {
+		return nil, err
+	}
+	if _, err := assessLoanFine(tx, borrowID); err != nil {
+		return nil, err
+	}
+
+	// set the copy aside for the next hold or put it back on the shelf
+	hold, err := passCopyOn(tx, c)
+	if err != nil {
+		return nil, err
+	}
+	loan, err := scanBorrow(tx.QueryRow("SELECT "+borrowColumns+" FROM borrow_records br LEFT JOIN copies c ON c.copy_id=br.copy_id WHERE br.borrow_id=?", borrowID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	*record = *loan
+	return hold, nil
+}
+
+// borrowColumns are the columns scanned by scanBorrow
+const borrowColumns = "br.borrow_id , br.user_id , br.usertype , br.book_id , COALESCE(br.copy_id , 0) , COALESCE(c.barcode , '') , br.borrow_date , br.due_date , br.return_date , br.renewals , br.fine_cents"
+
+// scanBorrow reads a borrow record selected with borrowColumns from borrow_records br LEFT JOIN copies c
+func scanBorrow(row interface{ Scan(...any) error }) (*Borrowrecords, error) {
+	var b Borrowrecords
+	var borrowDate, dueDate time.Time
+	var returnDate sql.NullTime
+	if err := row.Scan(&b.Borrowid, &b.Userid, &b.Usertype, &b.Bookid, &b.Copyid, &b.Barcode, &borrowDate, &dueDate, &returnDate, &b.Renewals, &b.Finecents); err != nil {
+		return nil, err
+	}
+	b.Borrowdate, b.Duedate = &borrowDate, &dueDate
+	if returnDate.Valid {
+		b.Returndate = &returnDate.Time
+	}
+	return &b, nil
+}
+
+// Renew pushes the due date of an open loan back by the policy's loan period until the policy's
+// max_renewals is used up. Overdue loans cannot be renewed, so a fine never spans two due dates,
+// and neither can loans of a book other readers are waiting for.
+func (s *MySQLLibraryRepository) Renew(ctx context.Context, borrowID int) (*Borrowrecords, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	loan, err := scanBorrow(tx.QueryRow("SELECT "+borrowColumns+" FROM borrow_records br LEFT JOIN copies c ON c.copy_id=br.copy_id WHERE br.borrow_id=? AND br.return_date IS NULL FOR UPDATE", borrowID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenBorrow
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy, err := loanPolicy(tx, loan.Usertype)
+	if err != nil {
+		return nil, err
+	}
+	var overdue bool
+	if err := tx.QueryRow("SELECT due_date < CURDATE() FROM borrow_records WHERE borrow_id=?", borrowID).Scan(&overdue); err != nil {
+		return nil, err
+	}
+	if overdue {
+		return nil, ErrLoanOverdue
+	}
+	var waiting bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM holds WHERE book_id=? AND status=?)", loan.Bookid, HoldWaiting).Scan(&waiting); err != nil {
+		return nil, err
+	}
+	if waiting {
+		return nil, ErrHoldsWaiting
+	}
+	if loan.Renewals >= policy.Maxrenewals {
+		return nil, renewalLimitError(*loan, policy)
+	}
+
+	if _, err := tx.Exec("UPDATE borrow_records SET due_date=DATE_ADD(due_date , INTERVAL ? DAY) , renewals=renewals+1 WHERE borrow_id=?", policy.Loandays, borrowID); err != nil {
+		return nil, err
+	}
+	loan, err = scanBorrow(tx.QueryRow("SELECT "+borrowColumns+" FROM borrow_records br LEFT JOIN copies c ON c.copy_id=br.copy_id WHERE br.borrow_id=?", borrowID))
+	if err != nil {
+		return nil, err
+	}
+	return loan, tx.Commit()
+}
+
+// fineBalanceQuery sums the unpaid fines of a borrower
+const fineBalanceQuery = "SELECT COALESCE(SUM(CASE WHEN kind=? THEN amount_cents ELSE -amount_cents END) , 0) FROM library_fine_ledger WHERE user_id=? AND usertype=?"
+
+// fineBalance is the unpaid fines of a borrower
+func fineBalance(q dbtx, usertype string, userID int) (int64, error) {
+	var balance int64
+	err := q.QueryRow(fineBalanceQuery, LedgerCharge, userID, usertype).Scan(&balance)
+	return balance, err
+}
+
+// insertFineEntry appends an entry to the fine ledger and sets its id and time
+func insertFineEntry(q dbtx, e *FineEntry) error {
+	e.Createdat = time.Now().UTC().Truncate(time.Second)
+	res, err := q.Exec("INSERT INTO library_fine_ledger (user_id , usertype , borrow_id , kind , amount_cents , memo , created_by , created_at) VALUES (? , ? , ? , ? , ? , ? , ? , ?)",
+		e.Userid, e.Usertype, e.Borrowid, e.Kind, e.Amountcents, e.Memo, e.Createdby, e.Createdat)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	e.Entryid = int(id)
+	return nil
+}
+
+// assessLoanFine charges what a loan's fine grew by since it was last assessed, counting up to its return
+// date or today while it is out. It returns the amount charged, the loan row is locked by the caller.
+func assessLoanFine(tx *sql.Tx, borrowID int) (int64, error) {
+	var userID, daysLate int
+	var usertype string
+	var assessed int64
+	err := tx.QueryRow("SELECT user_id , usertype , DATEDIFF(COALESCE(return_date , CURDATE()) , due_date) , fine_cents FROM borrow_records WHERE borrow_id=?", borrowID).
+		Scan(&userID, &usertype, &daysLate, &assessed)
+	if err != nil {
+		return 0, err
+	}
+	policy, err := loanPolicy(tx, usertype)
+	if errors.Is(err, ErrNoLoanPolicy) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	fine := fineFor(policy, daysLate)
+	if fine <= assessed {
+		return 0, nil
+	}
+
+	entry := FineEntry{Userid: userID, Usertype: usertype, Borrowid: &borrowID, Kind: LedgerCharge, Amountcents: fine - assessed, Memo: fineMemo(borrowID, daysLate), Createdby: "system"}
+	if err := insertFineEntry(tx, &entry); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE borrow_records SET fine_cents=? WHERE borrow_id=?", fine, borrowID); err != nil {
+		return 0, err
+	}
+	return entry.Amountcents, nil
+}
+
+// AssessOverdue charges the fines of open overdue loans, each in its own transaction, and returns how many were charged
+func (s *MySQLLibraryRepository) AssessOverdue(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT borrow_id FROM borrow_records WHERE return_date IS NULL AND due_date < CURDATE() ORDER BY borrow_id")
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	charged := 0
+	for _, id := range ids {
+		err := func() error {
+			tx, err := s.db.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+
+			// lock the loan, it may have been returned since it was listed
+			var open bool
+			if err := tx.QueryRow("SELECT return_date IS NULL FROM borrow_records WHERE borrow_id=? FOR UPDATE", id).Scan(&open); err != nil || !open {
+				return err
+			}
+			amount, err := assessLoanFine(tx, id)
+			if err != nil {
+				return err
+			}
+			if amount > 0 {
+				charged++
+			}
+			return tx.Commit()
+		}()
+		if err != nil {
+			return charged, err
+		}
+	}
+	return charged, nil
+}
+
+func (s *MySQLLibraryRepository) FineAccount(ctx context.Context, usertype string, userID int) (*FineAccount, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT entry_id , user_id , usertype , borrow_id , kind , amount_cents , memo , created_by , created_at
+		FROM library_fine_ledger WHERE user_id=? AND usertype=? ORDER BY entry_id`, userID, usertype)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	account := &FineAccount{Userid: userID, Usertype: usertype, Entries: []FineEntry{}}
+	for rows.Next() {
+		var e FineEntry
+		var borrowID sql.NullInt64
+		if err := rows.Scan(&e.Entryid, &e.Userid, &e.Usertype, &borrowID, &e.Kind, &e.Amountcents, &e.Memo, &e.Createdby, &e.Createdat); err != nil {
+			return nil, err
+		}
+		if borrowID.Valid {
+			id := int(borrowID.Int64)
+			e.Borrowid = &id
+		}
+		account.addFineEntry(e)
+	}
+	return account, rows.Err()
+}
+
+// PostFineEntry records a payment or waiver of at most the unpaid balance and returns the new balance.
+// The borrower's ledger is read with a locking read so two desks cannot both take the last of a balance.
+func (s *MySQLLibraryRepository) PostFineEntry(ctx context.Context, entry *FineEntry) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var balance int64
+	if err := tx.QueryRow(fineBalanceQuery+" FOR UPDATE", LedgerCharge, entry.Userid, entry.Usertype).Scan(&balance); err != nil {
+		return 0, err
+	}
+	if entry.Amountcents > balance {
+		return 0, ErrFineOverBalance
+	}
+	if err := insertFineEntry(tx, entry); err != nil {
+		return 0, err
+	}
+	return balance - entry.Amountcents, tx.Commit()
+}
+
+// holdColumns are the columns scanned by scanHold
+const holdColumns = "h.hold_id , h.book_id , h.user_id , h.usertype , h.status , COALESCE(c.barcode , '') , h.created_at , h.ready_at , h.pickup_by , h.closed_at"
+
+// scanHold reads a hold selected with holdColumns from holds h LEFT JOIN copies c
+func scanHold(row interface{ Scan(...any) error }) (*Hold, error) {
+	var h Hold
+	var readyAt, pickupBy, closedAt sql.NullTime
+	if err := row.Scan(&h.Holdid, &h.Bookid, &h.Userid, &h.Usertype, &h.Status, &h.Barcode, &h.Createdat, &readyAt, &pickupBy, &closedAt); err != nil {
+		return nil, err
+	}
+	if readyAt.Valid {
+		h.Readyat = &readyAt.Time
+	}
+	if pickupBy.Valid {
+		h.Pickupby = &pickupBy.Time
+	}
+	if closedAt.Valid {
+		h.Closedat = &closedAt.Time
+	}
+	return &h, nil
+}
+
+// getHold reads a hold with its queue position
+func getHold(q dbtx, id int) (*Hold, error) {
+	h, err := scanHold(q.QueryRow("SELECT "+holdColumns+" FROM holds h LEFT JOIN copies c ON c.copy_id=h.copy_id WHERE h.hold_id=?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if h.Status == HoldWaiting {
+		if err := q.QueryRow("SELECT COUNT(*) FROM holds WHERE book_id=? AND status=? AND hold_id<=?", h.Bookid, HoldWaiting, h.Holdid).Scan(&h.Position); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// passCopyOn sets a copy locked by lockCopy aside for the oldest waiting hold on its book, or shelves it when
+// nobody waits, and returns the hold it went to. The book row is locked first so a hold cannot be placed
+// between the queue check and the shelving, locks are always taken copy, then book, then holds.
+func passCopyOn(tx *sql.Tx, c *Copy) (*Hold, error) {
+	var bookID, holdID int
+	if err := tx.QueryRow("SELECT book_id FROM books WHERE book_id=? FOR UPDATE", c.Bookid).Scan(&bookID); err != nil {
+		return nil, err
+	}
+	var usertype string
+	err := tx.QueryRow("SELECT hold_id , usertype FROM holds WHERE book_id=? AND status=? ORDER BY hold_id LIMIT 1 FOR UPDATE", c.Bookid, HoldWaiting).Scan(&holdID, &usertype)
+	now := time.Now().UTC()
+	if err == sql.ErrNoRows {
+		_, err := tx.Exec("UPDATE copies SET status=? , updated_at=? WHERE copy_id=?", CopyAvailable, now, c.Copyid)
+		c.Status = CopyAvailable
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy, err := loanPolicy(tx, usertype)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE holds SET status=? , copy_id=? , ready_at=? , pickup_by=DATE_ADD(CURDATE() , INTERVAL ? DAY) WHERE hold_id=?",
+		HoldReady, c.Copyid, now.Truncate(time.Second), policy.Holdpickupdays, holdID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE copies SET status=? , updated_at=? WHERE copy_id=?", CopyOnHold, now, c.Copyid); err != nil {
+		return nil, err
+	}
+	c.Status = CopyOnHold
+	return getHold(tx, holdID)
+}
+
+// offerCopy passes a copy that was added or put back into service on to a waiting hold
+func (s *MySQLLibraryRepository) offerCopy(ctx context.Context, c *Copy) error {
+	if c.Status != CopyAvailable {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockCopy(tx, c.Barcode)
+	if err != nil {
+		return err
+	}
+	if locked.Status != CopyAvailable {
+		return nil
+	}
+	if _, err := passCopyOn(tx, locked); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	c.Status = locked.Status
+	return nil
+}
+
+// PlaceHold queues a reader for a book with no available copy. The book row is locked like in passCopyOn,
+// so a copy returned at the same moment either is seen as available here or finds the new hold waiting.
+func (s *MySQLLibraryRepository) PlaceHold(ctx context.Context, hold *Hold) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := loanPolicy(tx, hold.Usertype); err != nil {
+		return err
+	}
+	var bookID int
+	err = tx.QueryRow("SELECT book_id FROM books WHERE book_id=? FOR UPDATE", hold.Bookid).Scan(&bookID)
+	if err == sql.ErrNoRows {
+		return ErrBookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var available, active int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM copies WHERE book_id=? AND status=?", hold.Bookid, CopyAvailable).Scan(&available); err != nil {
+		return err
+	}
+	if available > 0 {
+		return ErrCopiesAvailable
+	}
+	if err := tx.QueryRow("SELECT COUNT(*) FROM holds WHERE book_id=? AND user_id=? AND usertype=? AND status IN (? , ?)",
+		hold.Bookid, hold.Userid, hold.Usertype, HoldWaiting, HoldReady).Scan(&active); err != nil {
+		return err
+	}
+	if active > 0 {
+		return ErrHoldExists
+	}
+
+	res, err := tx.Exec("INSERT INTO holds (book_id , user_id , usertype , status , created_at) VALUES (? , ? , ? , ? , ?)",
+		hold.Bookid, hold.Userid, hold.Usertype, HoldWaiting, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	placed, err := getHold(tx, int(id))
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*hold = *placed
+	return nil
+}
+
+func (s *MySQLLibraryRepository) GetHold(ctx context.Context, id int) (*Hold, error) {
+	return getHold(s.db, id)
+}
+
+// CancelHold cancels an active hold and passes a copy set aside for it on. The hold is read first to find
+// its copy, which is locked before the hold to keep the lock order of passCopyOn; if the hold became ready
+// in between, the cancellation starts over.
+func (s *MySQLLibraryRepository) CancelHold(ctx context.Context, id int) (*Hold, error) {
+	for {
+		hold, err := getHold(s.db, id)
+		if err != nil {
+			return nil, err
+		}
+		if !activeHold(hold.Status) {
+			return nil, ErrHoldClosed
+		}
+		cancelled, err := s.cancelHold(ctx, hold)
+		if err != errHoldMoved {
+			return cancelled, err
+		}
+	}
+}
+
+// errHoldMoved is returned by cancelHold when the hold changed after it was read
+var errHoldMoved = errors.New("hold changed while cancelling")
+
+// cancelHold cancels a hold in the state it was read in
+func (s *MySQLLibraryRepository) cancelHold(ctx context.Context, hold *Hold) (*Hold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var c *Copy
+	if hold.Barcode != "" {
+		if c, err = lockCopy(tx, hold.Barcode); err != nil {
+			return nil, err
+		}
+	}
+	var status, barcode string
+	if err := tx.QueryRow("SELECT h.status , COALESCE(c.barcode , '') FROM holds h LEFT JOIN copies c ON c.copy_id=h.copy_id WHERE h.hold_id=? FOR UPDATE", hold.Holdid).Scan(&status, &barcode); err != nil {
+		return nil, err
+	}
+	if status != hold.Status || barcode != hold.Barcode {
+		return nil, errHoldMoved
+	}
+	if _, err := tx.Exec("UPDATE holds SET status=? , closed_at=? WHERE hold_id=?", HoldCancelled, time.Now().UTC().Truncate(time.Second), hold.Holdid); err != nil {
+		return nil, err
+	}
+	if c != nil && c.Status == CopyOnHold {
+		if _, err := passCopyOn(tx, c); err != nil {
+			return nil, err
+		}
+	}
+	cancelled, err := getHold(tx, hold.Holdid)
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, tx.Commit()
+}
+
+// ExpireHolds closes the ready holds whose pickup date has passed and passes their copies on,
+// one transaction per hold with the copy locked first like in CancelHold
+func (s *MySQLLibraryRepository) ExpireHolds(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+holdColumns+" FROM holds h LEFT JOIN copies c ON c.copy_id=h.copy_id WHERE h.status=? AND h.pickup_by < CURDATE()", HoldReady)
+	if err != nil {
+		return 0, err
+	}
+	var due []*Hold
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, h := range due {
+		n, err := s.expireHold(ctx, h)
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+	}
+	return expired, nil
+}
+
+// expireHold expires one overdue ready hold and returns 1, or 0 when it was picked up or cancelled meanwhile
+func (s *MySQLLibraryRepository) expireHold(ctx context.Context, hold *Hold) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var c *Copy
+	if hold.Barcode != "" {
+		if c, err = lockCopy(tx, hold.Barcode); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.Exec("UPDATE holds SET status=? , closed_at=? WHERE hold_id=? AND status=? AND pickup_by < CURDATE()",
+		HoldExpired, time.Now().UTC().Truncate(time.Second), hold.Holdid, HoldReady)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	if c != nil && c.Status == CopyOnHold {
+		if _, err := passCopyOn(tx, c); err != nil {
+			return 0, err
+		}
+	}
+	return 1, tx.Commit()
+}
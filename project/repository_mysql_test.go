@@ -0,0 +1,114 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// newMySQLLendingLibrary creates a library in the test database holding one new book with the given copies.
+// Book ids, barcodes and the returned user id are unique to the run so tests can share a database.
+func newMySQLLendingLibrary(t *testing.T, copies int) (s *MySQLLibraryRepository, barcodes []string, userID int) {
+	t.Helper()
+	ctx := context.Background()
+	s = NewMySQLLibraryRepository(testMySQL(t).db)
+	run := int(time.Now().UnixNano() % 1e9)
+	lib := Library{Title: fmt.Sprintf("Central %d", run), Book: []LibraryBook{{Bookid: run, Bookname: "Go"}}}
+	if err := s.Create(ctx, &lib); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < copies; i++ {
+		c := Copy{Barcode: fmt.Sprintf("T%d-%d", run, i+1), Condition: ConditionGood, Status: CopyAvailable}
+		if err := s.AddCopy(ctx, lib.Libraryid, run, &c); err != nil {
+			t.Fatal(err)
+		}
+		barcodes = append(barcodes, c.Barcode)
+	}
+	return s, barcodes, run
+}
+
+// isDeadlock reports whether MySQL rolled the transaction back to break a deadlock
+func isDeadlock(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == 1213
+}
+
+func TestMySQLBorrowOneCopyInParallel(t *testing.T) {
+	const borrowers = 50
+	s, barcodes, userID := newMySQLLendingLibrary(t, 1)
+
+	var wg sync.WaitGroup
+	errs := make([]error, borrowers)
+	for i := 0; i < borrowers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.Borrow(context.Background(), &Borrowrecords{Userid: userID + i, Usertype: "lecturer", Barcode: barcodes[0]})
+		}(i)
+	}
+	wg.Wait()
+
+	lent := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			lent++
+		case !errors.Is(err, ErrCopyUnavailable):
+			t.Errorf("borrower %d: got %v, want %v", i+1, err, ErrCopyUnavailable)
+		}
+	}
+	if lent != 1 {
+		t.Fatalf("copy lent %d times, want 1", lent)
+	}
+	c, err := s.GetCopy(context.Background(), barcodes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status != CopyOnLoan {
+		t.Fatalf("copy status %q, want %q", c.Status, CopyOnLoan)
+	}
+}
+
+// Parallel borrows of different copies lock the same range of the user's loans, so MySQL may
+// roll some of them back as deadlocks. Those are refused borrows; none may exceed max_loans.
+func TestMySQLBorrowKeepsLoanLimitInParallel(t *testing.T) {
+	const copies = 20
+	s, barcodes, userID := newMySQLLendingLibrary(t, copies)
+	policy, err := loanPolicy(s.db, "student")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, copies)
+	for i, barcode := range barcodes {
+		wg.Add(1)
+		go func(i int, barcode string) {
+			defer wg.Done()
+			errs[i] = s.Borrow(context.Background(), &Borrowrecords{Userid: userID, Usertype: "student", Barcode: barcode})
+		}(i, barcode)
+	}
+	wg.Wait()
+
+	lent := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			lent++
+		case !errors.Is(err, ErrLoanLimit) && !isDeadlock(err):
+			t.Errorf("copy %s: got %v, want %v", barcodes[i], err, ErrLoanLimit)
+		}
+	}
+	var open int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM borrow_records WHERE user_id=? AND usertype=? AND return_date IS NULL", userID, "student").Scan(&open); err != nil {
+		t.Fatal(err)
+	}
+	if lent == 0 || lent > policy.Maxloans || open != lent {
+		t.Fatalf("student got %d loans with %d open records, want between 1 and max_loans %d", lent, open, policy.Maxloans)
+	}
+}
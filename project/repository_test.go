@@ -0,0 +1,21 @@
+package project
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewStudentRepositoryRefusesStoresOutsideMySQL(t *testing.T) {
+	for _, tc := range []struct {
+		store string
+		want  error
+	}{
+		{StoreMongoDB, ErrUnsupportedStore},
+		{StoreMemory, ErrUnsupportedStore},
+		{"postgres", ErrUnknownStore},
+	} {
+		if _, err := NewStudentRepository(tc.store, nil); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.store, err, tc.want)
+		}
+	}
+}
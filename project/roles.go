@@ -0,0 +1,140 @@
+package project
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// roles granted to staff accounts through the user_roles table
+const (
+	RoleRegistrar  = "registrar"
+	RoleHOD        = "hod"
+	RoleHR         = "hr"
+	RoleLibrarian  = "librarian"
+	RoleFinance    = "finance"
+	RoleAdmissions = "admissions"
+)
+
+// currentUser returns the email JwtMiddleware stored from the access token
+func currentUser(r *http.Request) string {
+	return r.Header.Get("X-User-Email")
+}
+
+// RoleLookup reports whether a user holds a role, it replaces the user_roles table when set on HybridHandler.Roles
+type RoleLookup func(email, role string) (bool, error)
+
+// hasRole reports whether the user holds a role. Without Roles or MySQL nobody holds one.
+func (a *HybridHandler) hasRole(email, role string) (bool, error) {
+	if email == "" {
+		return false, nil
+	}
+	if a.Roles != nil {
+		return a.Roles(email, role)
+	}
+	if a.MySQL == nil {
+		return false, nil
+	}
+	var n int
+	err := a.MySQL.db.QueryRow("SELECT COUNT(*) FROM user_roles WHERE email=? AND role=?", email, role).Scan(&n)
+	return n > 0, err
+}
+
+// requireRole writes a 403 and returns false unless the current user holds the role
+func (a *HybridHandler) requireRole(w http.ResponseWriter, r *http.Request, role string) bool {
+	ok, err := a.hasRole(currentUser(r), role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		http.Error(w, "requires "+role+" role", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireAnyRole writes a 403 and returns false unless the current user holds at least one of the roles
+func (a *HybridHandler) requireAnyRole(w http.ResponseWriter, r *http.Request, roles ...string) bool {
+	for _, role := range roles {
+		ok, err := a.hasRole(currentUser(r), role)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return false
+		}
+		if ok {
+			return true
+		}
+	}
+	http.Error(w, "requires one of the roles "+strings.Join(roles, ", "), http.StatusForbidden)
+	return false
+}
+
+// teachesSection reports whether a lecturer is an instructor of the section
+func (a *HybridHandler) teachesSection(lecturerID string, sectionID int) (bool, error) {
+	var n int
+	err := a.MySQL.db.QueryRow("SELECT COUNT(*) FROM section_instructors WHERE lecturer_id=? AND section_id=?", lecturerID, sectionID).Scan(&n)
+	return n > 0, err
+}
+
+// isSectionStaff reports whether the user is a registrar or a lecturer teaching the section
+func (a *HybridHandler) isSectionStaff(email string, sectionID int) (bool, error) {
+	registrar, err := a.hasRole(email, RoleRegistrar)
+	if err != nil || registrar {
+		return registrar, err
+	}
+
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	lecturer, err := a.findLecturerByEmail(ctx, email)
+	if err == ErrLecturerNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return a.teachesSection(lecturer.Id.Hex(), sectionID)
+}
+
+// requireSectionStaff writes a 403 and returns false unless the current user teaches the section or is a registrar
+func (a *HybridHandler) requireSectionStaff(w http.ResponseWriter, r *http.Request, sectionID int) bool {
+	ok, err := a.isSectionStaff(currentUser(r), sectionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		http.Error(w, "only lecturers teaching this section can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// isStudent reports whether the user's email belongs to the student
+func (a *HybridHandler) isStudent(email string, studentID int) (bool, error) {
+	if email == "" {
+		return false, nil
+	}
+	var n int
+	err := a.MySQL.db.QueryRow("SELECT COUNT(*) FROM students WHERE id=? AND email=?", studentID, email).Scan(&n)
+	return n > 0, err
+}
+
+// requireStudentOrRegistrar writes a 403 and returns false unless the current user is the student or a registrar
+func (a *HybridHandler) requireStudentOrRegistrar(w http.ResponseWriter, r *http.Request, studentID int) bool {
+	email := currentUser(r)
+	ok, err := a.isStudent(email, studentID)
+	if err == nil && !ok {
+		ok, err = a.hasRole(email, RoleRegistrar)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		http.Error(w, "only the student or a registrar can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,566 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Room represents a teaching room
+type Room struct {
+	Roomid   int      `json:"room_id"`
+	Name     string   `json:"name"`
+	Building string   `json:"building"`
+	Capacity int      `json:"capacity"`
+	Features []string `json:"features"`
+}
+
+// Meeting is one weekly meeting of a section, day_of_week runs 1 (Monday) to 7 (Sunday)
+type Meeting struct {
+	Meetingid int    `json:"meeting_id"`
+	Sectionid int    `json:"section_id"`
+	Roomid    int    `json:"room_id"`
+	Day       int    `json:"day_of_week"`
+	Starttime string `json:"start_time"`
+	Endtime   string `json:"end_time"`
+}
+
+// ScheduleConflict describes an existing meeting that clashes with a requested one.
+// Kind is room, lecturer, student or section; With holds the shared lecturer or student id.
+type ScheduleConflict struct {
+	Kind    string  `json:"kind"`
+	With    string  `json:"with,omitempty"`
+	Meeting Meeting `json:"meeting"`
+}
+
+// ScheduleConflictError is returned when a change would double-book a room, lecturer or student
+type ScheduleConflictError struct {
+	Conflicts []ScheduleConflict
+}
+
+func (e *ScheduleConflictError) Error() string {
+	return fmt.Sprintf("schedule conflict with %d existing meetings", len(e.Conflicts))
+}
+
+// TimetableEntry is one row of a weekly timetable view
+type TimetableEntry struct {
+	Day       int    `json:"day_of_week"`
+	Dayname   string `json:"day"`
+	Starttime string `json:"start_time"`
+	Endtime   string `json:"end_time"`
+	Sectionid int    `json:"section_id"`
+	Code      string `json:"code"`
+	Title     string `json:"title"`
+	Room      string `json:"room"`
+	Building  string `json:"building"`
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// parseClock converts "HH:MM" or "HH:MM:SS" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		t, err = time.Parse("15:04:05", s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// formatClock converts minutes after midnight into "HH:MM"
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// trimClock drops the seconds MySQL appends to TIME values
+func trimClock(s string) string {
+	if len(s) > 5 {
+		return s[:5]
+	}
+	return s
+}
+
+// ValidateRoom validates incoming room data
+func ValidateRoom(room Room) error {
+	if strings.TrimSpace(room.Name) == "" {
+		return fmt.Errorf("room name cannot be empty")
+	}
+	if strings.TrimSpace(room.Building) == "" {
+		return fmt.Errorf("building cannot be empty")
+	}
+	if room.Capacity <= 0 {
+		return fmt.Errorf("capacity must be greater than 0")
+	}
+	for _, f := range room.Features {
+		if strings.TrimSpace(f) == "" || strings.Contains(f, ",") {
+			return fmt.Errorf("invalid room feature %q", f)
+		}
+	}
+	return nil
+}
+
+// ValidateMeeting validates a weekly meeting pattern
+func ValidateMeeting(m Meeting) error {
+	if m.Roomid <= 0 {
+		return fmt.Errorf("invalid room_id")
+	}
+	return validateWeeklyWindow(m.Day, m.Starttime, m.Endtime)
+}
+
+// validateWeeklyWindow checks a day of week and a start/end time pair
+func validateWeeklyWindow(day int, startTime, endTime string) error {
+	if day < 1 || day > 7 {
+		return fmt.Errorf("day_of_week must be between 1 (Monday) and 7 (Sunday)")
+	}
+	start, err := parseClock(startTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(endTime)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("end_time must be after start_time")
+	}
+	return nil
+}
+
+// scanMeetings reads meeting rows selected as meeting_id , section_id , room_id , day_of_week , start_time , end_time
+func scanMeetings(rows *sql.Rows) ([]Meeting, error) {
+	defer rows.Close()
+	var meetings []Meeting
+	for rows.Next() {
+		var m Meeting
+		if err := rows.Scan(&m.Meetingid, &m.Sectionid, &m.Roomid, &m.Day, &m.Starttime, &m.Endtime); err != nil {
+			return nil, err
+		}
+		m.Starttime, m.Endtime = trimClock(m.Starttime), trimClock(m.Endtime)
+		meetings = append(meetings, m)
+	}
+	return meetings, rows.Err()
+}
+
+// findMeetingConflicts returns every meeting in the same term that overlaps the proposed
+// meeting and shares its room, one of its lecturers, one of its enrolled students or its section.
+func findMeetingConflicts(q dbtx, term string, m Meeting) ([]ScheduleConflict, error) {
+	const overlap = `FROM section_meetings o JOIN sections so ON so.section_id=o.section_id
+		%s
+		WHERE so.term=? AND o.day_of_week=? AND o.start_time<? AND o.end_time>? AND o.meeting_id<>? %s`
+	cols := "SELECT o.meeting_id , o.section_id , o.room_id , o.day_of_week , o.start_time , o.end_time , %s "
+	base := []interface{}{term, m.Day, m.Endtime, m.Starttime, m.Meetingid}
+
+	checks := []struct {
+		kind  string
+		with  string
+		join  string
+		where string
+		args  []interface{}
+	}{
+		{"room", "''", "", "AND o.room_id=?", []interface{}{m.Roomid}},
+		{"section", "''", "", "AND o.section_id=?", []interface{}{m.Sectionid}},
+		{"lecturer", "si.lecturer_id",
+			"JOIN section_instructors si ON si.section_id=o.section_id JOIN section_instructors mine ON mine.lecturer_id=si.lecturer_id",
+			"AND mine.section_id=? AND o.section_id<>mine.section_id", []interface{}{m.Sectionid}},
+		{"student", "CAST(e.student_id AS CHAR)",
+			"JOIN enrollments e ON e.section_id=o.section_id AND e.status='enrolled' JOIN enrollments mine ON mine.student_id=e.student_id AND mine.status='enrolled'",
+			"AND mine.section_id=? AND o.section_id<>mine.section_id", []interface{}{m.Sectionid}},
+	}
+
+	conflicts := []ScheduleConflict{}
+	for _, c := range checks {
+		query := fmt.Sprintf(cols, c.with) + fmt.Sprintf(overlap, c.join, c.where)
+		rows, err := q.Query(query, append(append([]interface{}{}, base...), c.args...)...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			conflict := ScheduleConflict{Kind: c.kind}
+			o := &conflict.Meeting
+			if err := rows.Scan(&o.Meetingid, &o.Sectionid, &o.Roomid, &o.Day, &o.Starttime, &o.Endtime, &conflict.With); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			o.Starttime, o.Endtime = trimClock(o.Starttime), trimClock(o.Endtime)
+			conflicts = append(conflicts, conflict)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return conflicts, nil
+}
+
+// sectionClashes returns meetings of the given other sections that overlap any meeting of sectionID in the same term.
+// others is a subquery selecting section_id, filled with othersArgs.
+func sectionClashes(q dbtx, kind, with string, sectionID int, others string, othersArgs ...interface{}) ([]ScheduleConflict, error) {
+	rows, err := q.Query(`SELECT o.meeting_id , o.section_id , o.room_id , o.day_of_week , o.start_time , o.end_time
+		FROM section_meetings mine
+		JOIN sections sm ON sm.section_id=mine.section_id
+		JOIN section_meetings o ON o.day_of_week=mine.day_of_week AND o.start_time<mine.end_time AND o.end_time>mine.start_time AND o.section_id<>mine.section_id
+		JOIN sections so ON so.section_id=o.section_id AND so.term=sm.term
+		WHERE mine.section_id=? AND o.section_id IN (`+others+`)`, append([]interface{}{sectionID}, othersArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	meetings, err := scanMeetings(rows)
+	if err != nil {
+		return nil, err
+	}
+	conflicts := []ScheduleConflict{}
+	for _, m := range meetings {
+		conflicts = append(conflicts, ScheduleConflict{Kind: kind, With: with, Meeting: m})
+	}
+	return conflicts, nil
+}
+
+// CreateRoomHandler handles creation of a room
+func (a *HybridHandler) CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Decode incoming JSON request body
+	var room Room
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// validate input
+	if err := ValidateRoom(room); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	res, err := a.MySQL.db.Exec("INSERT INTO rooms (name , building , capacity , features) VALUES (? , ? , ? , ?)", room.Name, room.Building, room.Capacity, strings.Join(room.Features, ","))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to insert room: %v", err), http.StatusInternalServerError)
+		return
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	room.Roomid = int(id)
+
+	go LogActivity("CREATE_ROOM", "system")
+	go AuditLog("CREATE", "ROOM", room.Roomid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(room)
+}
+
+// loadRooms fetches every room ordered by capacity
+func loadRooms(q dbtx) ([]Room, error) {
+	rows, err := q.Query("SELECT room_id , name , building , capacity , features FROM rooms ORDER BY capacity , room_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := []Room{}
+	for rows.Next() {
+		var room Room
+		var features string
+		if err := rows.Scan(&room.Roomid, &room.Name, &room.Building, &room.Capacity, &features); err != nil {
+			return nil, err
+		}
+		room.Features = []string{}
+		if features != "" {
+			room.Features = strings.Split(features, ",")
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+// GetRoomsHandler lists all rooms, optionally only those with a feature and minimum capacity
+func (a *HybridHandler) GetRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	rooms, err := loadRooms(a.MySQL.db)
+	if err != nil {
+		http.Error(w, "unable to fetch rooms", http.StatusInternalServerError)
+		return
+	}
+
+	feature := r.URL.Query().Get("feature")
+	minCapacity, _ := strconv.Atoi(r.URL.Query().Get("min_capacity"))
+	filtered := []Room{}
+	for _, room := range rooms {
+		if room.Capacity < minCapacity {
+			continue
+		}
+		if feature != "" && !hasFeature(room, feature) {
+			continue
+		}
+		filtered = append(filtered, room)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filtered)
+}
+
+// hasFeature reports whether a room lists the feature
+func hasFeature(room Room, feature string) bool {
+	for _, f := range room.Features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateMeetingHandler schedules a weekly meeting for a section, rejecting double-bookings
+func (a *HybridHandler) CreateMeetingHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var m Meeting
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	m.Sectionid = sectionID
+
+	// validate input
+	if err := ValidateMeeting(m); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+	start, _ := parseClock(m.Starttime)
+	end, _ := parseClock(m.Endtime)
+	m.Starttime, m.Endtime = formatClock(start), formatClock(end)
+
+	// Begin transaction, section and room rows are locked while conflicts are checked
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	_, capacity, err := lockSection(tx, sectionID)
+	if err != nil {
+		tx.Rollback()
+		writeEnrollmentError(w, err)
+		return
+	}
+	var term string
+	if err := tx.QueryRow("SELECT term FROM sections WHERE section_id=?", sectionID).Scan(&term); err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var roomCapacity int
+	if err := tx.QueryRow("SELECT capacity FROM rooms WHERE room_id=? FOR UPDATE", m.Roomid).Scan(&roomCapacity); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if roomCapacity < capacity {
+		tx.Rollback()
+		http.Error(w, fmt.Sprintf("room capacity %d is smaller than section capacity %d", roomCapacity, capacity), http.StatusBadRequest)
+		return
+	}
+
+	conflicts, err := findMeetingConflicts(tx, term, m)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(conflicts) > 0 {
+		tx.Rollback()
+		writeEnrollmentError(w, &ScheduleConflictError{Conflicts: conflicts})
+		return
+	}
+
+	res, err := tx.Exec("INSERT INTO section_meetings (section_id , room_id , day_of_week , start_time , end_time) VALUES (? , ? , ? , ? , ?)", sectionID, m.Roomid, m.Day, m.Starttime, m.Endtime)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "failed to insert meeting", http.StatusInternalServerError)
+		return
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+	m.Meetingid = int(id)
+
+	go LogActivity("CREATE_MEETING", "system")
+	go AuditLog("CREATE", "MEETING", m.Meetingid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(m)
+}
+
+// GetSectionMeetingsHandler lists the weekly meetings of a section
+func (a *HybridHandler) GetSectionMeetingsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract section id from URL
+	sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT meeting_id , section_id , room_id , day_of_week , start_time , end_time FROM section_meetings WHERE section_id=? ORDER BY day_of_week , start_time", sectionID)
+	if err != nil {
+		http.Error(w, "unable to fetch meetings", http.StatusInternalServerError)
+		return
+	}
+	meetings, err := scanMeetings(rows)
+	if err != nil {
+		http.Error(w, "rows scan failed", http.StatusInternalServerError)
+		return
+	}
+	if meetings == nil {
+		meetings = []Meeting{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(meetings)
+}
+
+// DeleteMeetingHandler removes a weekly meeting
+func (a *HybridHandler) DeleteMeetingHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	res, err := a.MySQL.db.Exec("DELETE FROM section_meetings WHERE meeting_id=?", id)
+	if err != nil {
+		http.Error(w, "unable to delete", http.StatusInternalServerError)
+		return
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "meeting not found", http.StatusNotFound)
+		return
+	}
+
+	go LogActivity("DELETE_MEETING", "system")
+	go AuditLog("DELETE", "MEETING", id, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("meeting deleted!"))
+}
+
+// timetable loads weekly timetable entries for the sections matched by the subquery
+func timetable(q dbtx, term string, sections string, args ...interface{}) ([]TimetableEntry, error) {
+	query := `SELECT m.day_of_week , m.start_time , m.end_time , s.section_id , c.code , c.title , r.name , r.building
+		FROM section_meetings m
+		JOIN sections s ON s.section_id=m.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		JOIN rooms r ON r.room_id=m.room_id
+		WHERE m.section_id IN (` + sections + `)`
+	if term != "" {
+		query += " AND s.term=?"
+		args = append(args, term)
+	}
+	query += " ORDER BY m.day_of_week , m.start_time"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []TimetableEntry{}
+	for rows.Next() {
+		var e TimetableEntry
+		if err := rows.Scan(&e.Day, &e.Starttime, &e.Endtime, &e.Sectionid, &e.Code, &e.Title, &e.Room, &e.Building); err != nil {
+			return nil, err
+		}
+		e.Starttime, e.Endtime = trimClock(e.Starttime), trimClock(e.Endtime)
+		e.Dayname = time.Weekday(e.Day % 7).String()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetStudentTimetableHandler returns a student's weekly timetable for their enrolled sections
+func (a *HybridHandler) GetStudentTimetableHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := timetable(a.MySQL.db, r.URL.Query().Get("term"), "SELECT section_id FROM enrollments WHERE student_id=? AND status='enrolled'", studentID)
+	if err != nil {
+		http.Error(w, "unable to fetch timetable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetLecturerTimetableHandler returns a lecturer's weekly timetable for the sections they teach
+func (a *HybridHandler) GetLecturerTimetableHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id := mux.Vars(r)["id"]
+
+	// Lecturer must exist in the lecturer store
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	if _, err := a.findLecturer(ctx, id); err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+
+	entries, err := timetable(a.MySQL.db, r.URL.Query().Get("term"), "SELECT section_id FROM section_instructors WHERE lecturer_id=?", id)
+	if err != nil {
+		http.Error(w, "unable to fetch timetable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
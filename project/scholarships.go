@@ -0,0 +1,739 @@
+package project
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// scholarship kinds, application and award statuses
+const (
+	ScholarshipMerit = "merit"
+	ScholarshipNeed  = "need"
+
+	ApplicationPending  = "pending"
+	ApplicationApproved = "approved"
+	ApplicationRejected = "rejected"
+
+	AwardActive     = "active"
+	AwardApplied    = "applied"
+	AwardNotRenewed = "not_renewed"
+)
+
+// Scholarship defines an award and who may receive it. Exactly one of amount_cents and percent
+// (of the term invoice) is set; income bands run from 1 (lowest income) to 5.
+type Scholarship struct {
+	Scholarshipid int       `json:"scholarship_id"`
+	Name          string    `json:"name"`
+	Kind          string    `json:"kind"`
+	Amountcents   int64     `json:"amount_cents"`
+	Percent       float64   `json:"percent"`
+	Mingpa        *float64  `json:"min_gpa,omitempty"`
+	Dept          string    `json:"dept,omitempty"`
+	Maxincomeband *int      `json:"max_income_band,omitempty"`
+	Renewable     bool      `json:"renewable"`
+	Renewalmingpa *float64  `json:"renewal_min_gpa,omitempty"`
+	Createdat     time.Time `json:"created_at"`
+}
+
+// ScholarshipApplication is a student's request for a scholarship starting in a term
+type ScholarshipApplication struct {
+	Applicationid int        `json:"application_id"`
+	Scholarshipid int        `json:"scholarship_id"`
+	Studentid     int        `json:"student_id"`
+	Term          string     `json:"term"`
+	Incomeband    *int       `json:"income_band,omitempty"`
+	Statement     string     `json:"statement"`
+	Status        string     `json:"status"`
+	Decisionnote  string     `json:"decision_note,omitempty"`
+	Decidedby     string     `json:"decided_by,omitempty"`
+	Decidedat     *time.Time `json:"decided_at,omitempty"`
+	Createdat     time.Time  `json:"created_at"`
+}
+
+// ScholarshipAward is an approved scholarship for one term, credited once the term is invoiced.
+// What the invoice no longer owes is kept as credit on the student's account.
+type ScholarshipAward struct {
+	Awardid       int       `json:"award_id"`
+	Applicationid int       `json:"application_id"`
+	Scholarshipid int       `json:"scholarship_id"`
+	Term          string    `json:"term"`
+	Status        string    `json:"status"`
+	Appliedcents  int64     `json:"applied_cents"`
+	Creditcents   int64     `json:"credit_cents"`
+	Invoiceid     *int      `json:"invoice_id,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	Createdat     time.Time `json:"created_at"`
+}
+
+// IneligibleError lists the eligibility rules a student does not meet
+type IneligibleError struct {
+	Reasons []string
+}
+
+func (e *IneligibleError) Error() string {
+	return "not eligible: " + strings.Join(e.Reasons, "; ")
+}
+
+// errors returned by the scholarship helpers
+var (
+	ErrScholarshipNotFound = errors.New("scholarship not found")
+	ErrApplicationNotFound = errors.New("application not found")
+	ErrAlreadyApplied      = errors.New("student has already applied for this scholarship")
+	ErrApplicationDecided  = errors.New("application has already been decided")
+)
+
+// ValidateScholarship validates incoming scholarship data
+func ValidateScholarship(s Scholarship) error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if s.Kind != ScholarshipMerit && s.Kind != ScholarshipNeed {
+		return fmt.Errorf("kind must be merit or need")
+	}
+	if (s.Amountcents > 0) == (s.Percent > 0) {
+		return fmt.Errorf("set exactly one of amount_cents and percent")
+	}
+	if s.Amountcents < 0 || s.Percent < 0 || s.Percent > 100 {
+		return fmt.Errorf("amount_cents must be positive and percent between 0 and 100")
+	}
+	for _, gpa := range []*float64{s.Mingpa, s.Renewalmingpa} {
+		if gpa != nil && (*gpa < 0 || *gpa > 4) {
+			return fmt.Errorf("gpa thresholds must be between 0 and 4")
+		}
+	}
+	if s.Maxincomeband != nil && (*s.Maxincomeband < 1 || *s.Maxincomeband > 5) {
+		return fmt.Errorf("max_income_band must be between 1 and 5")
+	}
+	if s.Kind == ScholarshipNeed && s.Maxincomeband == nil {
+		return fmt.Errorf("need based scholarships require max_income_band")
+	}
+	return nil
+}
+
+// loadScholarship fetches one scholarship
+func loadScholarship(q dbtx, id int) (*Scholarship, error) {
+	var s Scholarship
+	var minGPA, renewalGPA sql.NullFloat64
+	var dept sql.NullString
+	var band sql.NullInt64
+	err := q.QueryRow("SELECT scholarship_id , name , kind , amount_cents , percent , min_gpa , dept , max_income_band , renewable , renewal_min_gpa , created_at FROM scholarships WHERE scholarship_id=?", id).
+		Scan(&s.Scholarshipid, &s.Name, &s.Kind, &s.Amountcents, &s.Percent, &minGPA, &dept, &band, &s.Renewable, &renewalGPA, &s.Createdat)
+	if err == sql.ErrNoRows {
+		return nil, ErrScholarshipNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if minGPA.Valid {
+		s.Mingpa = &minGPA.Float64
+	}
+	if renewalGPA.Valid {
+		s.Renewalmingpa = &renewalGPA.Float64
+	}
+	if band.Valid {
+		b := int(band.Int64)
+		s.Maxincomeband = &b
+	}
+	s.Dept = dept.String
+	return &s, nil
+}
+
+// scholarshipEligibility checks department, GPA and income rules, returning an *IneligibleError with every failed rule
+func scholarshipEligibility(q dbtx, s *Scholarship, studentID int, incomeBand *int, minGPA *float64) error {
+	var dept sql.NullString
+	err := q.QueryRow("SELECT dept FROM students WHERE id=?", studentID).Scan(&dept)
+	if err == sql.ErrNoRows {
+		return ErrStudentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var reasons []string
+	if s.Dept != "" && !strings.EqualFold(s.Dept, dept.String) {
+		reasons = append(reasons, "only open to the "+s.Dept+" department")
+	}
+	if minGPA != nil {
+		gpa, err := studentGPA(q, studentID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case gpa.Credits == 0:
+			reasons = append(reasons, "no finalized grades to assess GPA")
+		case gpa.Cumulative < *minGPA:
+			reasons = append(reasons, fmt.Sprintf("cumulative GPA %.2f is below %.2f", gpa.Cumulative, *minGPA))
+		}
+	}
+	if s.Maxincomeband != nil {
+		switch {
+		case incomeBand == nil:
+			reasons = append(reasons, "income_band is required")
+		case *incomeBand > *s.Maxincomeband:
+			reasons = append(reasons, fmt.Sprintf("income band %d is above %d", *incomeBand, *s.Maxincomeband))
+		}
+	}
+	if len(reasons) > 0 {
+		return &IneligibleError{Reasons: reasons}
+	}
+	return nil
+}
+
+// splitAward divides an award between what is still owed on the invoice and account credit
+func splitAward(amount, outstanding int64) (applied, credit int64) {
+	applied = max(min(amount, outstanding), 0)
+	return applied, amount - applied
+}
+
+// applyAward credits an active award to an invoice, never more than is still owed on it.
+// The rest, e.g. when the invoice was already paid, is carried as credit on the student's account.
+func applyAward(tx dbtx, awardID, invoiceID int, actor string) error {
+	var s Scholarship
+	var studentID int
+	err := tx.QueryRow(`SELECT s.name , s.amount_cents , s.percent , sa.student_id FROM scholarship_awards aw
+		JOIN scholarship_applications sa ON sa.application_id=aw.application_id
+		JOIN scholarships s ON s.scholarship_id=sa.scholarship_id
+		WHERE aw.award_id=?`, awardID).Scan(&s.Name, &s.Amountcents, &s.Percent, &studentID)
+	if err != nil {
+		return err
+	}
+	var total int64
+	if err := tx.QueryRow("SELECT total_cents FROM invoices WHERE invoice_id=?", invoiceID).Scan(&total); err != nil {
+		return err
+	}
+	outstanding, err := receivableBalance(tx, "invoice_id", invoiceID)
+	if err != nil {
+		return err
+	}
+
+	amount := s.Amountcents
+	if s.Percent > 0 {
+		amount = int64(float64(total) * s.Percent / 100)
+	}
+	applied, credit := splitAward(amount, outstanding)
+	if applied > 0 {
+		if _, err := postLedger(tx, studentID, &invoiceID, LedgerAward, applied, "scholarship: "+s.Name, actor); err != nil {
+			return err
+		}
+	}
+	// posted without an invoice, the credit lowers the account balance below what the invoices owe
+	if credit > 0 {
+		if _, err := postLedger(tx, studentID, nil, LedgerAward, credit, "scholarship credit: "+s.Name, actor); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE scholarship_awards SET status=? , applied_cents=? , credit_cents=? , invoice_id=? WHERE award_id=?", AwardApplied, applied, credit, invoiceID, awardID); err != nil {
+		return err
+	}
+	_, err = refreshInvoiceStatus(tx, invoiceID)
+	return err
+}
+
+// applyTermAwards credits every active award of a student for a term to the term's invoice
+func applyTermAwards(tx dbtx, studentID int, term string, invoiceID int, actor string) error {
+	rows, err := tx.Query(`SELECT aw.award_id FROM scholarship_awards aw
+		JOIN scholarship_applications sa ON sa.application_id=aw.application_id
+		WHERE sa.student_id=? AND aw.term=? AND aw.status=? ORDER BY aw.award_id`, studentID, term, AwardActive)
+	if err != nil {
+		return err
+	}
+	var awards []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		awards = append(awards, id)
+	}
+	rows.Close()
+	for _, id := range awards {
+		if err := applyAward(tx, id, invoiceID, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantAward creates an active award for a term and credits it at once if the term is already invoiced
+func grantAward(tx dbtx, applicationID, studentID int, term, actor string) (int, error) {
+	res, err := tx.Exec("INSERT INTO scholarship_awards (application_id , term , status , created_at) VALUES (? , ? , ? , ?)", applicationID, term, AwardActive, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	var invoiceID int
+	err = tx.QueryRow("SELECT invoice_id FROM invoices WHERE student_id=? AND term=?", studentID, term).Scan(&invoiceID)
+	if err == sql.ErrNoRows {
+		return int(id), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(id), applyAward(tx, int(id), invoiceID, actor)
+}
+
+// writeScholarshipError maps scholarship errors to HTTP responses
+func writeScholarshipError(w http.ResponseWriter, err error) {
+	var ineligible *IneligibleError
+	switch {
+	case errors.Is(err, ErrScholarshipNotFound), errors.Is(err, ErrApplicationNotFound), errors.Is(err, ErrStudentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAlreadyApplied), errors.Is(err, ErrApplicationDecided):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &ineligible):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{"err": "not eligible", "reasons": ineligible.Reasons})
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// CreateScholarshipHandler defines a scholarship, finance only
+func (a *HybridHandler) CreateScholarshipHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleFinance) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var s Scholarship
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// Validate scholarship data
+	if err := ValidateScholarship(s); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	s.Createdat = time.Now()
+	var dept interface{}
+	if s.Dept != "" {
+		dept = s.Dept
+	}
+	res, err := a.MySQL.db.Exec("INSERT INTO scholarships (name , kind , amount_cents , percent , min_gpa , dept , max_income_band , renewable , renewal_min_gpa , created_at) VALUES (? , ? , ? , ? , ? , ? , ? , ? , ? , ?)",
+		s.Name, s.Kind, s.Amountcents, s.Percent, s.Mingpa, dept, s.Maxincomeband, s.Renewable, s.Renewalmingpa, s.Createdat)
+	if err != nil {
+		http.Error(w, "failed to create scholarship", http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+	s.Scholarshipid = int(id)
+
+	go LogActivity("CREATE_SCHOLARSHIP", currentUser(r))
+	go AuditLog("CREATE", "SCHOLARSHIP", s.Scholarshipid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// GetScholarshipsHandler lists all scholarships
+func (a *HybridHandler) GetScholarshipsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.MySQL.db.Query("SELECT scholarship_id FROM scholarships ORDER BY name")
+	if err != nil {
+		http.Error(w, "unable to fetch scholarships", http.StatusInternalServerError)
+		return
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	scholarships := []Scholarship{}
+	for _, id := range ids {
+		s, err := loadScholarship(a.MySQL.db, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		scholarships = append(scholarships, *s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scholarships)
+}
+
+// ApplyScholarshipHandler files an application after checking the eligibility rules
+func (a *HybridHandler) ApplyScholarshipHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract scholarship id from URL
+	scholarshipID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var app ScholarshipApplication
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if app.Studentid <= 0 || strings.TrimSpace(app.Term) == "" {
+		http.Error(w, "student_id and term are required", http.StatusBadRequest)
+		return
+	}
+	if app.Incomeband != nil && (*app.Incomeband < 1 || *app.Incomeband > 5) {
+		http.Error(w, "income_band must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, app.Studentid) {
+		return
+	}
+
+	s, err := loadScholarship(a.MySQL.db, scholarshipID)
+	if err != nil {
+		writeScholarshipError(w, err)
+		return
+	}
+	if err := scholarshipEligibility(a.MySQL.db, s, app.Studentid, app.Incomeband, s.Mingpa); err != nil {
+		writeScholarshipError(w, err)
+		return
+	}
+
+	app.Scholarshipid = scholarshipID
+	app.Status = ApplicationPending
+	app.Createdat = time.Now()
+	res, err := a.MySQL.db.Exec("INSERT IGNORE INTO scholarship_applications (scholarship_id , student_id , term , income_band , statement , status , created_at) VALUES (? , ? , ? , ? , ? , ? , ?)",
+		app.Scholarshipid, app.Studentid, app.Term, app.Incomeband, app.Statement, app.Status, app.Createdat)
+	if err != nil {
+		http.Error(w, "failed to save application", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeScholarshipError(w, ErrAlreadyApplied)
+		return
+	}
+	id, _ := res.LastInsertId()
+	app.Applicationid = int(id)
+
+	go LogActivity("APPLY_SCHOLARSHIP", currentUser(r))
+	go AuditLog("APPLY", "SCHOLARSHIP_APPLICATION", app.Applicationid, currentUser(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(app)
+}
+
+// scanApplications reads application rows in the column order of applicationColumns
+func scanApplications(rows *sql.Rows) ([]ScholarshipApplication, error) {
+	defer rows.Close()
+	apps := []ScholarshipApplication{}
+	for rows.Next() {
+		var app ScholarshipApplication
+		var band sql.NullInt64
+		var statement, note, decidedBy sql.NullString
+		var decidedAt sql.NullTime
+		if err := rows.Scan(&app.Applicationid, &app.Scholarshipid, &app.Studentid, &app.Term, &band, &statement, &app.Status, &note, &decidedBy, &decidedAt, &app.Createdat); err != nil {
+			return nil, err
+		}
+		if band.Valid {
+			b := int(band.Int64)
+			app.Incomeband = &b
+		}
+		if decidedAt.Valid {
+			app.Decidedat = &decidedAt.Time
+		}
+		app.Statement, app.Decisionnote, app.Decidedby = statement.String, note.String, decidedBy.String
+		apps = append(apps, app)
+	}
+	return apps, rows.Err()
+}
+
+// applicationColumns is the select list read by scanApplications
+const applicationColumns = "application_id , scholarship_id , student_id , term , income_band , statement , status , decision_note , decided_by , decided_at , created_at"
+
+// GetScholarshipApplicationsHandler lists the applications of a scholarship (?status=), finance only
+func (a *HybridHandler) GetScholarshipApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleFinance) {
+		return
+	}
+
+	// Extract scholarship id from URL
+	scholarshipID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	query := "SELECT " + applicationColumns + " FROM scholarship_applications WHERE scholarship_id=?"
+	args := []interface{}{scholarshipID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND status=?"
+		args = append(args, status)
+	}
+	rows, err := a.MySQL.db.Query(query+" ORDER BY created_at", args...)
+	if err != nil {
+		http.Error(w, "unable to fetch applications", http.StatusInternalServerError)
+		return
+	}
+	apps, err := scanApplications(rows)
+	if err != nil {
+		http.Error(w, "rows scan failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apps)
+}
+
+// DecideScholarshipApplicationHandler approves or rejects a pending application, finance only.
+// Approval re-checks eligibility and awards the scholarship for the application's term.
+func (a *HybridHandler) DecideScholarshipApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleFinance) {
+		return
+	}
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Approve bool   `json:"approve"`
+		Note    string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	actor := currentUser(r)
+	var app ScholarshipApplication
+	err = func() error {
+		tx, err := a.MySQL.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		rows, err := tx.Query("SELECT "+applicationColumns+" FROM scholarship_applications WHERE application_id=? FOR UPDATE", id)
+		if err != nil {
+			return err
+		}
+		apps, err := scanApplications(rows)
+		if err != nil {
+			return err
+		}
+		if len(apps) == 0 {
+			return ErrApplicationNotFound
+		}
+		app = apps[0]
+		if app.Status != ApplicationPending {
+			return ErrApplicationDecided
+		}
+
+		app.Status = ApplicationRejected
+		if req.Approve {
+			s, err := loadScholarship(tx, app.Scholarshipid)
+			if err != nil {
+				return err
+			}
+			if err := scholarshipEligibility(tx, s, app.Studentid, app.Incomeband, s.Mingpa); err != nil {
+				return err
+			}
+			if _, err := grantAward(tx, app.Applicationid, app.Studentid, app.Term, actor); err != nil {
+				return err
+			}
+			app.Status = ApplicationApproved
+		}
+		now := time.Now()
+		app.Decisionnote, app.Decidedby, app.Decidedat = req.Note, actor, &now
+		if _, err := tx.Exec("UPDATE scholarship_applications SET status=? , decision_note=? , decided_by=? , decided_at=? WHERE application_id=?",
+			app.Status, app.Decisionnote, actor, now, id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		writeScholarshipError(w, err)
+		return
+	}
+
+	go LogActivity("DECIDE_SCHOLARSHIP", actor)
+	go AuditLog(strings.ToUpper(app.Status), "SCHOLARSHIP_APPLICATION", id, actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+// EvaluateRenewalsHandler decides renewals of renewable scholarships from one term to the next, finance only.
+// Holders still meeting the rules (renewal_min_gpa when set, else min_gpa) get an award for to_term.
+func (a *HybridHandler) EvaluateRenewalsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleFinance) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var req struct {
+		Fromterm string `json:"from_term"`
+		Toterm   string `json:"to_term"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	if req.Fromterm == "" || req.Toterm == "" || req.Fromterm == req.Toterm {
+		http.Error(w, "from_term and to_term are required and must differ", http.StatusBadRequest)
+		return
+	}
+
+	// holders of renewable scholarships in from_term not yet evaluated for to_term
+	rows, err := a.MySQL.db.Query(`SELECT sa.application_id , sa.scholarship_id , sa.student_id , sa.income_band
+		FROM scholarship_awards aw
+		JOIN scholarship_applications sa ON sa.application_id=aw.application_id
+		JOIN scholarships s ON s.scholarship_id=sa.scholarship_id
+		LEFT JOIN scholarship_awards nxt ON nxt.application_id=sa.application_id AND nxt.term=?
+		WHERE aw.term=? AND aw.status IN (? , ?) AND s.renewable AND sa.status=? AND nxt.award_id IS NULL`,
+		req.Toterm, req.Fromterm, AwardActive, AwardApplied, ApplicationApproved)
+	if err != nil {
+		http.Error(w, "unable to fetch awards", http.StatusInternalServerError)
+		return
+	}
+	type holder struct {
+		application, scholarship, student int
+		band                              *int
+	}
+	var holders []holder
+	for rows.Next() {
+		var h holder
+		var band sql.NullInt64
+		if err := rows.Scan(&h.application, &h.scholarship, &h.student, &band); err != nil {
+			rows.Close()
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		if band.Valid {
+			b := int(band.Int64)
+			h.band = &b
+		}
+		holders = append(holders, h)
+	}
+	rows.Close()
+
+	actor := currentUser(r)
+	renewed := []int{}
+	notRenewed := map[int][]string{}
+	for _, h := range holders {
+		err := func() error {
+			tx, err := a.MySQL.db.Begin()
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			s, err := loadScholarship(tx, h.scholarship)
+			if err != nil {
+				return err
+			}
+			threshold := s.Mingpa
+			if s.Renewalmingpa != nil {
+				threshold = s.Renewalmingpa
+			}
+			err = scholarshipEligibility(tx, s, h.student, h.band, threshold)
+			var ineligible *IneligibleError
+			switch {
+			case errors.As(err, &ineligible):
+				if _, err := tx.Exec("INSERT INTO scholarship_awards (application_id , term , status , note , created_at) VALUES (? , ? , ? , ? , ?)",
+					h.application, req.Toterm, AwardNotRenewed, strings.Join(ineligible.Reasons, "; "), time.Now()); err != nil {
+					return err
+				}
+				notRenewed[h.application] = ineligible.Reasons
+			case err != nil:
+				return err
+			default:
+				if _, err := grantAward(tx, h.application, h.student, req.Toterm, actor); err != nil {
+					return err
+				}
+				renewed = append(renewed, h.application)
+			}
+			return tx.Commit()
+		}()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	go LogActivity("EVALUATE_SCHOLARSHIP_RENEWALS", actor)
+	go AuditLog("RENEW", "SCHOLARSHIPS", fmt.Sprintf("%s->%s renewed=%d not_renewed=%d", req.Fromterm, req.Toterm, len(renewed), len(notRenewed)), actor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"from_term": req.Fromterm, "to_term": req.Toterm, "renewed": renewed, "not_renewed": notRenewed})
+}
+
+// GetStudentScholarshipsHandler lists a student's applications and awards to the student or a registrar
+func (a *HybridHandler) GetStudentScholarshipsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+	if !a.requireStudentOrRegistrar(w, r, studentID) {
+		return
+	}
+
+	rows, err := a.MySQL.db.Query("SELECT "+applicationColumns+" FROM scholarship_applications WHERE student_id=? ORDER BY created_at", studentID)
+	if err != nil {
+		http.Error(w, "unable to fetch applications", http.StatusInternalServerError)
+		return
+	}
+	apps, err := scanApplications(rows)
+	if err != nil {
+		http.Error(w, "rows scan failed", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err = a.MySQL.db.Query(`SELECT aw.award_id , aw.application_id , sa.scholarship_id , aw.term , aw.status , aw.applied_cents , aw.credit_cents , aw.invoice_id , aw.note , aw.created_at
+		FROM scholarship_awards aw JOIN scholarship_applications sa ON sa.application_id=aw.application_id
+		WHERE sa.student_id=? ORDER BY aw.created_at`, studentID)
+	if err != nil {
+		http.Error(w, "unable to fetch awards", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	awards := []ScholarshipAward{}
+	for rows.Next() {
+		var aw ScholarshipAward
+		var invoiceID sql.NullInt64
+		var note sql.NullString
+		if err := rows.Scan(&aw.Awardid, &aw.Applicationid, &aw.Scholarshipid, &aw.Term, &aw.Status, &aw.Appliedcents, &aw.Creditcents, &invoiceID, &note, &aw.Createdat); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		if invoiceID.Valid {
+			id := int(invoiceID.Int64)
+			aw.Invoiceid = &id
+		}
+		aw.Note = note.String
+		awards = append(awards, aw)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"applications": apps, "awards": awards})
+}
@@ -0,0 +1,21 @@
+package project
+
+import "testing"
+
+func TestSplitAward(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		amount, outstanding int64
+		applied, credit     int64
+	}{
+		{"fits the invoice", 30000, 100000, 30000, 0},
+		{"more than is owed", 30000, 10000, 10000, 20000},
+		{"invoice already paid", 30000, 0, 0, 30000},
+		{"invoice overpaid", 30000, -5000, 0, 30000},
+	} {
+		applied, credit := splitAward(tc.amount, tc.outstanding)
+		if applied != tc.applied || credit != tc.credit {
+			t.Errorf("%s: applied %d, credit %d, want %d and %d", tc.name, applied, credit, tc.applied, tc.credit)
+		}
+	}
+}
@@ -13,13 +13,13 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// Student 	represent a student entity stored in mysql
+// Student 	represent a student entity stored through the StudentRepository
 type Student struct {
-	Id    int    `json:"id"`
-	Name  string `json:"name"`
-	Age   int    `json:"age"`
-	Email string `json:"email"`
-	Dept  string `json:"dept"`
+	Id    int    `json:"id" bson:"_id"`
+	Name  string `json:"name" bson:"name"`
+	Age   int    `json:"age" bson:"age"`
+	Email string `json:"email" bson:"email"`
+	Dept  string `json:"dept" bson:"dept"`
 }
 
 // Validatestudent validates incoming student data
@@ -53,6 +53,20 @@ func ValidateStudent(student Student) error {
 	return nil
 }
 
+// insertStudent stores a validated student and sets its auto generated id
+func insertStudent(q dbtx, student *Student) error {
+	res, err := q.Exec("INSERT INTO students (name , age , email , dept) VALUES (? , ? , ? , ?)", student.Name, student.Age, student.Email, student.Dept)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	student.Id = int(id)
+	return nil
+}
+
 // CreateStudentHandler handles creation of a new student
 func (a *HybridHandler) CreateStudentHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -71,20 +85,12 @@ func (a *HybridHandler) CreateStudentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Insert student record into MySQL database
-	res, err := a.MySQL.db.Exec("INSERT INTO students (name , age , email , dept) VALUES (? , ? , ? , ?)", students.Name, students.Age, students.Email, students.Dept)
-	if err != nil {
+	// Insert student record through the configured store
+	if err := a.Students.Create(r.Context(), &students); err != nil {
 		http.Error(w, "Unable to insert", http.StatusInternalServerError)
 		return
 	}
 
-	// auto_generated id
-	id, err := res.LastInsertId()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	students.Id = int(id)
-
 	// Lod activity and Audit trail
 	go LogActivity("CREATE_EMPLOYEE", "system")
 	go AuditLog("CREATE", "EMPLOYEE", students.Id, "system")
@@ -97,23 +103,11 @@ func (a *HybridHandler) CreateStudentHandler(w http.ResponseWriter, r *http.Requ
 
 // GetStudentHandler to get all students
 func (a *HybridHandler) GetStudentHandler(w http.ResponseWriter, r *http.Request) {
-	rows, err := a.MySQL.db.Query("SELECT id , name , age , email , dept FROM students")
+	students, err := a.Students.List(r.Context())
 	if err != nil {
 		http.Error(w, "unable to fetch students", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var students []Student
-	for rows.Next() {
-		var s Student
-		if err := rows.Scan(&s.Id, &s.Name, &s.Age, &s.Email, &s.Dept); err != nil {
-			http.Error(w, "rows scan failed", http.StatusInternalServerError)
-			return
-		}
-		students = append(students, s)
-
-	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(students)
 }
@@ -128,22 +122,29 @@ func (a *HybridHandler) GetstudentByIDHandler(w http.ResponseWriter, r *http.Req
 	go LogActivity("GET_EMPLOYEE", "system")
 
 	// Attempt to fetch from Redis cache first
-	value, err := a.Redis.Client.Get(a.Ctx, id).Result()
+	value, err := a.Redis.Get(a.Ctx, id).Result()
 	if err == nil {
 		log.Println("Cache Hit...")
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(value))
 		return
 	}
-	// cache miss fetching from MySQL database
-	fmt.Println("cache miss querying MySQL...")
-	row := a.MySQL.db.QueryRow("SELECT name , age , email , dept FROM students WHERE id=?", id)
-
-	var students Student
-	if err := row.Scan(&students.Name, &students.Age, &students.Email, &students.Dept); err != nil {
+	// cache miss fetching from the student store
+	fmt.Println("cache miss querying student store...")
+	idINT, err := strconv.Atoi(id)
+	if err != nil {
+		http.Error(w, "student not found ", http.StatusNotFound)
+		return
+	}
+	students, err := a.Students.Get(r.Context(), idINT)
+	if err == ErrStudentNotFound {
 		http.Error(w, "student not found ", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "unable to fetch student", http.StatusInternalServerError)
+		return
+	}
 
 	// Marshal student data for caching
 	jsonData, err := json.Marshal(students)
@@ -153,7 +154,7 @@ func (a *HybridHandler) GetstudentByIDHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// store result in a redis cache (short TTL)
-	go a.Redis.Client.Set(a.Ctx, id, jsonData, 10*time.Second)
+	go a.Redis.Set(a.Ctx, id, jsonData, 10*time.Second)
 
 	//  send response
 	w.Header().Set("Content-Type", "application/json")
@@ -178,21 +179,14 @@ func (a *HybridHandler) UpdateStudentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Execute updated query
-	res, err := a.MySQL.db.Exec("UPDATE students SET name=? , age=? , email=? , dept=? WHERE id=?", students.Name, students.Age, students.Email, students.Dept, students.Id)
-	if err != nil {
-		http.Error(w, "unable to update", http.StatusInternalServerError)
+	// Update the student, missing records are reported as not found
+	err := a.Students.Update(r.Context(), &students)
+	if err == ErrStudentNotFound {
+		http.Error(w, "user not found ", http.StatusNotFound)
 		return
 	}
-
-	//  check if record exsists
-	rows, err := res.RowsAffected()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if rows == 0 {
-		http.Error(w, "user not found ", http.StatusNotFound)
+		http.Error(w, "unable to update", http.StatusInternalServerError)
 		return
 	}
 
@@ -202,7 +196,7 @@ func (a *HybridHandler) UpdateStudentHandler(w http.ResponseWriter, r *http.Requ
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	go a.Redis.Client.Set(a.Ctx, fmt.Sprint(students.Id), jsonData, 10*time.Second)
+	go a.Redis.Set(a.Ctx, fmt.Sprint(students.Id), jsonData, 10*time.Second)
 
 	// Log update actions
 	go LogActivity("UPDATE_STUDENT", "system")
@@ -224,26 +218,19 @@ func (a *HybridHandler) DeleteStudentHandler(w http.ResponseWriter, r *http.Requ
 	// Convert id to integer
 	idINT, _ := strconv.Atoi(id)
 
-	// Execute delete query
-	res, err := a.MySQL.db.Exec("DELETE FROM students WHERE id=?", idINT)
-	if err != nil {
-		http.Error(w, "unable to delete", http.StatusInternalServerError)
+	// Delete the student, missing records are reported as not found
+	err := a.Students.Delete(r.Context(), idINT)
+	if err == ErrStudentNotFound {
+		http.Error(w, "student not found", http.StatusNotFound)
 		return
 	}
-
-	// Check if student exsists
-	rows, err := res.RowsAffected()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	if rows == 0 {
-		http.Error(w, "student not found", http.StatusNotFound)
+		http.Error(w, "unable to delete", http.StatusInternalServerError)
 		return
 	}
 
 	// Remove cache entry
-	go a.Redis.Client.Del(a.Ctx, id)
+	go a.Redis.Del(a.Ctx, id)
 
 	// Log delete response
 	go LogActivity("DELETE_STUDENTS", "system")
@@ -0,0 +1,52 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStudentCRUDHandlers(t *testing.T) {
+	a := newMemoryHandler()
+
+	w := serve(t, a.CreateStudentHandler, "POST", "/students", nil, "", Student{Name: "Ada", Age: 20, Email: "ada@gmail.com", Dept: "CS"})
+	wantStatus(t, w, http.StatusCreated)
+	var created Student
+	decode(t, w, &created)
+	if created.Id == 0 {
+		t.Fatal("created student has no id")
+	}
+	id := fmt.Sprint(created.Id)
+
+	w = serve(t, a.CreateStudentHandler, "POST", "/students", nil, "", Student{Name: "Bob", Age: 20, Email: "bob@yahoo.com", Dept: "CS"})
+	wantStatus(t, w, http.StatusBadRequest)
+
+	w = serve(t, a.GetstudentByIDHandler, "GET", "/students/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	var got Student
+	decode(t, w, &got)
+	if got != created {
+		t.Fatalf("got %+v, want %+v", got, created)
+	}
+
+	created.Dept = "Maths"
+	w = serve(t, a.UpdateStudentHandler, "PUT", "/students/"+id, map[string]string{"id": id}, "", created)
+	wantStatus(t, w, http.StatusOK)
+	w = serve(t, a.UpdateStudentHandler, "PUT", "/students/999", map[string]string{"id": "999"}, "", Student{Id: 999, Name: "Nobody", Age: 20, Email: "no@gmail.com", Dept: "CS"})
+	wantStatus(t, w, http.StatusNotFound)
+
+	w = serve(t, a.GetStudentHandler, "GET", "/students", nil, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	var students []Student
+	decode(t, w, &students)
+	if len(students) != 1 || students[0].Dept != "Maths" {
+		t.Fatalf("got %+v, want the updated student", students)
+	}
+
+	w = serve(t, a.DeleteStudentHandler, "DELETE", "/students/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusOK)
+	w = serve(t, a.GetstudentByIDHandler, "GET", "/students/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusNotFound)
+	w = serve(t, a.DeleteStudentHandler, "DELETE", "/students/"+id, map[string]string{"id": id}, "", nil)
+	wantStatus(t, w, http.StatusNotFound)
+}
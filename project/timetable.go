@@ -0,0 +1,521 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// timetable job and candidate statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+
+	CandidateDraft     = "draft"
+	CandidatePublished = "published"
+	CandidateDiscarded = "discarded"
+)
+
+// TimetableJobRequest describes the search space for an automatic timetable run
+type TimetableJobRequest struct {
+	Term        string `json:"term"`
+	Days        []int  `json:"days"`
+	Daystart    string `json:"day_start"`
+	Dayend      string `json:"day_end"`
+	Slotminutes int    `json:"slot_minutes"`
+	Candidates  int    `json:"candidates"`
+	Restarts    int    `json:"restarts"`
+}
+
+// TimetableJob tracks an asynchronous timetable generation run
+type TimetableJob struct {
+	Jobid      int        `json:"job_id"`
+	Term       string     `json:"term"`
+	Status     string     `json:"status"`
+	Progress   int        `json:"progress"`
+	Message    string     `json:"message"`
+	Createdat  time.Time  `json:"created_at"`
+	Finishedat *time.Time `json:"finished_at,omitempty"`
+}
+
+// TimetableCandidate is a generated timetable waiting for review
+type TimetableCandidate struct {
+	Candidateid int       `json:"candidate_id"`
+	Jobid       int       `json:"job_id"`
+	Penalty     float64   `json:"penalty"`
+	Status      string    `json:"status"`
+	Createdat   time.Time `json:"created_at"`
+	Entries     []Meeting `json:"entries,omitempty"`
+}
+
+// LecturerPreference is a weekly window a lecturer prefers to teach in
+type LecturerPreference struct {
+	Day       int    `json:"day_of_week"`
+	Starttime string `json:"start_time"`
+	Endtime   string `json:"end_time"`
+}
+
+// ErrStaleCandidate is returned when a candidate no longer fits the current enrollments or assignments
+var ErrStaleCandidate = errors.New("candidate timetable conflicts with current data, generate a new one")
+
+// solverConfig converts a job request into a solver configuration, applying defaults
+func (req TimetableJobRequest) solverConfig() (SolverConfig, error) {
+	cfg := SolverConfig{
+		Days:        req.Days,
+		SlotMinutes: req.Slotminutes,
+		Candidates:  req.Candidates,
+		Restarts:    req.Restarts,
+		Seed:        time.Now().UnixNano(),
+	}
+	if len(cfg.Days) == 0 {
+		cfg.Days = []int{1, 2, 3, 4, 5}
+	}
+	if cfg.SlotMinutes == 0 {
+		cfg.SlotMinutes = 30
+	}
+	if req.Daystart == "" {
+		req.Daystart = "09:00"
+	}
+	if req.Dayend == "" {
+		req.Dayend = "17:00"
+	}
+	var err error
+	if cfg.DayStart, err = parseClock(req.Daystart); err != nil {
+		return cfg, err
+	}
+	if cfg.DayEnd, err = parseClock(req.Dayend); err != nil {
+		return cfg, err
+	}
+	return cfg, ValidateSolverConfig(cfg)
+}
+
+// loadSolverInput reads the sections, attendees, rooms and preferences of a term
+func (a *HybridHandler) loadSolverInput(term string) (SolverInput, error) {
+	input := SolverInput{Preferences: map[string][]PreferredWindow{}}
+	index := map[int]int{}
+
+	rows, err := a.MySQL.db.Query("SELECT section_id , capacity , weekly_meetings , meeting_minutes FROM sections WHERE term=? ORDER BY section_id", term)
+	if err != nil {
+		return input, err
+	}
+	for rows.Next() {
+		var s SolverSection
+		if err := rows.Scan(&s.Sectionid, &s.Size, &s.Meetings, &s.Minutes); err != nil {
+			rows.Close()
+			return input, err
+		}
+		index[s.Sectionid] = len(input.Sections)
+		input.Sections = append(input.Sections, s)
+	}
+	rows.Close()
+	if len(input.Sections) == 0 {
+		return input, fmt.Errorf("term %s has no sections", term)
+	}
+
+	// lecturers per section
+	rows, err = a.MySQL.db.Query("SELECT si.section_id , si.lecturer_id FROM section_instructors si JOIN sections s ON s.section_id=si.section_id WHERE s.term=?", term)
+	if err != nil {
+		return input, err
+	}
+	for rows.Next() {
+		var sectionID int
+		var lecturerID string
+		if err := rows.Scan(&sectionID, &lecturerID); err != nil {
+			rows.Close()
+			return input, err
+		}
+		s := &input.Sections[index[sectionID]]
+		s.Lecturers = append(s.Lecturers, lecturerID)
+	}
+	rows.Close()
+
+	// enrolled students per section
+	rows, err = a.MySQL.db.Query("SELECT e.section_id , e.student_id FROM enrollments e JOIN sections s ON s.section_id=e.section_id WHERE s.term=? AND e.status=?", term, EnrollmentEnrolled)
+	if err != nil {
+		return input, err
+	}
+	for rows.Next() {
+		var sectionID, studentID int
+		if err := rows.Scan(&sectionID, &studentID); err != nil {
+			rows.Close()
+			return input, err
+		}
+		s := &input.Sections[index[sectionID]]
+		s.Students = append(s.Students, studentID)
+	}
+	rows.Close()
+
+	rooms, err := loadRooms(a.MySQL.db)
+	if err != nil {
+		return input, err
+	}
+	for _, room := range rooms {
+		input.Rooms = append(input.Rooms, SolverRoom{Roomid: room.Roomid, Capacity: room.Capacity})
+	}
+
+	// lecturer preferred windows
+	rows, err = a.MySQL.db.Query("SELECT lecturer_id , day_of_week , start_time , end_time FROM lecturer_preferences")
+	if err != nil {
+		return input, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var lecturerID, start, end string
+		var w PreferredWindow
+		if err := rows.Scan(&lecturerID, &w.Day, &start, &end); err != nil {
+			return input, err
+		}
+		w.Start, _ = parseClock(trimClock(start))
+		w.End, _ = parseClock(trimClock(end))
+		input.Preferences[lecturerID] = append(input.Preferences[lecturerID], w)
+	}
+	return input, rows.Err()
+}
+
+// updateTimetableJob records job status and progress
+func (a *HybridHandler) updateTimetableJob(jobID int, status string, progress int, message string) {
+	var finished interface{}
+	if status == JobCompleted || status == JobFailed {
+		finished = time.Now()
+	}
+	_, err := a.MySQL.db.Exec("UPDATE timetable_jobs SET status=? , progress=? , message=? , finished_at=COALESCE(? , finished_at) WHERE job_id=?", status, progress, message, finished, jobID)
+	if err != nil {
+		log.Printf("failed to update timetable job %d: %v", jobID, err)
+	}
+}
+
+// runTimetableJob generates candidate timetables in the background and stores them for review
+func (a *HybridHandler) runTimetableJob(jobID int, term string, cfg SolverConfig) {
+	a.updateTimetableJob(jobID, JobRunning, 0, "loading term data")
+
+	input, err := a.loadSolverInput(term)
+	if err != nil {
+		a.updateTimetableJob(jobID, JobFailed, 0, err.Error())
+		return
+	}
+
+	last := -1
+	schedules, err := SolveTimetable(input, cfg, func(percent int) {
+		if percent != last {
+			last = percent
+			a.updateTimetableJob(jobID, JobRunning, percent, "searching")
+		}
+	})
+	if err != nil {
+		a.updateTimetableJob(jobID, JobFailed, 100, err.Error())
+		return
+	}
+
+	// store candidates in one transaction so reviewers never see a partial set
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		a.updateTimetableJob(jobID, JobFailed, 100, err.Error())
+		return
+	}
+	for _, s := range schedules {
+		res, err := tx.Exec("INSERT INTO timetable_candidates (job_id , penalty , status , created_at) VALUES (? , ? , ? , ?)", jobID, s.Penalty, CandidateDraft, time.Now())
+		if err != nil {
+			tx.Rollback()
+			a.updateTimetableJob(jobID, JobFailed, 100, err.Error())
+			return
+		}
+		candidateID, _ := res.LastInsertId()
+		for _, p := range s.Placements {
+			_, err := tx.Exec("INSERT INTO timetable_candidate_entries (candidate_id , section_id , room_id , day_of_week , start_time , end_time) VALUES (? , ? , ? , ? , ? , ?)",
+				candidateID, p.Sectionid, p.Roomid, p.Day, formatClock(p.Start), formatClock(p.End))
+			if err != nil {
+				tx.Rollback()
+				a.updateTimetableJob(jobID, JobFailed, 100, err.Error())
+				return
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		a.updateTimetableJob(jobID, JobFailed, 100, err.Error())
+		return
+	}
+
+	a.updateTimetableJob(jobID, JobCompleted, 100, fmt.Sprintf("%d candidate timetables ready for review", len(schedules)))
+	go AuditLog("GENERATE", "TIMETABLE_JOB", jobID, "system")
+}
+
+// CreateTimetableJobHandler starts an asynchronous timetable generation for a term
+func (a *HybridHandler) CreateTimetableJobHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Decode incoming JSON request body
+	var req TimetableJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+
+	// validate input
+	cfg, err := req.solverConfig()
+	if err == nil && req.Term == "" {
+		err = fmt.Errorf("term cannot be empty")
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	job := TimetableJob{Term: req.Term, Status: JobQueued, Createdat: time.Now()}
+	res, err := a.MySQL.db.Exec("INSERT INTO timetable_jobs (term , status , created_at) VALUES (? , ? , ?)", job.Term, job.Status, job.Createdat)
+	if err != nil {
+		http.Error(w, "failed to create job", http.StatusInternalServerError)
+		return
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	job.Jobid = int(id)
+
+	// Run solver in background
+	go a.runTimetableJob(job.Jobid, job.Term, cfg)
+
+	go LogActivity("CREATE_TIMETABLE_JOB", "system")
+	go AuditLog("CREATE", "TIMETABLE_JOB", job.Jobid, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetTimetableJobHandler reports a generation job's status and progress
+func (a *HybridHandler) GetTimetableJobHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id := mux.Vars(r)["id"]
+
+	var job TimetableJob
+	var finished sql.NullTime
+	err := a.MySQL.db.QueryRow("SELECT job_id , term , status , progress , message , created_at , finished_at FROM timetable_jobs WHERE job_id=?", id).
+		Scan(&job.Jobid, &job.Term, &job.Status, &job.Progress, &job.Message, &job.Createdat, &finished)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if finished.Valid {
+		job.Finishedat = &finished.Time
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetTimetableCandidatesHandler lists the candidates of a job, best first
+func (a *HybridHandler) GetTimetableCandidatesHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id := mux.Vars(r)["id"]
+
+	rows, err := a.MySQL.db.Query("SELECT candidate_id , job_id , penalty , status , created_at FROM timetable_candidates WHERE job_id=? ORDER BY penalty", id)
+	if err != nil {
+		http.Error(w, "unable to fetch candidates", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	candidates := []TimetableCandidate{}
+	for rows.Next() {
+		var c TimetableCandidate
+		if err := rows.Scan(&c.Candidateid, &c.Jobid, &c.Penalty, &c.Status, &c.Createdat); err != nil {
+			http.Error(w, "rows scan failed", http.StatusInternalServerError)
+			return
+		}
+		candidates = append(candidates, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// candidateEntries loads the meetings of a candidate timetable
+func candidateEntries(q dbtx, candidateID int) ([]Meeting, error) {
+	rows, err := q.Query("SELECT 0 , section_id , room_id , day_of_week , start_time , end_time FROM timetable_candidate_entries WHERE candidate_id=? ORDER BY day_of_week , start_time", candidateID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMeetings(rows)
+}
+
+// GetTimetableCandidateHandler returns a candidate with all its meetings for review
+func (a *HybridHandler) GetTimetableCandidateHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	var c TimetableCandidate
+	err = a.MySQL.db.QueryRow("SELECT candidate_id , job_id , penalty , status , created_at FROM timetable_candidates WHERE candidate_id=?", id).
+		Scan(&c.Candidateid, &c.Jobid, &c.Penalty, &c.Status, &c.Createdat)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "candidate not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c.Entries, err = candidateEntries(a.MySQL.db, id); err != nil {
+		http.Error(w, "unable to fetch entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// publishCandidate replaces a term's meetings with the candidate's, re-checking every meeting for conflicts
+func publishCandidate(tx *sql.Tx, candidateID int) (string, error) {
+	var status, term string
+	var jobID int
+	err := tx.QueryRow("SELECT c.status , c.job_id , j.term FROM timetable_candidates c JOIN timetable_jobs j ON j.job_id=c.job_id WHERE c.candidate_id=? FOR UPDATE", candidateID).Scan(&status, &jobID, &term)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("candidate not found")
+	}
+	if err != nil {
+		return "", err
+	}
+	if status != CandidateDraft {
+		return "", fmt.Errorf("candidate is %s, only draft candidates can be published", status)
+	}
+
+	entries, err := candidateEntries(tx, candidateID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("DELETE m FROM section_meetings m JOIN sections s ON s.section_id=m.section_id WHERE s.term=?", term); err != nil {
+		return "", err
+	}
+	for _, m := range entries {
+		conflicts, err := findMeetingConflicts(tx, term, m)
+		if err != nil {
+			return "", err
+		}
+		if len(conflicts) > 0 {
+			return "", ErrStaleCandidate
+		}
+		if _, err := tx.Exec("INSERT INTO section_meetings (section_id , room_id , day_of_week , start_time , end_time) VALUES (? , ? , ? , ? , ?)", m.Sectionid, m.Roomid, m.Day, m.Starttime, m.Endtime); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE timetable_candidates SET status=? WHERE job_id=? AND candidate_id<>? AND status=?", CandidateDiscarded, jobID, candidateID, CandidateDraft); err != nil {
+		return "", err
+	}
+	_, err = tx.Exec("UPDATE timetable_candidates SET status=? WHERE candidate_id=?", CandidatePublished, candidateID)
+	return term, err
+}
+
+// PublishTimetableCandidateHandler publishes a reviewed candidate as the term's timetable
+func (a *HybridHandler) PublishTimetableCandidateHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	term, err := publishCandidate(tx, id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("PUBLISH_TIMETABLE", "system")
+	go AuditLog("PUBLISH", "TIMETABLE_CANDIDATE", id, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "timetable published", "term": term, "candidate_id": id})
+}
+
+// SetLecturerPreferencesHandler replaces a lecturer's preferred teaching windows
+func (a *HybridHandler) SetLecturerPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract id from URL
+	id := mux.Vars(r)["id"]
+
+	// Decode incoming JSON request body
+	var windows []LecturerPreference
+	if err := json.NewDecoder(r.Body).Decode(&windows); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	for _, win := range windows {
+		if err := validateWeeklyWindow(win.Day, win.Starttime, win.Endtime); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+			return
+		}
+	}
+
+	// Lecturer must exist in the lecturer store
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	if _, err := a.findLecturer(ctx, id); err != nil {
+		writeLecturerLookupError(w, err)
+		return
+	}
+
+	tx, err := a.MySQL.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM lecturer_preferences WHERE lecturer_id=?", id); err != nil {
+		tx.Rollback()
+		http.Error(w, "failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+	for _, win := range windows {
+		if _, err := tx.Exec("INSERT INTO lecturer_preferences (lecturer_id , day_of_week , start_time , end_time) VALUES (? , ? , ? , ?)", id, win.Day, win.Starttime, win.Endtime); err != nil {
+			tx.Rollback()
+			http.Error(w, "failed to update preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("UPDATE_LECTURER_PREFERENCES", "system")
+	go AuditLog("UPDATE", "LECTURER_PREFERENCES", id, "system")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"lecturer_id": id, "preferences": windows})
+}
@@ -0,0 +1,471 @@
+package project
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// SolverSection is a section to place, with everyone who must attend it
+type SolverSection struct {
+	Sectionid int
+	Size      int
+	Meetings  int
+	Minutes   int
+	Lecturers []string
+	Students  []int
+}
+
+// SolverRoom is a room the solver may use
+type SolverRoom struct {
+	Roomid   int
+	Capacity int
+}
+
+// PreferredWindow is a weekly window a lecturer prefers to teach in, times in minutes after midnight
+type PreferredWindow struct {
+	Day   int
+	Start int
+	End   int
+}
+
+// SolverConfig controls the search space and effort of the timetable solver
+type SolverConfig struct {
+	Days        []int
+	DayStart    int
+	DayEnd      int
+	SlotMinutes int
+	Candidates  int
+	Restarts    int
+	NodeLimit   int
+	Seed        int64
+}
+
+// SolverInput is everything the solver needs to build a term timetable
+type SolverInput struct {
+	Sections    []SolverSection
+	Rooms       []SolverRoom
+	Preferences map[string][]PreferredWindow
+}
+
+// Placement puts one meeting of a section in a room at a time
+type Placement struct {
+	Sectionid int
+	Roomid    int
+	Day       int
+	Start     int
+	End       int
+}
+
+// Schedule is a complete conflict-free timetable and its soft-constraint penalty (lower is better)
+type Schedule struct {
+	Placements []Placement
+	Penalty    float64
+}
+
+// soft constraint weights
+const (
+	penaltyOutsidePreference = 5.0
+	penaltyGapHour           = 1.0
+)
+
+// solverVar is one weekly meeting that needs a slot
+type solverVar struct {
+	section int // index into input.Sections
+}
+
+type solverState struct {
+	input     *SolverInput
+	cfg       SolverConfig
+	rng       *rand.Rand
+	vars      []solverVar
+	values    [][]Placement // candidate placements per section index
+	neighbors [][]int       // sections sharing a lecturer or student
+	placed    [][]Placement // placements per section index
+	roomBusy  map[int][]Placement
+	nodes     int
+}
+
+// personDay keys one lecturer's or one student's classes on a day
+type personDay struct {
+	lecturer string
+	student  int
+	day      int
+}
+
+// ValidateSolverConfig checks a solver configuration is usable
+func ValidateSolverConfig(cfg SolverConfig) error {
+	if len(cfg.Days) == 0 {
+		return fmt.Errorf("at least one teaching day is required")
+	}
+	for _, d := range cfg.Days {
+		if d < 1 || d > 7 {
+			return fmt.Errorf("invalid day %d, days run 1 (Monday) to 7 (Sunday)", d)
+		}
+	}
+	if cfg.DayEnd <= cfg.DayStart {
+		return fmt.Errorf("day_end must be after day_start")
+	}
+	if cfg.SlotMinutes <= 0 {
+		return fmt.Errorf("slot_minutes must be greater than 0")
+	}
+	return nil
+}
+
+// SolveTimetable searches for up to cfg.Candidates distinct conflict-free timetables.
+// Hard constraints: no room, lecturer or student is double-booked, rooms hold the section,
+// and a section meets at most once a day. Soft constraints: lecturers' preferred windows and
+// minimal idle gaps for lecturers and students. Each restart runs a randomised backtracking
+// search followed by hill climbing; progress is reported as a percentage after every restart.
+func SolveTimetable(input SolverInput, cfg SolverConfig, progress func(int)) ([]Schedule, error) {
+	if err := ValidateSolverConfig(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Candidates <= 0 {
+		cfg.Candidates = 3
+	}
+	if cfg.Restarts < cfg.Candidates {
+		cfg.Restarts = cfg.Candidates * 4
+	}
+	if cfg.NodeLimit <= 0 {
+		cfg.NodeLimit = 200000
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	values, err := candidateValues(&input, cfg)
+	if err != nil {
+		return nil, err
+	}
+	neighbors := conflictGraph(&input)
+
+	seen := map[string]bool{}
+	var schedules []Schedule
+	for restart := 0; restart < cfg.Restarts; restart++ {
+		st := &solverState{
+			input:     &input,
+			cfg:       cfg,
+			rng:       rng,
+			values:    values,
+			neighbors: neighbors,
+		}
+		if st.solve() {
+			st.improve(200 * len(st.vars))
+			s := st.schedule()
+			key := scheduleKey(s)
+			if !seen[key] {
+				seen[key] = true
+				schedules = append(schedules, s)
+			}
+		}
+		if progress != nil {
+			progress((restart + 1) * 100 / cfg.Restarts)
+		}
+	}
+	if len(schedules) == 0 {
+		return nil, fmt.Errorf("no conflict-free timetable found, add rooms, days or hours")
+	}
+
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Penalty < schedules[j].Penalty })
+	if len(schedules) > cfg.Candidates {
+		schedules = schedules[:cfg.Candidates]
+	}
+	return schedules, nil
+}
+
+// candidateValues lists every room and time a section's meeting could take, ignoring other sections
+func candidateValues(input *SolverInput, cfg SolverConfig) ([][]Placement, error) {
+	values := make([][]Placement, len(input.Sections))
+	for i, s := range input.Sections {
+		if s.Minutes <= 0 || s.Meetings <= 0 {
+			return nil, fmt.Errorf("section %d has no meeting length or count", s.Sectionid)
+		}
+		if s.Meetings > len(cfg.Days) {
+			return nil, fmt.Errorf("section %d needs %d meetings but only %d days are available", s.Sectionid, s.Meetings, len(cfg.Days))
+		}
+		for _, room := range input.Rooms {
+			if room.Capacity < s.Size {
+				continue
+			}
+			for _, day := range cfg.Days {
+				for start := cfg.DayStart; start+s.Minutes <= cfg.DayEnd; start += cfg.SlotMinutes {
+					values[i] = append(values[i], Placement{Sectionid: s.Sectionid, Roomid: room.Roomid, Day: day, Start: start, End: start + s.Minutes})
+				}
+			}
+		}
+		if len(values[i]) == 0 {
+			return nil, fmt.Errorf("section %d does not fit any room or time", s.Sectionid)
+		}
+	}
+	return values, nil
+}
+
+// conflictGraph links sections that share a lecturer or an enrolled student
+func conflictGraph(input *SolverInput) [][]int {
+	byLecturer := map[string][]int{}
+	byStudent := map[int][]int{}
+	for i, s := range input.Sections {
+		for _, l := range s.Lecturers {
+			byLecturer[l] = append(byLecturer[l], i)
+		}
+		for _, st := range s.Students {
+			byStudent[st] = append(byStudent[st], i)
+		}
+	}
+
+	linked := make([]map[int]bool, len(input.Sections))
+	for i := range linked {
+		linked[i] = map[int]bool{}
+	}
+	link := func(group []int) {
+		for _, a := range group {
+			for _, b := range group {
+				if a != b {
+					linked[a][b] = true
+				}
+			}
+		}
+	}
+	for _, g := range byLecturer {
+		link(g)
+	}
+	for _, g := range byStudent {
+		link(g)
+	}
+
+	neighbors := make([][]int, len(input.Sections))
+	for i, m := range linked {
+		for j := range m {
+			neighbors[i] = append(neighbors[i], j)
+		}
+		sort.Ints(neighbors[i])
+	}
+	return neighbors
+}
+
+func overlaps(a, b Placement) bool {
+	return a.Day == b.Day && a.Start < b.End && b.Start < a.End
+}
+
+// feasible checks the hard constraints for placing p for section index i
+func (st *solverState) feasible(i int, p Placement) bool {
+	for _, q := range st.placed[i] {
+		if q.Day == p.Day {
+			return false
+		}
+	}
+	for _, q := range st.roomBusy[p.Roomid] {
+		if overlaps(p, q) {
+			return false
+		}
+	}
+	for _, n := range st.neighbors[i] {
+		for _, q := range st.placed[n] {
+			if overlaps(p, q) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (st *solverState) place(i int, p Placement) {
+	st.placed[i] = append(st.placed[i], p)
+	st.roomBusy[p.Roomid] = append(st.roomBusy[p.Roomid], p)
+}
+
+func (st *solverState) unplace(i int, p Placement) {
+	st.placed[i] = removePlacement(st.placed[i], p)
+	st.roomBusy[p.Roomid] = removePlacement(st.roomBusy[p.Roomid], p)
+}
+
+func removePlacement(list []Placement, p Placement) []Placement {
+	for k, q := range list {
+		if q == p {
+			return append(list[:k], list[k+1:]...)
+		}
+	}
+	return list
+}
+
+// preferencePenalty scores a placement against its lecturers' preferred windows
+func (st *solverState) preferencePenalty(i int, p Placement) float64 {
+	penalty := 0.0
+	for _, l := range st.input.Sections[i].Lecturers {
+		windows := st.input.Preferences[l]
+		if len(windows) == 0 {
+			continue
+		}
+		inside := false
+		for _, w := range windows {
+			if w.Day == p.Day && w.Start <= p.Start && p.End <= w.End {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			penalty += penaltyOutsidePreference
+		}
+	}
+	return penalty
+}
+
+// localCost estimates how a placement affects the soft constraints, used to order values
+func (st *solverState) localCost(i int, p Placement) float64 {
+	cost := st.preferencePenalty(i, p)
+	for _, n := range st.neighbors[i] {
+		for _, q := range st.placed[n] {
+			if q.Day != p.Day {
+				continue
+			}
+			// back to back or overlapping placements leave no idle time
+			gap := max(q.Start-p.End, p.Start-q.End, 0)
+			cost += float64(gap) / 60.0 * penaltyGapHour / float64(len(st.neighbors[i]))
+		}
+	}
+	return cost
+}
+
+// solve runs one randomised backtracking search, most constrained sections first
+func (st *solverState) solve() bool {
+	n := len(st.input.Sections)
+	st.placed = make([][]Placement, n)
+	st.roomBusy = map[int][]Placement{}
+	st.vars = st.vars[:0]
+	st.nodes = 0
+
+	order := st.rng.Perm(n)
+	sort.SliceStable(order, func(a, b int) bool {
+		da := len(st.neighbors[order[a]]) * 1000 / len(st.values[order[a]])
+		db := len(st.neighbors[order[b]]) * 1000 / len(st.values[order[b]])
+		return da > db
+	})
+	for _, i := range order {
+		for k := 0; k < st.input.Sections[i].Meetings; k++ {
+			st.vars = append(st.vars, solverVar{section: i})
+		}
+	}
+	return st.backtrack(0)
+}
+
+func (st *solverState) backtrack(v int) bool {
+	if v == len(st.vars) {
+		return true
+	}
+	st.nodes++
+	if st.nodes > st.cfg.NodeLimit {
+		return false
+	}
+
+	i := st.vars[v].section
+	type scored struct {
+		p    Placement
+		cost float64
+	}
+	var options []scored
+	for _, p := range st.values[i] {
+		if st.feasible(i, p) {
+			options = append(options, scored{p, st.localCost(i, p) + st.rng.Float64()})
+		}
+	}
+	sort.Slice(options, func(a, b int) bool { return options[a].cost < options[b].cost })
+
+	for _, o := range options {
+		st.place(i, o.p)
+		if st.backtrack(v + 1) {
+			return true
+		}
+		st.unplace(i, o.p)
+		if st.nodes > st.cfg.NodeLimit {
+			return false
+		}
+	}
+	return false
+}
+
+// improve hill-climbs by moving single meetings to feasible placements that lower the penalty
+func (st *solverState) improve(iterations int) {
+	current := st.penalty()
+	for it := 0; it < iterations; it++ {
+		i := st.vars[st.rng.Intn(len(st.vars))].section
+		if len(st.placed[i]) == 0 {
+			continue
+		}
+		old := st.placed[i][st.rng.Intn(len(st.placed[i]))]
+		candidate := st.values[i][st.rng.Intn(len(st.values[i]))]
+
+		st.unplace(i, old)
+		if !st.feasible(i, candidate) {
+			st.place(i, old)
+			continue
+		}
+		st.place(i, candidate)
+		if next := st.penalty(); next < current {
+			current = next
+			continue
+		}
+		st.unplace(i, candidate)
+		st.place(i, old)
+	}
+}
+
+// penalty scores the full timetable against the soft constraints
+func (st *solverState) penalty() float64 {
+	total := 0.0
+	for i, list := range st.placed {
+		for _, p := range list {
+			total += st.preferencePenalty(i, p)
+		}
+	}
+
+	// idle time between the first and last class of each day per person
+	days := map[personDay][]Placement{}
+	for i, list := range st.placed {
+		s := st.input.Sections[i]
+		for _, p := range list {
+			for _, l := range s.Lecturers {
+				key := personDay{lecturer: l, day: p.Day}
+				days[key] = append(days[key], p)
+			}
+			for _, stu := range s.Students {
+				key := personDay{student: stu, day: p.Day}
+				days[key] = append(days[key], p)
+			}
+		}
+	}
+	for _, list := range days {
+		sort.Slice(list, func(a, b int) bool { return list[a].Start < list[b].Start })
+		for k := 1; k < len(list); k++ {
+			if gap := list[k].Start - list[k-1].End; gap > 0 {
+				total += float64(gap) / 60.0 * penaltyGapHour
+			}
+		}
+	}
+	return total
+}
+
+func (st *solverState) schedule() Schedule {
+	var placements []Placement
+	for _, list := range st.placed {
+		placements = append(placements, list...)
+	}
+	sort.Slice(placements, func(a, b int) bool {
+		if placements[a].Day != placements[b].Day {
+			return placements[a].Day < placements[b].Day
+		}
+		if placements[a].Start != placements[b].Start {
+			return placements[a].Start < placements[b].Start
+		}
+		return placements[a].Sectionid < placements[b].Sectionid
+	})
+	return Schedule{Placements: placements, Penalty: st.penalty()}
+}
+
+// scheduleKey identifies a schedule so identical restarts are only kept once
+func scheduleKey(s Schedule) string {
+	var b strings.Builder
+	for _, p := range s.Placements {
+		fmt.Fprintf(&b, "%d/%d/%d/%d;", p.Sectionid, p.Roomid, p.Day, p.Start)
+	}
+	return b.String()
+}
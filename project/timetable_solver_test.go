@@ -0,0 +1,294 @@
+package project
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidateSolverConfig(t *testing.T) {
+	valid := SolverConfig{Days: []int{1, 2, 3}, DayStart: 480, DayEnd: 1020, SlotMinutes: 30}
+	for _, tc := range []struct {
+		name    string
+		edit    func(*SolverConfig)
+		wantErr bool
+	}{
+		{"valid", func(c *SolverConfig) {}, false},
+		{"no days", func(c *SolverConfig) { c.Days = nil }, true},
+		{"day 0", func(c *SolverConfig) { c.Days = []int{0} }, true},
+		{"day 8", func(c *SolverConfig) { c.Days = []int{1, 8} }, true},
+		{"sunday", func(c *SolverConfig) { c.Days = []int{7} }, false},
+		{"end before start", func(c *SolverConfig) { c.DayEnd = c.DayStart - 60 }, true},
+		{"end at start", func(c *SolverConfig) { c.DayEnd = c.DayStart }, true},
+		{"zero slot", func(c *SolverConfig) { c.SlotMinutes = 0 }, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := valid
+			cfg.Days = append([]int(nil), valid.Days...)
+			tc.edit(&cfg)
+			if err := ValidateSolverConfig(cfg); (err != nil) != tc.wantErr {
+				t.Fatalf("got %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// checkHardConstraints fails the test if a schedule double-books a room, lecturer or student,
+// overfills a room, meets a section twice a day, leaves the teaching day or misses a meeting
+func checkHardConstraints(t *testing.T, input SolverInput, cfg SolverConfig, s Schedule) {
+	t.Helper()
+	sections := map[int]SolverSection{}
+	for _, sec := range input.Sections {
+		sections[sec.Sectionid] = sec
+	}
+	capacity := map[int]int{}
+	for _, r := range input.Rooms {
+		capacity[r.Roomid] = r.Capacity
+	}
+
+	meetings := map[int]int{}
+	days := map[string]bool{}
+	for _, p := range s.Placements {
+		sec := sections[p.Sectionid]
+		meetings[p.Sectionid]++
+		if capacity[p.Roomid] < sec.Size {
+			t.Errorf("section %d of %d students in room %d of %d seats", sec.Sectionid, sec.Size, p.Roomid, capacity[p.Roomid])
+		}
+		if p.Start < cfg.DayStart || p.End > cfg.DayEnd || p.End-p.Start != sec.Minutes {
+			t.Errorf("placement %+v outside the teaching day or of the wrong length", p)
+		}
+		day := fmt.Sprintf("%d/%d", p.Sectionid, p.Day)
+		if days[day] {
+			t.Errorf("section %d meets twice on day %d", p.Sectionid, p.Day)
+		}
+		days[day] = true
+	}
+	for _, sec := range input.Sections {
+		if meetings[sec.Sectionid] != sec.Meetings {
+			t.Errorf("section %d has %d meetings, want %d", sec.Sectionid, meetings[sec.Sectionid], sec.Meetings)
+		}
+	}
+
+	for a, p := range s.Placements {
+		for _, q := range s.Placements[a+1:] {
+			if !overlaps(p, q) {
+				continue
+			}
+			if p.Roomid == q.Roomid {
+				t.Errorf("room %d double-booked by %+v and %+v", p.Roomid, p, q)
+			}
+			for _, l := range sections[p.Sectionid].Lecturers {
+				for _, m := range sections[q.Sectionid].Lecturers {
+					if l == m {
+						t.Errorf("lecturer %s double-booked by %+v and %+v", l, p, q)
+					}
+				}
+			}
+			for _, x := range sections[p.Sectionid].Students {
+				for _, y := range sections[q.Sectionid].Students {
+					if x == y {
+						t.Errorf("student %d double-booked by %+v and %+v", x, p, q)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSolveTimetableKeepsHardConstraints(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input SolverInput
+		cfg   SolverConfig
+	}{
+		{
+			name: "one room for everything",
+			input: SolverInput{
+				Sections: []SolverSection{
+					{Sectionid: 1, Size: 20, Meetings: 2, Minutes: 60, Lecturers: []string{"a"}},
+					{Sectionid: 2, Size: 20, Meetings: 2, Minutes: 60, Lecturers: []string{"b"}},
+					{Sectionid: 3, Size: 20, Meetings: 2, Minutes: 90, Lecturers: []string{"c"}},
+				},
+				Rooms: []SolverRoom{{Roomid: 1, Capacity: 30}},
+			},
+			cfg: SolverConfig{Days: []int{1, 2}, DayStart: 540, DayEnd: 780, SlotMinutes: 30, Seed: 1},
+		},
+		{
+			name: "shared lecturer and students",
+			input: SolverInput{
+				Sections: []SolverSection{
+					{Sectionid: 1, Size: 10, Meetings: 3, Minutes: 60, Lecturers: []string{"a"}, Students: []int{1, 2}},
+					{Sectionid: 2, Size: 10, Meetings: 3, Minutes: 60, Lecturers: []string{"a"}, Students: []int{3}},
+					{Sectionid: 3, Size: 10, Meetings: 3, Minutes: 60, Lecturers: []string{"b"}, Students: []int{1, 3}},
+					{Sectionid: 4, Size: 10, Meetings: 3, Minutes: 60, Lecturers: []string{"b", "c"}, Students: []int{2}},
+				},
+				Rooms: []SolverRoom{{Roomid: 1, Capacity: 10}, {Roomid: 2, Capacity: 10}, {Roomid: 3, Capacity: 10}},
+			},
+			cfg: SolverConfig{Days: []int{1, 2, 3}, DayStart: 540, DayEnd: 720, SlotMinutes: 60, Seed: 2},
+		},
+		{
+			name: "rooms by size",
+			input: SolverInput{
+				Sections: []SolverSection{
+					{Sectionid: 1, Size: 120, Meetings: 2, Minutes: 60, Lecturers: []string{"a"}},
+					{Sectionid: 2, Size: 100, Meetings: 2, Minutes: 60, Lecturers: []string{"b"}},
+					{Sectionid: 3, Size: 25, Meetings: 2, Minutes: 60, Lecturers: []string{"c"}},
+				},
+				Rooms: []SolverRoom{{Roomid: 1, Capacity: 150}, {Roomid: 2, Capacity: 30}},
+			},
+			cfg: SolverConfig{Days: []int{1, 2, 3, 4}, DayStart: 540, DayEnd: 660, SlotMinutes: 60, Candidates: 5, Seed: 3},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schedules, err := SolveTimetable(tc.input, tc.cfg, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range schedules {
+				checkHardConstraints(t, tc.input, tc.cfg, s)
+			}
+		})
+	}
+}
+
+func TestSolveTimetableRefusesImpossibleInput(t *testing.T) {
+	cfg := SolverConfig{Days: []int{1}, DayStart: 540, DayEnd: 660, SlotMinutes: 60, Seed: 1}
+	for _, tc := range []struct {
+		name  string
+		input SolverInput
+	}{
+		{"too big for every room", SolverInput{
+			Sections: []SolverSection{{Sectionid: 1, Size: 50, Meetings: 1, Minutes: 60}},
+			Rooms:    []SolverRoom{{Roomid: 1, Capacity: 40}},
+		}},
+		{"more meetings than days", SolverInput{
+			Sections: []SolverSection{{Sectionid: 1, Size: 10, Meetings: 2, Minutes: 60}},
+			Rooms:    []SolverRoom{{Roomid: 1, Capacity: 40}},
+		}},
+		{"one lecturer, three hours, two slots", SolverInput{
+			Sections: []SolverSection{
+				{Sectionid: 1, Size: 10, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+				{Sectionid: 2, Size: 10, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+				{Sectionid: 3, Size: 10, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+			},
+			Rooms: []SolverRoom{{Roomid: 1, Capacity: 40}, {Roomid: 2, Capacity: 40}},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := SolveTimetable(tc.input, cfg, nil); err == nil {
+				t.Fatal("got a timetable, want an error")
+			}
+		})
+	}
+}
+
+func TestTimetablePenalty(t *testing.T) {
+	input := SolverInput{
+		Sections: []SolverSection{
+			{Sectionid: 1, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+			{Sectionid: 2, Meetings: 1, Minutes: 60, Lecturers: []string{"a", "b"}},
+		},
+		Preferences: map[string][]PreferredWindow{
+			"a": {{Day: 1, Start: 540, End: 720}},
+			"b": {{Day: 2, Start: 540, End: 720}},
+		},
+	}
+	for _, tc := range []struct {
+		name   string
+		placed [][]Placement
+		want   float64
+	}{
+		{"a inside, b outside", [][]Placement{
+			{{Sectionid: 1, Day: 1, Start: 540, End: 600}},
+			{{Sectionid: 2, Day: 1, Start: 600, End: 660}},
+		}, penaltyOutsidePreference},
+		{"a outside twice", [][]Placement{
+			{{Sectionid: 1, Day: 3, Start: 540, End: 600}},
+			{{Sectionid: 2, Day: 2, Start: 540, End: 600}},
+		}, 2 * penaltyOutsidePreference},
+		{"window overrun", [][]Placement{
+			{{Sectionid: 1, Day: 1, Start: 690, End: 750}},
+			{{Sectionid: 2, Day: 2, Start: 600, End: 660}},
+		}, 2 * penaltyOutsidePreference},
+		{"two hour gap for a", [][]Placement{
+			{{Sectionid: 1, Day: 1, Start: 540, End: 600}},
+			{{Sectionid: 2, Day: 1, Start: 720, End: 780}},
+		}, 2*penaltyOutsidePreference + 2*penaltyGapHour},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st := &solverState{input: &input, placed: tc.placed}
+			if got := st.penalty(); got != tc.want {
+				t.Fatalf("penalty %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLocalCostNeverRewardsAdjacentClasses(t *testing.T) {
+	input := SolverInput{Sections: []SolverSection{
+		{Sectionid: 1, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+		{Sectionid: 2, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+	}}
+	st := &solverState{
+		input:     &input,
+		neighbors: [][]int{{1}, {0}},
+		placed:    [][]Placement{nil, {{Sectionid: 2, Day: 1, Start: 600, End: 660}}},
+	}
+	for _, tc := range []struct {
+		name string
+		p    Placement
+		want float64
+	}{
+		{"right before", Placement{Sectionid: 1, Day: 1, Start: 540, End: 600}, 0},
+		{"right after", Placement{Sectionid: 1, Day: 1, Start: 660, End: 720}, 0},
+		{"an hour after", Placement{Sectionid: 1, Day: 1, Start: 720, End: 780}, penaltyGapHour},
+		{"another day", Placement{Sectionid: 1, Day: 2, Start: 540, End: 600}, 0},
+	} {
+		if got := st.localCost(0, tc.p); got != tc.want {
+			t.Errorf("%s: cost %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSolveTimetableOrdersByLecturerPreference(t *testing.T) {
+	input := SolverInput{
+		Sections: []SolverSection{
+			{Sectionid: 1, Size: 10, Meetings: 1, Minutes: 60, Lecturers: []string{"a"}},
+			{Sectionid: 2, Size: 10, Meetings: 1, Minutes: 60, Lecturers: []string{"b"}},
+			{Sectionid: 3, Size: 10, Meetings: 1, Minutes: 60, Lecturers: []string{"c"}},
+		},
+		Rooms: []SolverRoom{{Roomid: 1, Capacity: 10}, {Roomid: 2, Capacity: 10}},
+		Preferences: map[string][]PreferredWindow{
+			"a": {{Day: 1, Start: 540, End: 600}},
+			"b": {{Day: 2, Start: 600, End: 720}},
+		},
+	}
+	cfg := SolverConfig{Days: []int{1, 2}, DayStart: 540, DayEnd: 720, SlotMinutes: 60, Candidates: 5, Seed: 4}
+	schedules, err := SolveTimetable(input, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedules[0].Penalty != 0 {
+		t.Fatalf("best candidate has penalty %v, want 0 with every lecturer in a preferred window", schedules[0].Penalty)
+	}
+	for k, s := range schedules {
+		if k > 0 && s.Penalty < schedules[k-1].Penalty {
+			t.Fatalf("candidate %d has penalty %v, lower than %v before it", k, s.Penalty, schedules[k-1].Penalty)
+		}
+		outside := 0
+		for _, p := range s.Placements {
+			switch p.Sectionid {
+			case 1:
+				if p.Day != 1 || p.Start != 540 {
+					outside++
+				}
+			case 2:
+				if p.Day != 2 || p.Start < 600 {
+					outside++
+				}
+			}
+		}
+		if want := float64(outside) * penaltyOutsidePreference; s.Penalty != want {
+			t.Errorf("candidate %d %+v has penalty %v, want %v", k, s.Placements, s.Penalty, want)
+		}
+	}
+}
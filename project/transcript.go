@@ -0,0 +1,281 @@
+package project
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TranscriptCourse is one graded course on a transcript
+type TranscriptCourse struct {
+	Code        string  `json:"code"`
+	Title       string  `json:"title"`
+	Credits     int     `json:"credits"`
+	Letter      string  `json:"letter"`
+	Gradepoints float64 `json:"grade_points"`
+}
+
+// TranscriptTerm groups the courses of one finalized term
+type TranscriptTerm struct {
+	Term    string             `json:"term"`
+	Courses []TranscriptCourse `json:"courses"`
+	Credits int                `json:"credits"`
+	GPA     float64            `json:"gpa"`
+}
+
+// Transcript is an official record of a student's finalized grades
+type Transcript struct {
+	Student          Student          `json:"student"`
+	Terms            []TranscriptTerm `json:"terms"`
+	Credits          int              `json:"credits"`
+	Cumulative       float64          `json:"cumulative_gpa"`
+	Issuedat         time.Time        `json:"issued_at"`
+	Verificationcode string           `json:"verification_code"`
+	Hash             string           `json:"hash"`
+	Signature        string           `json:"signature"`
+}
+
+// buildTranscript assembles a student's transcript from finalized grades
+func buildTranscript(q dbtx, studentID int) (*Transcript, error) {
+	t := &Transcript{Terms: []TranscriptTerm{}}
+	err := q.QueryRow("SELECT id , name , age , email , dept FROM students WHERE id=?", studentID).
+		Scan(&t.Student.Id, &t.Student.Name, &t.Student.Age, &t.Student.Email, &t.Student.Dept)
+	if err == sql.ErrNoRows {
+		return nil, ErrStudentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`SELECT s.term , c.code , c.title , fg.credits , fg.letter , fg.grade_points
+		FROM final_grades fg
+		JOIN sections s ON s.section_id=fg.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		JOIN term_locks tl ON tl.term=s.term
+		WHERE fg.student_id=? ORDER BY tl.finalized_at , s.term , c.code`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allPoints []float64
+	var allCredits []int
+	for rows.Next() {
+		var term string
+		var c TranscriptCourse
+		if err := rows.Scan(&term, &c.Code, &c.Title, &c.Credits, &c.Letter, &c.Gradepoints); err != nil {
+			return nil, err
+		}
+		if len(t.Terms) == 0 || t.Terms[len(t.Terms)-1].Term != term {
+			t.Terms = append(t.Terms, TranscriptTerm{Term: term})
+		}
+		last := &t.Terms[len(t.Terms)-1]
+		last.Courses = append(last.Courses, c)
+		allPoints = append(allPoints, c.Gradepoints)
+		allCredits = append(allCredits, c.Credits)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range t.Terms {
+		var points []float64
+		var credits []int
+		for _, c := range t.Terms[i].Courses {
+			points = append(points, c.Gradepoints)
+			credits = append(credits, c.Credits)
+		}
+		t.Terms[i].GPA, t.Terms[i].Credits = gradePointAverage(points, credits)
+	}
+	t.Cumulative, t.Credits = gradePointAverage(allPoints, allCredits)
+	return t, nil
+}
+
+// TranscriptKey signs issued transcripts. It is kept apart from the JWT secret so rotating
+// that secret does not invalidate every transcript ever issued.
+var TranscriptKey []byte
+
+// contentHash hashes the academic content of a transcript, excluding issue metadata and the
+// student's contact details, which the student may edit after issue
+func (t *Transcript) contentHash() string {
+	content, _ := json.Marshal(struct {
+		Studentid  int              `json:"student_id"`
+		Name       string           `json:"name"`
+		Terms      []TranscriptTerm `json:"terms"`
+		Credits    int              `json:"credits"`
+		Cumulative float64          `json:"cumulative_gpa"`
+	}{t.Student.Id, t.Student.Name, t.Terms, t.Credits, t.Cumulative})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// transcriptSignature signs a verification code and content hash with the transcript key
+func transcriptSignature(code, hash string) string {
+	mac := hmac.New(sha256.New, TranscriptKey)
+	mac.Write([]byte(code + ":" + hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newVerificationCode returns a random 16 character verification code
+func newVerificationCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// transcriptLines renders a transcript as plain text lines for the PDF
+func transcriptLines(t *Transcript) []string {
+	lines := []string{
+		"OFFICIAL ACADEMIC TRANSCRIPT",
+		"",
+		fmt.Sprintf("Student:    %s (ID %d)", t.Student.Name, t.Student.Id),
+		fmt.Sprintf("Email:      %s", t.Student.Email),
+		fmt.Sprintf("Department: %s", t.Student.Dept),
+		fmt.Sprintf("Issued:     %s", t.Issuedat.Format("2006-01-02 15:04 MST")),
+		"",
+	}
+	for _, term := range t.Terms {
+		lines = append(lines, "Term "+term.Term, fmt.Sprintf("  %-10s %-40s %7s %5s %6s", "Code", "Title", "Credits", "Grade", "Points"))
+		for _, c := range term.Courses {
+			title := c.Title
+			if len(title) > 40 {
+				title = title[:40]
+			}
+			lines = append(lines, fmt.Sprintf("  %-10s %-40s %7d %5s %6.2f", c.Code, title, c.Credits, c.Letter, c.Gradepoints))
+		}
+		lines = append(lines, fmt.Sprintf("  Term credits: %d   Term GPA: %.2f", term.Credits, term.GPA), "")
+	}
+	lines = append(lines,
+		fmt.Sprintf("Total credits: %d   Cumulative GPA: %.2f", t.Credits, t.Cumulative),
+		"",
+		"Verification code: "+t.Verificationcode,
+		"Content hash: "+t.Hash,
+		"Signature: "+t.Signature,
+		"Verify at /transcripts/verify/"+t.Verificationcode,
+	)
+	return lines
+}
+
+// GetStudentTranscriptHandler issues an official transcript as PDF (default) or JSON (?format=json), registrar only
+func (a *HybridHandler) GetStudentTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleRegistrar) {
+		return
+	}
+
+	// Extract id from URL
+	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	transcript, err := buildTranscript(a.MySQL.db, studentID)
+	if err != nil {
+		writeEnrollmentError(w, err)
+		return
+	}
+
+	// sign and record the issued transcript
+	code, err := newVerificationCode()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	transcript.Issuedat = time.Now()
+	transcript.Verificationcode = code
+	transcript.Hash = transcript.contentHash()
+	transcript.Signature = transcriptSignature(code, transcript.Hash)
+
+	actor := currentUser(r)
+	_, err = a.MySQL.db.Exec("INSERT INTO transcripts (verification_code , student_id , content_hash , signature , issued_at , issued_by) VALUES (? , ? , ? , ? , ? , ?)",
+		code, studentID, transcript.Hash, transcript.Signature, transcript.Issuedat, actor)
+	if err != nil {
+		http.Error(w, "failed to record transcript", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("ISSUE_TRANSCRIPT", actor)
+	go AuditLog("ISSUE", "TRANSCRIPT", code, actor)
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transcript)
+		return
+	}
+
+	pdf := renderPDF(transcriptLines(transcript), map[string]string{
+		"Title":    "Official Transcript - " + transcript.Student.Name,
+		"Subject":  "Verification code " + code,
+		"Keywords": "sha256:" + transcript.Hash + " sig:" + transcript.Signature,
+		"Creator":  "College Management System",
+	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=transcript-%d-%s.pdf", studentID, code))
+	w.Write(pdf)
+}
+
+// VerifyTranscriptHandler confirms a transcript was issued here and that neither the record nor the
+// student's grades changed since; ?hash= checks the hash printed on a presented document as well
+func (a *HybridHandler) VerifyTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Extract code from URL
+	code := strings.ToUpper(mux.Vars(r)["code"])
+
+	var studentID int
+	var storedHash, signature string
+	var issuedAt time.Time
+	err := a.MySQL.db.QueryRow("SELECT student_id , content_hash , signature , issued_at FROM transcripts WHERE verification_code=?", code).
+		Scan(&studentID, &storedHash, &signature, &issuedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "unknown verification code", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// signature proves the stored hash was written by this server
+	signatureValid := hmac.Equal([]byte(signature), []byte(transcriptSignature(code, storedHash)))
+
+	// recompute from current data to detect grade changes after issue
+	current, err := buildTranscript(a.MySQL.db, studentID)
+	if err != nil {
+		writeEnrollmentError(w, err)
+		return
+	}
+	recordUnchanged := current.contentHash() == storedHash
+
+	result := map[string]interface{}{
+		"verification_code": code,
+		"student_id":        studentID,
+		"student_name":      current.Student.Name,
+		"issued_at":         issuedAt,
+		"signature_valid":   signatureValid,
+		"record_unchanged":  recordUnchanged,
+	}
+	valid := signatureValid && recordUnchanged
+	if presented := r.URL.Query().Get("hash"); presented != "" {
+		documentMatches := strings.EqualFold(presented, storedHash)
+		result["document_matches"] = documentMatches
+		valid = valid && documentMatches
+	}
+	result["valid"] = valid
+
+	go LogActivity("VERIFY_TRANSCRIPT", "public")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
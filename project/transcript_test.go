@@ -0,0 +1,40 @@
+package project
+
+import "testing"
+
+func TestTranscriptHashIgnoresContactDetails(t *testing.T) {
+	issued := Transcript{
+		Student:    Student{Id: 7, Name: "Asha", Age: 19, Email: "asha@college.edu", Dept: "CS"},
+		Terms:      []TranscriptTerm{{Term: "2025-FALL", Courses: []TranscriptCourse{{Code: "CS101", Credits: 4, Letter: "A", Gradepoints: 4}}, Credits: 4, GPA: 4}},
+		Credits:    4,
+		Cumulative: 4,
+	}
+	hash := issued.contentHash()
+
+	edited := issued
+	edited.Student.Age, edited.Student.Email = 20, "asha@mail.example"
+	if edited.contentHash() != hash {
+		t.Fatal("editing age or email changed the transcript hash")
+	}
+
+	regraded := issued
+	regraded.Cumulative = 3.7
+	if regraded.contentHash() == hash {
+		t.Fatal("changing the cumulative GPA kept the transcript hash")
+	}
+}
+
+func TestTranscriptSignatureSurvivesJWTRotation(t *testing.T) {
+	defer func(jwtKey, transcriptKey []byte) { SecretKey, TranscriptKey = jwtKey, transcriptKey }(SecretKey, TranscriptKey)
+	SecretKey, TranscriptKey = []byte("jwt-1"), []byte("transcripts")
+	sig := transcriptSignature("ABCD", "hash")
+
+	SecretKey = []byte("jwt-2")
+	if transcriptSignature("ABCD", "hash") != sig {
+		t.Fatal("rotating JWT_SECRET changed the transcript signature")
+	}
+	TranscriptKey = []byte("other")
+	if transcriptSignature("ABCD", "hash") == sig {
+		t.Fatal("the transcript signature does not depend on the transcript key")
+	}
+}
@@ -0,0 +1,295 @@
+package project
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// workload statuses
+const (
+	WorkloadOK          = "ok"
+	WorkloadOverloaded  = "overloaded"
+	WorkloadUnderloaded = "underloaded"
+	WorkloadNoLimit     = "no_limit"
+)
+
+// WorkloadLimit is the contracted teaching load for a designation
+type WorkloadLimit struct {
+	Designation     string    `json:"designation"`
+	Mincredithours  int       `json:"min_credit_hours"`
+	Maxcredithours  int       `json:"max_credit_hours"`
+	Maxcontacthours float64   `json:"max_contact_hours"`
+	Maxsections     int       `json:"max_sections"`
+	Updatedby       string    `json:"updated_by,omitempty"`
+	Updatedat       time.Time `json:"updated_at"`
+}
+
+// LecturerWorkload is one lecturer's teaching load for a term.
+// Credit hours count sections taught as primary instructor, contact hours are weekly meeting hours across all sections.
+type LecturerWorkload struct {
+	Lecturerid   string   `json:"lecturer_id"`
+	Name         string   `json:"name"`
+	Designation  string   `json:"designation"`
+	Department   string   `json:"department,omitempty"`
+	Sections     int      `json:"sections"`
+	Credithours  int      `json:"credit_hours"`
+	Contacthours float64  `json:"contact_hours"`
+	Students     int      `json:"students"`
+	Status       string   `json:"status"`
+	Reasons      []string `json:"reasons,omitempty"`
+}
+
+// ValidateWorkloadLimit validates a workload limit before DB operations
+func ValidateWorkloadLimit(l WorkloadLimit) error {
+	if strings.TrimSpace(l.Designation) == "" {
+		return fmt.Errorf("designation cannot be empty")
+	}
+	if l.Mincredithours < 0 || l.Maxcredithours <= 0 || l.Mincredithours > l.Maxcredithours {
+		return fmt.Errorf("max_credit_hours must be positive and not below min_credit_hours")
+	}
+	if l.Maxcontacthours <= 0 || l.Maxcontacthours > 168 {
+		return fmt.Errorf("max_contact_hours must be between 0 and 168")
+	}
+	if l.Maxsections <= 0 {
+		return fmt.Errorf("max_sections must be greater than 0")
+	}
+	return nil
+}
+
+// loadWorkloadLimits returns the configured limits keyed by lower-cased designation
+func loadWorkloadLimits(q dbtx) (map[string]WorkloadLimit, error) {
+	rows, err := q.Query("SELECT designation , min_credit_hours , max_credit_hours , max_contact_hours , max_sections , updated_by , updated_at FROM workload_limits ORDER BY designation")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := map[string]WorkloadLimit{}
+	for rows.Next() {
+		var l WorkloadLimit
+		if err := rows.Scan(&l.Designation, &l.Mincredithours, &l.Maxcredithours, &l.Maxcontacthours, &l.Maxsections, &l.Updatedby, &l.Updatedat); err != nil {
+			return nil, err
+		}
+		limits[strings.ToLower(l.Designation)] = l
+	}
+	return limits, rows.Err()
+}
+
+// flagWorkload sets the status of a workload against its designation's limit
+func flagWorkload(wl *LecturerWorkload, limits map[string]WorkloadLimit) {
+	limit, ok := limits[strings.ToLower(strings.TrimSpace(wl.Designation))]
+	if !ok {
+		wl.Status = WorkloadNoLimit
+		return
+	}
+	wl.Status = WorkloadOK
+	if wl.Credithours > limit.Maxcredithours {
+		wl.Reasons = append(wl.Reasons, fmt.Sprintf("credit hours %d above %d", wl.Credithours, limit.Maxcredithours))
+	}
+	if wl.Contacthours > limit.Maxcontacthours {
+		wl.Reasons = append(wl.Reasons, fmt.Sprintf("contact hours %.2f above %.2f", wl.Contacthours, limit.Maxcontacthours))
+	}
+	if wl.Sections > limit.Maxsections {
+		wl.Reasons = append(wl.Reasons, fmt.Sprintf("sections %d above %d", wl.Sections, limit.Maxsections))
+	}
+	if len(wl.Reasons) > 0 {
+		wl.Status = WorkloadOverloaded
+		return
+	}
+	if wl.Credithours < limit.Mincredithours {
+		wl.Status = WorkloadUnderloaded
+		wl.Reasons = append(wl.Reasons, fmt.Sprintf("credit hours %d below %d", wl.Credithours, limit.Mincredithours))
+	}
+}
+
+// termWorkloads aggregates the MySQL teaching data of a term per lecturer id
+func termWorkloads(q dbtx, term string) (map[string]*LecturerWorkload, error) {
+	rows, err := q.Query(`SELECT si.lecturer_id , COUNT(*) ,
+		COALESCE(SUM(CASE WHEN si.role=? THEN c.credits ELSE 0 END) , 0) ,
+		COALESCE(SUM(mt.seconds) , 0) , COALESCE(SUM(en.students) , 0)
+		FROM section_instructors si
+		JOIN sections s ON s.section_id=si.section_id
+		JOIN courses c ON c.course_id=s.course_id
+		LEFT JOIN (SELECT section_id , SUM(TIME_TO_SEC(TIMEDIFF(end_time , start_time))) AS seconds FROM section_meetings GROUP BY section_id) mt ON mt.section_id=s.section_id
+		LEFT JOIN (SELECT section_id , COUNT(*) AS students FROM enrollments WHERE status IN (? , ? , ?) GROUP BY section_id) en ON en.section_id=s.section_id
+		WHERE s.term=? GROUP BY si.lecturer_id`,
+		InstructorPrimary, EnrollmentEnrolled, EnrollmentCompleted, EnrollmentFailed, term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loads := map[string]*LecturerWorkload{}
+	for rows.Next() {
+		var wl LecturerWorkload
+		var seconds float64
+		if err := rows.Scan(&wl.Lecturerid, &wl.Sections, &wl.Credithours, &seconds, &wl.Students); err != nil {
+			return nil, err
+		}
+		wl.Contacthours = round2(seconds / 3600)
+		loads[wl.Lecturerid] = &wl
+	}
+	return loads, rows.Err()
+}
+
+// writeWorkloadCSV writes the workload report as CSV
+func writeWorkloadCSV(w http.ResponseWriter, term string, report []LecturerWorkload) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=workload-%s.csv", term))
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"lecturer_id", "name", "designation", "department", "sections", "credit_hours", "contact_hours", "students", "status", "reasons"})
+	for _, wl := range report {
+		cw.Write([]string{
+			wl.Lecturerid, wl.Name, wl.Designation, wl.Department,
+			strconv.Itoa(wl.Sections), strconv.Itoa(wl.Credithours),
+			strconv.FormatFloat(wl.Contacthours, 'f', 2, 64), strconv.Itoa(wl.Students),
+			wl.Status, strings.Join(wl.Reasons, "; "),
+		})
+	}
+	cw.Flush()
+}
+
+// SaveWorkloadLimitHandler creates or replaces the limit of a designation, hr only
+func (a *HybridHandler) SaveWorkloadLimitHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireRole(w, r, RoleHR) {
+		return
+	}
+
+	// Decode incoming JSON request body
+	var limit WorkloadLimit
+	if err := json.NewDecoder(r.Body).Decode(&limit); err != nil {
+		http.Error(w, "failed to decode response", http.StatusInternalServerError)
+		return
+	}
+	limit.Designation = mux.Vars(r)["designation"]
+
+	// validate limit data
+	if err := ValidateWorkloadLimit(limit); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"err": err.Error()})
+		return
+	}
+
+	limit.Updatedby, limit.Updatedat = currentUser(r), time.Now()
+	_, err := a.MySQL.db.Exec(`INSERT INTO workload_limits (designation , min_credit_hours , max_credit_hours , max_contact_hours , max_sections , updated_by , updated_at)
+		VALUES (? , ? , ? , ? , ? , ? , ?)
+		ON DUPLICATE KEY UPDATE min_credit_hours=VALUES(min_credit_hours) , max_credit_hours=VALUES(max_credit_hours) ,
+		max_contact_hours=VALUES(max_contact_hours) , max_sections=VALUES(max_sections) , updated_by=VALUES(updated_by) , updated_at=VALUES(updated_at)`,
+		limit.Designation, limit.Mincredithours, limit.Maxcredithours, limit.Maxcontacthours, limit.Maxsections, limit.Updatedby, limit.Updatedat)
+	if err != nil {
+		http.Error(w, "failed to save workload limit", http.StatusInternalServerError)
+		return
+	}
+
+	go LogActivity("SAVE_WORKLOAD_LIMIT", limit.Updatedby)
+	go AuditLog("SAVE", "WORKLOAD_LIMIT", limit.Designation, limit.Updatedby)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limit)
+}
+
+// GetWorkloadLimitsHandler lists the configured limits
+func (a *HybridHandler) GetWorkloadLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	limits, err := loadWorkloadLimits(a.MySQL.db)
+	if err != nil {
+		http.Error(w, "unable to fetch workload limits", http.StatusInternalServerError)
+		return
+	}
+	list := []WorkloadLimit{}
+	for _, l := range limits {
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Designation < list[j].Designation })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetWorkloadReportHandler reports every lecturer's load for ?term= against the limit of their designation,
+// optionally for one ?department= or ?status=. ?format=csv returns a CSV download. hod, hr or registrar only.
+func (a *HybridHandler) GetWorkloadReportHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.requireAnyRole(w, r, RoleHOD, RoleHR, RoleRegistrar) {
+		return
+	}
+	term := r.URL.Query().Get("term")
+	if term == "" {
+		http.Error(w, "term is required", http.StatusBadRequest)
+		return
+	}
+
+	loads, err := termWorkloads(a.MySQL.db, term)
+	if err != nil {
+		http.Error(w, "unable to compute workloads", http.StatusInternalServerError)
+		return
+	}
+	limits, err := loadWorkloadLimits(a.MySQL.db)
+	if err != nil {
+		http.Error(w, "unable to fetch workload limits", http.StatusInternalServerError)
+		return
+	}
+
+	// all lecturers from the lecturer store, so idle staff show up as underloaded
+	ctx, cancel := context.WithTimeout(a.Ctx, 10*time.Second)
+	defer cancel()
+	department := r.URL.Query().Get("department")
+	lecturers, err := a.Lecturers.FindAll(ctx, department)
+	if err != nil {
+		http.Error(w, "failed to fetch lecturers", http.StatusInternalServerError)
+		return
+	}
+
+	report := []LecturerWorkload{}
+	for _, l := range lecturers {
+		wl := LecturerWorkload{Lecturerid: l.Id.Hex()}
+		if load, ok := loads[wl.Lecturerid]; ok {
+			wl = *load
+			delete(loads, wl.Lecturerid)
+		}
+		wl.Name, wl.Designation, wl.Department = l.Name, l.Designation, l.Department
+		report = append(report, wl)
+	}
+	// instructors whose lecturer record no longer exists
+	if department == "" {
+		for _, load := range loads {
+			report = append(report, *load)
+		}
+	}
+
+	status := r.URL.Query().Get("status")
+	filtered := report[:0]
+	for i := range report {
+		flagWorkload(&report[i], limits)
+		if status == "" || report[i].Status == status {
+			filtered = append(filtered, report[i])
+		}
+	}
+	report = filtered
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Department != report[j].Department {
+			return report[i].Department < report[j].Department
+		}
+		if report[i].Name != report[j].Name {
+			return report[i].Name < report[j].Name
+		}
+		return report[i].Lecturerid < report[j].Lecturerid
+	})
+
+	go LogActivity("WORKLOAD_REPORT", currentUser(r))
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeWorkloadCSV(w, term, report)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"term": term, "lecturers": report})
+}